@@ -0,0 +1,118 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"os"
+	"time"
+
+	"consulutil"
+)
+
+// adminToken guards the /gateway/* operator endpoints. When empty, those
+// endpoints are disabled entirely.
+var adminToken = os.Getenv("GATEWAY_ADMIN_TOKEN")
+
+// requireAdmin rejects requests that do not present the configured admin
+// token in the X-Admin-Token header.
+func requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		presented := r.Header.Get("X-Admin-Token")
+		if adminToken == "" || subtle.ConstantTimeCompare([]byte(presented), []byte(adminToken)) != 1 {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// handleBreakers lists the circuit breaker state of every upstream service.
+func handleBreakers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"breakers": breakers.snapshots(),
+	})
+}
+
+// handleStats reports gateway counters with derived ratios for operators.
+func handleStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"discovery_cache": discovery.Stats(),
+	})
+}
+
+// handleResolve runs the routing logic for ?path= without proxying and
+// reports which service, instance, and rewritten path it would use. An
+// X-Canary header is honoured as it would be on the proxied request.
+func handleResolve(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Query().Get("path")
+	if path == "" {
+		http.Error(w, "Missing path query parameter", http.StatusBadRequest)
+		return
+	}
+
+	serviceName, forwardPath, err := parseRoute(path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	target, err := discoverService(serviceName, routingTag(r))
+	if err != nil {
+		http.Error(w, "Service not available: "+err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"service":      serviceName,
+		"instance":     target.String(),
+		"forward_path": forwardPath,
+	})
+}
+
+// discoveredService is one entry of the /_gateway/services listing.
+type discoveredService struct {
+	Name      string                `json:"name"`
+	Instances []consulutil.Instance `json:"instances"`
+	// CachedAt is when the discovery cache last refreshed this service; it
+	// is omitted while nothing is cached.
+	CachedAt *time.Time `json:"cached_at,omitempty"`
+}
+
+// handleServices lists every service registered in Consul with its healthy
+// instances, queried live so it shows what the next cache refresh would see.
+func handleServices(w http.ResponseWriter, r *http.Request) {
+	if consul == nil {
+		http.Error(w, "Consul discovery is not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	names, err := consul.Services()
+	if err != nil {
+		http.Error(w, "Service listing failed: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	services := make([]discoveredService, 0, len(names))
+	for _, name := range names {
+		instances, err := consul.Instances(name)
+		if err != nil {
+			http.Error(w, "Service listing failed: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		entry := discoveredService{Name: name, Instances: instances}
+		if fetchedAt, ok := discovery.FetchedAt(name); ok {
+			entry.CachedAt = &fetchedAt
+		}
+		services = append(services, entry)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"cache_mode": discovery.mode,
+		"services":   services,
+	})
+}
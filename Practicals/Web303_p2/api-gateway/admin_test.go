@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withAdminToken(t *testing.T, token string) {
+	orig := adminToken
+	adminToken = token
+	t.Cleanup(func() { adminToken = orig })
+}
+
+func TestHandleBreakersListsState(t *testing.T) {
+	withAdminToken(t, "secret")
+
+	origBreakers := breakers
+	breakers = newBreakerRegistry(2, time.Minute)
+	defer func() { breakers = origBreakers }()
+
+	open := breakers.get("users-service")
+	open.RecordFailure()
+	open.RecordFailure()
+	breakers.get("products-service").RecordSuccess()
+
+	req := httptest.NewRequest(http.MethodGet, "/gateway/breakers", nil)
+	req.Header.Set("X-Admin-Token", "secret")
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var body struct {
+		Breakers []breakerSnapshot `json:"breakers"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	require.Len(t, body.Breakers, 2)
+
+	assert.Equal(t, "products-service", body.Breakers[0].Service)
+	assert.Equal(t, "closed", body.Breakers[0].State)
+	assert.Zero(t, body.Breakers[0].HalfOpenInSeconds)
+
+	assert.Equal(t, "users-service", body.Breakers[1].Service)
+	assert.Equal(t, "open", body.Breakers[1].State)
+	assert.Equal(t, 2, body.Breakers[1].Failures)
+	assert.InDelta(t, 60, body.Breakers[1].HalfOpenInSeconds, 1)
+}
+
+func TestHandleBreakersRequiresAdmin(t *testing.T) {
+	withAdminToken(t, "secret")
+
+	req := httptest.NewRequest(http.MethodGet, "/gateway/breakers", nil)
+	req.Header.Set("X-Admin-Token", "wrong")
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestHandleResolveReportsRoute(t *testing.T) {
+	withAdminToken(t, "secret")
+	withFakeConsul(t, &fakeHealth{entries: map[string][]*consulapi.ServiceEntry{
+		"users-service": {serviceEntry("users-1", "10.0.0.5", 8081)},
+	}})
+
+	req := httptest.NewRequest(http.MethodGet, "/gateway/resolve?path=/api/users/42", nil)
+	req.Header.Set("X-Admin-Token", "secret")
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{
+		"service": "users-service",
+		"instance": "http://10.0.0.5:8081",
+		"forward_path": "/42"
+	}`, rec.Body.String())
+}
+
+func TestHandleResolveInvalidPath(t *testing.T) {
+	withAdminToken(t, "secret")
+
+	req := httptest.NewRequest(http.MethodGet, "/gateway/resolve?path=/nope", nil)
+	req.Header.Set("X-Admin-Token", "secret")
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestHandleServicesListsDiscoveredBackends(t *testing.T) {
+	withAdminToken(t, "secret")
+	withFakeConsul(t, &fakeHealth{entries: map[string][]*consulapi.ServiceEntry{
+		"users-service":    {serviceEntry("users-1", "10.0.0.5", 8081), serviceEntry("users-2", "10.0.0.6", 8081)},
+		"products-service": {serviceEntry("products-1", "10.0.0.7", 8082)},
+	}})
+	discovery = newServiceCache(cacheLazy, time.Minute, lookupInstances)
+	_, err := discovery.Get("users-service")
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/_gateway/services", nil)
+	req.Header.Set("X-Admin-Token", "secret")
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var body struct {
+		CacheMode string `json:"cache_mode"`
+		Services  []struct {
+			Name      string `json:"name"`
+			Instances []struct {
+				ID      string `json:"id"`
+				Address string `json:"address"`
+				Port    int    `json:"port"`
+			} `json:"instances"`
+			CachedAt *time.Time `json:"cached_at"`
+		} `json:"services"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+
+	assert.Equal(t, "lazy", body.CacheMode)
+	require.Len(t, body.Services, 2)
+	products, users := body.Services[0], body.Services[1]
+	assert.Equal(t, "products-service", products.Name)
+	require.Len(t, products.Instances, 1)
+	assert.Equal(t, "10.0.0.7", products.Instances[0].Address)
+	assert.Equal(t, 8082, products.Instances[0].Port)
+	assert.Nil(t, products.CachedAt, "never looked up through the cache")
+
+	assert.Equal(t, "users-service", users.Name)
+	require.Len(t, users.Instances, 2)
+	assert.Equal(t, "users-1", users.Instances[0].ID)
+	assert.Equal(t, "10.0.0.6", users.Instances[1].Address)
+	assert.NotNil(t, users.CachedAt)
+}
+
+func TestHandleServicesIsNotProxied(t *testing.T) {
+	withAdminToken(t, "secret")
+	withFakeConsul(t, &fakeHealth{entries: map[string][]*consulapi.ServiceEntry{}})
+
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/_gateway/services", nil))
+	assert.Equal(t, http.StatusForbidden, rec.Code, "admin endpoint, not a route to a '_gateway' service")
+}
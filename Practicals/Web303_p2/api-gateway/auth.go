@@ -0,0 +1,278 @@
+package main
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var errMissingToken = errors.New("missing bearer token")
+
+// jwtValidator verifies bearer tokens presented to the gateway. HS256 tokens
+// are checked against hmacSecret and RS256 tokens against rsaKeys, which are
+// indexed by JWKS key ID.
+type jwtValidator struct {
+	hmacSecret []byte
+	rsaKeys    map[string]*rsa.PublicKey
+}
+
+// Enabled reports whether a verification key has been configured.
+func (v *jwtValidator) Enabled() bool {
+	return v != nil && (len(v.hmacSecret) > 0 || len(v.rsaKeys) > 0)
+}
+
+// methods lists the signing algorithms a configured key can verify, so a
+// token cannot pick an algorithm the gateway has no key for.
+func (v *jwtValidator) methods() []string {
+	var methods []string
+	if len(v.hmacSecret) > 0 {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if len(v.rsaKeys) > 0 {
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	return methods
+}
+
+// Validate parses and verifies a token, returning its claims. Expiry and
+// not-before are enforced by the parser.
+func (v *jwtValidator) Validate(raw string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, v.key, jwt.WithValidMethods(v.methods()))
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %w", err)
+	}
+	return claims, nil
+}
+
+// key selects the verification key for a token. RS256 tokens are matched on
+// their kid header; a kid may only be omitted when the JWKS holds one key.
+func (v *jwtValidator) key(t *jwt.Token) (interface{}, error) {
+	if t.Method.Alg() == jwt.SigningMethodHS256.Alg() {
+		return v.hmacSecret, nil
+	}
+	kid, _ := t.Header["kid"].(string)
+	if key, ok := v.rsaKeys[kid]; ok {
+		return key, nil
+	}
+	if kid == "" && len(v.rsaKeys) == 1 {
+		for _, key := range v.rsaKeys {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// jwksFetchTimeout bounds the startup fetch of a remote JWKS document.
+const jwksFetchTimeout = 10 * time.Second
+
+// loadJWKS reads the RSA signing keys from a JWKS document at an http(s)
+// URL or a local file path. Keys marked for encryption are ignored.
+func loadJWKS(source string) (map[string]*rsa.PublicKey, error) {
+	var body []byte
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		client := &http.Client{Timeout: jwksFetchTimeout}
+		resp, err := client.Get(source)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("fetching %s: status %d", source, resp.StatusCode)
+		}
+		if body, err = io.ReadAll(resp.Body); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		if body, err = os.ReadFile(source); err != nil {
+			return nil, err
+		}
+	}
+
+	var doc struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("parsing JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, jwk := range doc.Keys {
+		if jwk.Kty != "RSA" || jwk.Use == "enc" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: invalid modulus: %w", jwk.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("key %q: invalid exponent", jwk.Kid)
+		}
+		keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS contains no RSA signing keys")
+	}
+	return keys, nil
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer ..." header.
+func bearerToken(r *http.Request) (string, error) {
+	header := r.Header.Get("Authorization")
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", errMissingToken
+	}
+	return strings.TrimSpace(token), nil
+}
+
+// authValidator is configured from GATEWAY_JWT_SECRET and GATEWAY_JWT_JWKS
+// in main. When nil, proxied requests are not authenticated.
+var authValidator *jwtValidator
+
+// Trusted identity headers set from validated claims. Client-supplied copies
+// are always removed so upstreams can rely on them.
+const (
+	userIDHeader    = "X-User-ID"
+	userRolesHeader = "X-User-Roles"
+)
+
+// defaultPublicPaths are reachable without a token unless
+// GATEWAY_AUTH_PUBLIC_PATHS overrides them.
+const defaultPublicPaths = "/api/*/health"
+
+// publicPaths holds path.Match patterns exempt from authentication.
+var publicPaths = splitList(defaultPublicPaths)
+
+// splitList splits a comma-separated list, dropping blank entries.
+func splitList(raw string) []string {
+	var patterns []string
+	for _, p := range strings.Split(raw, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// isPublicPath reports whether the request path matches a public pattern.
+func isPublicPath(p string) bool {
+	p = path.Clean(p)
+	for _, pattern := range publicPaths {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// requireAuth validates the bearer token on proxied requests and forwards
+// the caller's identity to upstreams as trusted headers.
+func requireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Del(userIDHeader)
+		r.Header.Del(userRolesHeader)
+		if !authValidator.Enabled() || isPublicPath(r.URL.Path) {
+			next(w, r)
+			return
+		}
+
+		token, err := bearerToken(r)
+		if err == nil {
+			var claims jwt.MapClaims
+			if claims, err = authValidator.Validate(token); err == nil {
+				forwardIdentity(r, claims)
+				next(w, r)
+				return
+			}
+		}
+		logFor(r).Info("rejected unauthenticated request", "error", err)
+		rejectUnauthorized(w, err)
+	}
+}
+
+// forwardIdentity copies the subject and roles claims onto the request.
+func forwardIdentity(r *http.Request, claims jwt.MapClaims) {
+	if sub, err := claims.GetSubject(); err == nil && sub != "" {
+		r.Header.Set(userIDHeader, sub)
+	}
+	var roles []string
+	switch v := claims["roles"].(type) {
+	case string:
+		roles = splitList(v)
+	case []interface{}:
+		for _, role := range v {
+			if s, ok := role.(string); ok && s != "" {
+				roles = append(roles, s)
+			}
+		}
+	}
+	if len(roles) > 0 {
+		r.Header.Set(userRolesHeader, strings.Join(roles, ","))
+	}
+}
+
+// reasonUnauthorized marks requests refused for a missing or bad token.
+const reasonUnauthorized = "unauthorized"
+
+// rejectUnauthorized answers 401 with a bearer challenge.
+func rejectUnauthorized(w http.ResponseWriter, err error) {
+	challenge := `Bearer`
+	if !errors.Is(err, errMissingToken) {
+		challenge = `Bearer error="invalid_token"`
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	w.Header().Set("X-Gateway-Reason", reasonUnauthorized)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error(), "reason": reasonUnauthorized})
+}
+
+// sensitiveClaims are never echoed back by the verify endpoint.
+var sensitiveClaims = []string{"password", "secret", "api_key", "refresh_token"}
+
+// handleAuthVerify validates the caller's bearer token without proxying and
+// returns its non-sensitive claims.
+func handleAuthVerify(w http.ResponseWriter, r *http.Request) {
+	if !authValidator.Enabled() {
+		http.Error(w, "Authentication is not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	token, err := bearerToken(r)
+	if err == nil {
+		var claims jwt.MapClaims
+		if claims, err = authValidator.Validate(token); err == nil {
+			for _, name := range sensitiveClaims {
+				delete(claims, name)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{"valid": true, "claims": claims})
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(map[string]interface{}{"valid": false, "error": err.Error()})
+}
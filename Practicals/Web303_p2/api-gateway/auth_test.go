@@ -0,0 +1,185 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testJWTSecret = "test-secret"
+
+func withJWTSecret(t *testing.T) {
+	orig := authValidator
+	authValidator = &jwtValidator{hmacSecret: []byte(testJWTSecret)}
+	t.Cleanup(func() { authValidator = orig })
+}
+
+func signToken(t *testing.T, claims jwt.MapClaims) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testJWTSecret))
+	require.NoError(t, err)
+	return token
+}
+
+func verify(token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/gateway/auth/verify", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAuthVerifyValidToken(t *testing.T) {
+	withJWTSecret(t)
+	token := signToken(t, jwt.MapClaims{
+		"sub":      "42",
+		"roles":    []string{"owner"},
+		"password": "hunter2",
+		"exp":      time.Now().Add(time.Hour).Unix(),
+	})
+
+	rec := verify(token)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var body struct {
+		Valid  bool                   `json:"valid"`
+		Claims map[string]interface{} `json:"claims"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.True(t, body.Valid)
+	assert.Equal(t, "42", body.Claims["sub"])
+	assert.NotContains(t, body.Claims, "password")
+}
+
+func TestAuthVerifyExpiredToken(t *testing.T) {
+	withJWTSecret(t)
+	token := signToken(t, jwt.MapClaims{"sub": "42", "exp": time.Now().Add(-time.Minute).Unix()})
+
+	rec := verify(token)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"valid":false`)
+}
+
+func TestAuthVerifyMissingToken(t *testing.T) {
+	withJWTSecret(t)
+	assert.Equal(t, http.StatusUnauthorized, verify("").Code)
+}
+
+// proxyAuthed sends a request through the router to a users-service upstream
+// and returns the headers the upstream saw.
+func proxyAuthed(t *testing.T, path string, headers map[string]string) (*httptest.ResponseRecorder, http.Header) {
+	var seen http.Header
+	withUpstream(t, func(w http.ResponseWriter, r *http.Request) { seen = r.Header.Clone() })
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, req)
+	return rec, seen
+}
+
+func TestRequireAuthForwardsClaims(t *testing.T) {
+	withJWTSecret(t)
+	token := signToken(t, jwt.MapClaims{
+		"sub":   "42",
+		"roles": []string{"owner", "staff"},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	})
+
+	rec, seen := proxyAuthed(t, "/api/users/1", map[string]string{"Authorization": "Bearer " + token})
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "42", seen.Get("X-User-ID"))
+	assert.Equal(t, "owner,staff", seen.Get("X-User-Roles"))
+}
+
+func TestRequireAuthRejectsExpiredToken(t *testing.T) {
+	withJWTSecret(t)
+	token := signToken(t, jwt.MapClaims{"sub": "42", "exp": time.Now().Add(-time.Minute).Unix()})
+
+	rec, seen := proxyAuthed(t, "/api/users/1", map[string]string{"Authorization": "Bearer " + token})
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, `Bearer error="invalid_token"`, rec.Header().Get("WWW-Authenticate"))
+	assert.Nil(t, seen, "request must not reach the upstream")
+}
+
+func TestRequireAuthRejectsMissingToken(t *testing.T) {
+	withJWTSecret(t)
+
+	rec, seen := proxyAuthed(t, "/api/users/1", nil)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, "unauthorized", rec.Header().Get("X-Gateway-Reason"))
+	assert.Nil(t, seen)
+}
+
+func TestRequireAuthStripsSpoofedIdentity(t *testing.T) {
+	withJWTSecret(t)
+	token := signToken(t, jwt.MapClaims{"sub": "42", "exp": time.Now().Add(time.Hour).Unix()})
+
+	rec, seen := proxyAuthed(t, "/api/users/1", map[string]string{
+		"Authorization": "Bearer " + token,
+		"X-User-ID":     "1",
+		"X-User-Roles":  "admin",
+	})
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "42", seen.Get("X-User-ID"))
+	assert.Empty(t, seen.Get("X-User-Roles"), "token carries no roles")
+}
+
+func TestRequireAuthAllowsPublicPaths(t *testing.T) {
+	withJWTSecret(t)
+
+	rec, seen := proxyAuthed(t, "/api/users/health", map[string]string{"X-User-ID": "1"})
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, seen.Get("X-User-ID"), "spoofed identity is stripped on public paths too")
+}
+
+func TestRequireAuthVerifiesRS256AgainstJWKS(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	jwks := fmt.Sprintf(`{"keys": [{"kty": "RSA", "kid": "k1", "use": "sig", "n": %q, "e": %q}]}`,
+		base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()))
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, []byte(jwks), 0o600))
+
+	keys, err := loadJWKS(path)
+	require.NoError(t, err)
+	orig := authValidator
+	authValidator = &jwtValidator{rsaKeys: keys}
+	t.Cleanup(func() { authValidator = orig })
+
+	signed := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"sub": "7", "exp": time.Now().Add(time.Hour).Unix()})
+	signed.Header["kid"] = "k1"
+	token, err := signed.SignedString(key)
+	require.NoError(t, err)
+
+	rec, seen := proxyAuthed(t, "/api/users/1", map[string]string{"Authorization": "Bearer " + token})
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "7", seen.Get("X-User-ID"))
+
+	// An HS256 token is refused when only RSA keys are configured
+	rec, _ = proxyAuthed(t, "/api/users/1", map[string]string{"Authorization": "Bearer " + signToken(t, jwt.MapClaims{"sub": "7"})})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"consulutil"
+)
+
+// LoadBalancer chooses one of a service's healthy instances for a request.
+// instances is never empty.
+type LoadBalancer interface {
+	Pick(service string, instances []*url.URL) *url.URL
+}
+
+// roundRobin cycles through instances with an independent counter per service.
+type roundRobin struct {
+	mu       sync.Mutex
+	counters map[string]*atomic.Uint64
+}
+
+func newRoundRobin() *roundRobin {
+	return &roundRobin{counters: make(map[string]*atomic.Uint64)}
+}
+
+func (rr *roundRobin) Pick(service string, instances []*url.URL) *url.URL {
+	rr.mu.Lock()
+	counter, ok := rr.counters[service]
+	if !ok {
+		counter = new(atomic.Uint64)
+		rr.counters[service] = counter
+	}
+	rr.mu.Unlock()
+
+	n := counter.Add(1) - 1
+	return instances[n%uint64(len(instances))]
+}
+
+// randomBalancer picks an instance uniformly at random.
+type randomBalancer struct{}
+
+func (randomBalancer) Pick(service string, instances []*url.URL) *url.URL {
+	return instances[rand.IntN(len(instances))]
+}
+
+// weightTable remembers the Consul "weight" metadata of each instance, keyed
+// by service and host:port, as of the latest lookup.
+type weightTable struct {
+	mu        sync.RWMutex
+	byService map[string]map[string]int
+}
+
+func newWeightTable() *weightTable {
+	return &weightTable{byService: make(map[string]map[string]int)}
+}
+
+// Update replaces the weights recorded for service.
+func (t *weightTable) Update(service string, instances []consulutil.Instance) {
+	weights := make(map[string]int, len(instances))
+	for _, instance := range instances {
+		weights[net.JoinHostPort(instance.Address, strconv.Itoa(instance.Port))] = instanceWeight(service, instance)
+	}
+	t.mu.Lock()
+	t.byService[service] = weights
+	t.mu.Unlock()
+}
+
+// Weight is the recorded weight of host within service, or 1 if unknown.
+func (t *weightTable) Weight(service, host string) int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if w, ok := t.byService[service][host]; ok {
+		return w
+	}
+	return 1
+}
+
+// instanceWeight parses Meta["weight"], treating a missing value as 1 and an
+// invalid one as 1 with a warning.
+func instanceWeight(service string, instance consulutil.Instance) int {
+	raw, ok := instance.Meta["weight"]
+	if !ok {
+		return 1
+	}
+	w, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil || w < 1 {
+		log.Printf("Ignoring invalid weight %q on '%s' instance %s, using 1", raw, service, instance.ID)
+		return 1
+	}
+	return w
+}
+
+// instanceWeights is refreshed by every Consul lookup.
+var instanceWeights = newWeightTable()
+
+// weightedBalancer picks an instance at random with probability proportional
+// to its weight, so an instance with weight 2 takes twice the traffic of one
+// with weight 1.
+type weightedBalancer struct {
+	weights *weightTable
+}
+
+func (b weightedBalancer) Pick(service string, instances []*url.URL) *url.URL {
+	total := 0
+	for _, instance := range instances {
+		total += b.weights.Weight(service, instance.Host)
+	}
+	n := rand.IntN(total)
+	for _, instance := range instances {
+		if n -= b.weights.Weight(service, instance.Host); n < 0 {
+			return instance
+		}
+	}
+	return instances[len(instances)-1]
+}
+
+// newLoadBalancer returns the strategy named by GATEWAY_LOAD_BALANCER.
+func newLoadBalancer(name string) (LoadBalancer, error) {
+	switch name {
+	case "", "round_robin":
+		return newRoundRobin(), nil
+	case "random":
+		return randomBalancer{}, nil
+	case "weighted":
+		return weightedBalancer{weights: instanceWeights}, nil
+	default:
+		return nil, fmt.Errorf("unknown load balancer %q", name)
+	}
+}
+
+// balancer spreads requests across the instances discovery returns.
+var balancer LoadBalancer = newRoundRobin()
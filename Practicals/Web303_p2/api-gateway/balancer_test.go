@@ -0,0 +1,132 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+
+	"consulutil"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withBalancer(t *testing.T, lb LoadBalancer) {
+	orig := balancer
+	balancer = lb
+	t.Cleanup(func() { balancer = orig })
+}
+
+func threeUsersInstances(t *testing.T) {
+	withFakeConsul(t, &fakeHealth{entries: map[string][]*consulapi.ServiceEntry{
+		"users-service": {
+			serviceEntry("users-1", "10.0.0.1", 8081),
+			serviceEntry("users-2", "10.0.0.2", 8081),
+			serviceEntry("users-3", "10.0.0.3", 8081),
+		},
+	}})
+}
+
+func TestDiscoverServiceRoundRobin(t *testing.T) {
+	threeUsersInstances(t)
+	withBalancer(t, newRoundRobin())
+
+	hits := make(map[string]int)
+	for i := 0; i < 30; i++ {
+		target, err := discoverService("users-service", "")
+		require.NoError(t, err)
+		hits[target.Host]++
+	}
+
+	assert.Equal(t, map[string]int{"10.0.0.1:8081": 10, "10.0.0.2:8081": 10, "10.0.0.3:8081": 10}, hits)
+}
+
+func TestDiscoverServiceRandomSpreadsLoad(t *testing.T) {
+	threeUsersInstances(t)
+	withBalancer(t, randomBalancer{})
+
+	hits := make(map[string]int)
+	for i := 0; i < 300; i++ {
+		target, err := discoverService("users-service", "")
+		require.NoError(t, err)
+		hits[target.Host]++
+	}
+
+	require.Len(t, hits, 3)
+	for host, n := range hits {
+		assert.InDelta(t, 100, n, 50, host)
+	}
+}
+
+func TestRoundRobinCountersArePerService(t *testing.T) {
+	rr := newRoundRobin()
+	a, b := &url.URL{Host: "a"}, &url.URL{Host: "b"}
+
+	assert.Equal(t, a, rr.Pick("users-service", []*url.URL{a, b}))
+	assert.Equal(t, a, rr.Pick("products-service", []*url.URL{a, b}))
+	assert.Equal(t, b, rr.Pick("users-service", []*url.URL{a, b}))
+}
+
+func TestNewLoadBalancer(t *testing.T) {
+	lb, err := newLoadBalancer("")
+	require.NoError(t, err)
+	assert.IsType(t, &roundRobin{}, lb)
+
+	lb, err = newLoadBalancer("random")
+	require.NoError(t, err)
+	assert.IsType(t, randomBalancer{}, lb)
+
+	lb, err = newLoadBalancer("weighted")
+	require.NoError(t, err)
+	assert.IsType(t, weightedBalancer{}, lb)
+
+	_, err = newLoadBalancer("least_conn")
+	assert.Error(t, err)
+}
+
+func weightedEntry(id, address, weight string) *consulapi.ServiceEntry {
+	entry := serviceEntry(id, address, 8081)
+	if weight != "" {
+		entry.Service.Meta = map[string]string{"weight": weight}
+	}
+	return entry
+}
+
+func TestDiscoverServiceWeighted(t *testing.T) {
+	withFakeConsul(t, &fakeHealth{entries: map[string][]*consulapi.ServiceEntry{
+		"users-service": {
+			weightedEntry("users-1", "10.0.0.1", "1"),
+			weightedEntry("users-2", "10.0.0.2", "2"),
+			weightedEntry("users-3", "10.0.0.3", "7"),
+		},
+	}})
+	withBalancer(t, weightedBalancer{weights: instanceWeights})
+
+	const iterations = 10000
+	hits := make(map[string]int)
+	for i := 0; i < iterations; i++ {
+		target, err := discoverService("users-service", "")
+		require.NoError(t, err)
+		hits[target.Host]++
+	}
+
+	assert.InDelta(t, 0.1, float64(hits["10.0.0.1:8081"])/iterations, 0.02)
+	assert.InDelta(t, 0.2, float64(hits["10.0.0.2:8081"])/iterations, 0.03)
+	assert.InDelta(t, 0.7, float64(hits["10.0.0.3:8081"])/iterations, 0.03)
+}
+
+func TestInstanceWeightDefaults(t *testing.T) {
+	weights := newWeightTable()
+	weights.Update("users-service", []consulutil.Instance{
+		{ID: "missing", Address: "10.0.0.1", Port: 8081},
+		{ID: "garbage", Address: "10.0.0.2", Port: 8081, Meta: map[string]string{"weight": "heavy"}},
+		{ID: "zero", Address: "10.0.0.3", Port: 8081, Meta: map[string]string{"weight": "0"}},
+		{ID: "five", Address: "10.0.0.4", Port: 8081, Meta: map[string]string{"weight": " 5 "}},
+	})
+
+	assert.Equal(t, 1, weights.Weight("users-service", "10.0.0.1:8081"))
+	assert.Equal(t, 1, weights.Weight("users-service", "10.0.0.2:8081"))
+	assert.Equal(t, 1, weights.Weight("users-service", "10.0.0.3:8081"))
+	assert.Equal(t, 5, weights.Weight("users-service", "10.0.0.4:8081"))
+	assert.Equal(t, 1, weights.Weight("products-service", "10.0.0.4:8081"), "unknown instances weigh 1")
+}
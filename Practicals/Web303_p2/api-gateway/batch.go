@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+
+	"consulutil"
+)
+
+const (
+	defaultBatchConcurrency = 8
+	defaultBatchMaxRequests = 100
+)
+
+var (
+	// batchConcurrency bounds how many sub-requests run at once.
+	batchConcurrency = defaultBatchConcurrency
+	// batchMaxRequests is the largest batch accepted before returning 400.
+	batchMaxRequests = defaultBatchMaxRequests
+	// batchHandler executes each sub-request; it is the proxy in production.
+	batchHandler http.Handler = requireAuth(routeRequest)
+)
+
+type batchSubRequest struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+type batchSubResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body"`
+}
+
+// handleBatch fans a list of sub-requests out through the proxy using a
+// bounded worker pool and returns their responses in request order.
+func handleBatch(w http.ResponseWriter, r *http.Request) {
+	var batch struct {
+		Requests []batchSubRequest `json:"requests"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+		http.Error(w, "Invalid batch body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(batch.Requests) == 0 {
+		http.Error(w, "Batch must contain at least one request", http.StatusBadRequest)
+		return
+	}
+	if len(batch.Requests) > batchMaxRequests {
+		http.Error(w, fmt.Sprintf("Batch exceeds maximum of %d requests", batchMaxRequests), http.StatusBadRequest)
+		return
+	}
+
+	results := make([]batchSubResponse, len(batch.Requests))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	workers := batchConcurrency
+	if workers > len(batch.Requests) {
+		workers = len(batch.Requests)
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				results[idx] = runSubRequest(r, batch.Requests[idx])
+			}
+		}()
+	}
+	for idx := range batch.Requests {
+		jobs <- idx
+	}
+	close(jobs)
+	wg.Wait()
+
+	log.Printf("Completed batch of %d sub-requests", len(results))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"responses": results})
+}
+
+// runSubRequest executes one sub-request in the context of the parent.
+func runSubRequest(parent *http.Request, sub batchSubRequest) batchSubResponse {
+	method := sub.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	req, err := http.NewRequestWithContext(parent.Context(), method, sub.Path, bytes.NewReader(sub.Body))
+	if err != nil {
+		return batchSubResponse{Status: http.StatusBadRequest, Body: "invalid sub-request: " + err.Error()}
+	}
+	req.RemoteAddr = parent.RemoteAddr
+	for k, v := range sub.Headers {
+		req.Header.Set(k, v)
+	}
+	// Sub-requests act as the batch caller unless they name their own token
+	if req.Header.Get("Authorization") == "" {
+		req.Header.Set("Authorization", parent.Header.Get("Authorization"))
+	}
+
+	buf := consulutil.NewResponseBuffer()
+	batchHandler.ServeHTTP(buf, req)
+
+	headers := make(map[string]string, len(buf.Header()))
+	for k := range buf.Header() {
+		headers[k] = buf.Header().Get(k)
+	}
+	return batchSubResponse{Status: buf.Status(), Headers: headers, Body: buf.Body()}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func batchBody(n int) string {
+	subs := make([]string, n)
+	for i := range subs {
+		subs[i] = fmt.Sprintf(`{"method":"GET","path":"/api/users/%d"}`, i)
+	}
+	return `{"requests":[` + strings.Join(subs, ",") + `]}`
+}
+
+func TestHandleBatchBoundsConcurrencyAndPreservesOrder(t *testing.T) {
+	var inFlight, peak int32
+	origHandler, origLimit := batchHandler, batchConcurrency
+	batchConcurrency = 4
+	batchHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(2 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		fmt.Fprint(w, r.URL.Path)
+	})
+	defer func() { batchHandler, batchConcurrency = origHandler, origLimit }()
+
+	req := httptest.NewRequest(http.MethodPost, "/gateway/batch", strings.NewReader(batchBody(50)))
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var body struct {
+		Responses []batchSubResponse `json:"responses"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	require.Len(t, body.Responses, 50)
+	for i, resp := range body.Responses {
+		assert.Equal(t, http.StatusOK, resp.Status)
+		assert.Equal(t, fmt.Sprintf("/api/users/%d", i), resp.Body)
+	}
+
+	assert.LessOrEqual(t, atomic.LoadInt32(&peak), int32(4))
+	assert.Greater(t, atomic.LoadInt32(&peak), int32(1))
+}
+
+func TestHandleBatchRejectsOversizedBatch(t *testing.T) {
+	origMax := batchMaxRequests
+	batchMaxRequests = 10
+	defer func() { batchMaxRequests = origMax }()
+
+	req := httptest.NewRequest(http.MethodPost, "/gateway/batch", strings.NewReader(batchBody(11)))
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestRunSubRequestCapturesStatusAndHeaders(t *testing.T) {
+	origHandler := batchHandler
+	batchHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "/api/users/7")
+		w.WriteHeader(http.StatusCreated)
+		w.WriteHeader(http.StatusInternalServerError)
+		http.NewResponseController(w).Flush()
+		fmt.Fprint(w, `{"id":7}`)
+	})
+	defer func() { batchHandler = origHandler }()
+
+	parent := httptest.NewRequest(http.MethodPost, "/gateway/batch", nil)
+	resp := runSubRequest(parent, batchSubRequest{Method: http.MethodPost, Path: "/api/users"})
+
+	assert.Equal(t, http.StatusCreated, resp.Status, "only the first WriteHeader counts")
+	assert.Equal(t, "/api/users/7", resp.Headers["Location"])
+	assert.Equal(t, `{"id":7}`, resp.Body)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// defaultBodyLogMaxBytes caps how much of each body DEBUG_BODY_LOG captures.
+const defaultBodyLogMaxBytes = 4096
+
+// defaultBodyLogRedactFields are the JSON fields masked in logged bodies.
+const defaultBodyLogRedactFields = "password,token"
+
+// redacted replaces every masked header and field value.
+const redacted = "[REDACTED]"
+
+// redactedHeaders never appear in body logs with their real values.
+var redactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Admin-Token"}
+
+// bodyLog, when set, logs the proxied request and response bodies. It is
+// enabled by DEBUG_BODY_LOG for debugging a misbehaving backend only: it
+// buffers up to maxBytes of every body.
+var bodyLog *bodyLogger
+
+type bodyLogger struct {
+	maxBytes int
+	// fields are lower-cased; JSON keys match them case-insensitively
+	fields map[string]bool
+	// fieldPattern masks string and scalar values of fields in bodies that
+	// do not parse, usually because they were cut at maxBytes
+	fieldPattern *regexp.Regexp
+}
+
+func newBodyLogger(maxBytes int, fields []string) *bodyLogger {
+	b := &bodyLogger{maxBytes: maxBytes, fields: make(map[string]bool, len(fields))}
+	quoted := make([]string, 0, len(fields))
+	for _, field := range fields {
+		b.fields[strings.ToLower(field)] = true
+		quoted = append(quoted, regexp.QuoteMeta(field))
+	}
+	if len(quoted) > 0 {
+		b.fieldPattern = regexp.MustCompile(`(?i)("(?:` + strings.Join(quoted, "|") + `)"\s*:\s*)("(?:[^"\\]|\\.)*"?|[^,}\]\s]+)`)
+	}
+	return b
+}
+
+// LogRequest logs the start of req's body and headers, then restores the
+// body so the upstream still receives every byte.
+func (b *bodyLogger) LogRequest(logger *slog.Logger, service string, req *http.Request) {
+	var body []byte
+	var truncated bool
+	req.Body, body, truncated = b.capture(req.Body)
+	logger.Info("proxied request body",
+		"service", service,
+		"method", req.Method,
+		"path", req.URL.Path,
+		"headers", redactHeaders(req.Header),
+		"body", b.redactBody(req.Header, body, truncated),
+		"truncated", truncated,
+	)
+}
+
+// LogResponse is LogRequest for an upstream response.
+func (b *bodyLogger) LogResponse(logger *slog.Logger, service string, resp *http.Response) {
+	var body []byte
+	var truncated bool
+	resp.Body, body, truncated = b.capture(resp.Body)
+	logger.Info("proxied response body",
+		"service", service,
+		"status", resp.StatusCode,
+		"headers", redactHeaders(resp.Header),
+		"body", b.redactBody(resp.Header, body, truncated),
+		"truncated", truncated,
+	)
+}
+
+// capture reads up to maxBytes of body and returns a replacement that
+// replays them ahead of whatever was not read.
+func (b *bodyLogger) capture(body io.ReadCloser) (io.ReadCloser, []byte, bool) {
+	if body == nil || body == http.NoBody {
+		return body, nil, false
+	}
+	head, err := io.ReadAll(io.LimitReader(body, int64(b.maxBytes)+1))
+	truncated := len(head) > b.maxBytes
+	replay := struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), errReader{err}, body), body}
+	if truncated {
+		head = head[:b.maxBytes]
+	}
+	return replay, head, truncated
+}
+
+// errReader hands a read error hit while capturing on to the proxy.
+type errReader struct{ err error }
+
+func (e errReader) Read([]byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+	return 0, io.EOF
+}
+
+// redactBody masks the configured fields of a JSON body. Compressed and
+// other non-text bodies are summarised rather than logged.
+func (b *bodyLogger) redactBody(header http.Header, body []byte, truncated bool) string {
+	if len(body) == 0 {
+		return ""
+	}
+	if encoding := header.Get("Content-Encoding"); encoding != "" && encoding != "identity" {
+		return "[" + encoding + "-encoded body omitted]"
+	}
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	if mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+		return string(body)
+	}
+
+	if !truncated {
+		var doc any
+		if err := json.Unmarshal(body, &doc); err == nil {
+			if out, err := json.Marshal(b.redactValue(doc)); err == nil {
+				return string(out)
+			}
+		}
+	}
+	if b.fieldPattern == nil {
+		return string(body)
+	}
+	return b.fieldPattern.ReplaceAllString(string(body), `${1}"`+redacted+`"`)
+}
+
+func (b *bodyLogger) redactValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if b.fields[strings.ToLower(key)] {
+				v[key] = redacted
+			} else {
+				v[key] = b.redactValue(value)
+			}
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = b.redactValue(value)
+		}
+	}
+	return v
+}
+
+// redactHeaders copies h with the sensitive headers masked.
+func redactHeaders(h http.Header) http.Header {
+	out := h.Clone()
+	for _, name := range redactedHeaders {
+		if _, ok := out[name]; ok {
+			out[name] = []string{redacted}
+		}
+	}
+	return out
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withBodyLog(t *testing.T, maxBytes int, fields ...string) *bytes.Buffer {
+	logs := captureAccessLog(t)
+	orig := bodyLog
+	bodyLog = newBodyLogger(maxBytes, fields)
+	t.Cleanup(func() { bodyLog = orig })
+	return logs
+}
+
+// bodyLogLines returns the logged bodies keyed by message.
+func bodyLogLines(t *testing.T, logs *bytes.Buffer) map[string]map[string]any {
+	lines := map[string]map[string]any{}
+	scanner := bufio.NewScanner(logs)
+	for scanner.Scan() {
+		var line map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		if msg, _ := line["msg"].(string); strings.HasPrefix(msg, "proxied ") {
+			lines[msg] = line
+		}
+	}
+	return lines
+}
+
+func TestBodyLogRedactsAndForwardsBodiesIntact(t *testing.T) {
+	const reqBody = `{"email": "pema@example.com", "password": "hunter2", "profile": {"Token": "t-123"}}`
+	const respBody = `{"id": 7, "token": "session-abc", "roles": ["staff"]}`
+	logs := withBodyLog(t, 4096, "password", "token")
+	var received []byte
+	var receivedAuth string
+	withUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		received, _ = io.ReadAll(r.Body)
+		receivedAuth = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=session-abc")
+		w.Write([]byte(respBody))
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/users/login", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer secret-jwt")
+	req.Header.Set("Cookie", "session=cookie-value")
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, reqBody, string(received), "the upstream sees the original body")
+	assert.Equal(t, "Bearer secret-jwt", receivedAuth, "headers are only masked in the log")
+	assert.Equal(t, respBody, rec.Body.String(), "the client sees the original response")
+
+	out := logs.String()
+	for _, secret := range []string{"hunter2", "t-123", "session-abc", "secret-jwt", "cookie-value"} {
+		assert.NotContains(t, out, secret)
+	}
+
+	lines := bodyLogLines(t, logs)
+	request := lines["proxied request body"]
+	require.NotNil(t, request)
+	assert.JSONEq(t, `{"email": "pema@example.com", "password": "[REDACTED]", "profile": {"Token": "[REDACTED]"}}`, request["body"].(string))
+	assert.Equal(t, "users-service", request["service"])
+	headers := request["headers"].(map[string]any)
+	assert.Equal(t, []any{redacted}, headers["Authorization"])
+	assert.Equal(t, []any{redacted}, headers["Cookie"])
+	assert.Equal(t, []any{"application/json"}, headers["Content-Type"])
+
+	response := lines["proxied response body"]
+	require.NotNil(t, response)
+	assert.JSONEq(t, `{"id": 7, "token": "[REDACTED]", "roles": ["staff"]}`, response["body"].(string))
+	assert.EqualValues(t, http.StatusOK, response["status"])
+	assert.Equal(t, false, response["truncated"])
+}
+
+func TestBodyLogCapsCapturedBytes(t *testing.T) {
+	reqBody := `{"password": "hunter2hunter2", "padding": "` + strings.Repeat("x", 200) + `"}`
+	logs := withBodyLog(t, 24, "password")
+	var received []byte
+	withUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		received, _ = io.ReadAll(r.Body)
+	})
+
+	req := httptest.NewRequest(http.MethodPut, "/api/users/1", strings.NewReader(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, reqBody, string(received), "bytes past the cap are still forwarded")
+
+	request := bodyLogLines(t, logs)["proxied request body"]
+	require.NotNil(t, request)
+	assert.Equal(t, true, request["truncated"])
+	assert.Equal(t, `{"password": "[REDACTED]"`, request["body"])
+	assert.NotContains(t, logs.String(), "hunter")
+}
+
+func TestRedactBody(t *testing.T) {
+	b := newBodyLogger(4096, []string{"password"})
+	jsonHeader := http.Header{"Content-Type": {"application/json; charset=utf-8"}}
+
+	assert.Equal(t, `{"password":"[REDACTED]"}`, b.redactBody(jsonHeader, []byte(`{"password": 42}`), false))
+	assert.Equal(t, `[{"PASSWORD":"[REDACTED]"}]`, b.redactBody(jsonHeader, []byte(`[{"PASSWORD": "x"}]`), false))
+	assert.Equal(t, `{"password": "[REDACTED]", "n": 1`, b.redactBody(jsonHeader, []byte(`{"password": "x\"y", "n": 1`), true))
+	assert.Equal(t, "plain text", b.redactBody(http.Header{"Content-Type": {"text/plain"}}, []byte("plain text"), false))
+	assert.Equal(t, "[gzip-encoded body omitted]", b.redactBody(http.Header{"Content-Encoding": {"gzip"}}, []byte{0x1f, 0x8b}, false))
+	assert.Empty(t, b.redactBody(jsonHeader, nil, false))
+}
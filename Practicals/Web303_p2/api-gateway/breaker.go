@@ -0,0 +1,202 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+)
+
+// breakerState is the position of a circuit breaker in its state machine.
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// circuitBreaker trips after a run of consecutive upstream failures and
+// short-circuits requests until the cooldown elapses, then lets a single
+// probe through to decide whether to close again.
+type circuitBreaker struct {
+	service string
+
+	mu            sync.Mutex
+	state         breakerState
+	failures      int
+	openedAt      time.Time
+	probeInFlight bool
+
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// Allow reports whether a request may be sent upstream.
+func (b *circuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.setState(breakerHalfOpen)
+		b.probeInFlight = true
+		return true
+	case breakerHalfOpen:
+		if b.probeInFlight {
+			return false
+		}
+		b.probeInFlight = true
+		return true
+	default:
+		return true
+	}
+}
+
+// RecordSuccess closes the breaker and resets the failure count.
+func (b *circuitBreaker) RecordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.setState(breakerClosed)
+	b.failures = 0
+	b.probeInFlight = false
+}
+
+// RecordFailure counts a failed upstream call, tripping the breaker once the
+// threshold is reached or immediately if a half-open probe fails.
+func (b *circuitBreaker) RecordFailure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probeInFlight = false
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.setState(breakerOpen)
+		b.openedAt = b.now()
+	}
+}
+
+// setState moves the breaker to next, logging and counting real
+// transitions. Callers hold b.mu.
+func (b *circuitBreaker) setState(next breakerState) {
+	if b.state == next {
+		return
+	}
+	log.Printf("Circuit breaker for '%s' %s -> %s after %d consecutive failure(s)", b.service, b.state, next, b.failures)
+	b.state = next
+	breakerStateGauge.WithLabelValues(b.service).Set(float64(next))
+	breakerTransitions.WithLabelValues(b.service, next.String()).Inc()
+}
+
+var (
+	breakerStateGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gateway_circuit_breaker_state",
+		Help: "Circuit breaker state per service: 0 closed, 1 open, 2 half-open.",
+	}, []string{"service"})
+
+	breakerTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_circuit_breaker_transitions_total",
+		Help: "Circuit breaker state changes per service, by the state entered.",
+	}, []string{"service", "state"})
+)
+
+func init() {
+	metricsRegistry.MustRegister(breakerStateGauge, breakerTransitions)
+}
+
+// breakerSnapshot is the JSON view of a breaker exposed to operators.
+type breakerSnapshot struct {
+	Service           string  `json:"service"`
+	State             string  `json:"state"`
+	Failures          int     `json:"failures"`
+	HalfOpenInSeconds float64 `json:"half_open_in_seconds"`
+}
+
+func (b *circuitBreaker) snapshot(service string) breakerSnapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	snap := breakerSnapshot{Service: service, State: b.state.String(), Failures: b.failures}
+	if b.state == breakerOpen {
+		if remaining := b.cooldown - b.now().Sub(b.openedAt); remaining > 0 {
+			snap.HalfOpenInSeconds = remaining.Seconds()
+		}
+	}
+	return snap
+}
+
+// breakerRegistry lazily creates one breaker per upstream service.
+type breakerRegistry struct {
+	mu        sync.Mutex
+	breakers  map[string]*circuitBreaker
+	threshold int
+	cooldown  time.Duration
+}
+
+func newBreakerRegistry(threshold int, cooldown time.Duration) *breakerRegistry {
+	return &breakerRegistry{
+		breakers:  make(map[string]*circuitBreaker),
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+func (r *breakerRegistry) get(service string) *circuitBreaker {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, ok := r.breakers[service]
+	if !ok {
+		b = newCircuitBreaker(r.threshold, r.cooldown)
+		b.service = service
+		breakerStateGauge.WithLabelValues(service).Set(float64(breakerClosed))
+		r.breakers[service] = b
+	}
+	return b
+}
+
+// snapshots returns the state of every known breaker sorted by service name.
+func (r *breakerRegistry) snapshots() []breakerSnapshot {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.breakers))
+	for name := range r.breakers {
+		names = append(names, name)
+	}
+	r.mu.Unlock()
+
+	sort.Strings(names)
+	out := make([]breakerSnapshot, 0, len(names))
+	for _, name := range names {
+		out = append(out, r.get(name).snapshot(name))
+	}
+	return out
+}
+
+var breakers = newBreakerRegistry(defaultBreakerThreshold, defaultBreakerCooldown)
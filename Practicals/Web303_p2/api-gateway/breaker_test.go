@@ -0,0 +1,81 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withBreakers(t *testing.T, threshold int, cooldown time.Duration) {
+	orig := breakers
+	breakers = newBreakerRegistry(threshold, cooldown)
+	t.Cleanup(func() { breakers = orig })
+}
+
+func TestCircuitBreakerStates(t *testing.T) {
+	withBreakers(t, 3, time.Minute)
+	clock := &fakeClock{t: time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)}
+	b := breakers.get("states-service")
+	b.now = clock.Now
+	state := func() float64 { return testutil.ToFloat64(breakerStateGauge.WithLabelValues("states-service")) }
+
+	// Closed: failures below the threshold still let requests through
+	b.RecordFailure()
+	b.RecordFailure()
+	assert.True(t, b.Allow())
+	assert.Equal(t, float64(breakerClosed), state())
+
+	// Open: the threshold trips it and requests short-circuit until cooldown
+	b.RecordFailure()
+	assert.False(t, b.Allow())
+	assert.Equal(t, float64(breakerOpen), state())
+
+	// Half-open: one probe after the cooldown, others wait for its verdict
+	clock.Advance(time.Minute)
+	assert.True(t, b.Allow())
+	assert.False(t, b.Allow())
+	assert.Equal(t, float64(breakerHalfOpen), state())
+
+	// A failed probe reopens immediately
+	b.RecordFailure()
+	assert.False(t, b.Allow())
+	assert.Equal(t, float64(breakerOpen), state())
+
+	// A successful probe closes it again
+	clock.Advance(time.Minute)
+	require.True(t, b.Allow())
+	b.RecordSuccess()
+	assert.True(t, b.Allow())
+	assert.Equal(t, float64(breakerClosed), state())
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(breakerTransitions.WithLabelValues("states-service", "open")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(breakerTransitions.WithLabelValues("states-service", "half-open")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(breakerTransitions.WithLabelValues("states-service", "closed")))
+}
+
+func TestUpstream5xxTripsBreaker(t *testing.T) {
+	withBreakers(t, 2, time.Minute)
+	calls := 0
+	withUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	router := newRouter()
+
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/users", nil))
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/users", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, 2, calls, "an open breaker never reaches the upstream")
+	assert.Equal(t, breakerOpen.String(), breakers.get("users-service").snapshot("users-service").State)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"net/http/httputil"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// sizedBufferPool is an httputil.BufferPool handing out fixed-size buffers.
+type sizedBufferPool struct {
+	size int
+	pool sync.Pool
+}
+
+func newSizedBufferPool(size int) *sizedBufferPool {
+	p := &sizedBufferPool{size: size}
+	p.pool.New = func() interface{} {
+		buf := make([]byte, size)
+		return &buf
+	}
+	return p
+}
+
+func (p *sizedBufferPool) Get() []byte {
+	return *p.pool.Get().(*[]byte)
+}
+
+func (p *sizedBufferPool) Put(buf []byte) {
+	if cap(buf) != p.size {
+		return
+	}
+	p.pool.Put(&buf)
+}
+
+// proxyBuffers chooses the copy buffer used when streaming upstream bodies.
+// A size of zero leaves httputil.ReverseProxy on its built-in 32KB buffer.
+type proxyBuffers struct {
+	defaultSize int
+	perService  map[string]int
+
+	mu    sync.Mutex
+	pools map[int]*sizedBufferPool
+}
+
+func newProxyBuffers(defaultSize int, perService map[string]int) *proxyBuffers {
+	return &proxyBuffers{defaultSize: defaultSize, perService: perService, pools: make(map[int]*sizedBufferPool)}
+}
+
+// For returns the buffer pool for serviceName, or nil for the default.
+func (b *proxyBuffers) For(serviceName string) httputil.BufferPool {
+	size, ok := b.perService[serviceName]
+	if !ok {
+		size = b.defaultSize
+	}
+	if size <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	pool, ok := b.pools[size]
+	if !ok {
+		pool = newSizedBufferPool(size)
+		b.pools[size] = pool
+	}
+	return pool
+}
+
+var bufferPools = newProxyBuffers(0, nil)
+
+// parseKeyValues parses "a=1,b=2" style configuration into a map.
+func parseKeyValues(raw string) (map[string]string, error) {
+	out := make(map[string]string)
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid entry %q, expected key=value", pair)
+		}
+		out[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return out, nil
+}
+
+// parseBufferSizes parses "users-service=65536,..." into per-service sizes.
+func parseBufferSizes(raw string) (map[string]int, error) {
+	pairs, err := parseKeyValues(raw)
+	if err != nil {
+		return nil, err
+	}
+	sizes := make(map[string]int, len(pairs))
+	for service, value := range pairs {
+		size, err := strconv.Atoi(value)
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("invalid buffer size %q for %s", value, service)
+		}
+		sizes[service] = size
+	}
+	return sizes, nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyBuffersFor(t *testing.T) {
+	buffers := newProxyBuffers(0, map[string]int{"media-service": 256 * 1024})
+
+	assert.Nil(t, buffers.For("users-service"), "default should keep the stdlib buffer")
+
+	pool := buffers.For("media-service")
+	require.NotNil(t, pool)
+	assert.Len(t, pool.Get(), 256*1024)
+	assert.Same(t, pool, buffers.For("media-service"))
+}
+
+func TestParseBufferSizes(t *testing.T) {
+	sizes, err := parseBufferSizes("users-service=4096, media-service = 262144")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"users-service": 4096, "media-service": 262144}, sizes)
+
+	_, err = parseBufferSizes("users-service=big")
+	assert.Error(t, err)
+}
+
+func BenchmarkProxyBufferSize(b *testing.B) {
+	payload := make([]byte, 8<<20)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(payload)
+	}))
+	defer upstream.Close()
+	target, _ := url.Parse(upstream.URL)
+
+	for _, size := range []int{0, 4 << 10, 32 << 10, 256 << 10, 1 << 20} {
+		name := "default"
+		if size > 0 {
+			name = fmt.Sprintf("%dKB", size>>10)
+		}
+		b.Run(name, func(b *testing.B) {
+			proxy := httputil.NewSingleHostReverseProxy(target)
+			proxy.BufferPool = newProxyBuffers(size, nil).For("bench")
+			gateway := httptest.NewServer(proxy)
+			defer gateway.Close()
+
+			b.SetBytes(int64(len(payload)))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				resp, err := http.Get(gateway.URL)
+				if err != nil {
+					b.Fatal(err)
+				}
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+			}
+		})
+	}
+}
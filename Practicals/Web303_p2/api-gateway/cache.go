@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"consulutil"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const defaultDiscoveryTTL = 10 * time.Second
+
+// cacheMode selects how discovery results are reused between requests.
+type cacheMode string
+
+const (
+	// cacheOff queries Consul on every request.
+	cacheOff cacheMode = "off"
+	// cacheLazy populates the cache on the first request for a service and
+	// reuses it until the TTL expires, with one in-flight lookup per service.
+	// It is the default.
+	cacheLazy cacheMode = "lazy"
+)
+
+func parseCacheMode(raw string) (cacheMode, error) {
+	switch mode := cacheMode(raw); mode {
+	case "":
+		return cacheLazy, nil
+	case cacheOff, cacheLazy:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown discovery cache mode %q", raw)
+	}
+}
+
+type cacheEntry struct {
+	instances []*url.URL
+	fetchedAt time.Time
+}
+
+// serviceCache memoizes healthy instance URLs per service name.
+type serviceCache struct {
+	mode   cacheMode
+	ttl    time.Duration
+	lookup func(serviceName string) ([]*url.URL, error)
+	now    func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	flight  singleflight.Group
+
+	// hits and misses count lookups served from, or missing, the cache.
+	// Nothing is counted while the cache is off.
+	hits, misses atomic.Uint64
+}
+
+func newServiceCache(mode cacheMode, ttl time.Duration, lookup func(string) ([]*url.URL, error)) *serviceCache {
+	return &serviceCache{
+		mode:    mode,
+		ttl:     ttl,
+		lookup:  lookup,
+		now:     time.Now,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+// Get returns the healthy instances for serviceName, consulting the cache
+// according to the configured mode.
+func (c *serviceCache) Get(serviceName string) ([]*url.URL, error) {
+	if c.mode == cacheOff {
+		return c.lookup(serviceName)
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[serviceName]
+	c.mu.Unlock()
+	if ok && c.now().Sub(entry.fetchedAt) < c.ttl {
+		c.hits.Add(1)
+		return entry.instances, nil
+	}
+	c.misses.Add(1)
+
+	// Collapse concurrent misses for the same service into one lookup
+	v, err, _ := c.flight.Do(serviceName, func() (interface{}, error) {
+		instances, err := c.lookup(serviceName)
+		if errors.Is(err, consulutil.ErrNoHealthyInstances) {
+			// Discovery answered and every instance is down; the stale
+			// entry would keep sending traffic to dead backends
+			c.mu.Lock()
+			delete(c.entries, serviceName)
+			c.mu.Unlock()
+			return nil, err
+		}
+		if err != nil {
+			// Prefer a stale answer over failing while Consul is unreachable;
+			// fetchedAt is left alone so the next request retries the lookup
+			if ok {
+				log.Printf("Discovery for '%s' failed, serving stale entry from %s: %v",
+					serviceName, entry.fetchedAt.Format(time.RFC3339), err)
+				return entry.instances, nil
+			}
+			return nil, err
+		}
+		c.mu.Lock()
+		c.entries[serviceName] = cacheEntry{instances: instances, fetchedAt: c.now()}
+		c.mu.Unlock()
+		return instances, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]*url.URL), nil
+}
+
+// FetchedAt reports when serviceName's cached instances were last refreshed
+// from Consul, and whether anything is cached for it.
+func (c *serviceCache) FetchedAt(serviceName string) (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[serviceName]
+	return entry.fetchedAt, ok
+}
+
+// cacheStats is a point-in-time view of the cache counters.
+type cacheStats struct {
+	Hits     uint64  `json:"hits"`
+	Misses   uint64  `json:"misses"`
+	HitRatio float64 `json:"hit_ratio"`
+}
+
+func (c *serviceCache) Stats() cacheStats {
+	stats := cacheStats{Hits: c.hits.Load(), Misses: c.misses.Load()}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"consulutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLazyCacheSingleLookupUnderConcurrentMisses(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	lookup := func(name string) ([]*url.URL, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		u, _ := url.Parse("http://users:8081")
+		return []*url.URL{u}, nil
+	}
+	cache := newServiceCache(cacheLazy, time.Minute, lookup)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			instances, err := cache.Get("users-service")
+			assert.NoError(t, err)
+			assert.Len(t, instances, 1)
+		}()
+	}
+
+	// Give every goroutine a chance to join the in-flight lookup
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	_, err := cache.Get("users-service")
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls), "cached entry should be reused within the TTL")
+}
+
+func TestCacheOffAlwaysQueries(t *testing.T) {
+	calls := 0
+	cache := newServiceCache(cacheOff, time.Minute, func(string) ([]*url.URL, error) {
+		calls++
+		return []*url.URL{{Host: "x"}}, nil
+	})
+
+	cache.Get("a")
+	cache.Get("a")
+	assert.Equal(t, 2, calls)
+}
+
+func TestParseCacheMode(t *testing.T) {
+	mode, err := parseCacheMode("")
+	require.NoError(t, err)
+	assert.Equal(t, cacheLazy, mode, "caching is on by default")
+
+	mode, err = parseCacheMode("off")
+	require.NoError(t, err)
+	assert.Equal(t, cacheOff, mode)
+
+	mode, err = parseCacheMode("lazy")
+	require.NoError(t, err)
+	assert.Equal(t, cacheLazy, mode)
+
+	_, err = parseCacheMode("eager")
+	assert.Error(t, err)
+}
+
+func TestCacheCountsHitsAndMisses(t *testing.T) {
+	clock := time.Now()
+	cache := newServiceCache(cacheLazy, time.Minute, func(string) ([]*url.URL, error) {
+		return []*url.URL{{Host: "x"}}, nil
+	})
+	cache.now = func() time.Time { return clock }
+
+	cache.Get("a") // miss
+	cache.Get("a") // hit
+	cache.Get("a") // hit
+	cache.Get("b") // miss
+	clock = clock.Add(2 * time.Minute)
+	cache.Get("a") // expired: miss
+
+	stats := cache.Stats()
+	assert.Equal(t, uint64(2), stats.Hits)
+	assert.Equal(t, uint64(3), stats.Misses)
+	assert.InDelta(t, 0.4, stats.HitRatio, 1e-9)
+
+	off := newServiceCache(cacheOff, time.Minute, func(string) ([]*url.URL, error) { return nil, nil })
+	off.Get("a")
+	assert.Equal(t, cacheStats{}, off.Stats())
+}
+
+// scriptedLookup returns the queued results in order and counts calls.
+type scriptedLookup struct {
+	results []error
+	calls   int
+}
+
+func (s *scriptedLookup) lookup(name string) ([]*url.URL, error) {
+	err := s.results[s.calls]
+	s.calls++
+	if err != nil {
+		return nil, err
+	}
+	return []*url.URL{{Scheme: "http", Host: fmt.Sprintf("%s-%d:8080", name, s.calls)}}, nil
+}
+
+func TestLazyCacheHitMissAndExpiry(t *testing.T) {
+	clock := time.Now()
+	script := &scriptedLookup{results: []error{nil, nil}}
+	cache := newServiceCache(cacheLazy, 10*time.Second, script.lookup)
+	cache.now = func() time.Time { return clock }
+
+	miss, err := cache.Get("users")
+	require.NoError(t, err)
+	assert.Equal(t, 1, script.calls, "first request is a live query")
+
+	clock = clock.Add(9 * time.Second)
+	hit, err := cache.Get("users")
+	require.NoError(t, err)
+	assert.Equal(t, 1, script.calls, "fresh entries are served from cache")
+	assert.Equal(t, miss, hit)
+
+	clock = clock.Add(2 * time.Second)
+	refreshed, err := cache.Get("users")
+	require.NoError(t, err)
+	assert.Equal(t, 2, script.calls, "expired entries are refreshed lazily")
+	assert.Equal(t, "users-2:8080", refreshed[0].Host)
+}
+
+func TestLazyCacheServesStaleOnError(t *testing.T) {
+	clock := time.Now()
+	consulDown := errors.New("consul unreachable")
+	script := &scriptedLookup{results: []error{nil, consulDown, nil}}
+	cache := newServiceCache(cacheLazy, 10*time.Second, script.lookup)
+	cache.now = func() time.Time { return clock }
+
+	warm, err := cache.Get("users")
+	require.NoError(t, err)
+
+	clock = clock.Add(time.Minute)
+	stale, err := cache.Get("users")
+	require.NoError(t, err, "a warm entry masks the Consul error")
+	assert.Equal(t, warm, stale)
+
+	fresh, err := cache.Get("users")
+	require.NoError(t, err)
+	assert.Equal(t, 3, script.calls, "stale entries do not reset the TTL")
+	assert.Equal(t, "users-3:8080", fresh[0].Host)
+
+	cold := newServiceCache(cacheLazy, 10*time.Second, (&scriptedLookup{results: []error{consulDown}}).lookup)
+	_, err = cold.Get("users")
+	assert.ErrorIs(t, err, consulDown, "without a cached entry the error surfaces")
+}
+
+func TestLazyCacheEvictsWhenAllInstancesAreUnhealthy(t *testing.T) {
+	clock := time.Now()
+	allDown := fmt.Errorf("%w for 'users'", consulutil.ErrNoHealthyInstances)
+	script := &scriptedLookup{results: []error{nil, allDown, allDown, nil}}
+	cache := newServiceCache(cacheLazy, 10*time.Second, script.lookup)
+	cache.now = func() time.Time { return clock }
+
+	_, err := cache.Get("users")
+	require.NoError(t, err)
+
+	clock = clock.Add(time.Minute)
+	_, err = cache.Get("users")
+	assert.ErrorIs(t, err, consulutil.ErrNoHealthyInstances, "dead instances are not served stale")
+	_, ok := cache.FetchedAt("users")
+	assert.False(t, ok, "the entry is evicted")
+
+	_, err = cache.Get("users")
+	assert.ErrorIs(t, err, consulutil.ErrNoHealthyInstances)
+	recovered, err := cache.Get("users")
+	require.NoError(t, err)
+	assert.Equal(t, "users-4:8080", recovered[0].Host)
+}
+
+func TestLazyCacheFollowsStaticHealthPolls(t *testing.T) {
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+	static, err := newStaticBackends(map[string][]string{"users-service": {srv.URL}})
+	require.NoError(t, err)
+	static.PollOnce(context.Background())
+	clock := time.Now()
+	cache := newServiceCache(cacheLazy, 10*time.Second, static.lookup)
+	cache.now = func() time.Time { return clock }
+
+	_, err = cache.Get("users-service")
+	require.NoError(t, err)
+
+	status = http.StatusServiceUnavailable
+	static.PollOnce(context.Background())
+	clock = clock.Add(time.Minute)
+	_, err = cache.Get("users-service")
+	assert.ErrorIs(t, err, consulutil.ErrNoHealthyInstances, "a failed poll takes the backend out of rotation")
+}
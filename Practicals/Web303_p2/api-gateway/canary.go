@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// canaryTag is the Consul tag carried by instances that receive canary
+// traffic.
+const canaryTag = "canary"
+
+// routingTag picks the Consul tag a request prefers: canaryTag when the
+// client sends X-Canary: true, otherwise none.
+func routingTag(r *http.Request) string {
+	if strings.EqualFold(strings.TrimSpace(r.Header.Get("X-Canary")), "true") {
+		return canaryTag
+	}
+	return ""
+}
+
+// taggedCaches holds one discovery cache per Consul tag, each built on first
+// use with its parent's mode and TTL.
+type taggedCaches struct {
+	parent *serviceCache
+
+	mu     sync.Mutex
+	caches map[string]*serviceCache
+}
+
+func newTaggedCaches(parent *serviceCache) *taggedCaches {
+	return &taggedCaches{parent: parent, caches: make(map[string]*serviceCache)}
+}
+
+// taggedDiscovery resolves service names to their healthy tagged instances.
+var taggedDiscovery = newTaggedCaches(discovery)
+
+// For returns the cache of instances registered with tag.
+func (t *taggedCaches) For(tag string) *serviceCache {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	cache, ok := t.caches[tag]
+	if !ok {
+		cache = newServiceCache(t.parent.mode, t.parent.ttl, func(serviceName string) ([]*url.URL, error) {
+			return lookupTaggedInstances(serviceName, tag)
+		})
+		t.caches[tag] = cache
+	}
+	return cache
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func canaryEntry(t *testing.T, id string, srv *httptest.Server) *consulapi.ServiceEntry {
+	entry := upstreamEntry(t, id, srv)
+	entry.Service.Tags = []string{canaryTag}
+	return entry
+}
+
+// namedUpstream answers every request with name in X-Served-By.
+func namedUpstream(name string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Served-By", name)
+	}))
+}
+
+func servedBy(t *testing.T, canary string) string {
+	req := httptest.NewRequest(http.MethodGet, "/api/users/1", nil)
+	if canary != "" {
+		req.Header.Set("X-Canary", canary)
+	}
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	return rec.Header().Get("X-Served-By")
+}
+
+func TestCanaryHeaderSteersToTaggedInstances(t *testing.T) {
+	stable := namedUpstream("stable")
+	defer stable.Close()
+	canary := namedUpstream("canary")
+	defer canary.Close()
+	withFakeConsul(t, &fakeHealth{entries: map[string][]*consulapi.ServiceEntry{
+		"users-service": {upstreamEntry(t, "users-1", stable), canaryEntry(t, "users-2", canary)},
+	}})
+
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, "canary", servedBy(t, "true"))
+	}
+	assert.Equal(t, "canary", servedBy(t, "TRUE"))
+
+	// Without the header the canary stays in the normal rotation
+	seen := map[string]bool{}
+	for i := 0; i < 4; i++ {
+		seen[servedBy(t, "")] = true
+	}
+	assert.Equal(t, map[string]bool{"stable": true, "canary": true}, seen)
+}
+
+func TestCanaryHeaderFallsBackWithoutTaggedInstances(t *testing.T) {
+	stable := namedUpstream("stable")
+	defer stable.Close()
+	withFakeConsul(t, &fakeHealth{entries: map[string][]*consulapi.ServiceEntry{
+		"users-service": {upstreamEntry(t, "users-1", stable)},
+	}})
+
+	assert.Equal(t, "stable", servedBy(t, "true"))
+}
+
+func TestRoutingTag(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/users/1", nil)
+	assert.Empty(t, routingTag(req))
+	req.Header.Set("X-Canary", "yes")
+	assert.Empty(t, routingTag(req), "only true opts in")
+	req.Header.Set("X-Canary", " true ")
+	assert.Equal(t, canaryTag, routingTag(req))
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chunkedBackend streams n flushed chunks without a Content-Length.
+func chunkedBackend(n int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(w, "chunk-%02d;", i)
+			w.(http.Flusher).Flush()
+		}
+	}
+}
+
+func TestChunkedResponsePassesThroughAndIsCounted(t *testing.T) {
+	withUpstream(t, chunkedBackend(10))
+	before := upstreamBytesFor("users-service")
+
+	gateway := httptest.NewServer(http.HandlerFunc(routeRequest))
+	defer gateway.Close()
+
+	resp, err := http.Get(gateway.URL + "/api/users/stream")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	expected := ""
+	for i := 0; i < 10; i++ {
+		expected += fmt.Sprintf("chunk-%02d;", i)
+	}
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, expected, string(body))
+	assert.Equal(t, int64(-1), resp.ContentLength, "response should stay chunked")
+	assert.Equal(t, []string{"chunked"}, resp.TransferEncoding)
+	assert.Equal(t, uint64(len(expected)), upstreamBytesFor("users-service")-before)
+
+	rec := httptest.NewRecorder()
+	handleMetrics(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, rec.Body.String(), `gateway_upstream_response_bytes_total{service="users-service"}`)
+}
+
+func TestIdempotencySkipsStreamedResponses(t *testing.T) {
+	withUpstream(t, chunkedBackend(3))
+	handler := (&idempotency{store: newMemoryIdempotencyStore(), ttl: time.Minute}).Wrap(http.HandlerFunc(routeRequest))
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/api/users/stream", nil)
+		req.Header.Set("Idempotency-Key", "abc")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "chunk-00;chunk-01;chunk-02;", rec.Body.String())
+		assert.Empty(t, rec.Header().Get("Idempotent-Replayed"), "streamed responses are never replayed")
+	}
+}
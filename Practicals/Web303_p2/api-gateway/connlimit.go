@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// perIPRetryAfter is the back-off suggested to clients over their IP limit.
+const perIPRetryAfter = time.Second
+
+// ipLimiter caps concurrent in-flight requests per client IP so one client
+// cannot hold every connection. A limit of zero disables it; clients in
+// exempt (e.g. internal ranges) are never limited.
+type ipLimiter struct {
+	limit  int
+	exempt []*net.IPNet
+
+	mu     sync.Mutex
+	active map[string]int
+}
+
+func newIPLimiter(limit int, exempt []*net.IPNet) *ipLimiter {
+	return &ipLimiter{limit: limit, exempt: exempt, active: make(map[string]int)}
+}
+
+// parseCIDRs parses a comma-separated list of CIDR ranges.
+func parseCIDRs(raw string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", part, err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+func (l *ipLimiter) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if l.limit <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ip := clientIP(r)
+		if ip == nil || l.isExempt(ip) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := ip.String()
+		if !l.acquire(key) {
+			rejectRateLimited(w, perIPRetryAfter)
+			return
+		}
+		defer l.release(key)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (l *ipLimiter) acquire(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.active[key] >= l.limit {
+		return false
+	}
+	l.active[key]++
+	return true
+}
+
+func (l *ipLimiter) release(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.active[key]--; l.active[key] <= 0 {
+		delete(l.active, key)
+	}
+}
+
+func (l *ipLimiter) isExempt(ip net.IP) bool {
+	for _, n := range l.exempt {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP is the peer address of the connection. Forwarding headers are
+// deliberately ignored since clients can forge them to dodge the limit.
+func clientIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// holdingHandler blocks every request until release is closed.
+func holdingHandler(started *sync.WaitGroup, release chan struct{}) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started.Done()
+		<-release
+	})
+}
+
+func requestFrom(addr string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/api/users/1", nil)
+	req.RemoteAddr = addr
+	return req
+}
+
+func TestIPLimiterRejectsExcessFromOneIP(t *testing.T) {
+	var started sync.WaitGroup
+	release := make(chan struct{})
+	handler := newIPLimiter(2, nil).Wrap(holdingHandler(&started, release))
+
+	var done sync.WaitGroup
+	for _, port := range []string{"5001", "5002"} {
+		started.Add(1)
+		done.Add(1)
+		go func(port string) {
+			defer done.Done()
+			handler.ServeHTTP(httptest.NewRecorder(), requestFrom("203.0.113.7:"+port))
+		}(port)
+	}
+	started.Wait()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, requestFrom("203.0.113.7:5003"))
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, reasonRateLimited, rec.Header().Get("X-Gateway-Reason"))
+
+	// Another client is unaffected
+	started.Add(1)
+	done.Add(1)
+	go func() {
+		defer done.Done()
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, requestFrom("198.51.100.2:6000"))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}()
+	started.Wait()
+
+	close(release)
+	done.Wait()
+
+	// Slots are released once requests finish
+	started.Add(1)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, requestFrom("203.0.113.7:5004"))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestIPLimiterExemptsTrustedRanges(t *testing.T) {
+	exempt, err := parseCIDRs("10.0.0.0/8, 127.0.0.1/32")
+	require.NoError(t, err)
+
+	var started sync.WaitGroup
+	release := make(chan struct{})
+	handler := newIPLimiter(1, exempt).Wrap(holdingHandler(&started, release))
+
+	var done sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		started.Add(1)
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, requestFrom("10.1.2.3:7000"))
+			assert.Equal(t, http.StatusOK, rec.Code)
+		}()
+	}
+	started.Wait()
+	close(release)
+	done.Wait()
+
+	_, err = parseCIDRs("10.0.0.0")
+	assert.Error(t, err)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"consulutil"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeHealth is an in-memory stand-in for the Consul health API.
+type fakeHealth struct {
+	entries map[string][]*consulapi.ServiceEntry
+	err     error
+	calls   int
+}
+
+func (f *fakeHealth) Service(service, tag string, passingOnly bool, q *consulapi.QueryOptions) ([]*consulapi.ServiceEntry, *consulapi.QueryMeta, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, nil, f.err
+	}
+	if tag == "" {
+		return f.entries[service], &consulapi.QueryMeta{}, nil
+	}
+	var tagged []*consulapi.ServiceEntry
+	for _, entry := range f.entries[service] {
+		if slices.Contains(entry.Service.Tags, tag) {
+			tagged = append(tagged, entry)
+		}
+	}
+	return tagged, &consulapi.QueryMeta{}, nil
+}
+
+// Services lists every service the fake has entries for, as the catalog would.
+func (f *fakeHealth) Services(q *consulapi.QueryOptions) (map[string][]string, *consulapi.QueryMeta, error) {
+	if f.err != nil {
+		return nil, nil, f.err
+	}
+	out := make(map[string][]string, len(f.entries))
+	for name := range f.entries {
+		out[name] = nil
+	}
+	return out, &consulapi.QueryMeta{}, nil
+}
+
+func serviceEntry(id, address string, port int) *consulapi.ServiceEntry {
+	return &consulapi.ServiceEntry{
+		Service: &consulapi.AgentService{ID: id, Address: address, Port: port},
+	}
+}
+
+// withFakeConsul swaps in fake Consul health data and an uncached discovery
+// layer for the duration of a test.
+func withFakeConsul(t *testing.T, fake consulutil.Health) {
+	origConsul, origDiscovery, origTagged := consul, discovery, taggedDiscovery
+	catalog, _ := fake.(consulutil.Catalog)
+	consul = consulutil.NewFromAPI(nil, fake, catalog)
+	discovery = newServiceCache(cacheOff, defaultDiscoveryTTL, lookupInstances)
+	taggedDiscovery = newTaggedCaches(discovery)
+	t.Cleanup(func() { consul, discovery, taggedDiscovery = origConsul, origDiscovery, origTagged })
+}
+
+func TestLookupInstancesCollapsesDuplicateIDs(t *testing.T) {
+	stale := serviceEntry("users-1", "10.0.0.5", 8081)
+	stale.Service.ModifyIndex = 10
+	fresh := serviceEntry("users-1", "10.0.0.9", 8081)
+	fresh.Service.ModifyIndex = 42
+
+	withFakeConsul(t, &fakeHealth{entries: map[string][]*consulapi.ServiceEntry{
+		"users-service": {fresh, serviceEntry("users-2", "10.0.0.6", 8081), stale},
+	}})
+
+	instances, err := lookupInstances("users-service")
+	require.NoError(t, err)
+	require.Len(t, instances, 2)
+	assert.Equal(t, "http://10.0.0.9:8081", instances[0].String())
+	assert.Equal(t, "http://10.0.0.6:8081", instances[1].String())
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// defaultErrorPage is the errorPages key used for services without their own.
+const defaultErrorPage = "*"
+
+// errorPageData is passed to HTML error templates.
+type errorPageData struct {
+	Status     int
+	StatusText string
+	Message    string
+	Service    string
+}
+
+// errorPages holds HTML templates for gateway-generated errors, keyed by
+// service name or defaultErrorPage. Empty means plain-text errors only.
+var errorPages = map[string]*template.Template{}
+
+// loadErrorPages parses "users-service=/path/a.html,*=/path/b.html" and
+// compiles each referenced file as an html/template.
+func loadErrorPages(raw string) (map[string]*template.Template, error) {
+	paths, err := parseKeyValues(raw)
+	if err != nil {
+		return nil, err
+	}
+	pages := make(map[string]*template.Template, len(paths))
+	for service, path := range paths {
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error page for %s: %w", service, err)
+		}
+		tmpl, err := template.New(service).Parse(string(body))
+		if err != nil {
+			return nil, fmt.Errorf("error page for %s: %w", service, err)
+		}
+		pages[service] = tmpl
+	}
+	return pages, nil
+}
+
+// writeGatewayError replies to an error produced by the gateway itself (never
+// one relayed from a backend). Browsers get the service's HTML page when one
+// is configured, JSON clients get {"error": ...}, everyone else plain text.
+func writeGatewayError(w http.ResponseWriter, r *http.Request, service string, status int, message string) {
+	switch preferredErrorFormat(r.Header.Get("Accept")) {
+	case "text/html":
+		tmpl := errorPages[service]
+		if tmpl == nil {
+			tmpl = errorPages[defaultErrorPage]
+		}
+		if tmpl == nil {
+			break
+		}
+		var buf bytes.Buffer
+		data := errorPageData{Status: status, StatusText: http.StatusText(status), Message: message, Service: service}
+		if err := tmpl.Execute(&buf, data); err != nil {
+			log.Printf("Error page for '%s' failed to render: %v", service, err)
+			break
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(status)
+		w.Write(buf.Bytes())
+		return
+	case "application/json":
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": message})
+		return
+	}
+	http.Error(w, message, status)
+}
+
+// preferredErrorFormat picks "text/html" or "application/json" from an
+// Accept header by q-value, or "" when the client named neither. Wildcards
+// are ignored so curl's "*/*" keeps getting plain text.
+func preferredErrorFormat(accept string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || (mediaType != "text/html" && mediaType != "application/json") {
+			continue
+		}
+		q := 1.0
+		if raw, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(raw, 64); err != nil {
+				continue
+			}
+		}
+		if q > bestQ {
+			best, bestQ = mediaType, q
+		}
+	}
+	return best
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withErrorPages(t *testing.T, pages map[string]*template.Template) {
+	origPages, origBreakers := errorPages, breakers
+	errorPages = pages
+	breakers = newBreakerRegistry(defaultBreakerThreshold, time.Minute)
+	t.Cleanup(func() { errorPages, breakers = origPages, origBreakers })
+}
+
+func unavailableRequest(t *testing.T, accept string) *httptest.ResponseRecorder {
+	withFakeConsul(t, &fakeHealth{err: errors.New("consul down")})
+	req := httptest.NewRequest(http.MethodGet, "/api/users/1", nil)
+	req.Header.Set("Accept", accept)
+	rec := httptest.NewRecorder()
+	routeRequest(rec, req)
+	return rec
+}
+
+func TestGatewayErrorHTMLForBrowsers(t *testing.T) {
+	withErrorPages(t, map[string]*template.Template{
+		"users-service":  template.Must(template.New("u").Parse(`<h1>{{.Status}} {{.StatusText}}</h1><p>{{.Service}}: {{.Message}}</p>`)),
+		defaultErrorPage: template.Must(template.New("d").Parse(`<p>generic</p>`)),
+	})
+
+	rec := unavailableRequest(t, "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "<h1>503 Service Unavailable</h1><p>users-service: Service not available</p>", rec.Body.String())
+}
+
+func TestGatewayErrorFallsBackToDefaultPage(t *testing.T) {
+	withErrorPages(t, map[string]*template.Template{
+		defaultErrorPage: template.Must(template.New("d").Parse(`<p>{{.Status}}</p>`)),
+	})
+
+	rec := unavailableRequest(t, "text/html")
+	assert.Equal(t, "<p>503</p>", rec.Body.String())
+}
+
+func TestGatewayErrorJSONForAPIClients(t *testing.T) {
+	withErrorPages(t, map[string]*template.Template{
+		defaultErrorPage: template.Must(template.New("d").Parse(`<p>generic</p>`)),
+	})
+
+	rec := unavailableRequest(t, "application/json, text/html;q=0.5")
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var body map[string]string
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.Equal(t, "Service not available", body["error"])
+}
+
+func TestGatewayErrorPlainTextWithoutPreference(t *testing.T) {
+	withErrorPages(t, map[string]*template.Template{})
+
+	for _, accept := range []string{"", "*/*", "text/html"} {
+		rec := unavailableRequest(t, accept)
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Equal(t, "text/plain; charset=utf-8", rec.Header().Get("Content-Type"), accept)
+	}
+}
+
+func TestLoadErrorPages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "503.html")
+	require.NoError(t, os.WriteFile(path, []byte(`<p>{{.Message}}</p>`), 0o600))
+
+	pages, err := loadErrorPages("users-service=" + path)
+	require.NoError(t, err)
+	assert.Contains(t, pages, "users-service")
+
+	_, err = loadErrorPages("users-service=/does/not/exist.html")
+	assert.Error(t, err)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultHealthCheckTimeout bounds the whole /_gateway/health report, so one
+// hung backend cannot hold the response past it.
+const defaultHealthCheckTimeout = 3 * time.Second
+
+// healthCheckTimeout is the deadline shared by every probe of one report.
+var healthCheckTimeout = defaultHealthCheckTimeout
+
+// healthServices lists the services /_gateway/health reports on. When empty,
+// every service registered in Consul is checked.
+var healthServices []string
+
+// healthClient probes backend /health endpoints; the request context carries
+// the deadline.
+var healthClient = &http.Client{}
+
+// instanceHealth is one probed instance in the /_gateway/health report.
+type instanceHealth struct {
+	URL       string `json:"url"`
+	Healthy   bool   `json:"healthy"`
+	Status    int    `json:"status,omitempty"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// serviceHealth aggregates the instances of one service. A service is
+// "healthy" when every instance answered 200, "degraded" when only some did
+// and "unhealthy" when none did or Consul had none to offer.
+type serviceHealth struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	// LatencyMS is the slowest probe, the time the service took to report
+	LatencyMS int64            `json:"latency_ms"`
+	Instances []instanceHealth `json:"instances"`
+	Error     string           `json:"error,omitempty"`
+}
+
+// handleHealth reports the health of every configured service by querying
+// Consul for its instances and probing each one's /health concurrently. It
+// answers 503 when any service has no healthy instance.
+func handleHealth(w http.ResponseWriter, r *http.Request) {
+	if consul == nil {
+		http.Error(w, "Consul discovery is not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	names := healthServices
+	if len(names) == 0 {
+		var err error
+		if names, err = consul.Services(); err != nil {
+			http.Error(w, "Service listing failed: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+	defer cancel()
+
+	services := make([]serviceHealth, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			services[i] = checkService(ctx, name)
+		}(i, name)
+	}
+	wg.Wait()
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+
+	healthy, status := true, http.StatusOK
+	for _, s := range services {
+		if s.Status == "unhealthy" {
+			healthy, status = false, http.StatusServiceUnavailable
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"healthy":  healthy,
+		"services": services,
+	})
+}
+
+// checkService probes every instance Consul lists as passing for name.
+func checkService(ctx context.Context, name string) serviceHealth {
+	report := serviceHealth{Name: name, Status: "unhealthy", Instances: []instanceHealth{}}
+	instances, err := consul.Instances(name)
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	if len(instances) == 0 {
+		report.Error = "no healthy instances registered"
+		return report
+	}
+
+	report.Instances = make([]instanceHealth, len(instances))
+	var wg sync.WaitGroup
+	for i, instance := range instances {
+		endpoint, err := instance.URL()
+		if err != nil {
+			report.Instances[i] = instanceHealth{URL: instance.Address, Error: err.Error()}
+			continue
+		}
+		wg.Add(1)
+		go func(i int, target string) {
+			defer wg.Done()
+			report.Instances[i] = probeInstance(ctx, target)
+		}(i, endpoint.JoinPath("health").String())
+	}
+	wg.Wait()
+
+	up := 0
+	for _, instance := range report.Instances {
+		if instance.Healthy {
+			up++
+		}
+		report.LatencyMS = max(report.LatencyMS, instance.LatencyMS)
+	}
+	switch up {
+	case len(report.Instances):
+		report.Status = "healthy"
+	case 0:
+		report.Status = "unhealthy"
+	default:
+		report.Status = "degraded"
+	}
+	return report
+}
+
+// probeInstance GETs target; only a 200 counts as healthy.
+func probeInstance(ctx context.Context, target string) instanceHealth {
+	result := instanceHealth{URL: target}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	start := time.Now()
+	resp, err := healthClient.Do(req)
+	result.LatencyMS = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	resp.Body.Close()
+	result.Status = resp.StatusCode
+	result.Healthy = resp.StatusCode == http.StatusOK
+	return result
+}
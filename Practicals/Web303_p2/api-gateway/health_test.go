@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// upstreamEntry registers an httptest server as a Consul service instance.
+func upstreamEntry(t *testing.T, id string, srv *httptest.Server) *consulapi.ServiceEntry {
+	host, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	require.NoError(t, err)
+	p, err := strconv.Atoi(port)
+	require.NoError(t, err)
+	return serviceEntry(id, host, p)
+}
+
+func stubHealth(status int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(status)
+	}))
+}
+
+type healthReport struct {
+	Healthy  bool            `json:"healthy"`
+	Services []serviceHealth `json:"services"`
+}
+
+func getHealth(t *testing.T) (int, healthReport) {
+	req := httptest.NewRequest(http.MethodGet, "/_gateway/health", nil)
+	req.Header.Set("X-Admin-Token", "secret")
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, req)
+
+	var report healthReport
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report), rec.Body.String())
+	return rec.Code, report
+}
+
+func TestHealthAggregatesHealthyAndFailingServices(t *testing.T) {
+	healthy := stubHealth(http.StatusOK)
+	defer healthy.Close()
+	failing := stubHealth(http.StatusServiceUnavailable)
+	defer failing.Close()
+
+	withAdminToken(t, "secret")
+	withFakeConsul(t, &fakeHealth{entries: map[string][]*consulapi.ServiceEntry{
+		"users-service":    {upstreamEntry(t, "users-1", healthy)},
+		"products-service": {upstreamEntry(t, "products-1", failing)},
+	}})
+
+	code, report := getHealth(t)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.False(t, report.Healthy)
+	require.Len(t, report.Services, 2)
+
+	products, users := report.Services[0], report.Services[1]
+	assert.Equal(t, "products-service", products.Name)
+	assert.Equal(t, "unhealthy", products.Status)
+	require.Len(t, products.Instances, 1)
+	assert.Equal(t, http.StatusServiceUnavailable, products.Instances[0].Status)
+	assert.False(t, products.Instances[0].Healthy)
+
+	assert.Equal(t, "users-service", users.Name)
+	assert.Equal(t, "healthy", users.Status)
+	require.Len(t, users.Instances, 1)
+	assert.True(t, users.Instances[0].Healthy)
+	assert.Equal(t, healthy.URL+"/health", users.Instances[0].URL)
+}
+
+func TestHealthIsOKWhileEveryServiceHasAHealthyInstance(t *testing.T) {
+	healthy := stubHealth(http.StatusOK)
+	defer healthy.Close()
+	failing := stubHealth(http.StatusInternalServerError)
+	defer failing.Close()
+
+	withAdminToken(t, "secret")
+	withFakeConsul(t, &fakeHealth{entries: map[string][]*consulapi.ServiceEntry{
+		"users-service": {upstreamEntry(t, "users-1", healthy), upstreamEntry(t, "users-2", failing)},
+	}})
+
+	code, report := getHealth(t)
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, report.Healthy)
+	require.Len(t, report.Services, 1)
+	assert.Equal(t, "degraded", report.Services[0].Status)
+}
+
+func TestHealthTimesOutHungInstancesTogether(t *testing.T) {
+	release := make(chan struct{})
+	hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer hung.Close()
+	defer close(release)
+
+	orig, origServices := healthCheckTimeout, healthServices
+	healthCheckTimeout = 100 * time.Millisecond
+	t.Cleanup(func() { healthCheckTimeout, healthServices = orig, origServices })
+
+	withAdminToken(t, "secret")
+	withFakeConsul(t, &fakeHealth{entries: map[string][]*consulapi.ServiceEntry{
+		"users-service":    {upstreamEntry(t, "users-1", hung)},
+		"products-service": {upstreamEntry(t, "products-1", hung)},
+		"orders-service":   {},
+		"unlisted-service": {upstreamEntry(t, "unlisted-1", hung)},
+	}})
+	// Only the configured services are reported, even one Consul has no instances for
+	healthServices = []string{"users-service", "products-service", "orders-service"}
+
+	start := time.Now()
+	code, report := getHealth(t)
+	assert.Less(t, time.Since(start), time.Second, "services are checked concurrently under one deadline")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	require.Len(t, report.Services, 3)
+	for _, s := range report.Services {
+		assert.Equal(t, "unhealthy", s.Status, s.Name)
+	}
+	assert.Equal(t, "orders-service", report.Services[0].Name)
+	assert.NotEmpty(t, report.Services[0].Error)
+	assert.NotEmpty(t, report.Services[1].Instances[0].Error)
+}
+
+func TestHealthRequiresAdmin(t *testing.T) {
+	withAdminToken(t, "secret")
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/_gateway/health", nil))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// defaultIdempotencyTTL is how long a completed response is replayable.
+const defaultIdempotencyTTL = 24 * time.Hour
+
+// maxIdempotentBody caps the response size kept for replay; larger responses
+// are passed through without being stored.
+const maxIdempotentBody = 1 << 20
+
+// errKeyInFlight means another request with the same key has not finished.
+var errKeyInFlight = errors.New("idempotency key is in use by an in-flight request")
+
+// storedResponse is a response saved for replay under an idempotency key.
+type storedResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// idempotencyStore persists idempotency keys. Implementations must make
+// Reserve atomic so that concurrent requests with one key run only once.
+type idempotencyStore interface {
+	// Reserve claims key for a new request. It returns the stored response
+	// when the key has already completed, nil when the caller now owns the
+	// key, or errKeyInFlight when another request owns it.
+	Reserve(ctx context.Context, key string, ttl time.Duration) (*storedResponse, error)
+	// Save records the response for a key the caller reserved.
+	Save(ctx context.Context, key string, resp *storedResponse, ttl time.Duration) error
+	// Release forgets a reserved key so the request can be retried.
+	Release(ctx context.Context, key string) error
+}
+
+// memoryIdempotencyStore keeps keys in process memory. It is the default,
+// but keys are lost on restart and not shared between replicas.
+type memoryIdempotencyStore struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]memoryIdempotencyEntry
+	ops     int
+}
+
+type memoryIdempotencyEntry struct {
+	resp      *storedResponse // nil while the request is in flight
+	expiresAt time.Time
+}
+
+// memorySweepEvery is how many Reserve calls pass between expiry sweeps.
+const memorySweepEvery = 1024
+
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{now: time.Now, entries: make(map[string]memoryIdempotencyEntry)}
+}
+
+func (s *memoryIdempotencyStore) Reserve(ctx context.Context, key string, ttl time.Duration) (*storedResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if s.ops++; s.ops%memorySweepEvery == 0 {
+		for k, e := range s.entries {
+			if !now.Before(e.expiresAt) {
+				delete(s.entries, k)
+			}
+		}
+	}
+
+	if entry, ok := s.entries[key]; ok && now.Before(entry.expiresAt) {
+		if entry.resp == nil {
+			return nil, errKeyInFlight
+		}
+		return entry.resp, nil
+	}
+	s.entries[key] = memoryIdempotencyEntry{expiresAt: now.Add(ttl)}
+	return nil, nil
+}
+
+func (s *memoryIdempotencyStore) Save(ctx context.Context, key string, resp *storedResponse, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = memoryIdempotencyEntry{resp: resp, expiresAt: s.now().Add(ttl)}
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// redisIdempotencyStore shares keys between gateway replicas. Expiry is left
+// to Redis key TTLs.
+type redisIdempotencyStore struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+// redisPending marks a key reserved by an in-flight request.
+const redisPending = "pending"
+
+func newRedisIdempotencyStore(client redis.UniversalClient) *redisIdempotencyStore {
+	return &redisIdempotencyStore{client: client, prefix: "gateway:idempotency:"}
+}
+
+func (s *redisIdempotencyStore) Reserve(ctx context.Context, key string, ttl time.Duration) (*storedResponse, error) {
+	k := s.prefix + key
+	for {
+		claimed, err := s.client.SetNX(ctx, k, redisPending, ttl).Result()
+		if err != nil {
+			return nil, err
+		}
+		if claimed {
+			return nil, nil
+		}
+
+		raw, err := s.client.Get(ctx, k).Bytes()
+		if errors.Is(err, redis.Nil) {
+			// Expired between SETNX and GET, so the previous owner is gone;
+			// try to claim it again
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if string(raw) == redisPending {
+			return nil, errKeyInFlight
+		}
+
+		var resp storedResponse
+		if err := json.Unmarshal(raw, &resp); err != nil {
+			return nil, fmt.Errorf("corrupt idempotency entry %q: %w", key, err)
+		}
+		return &resp, nil
+	}
+}
+
+func (s *redisIdempotencyStore) Save(ctx context.Context, key string, resp *storedResponse, ttl time.Duration) error {
+	raw, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, s.prefix+key, raw, ttl).Err()
+}
+
+func (s *redisIdempotencyStore) Release(ctx context.Context, key string) error {
+	return s.client.Del(ctx, s.prefix+key).Err()
+}
+
+// idempotencyKeys applies Idempotency-Key headers to proxied requests. main
+// sets its store; it stays disabled until then.
+var idempotencyKeys = &idempotency{}
+
+// idempotency replays responses for repeated POST/PATCH requests carrying the
+// same Idempotency-Key header. A nil store disables it. It must run inside
+// requireAuth, since keys are scoped to the caller it identifies.
+type idempotency struct {
+	store idempotencyStore
+	ttl   time.Duration
+}
+
+// callerScope names who sent r, so one caller's key never replays another's
+// response: the token subject when auth forwarded one, otherwise a hash of
+// the Authorization header, which is empty for anonymous callers.
+func callerScope(r *http.Request) string {
+	if sub := r.Header.Get(userIDHeader); sub != "" {
+		return "sub:" + sub
+	}
+	sum := sha256.Sum256([]byte(r.Header.Get("Authorization")))
+	return "auth:" + hex.EncodeToString(sum[:])
+}
+
+func (m *idempotency) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if m.store == nil || key == "" || (r.Method != http.MethodPost && r.Method != http.MethodPatch) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Scope keys to the caller and endpoint so clients cannot collide
+		// with each other or across routes
+		scoped := callerScope(r) + " " + r.Method + " " + r.URL.Path + " " + key
+		stored, err := m.store.Reserve(r.Context(), scoped, m.ttl)
+		switch {
+		case errors.Is(err, errKeyInFlight):
+			http.Error(w, "A request with this Idempotency-Key is still in progress", http.StatusConflict)
+			return
+		case err != nil:
+			// Fail open: a store outage should not take the gateway down
+			log.Printf("Idempotency store unavailable, serving without it: %v", err)
+			next.ServeHTTP(w, r)
+			return
+		case stored != nil:
+			for name, values := range stored.Header {
+				w.Header()[name] = values
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(stored.Status)
+			w.Write(stored.Body)
+			return
+		}
+
+		ctx := context.WithoutCancel(r.Context())
+		release := func() {
+			if err := m.store.Release(ctx, scoped); err != nil {
+				log.Printf("Failed to release idempotency key: %v", err)
+			}
+		}
+		completed := false
+		defer func() {
+			// A panic is on its way to recoverMiddleware; free the key so
+			// retries are not refused as in flight until the TTL runs out
+			if !completed {
+				release()
+			}
+		}()
+
+		capture := &captureWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(capture, r)
+		completed = true
+
+		// Server errors, oversized bodies and streamed responses (which have no
+		// reliable size) are not replayed, so retries reach the backend again
+		if capture.status >= http.StatusInternalServerError || capture.overflow || capture.streamed {
+			release()
+			return
+		}
+		resp := &storedResponse{Status: capture.status, Header: w.Header().Clone(), Body: capture.body.Bytes()}
+		if err := m.store.Save(ctx, scoped, resp, m.ttl); err != nil {
+			log.Printf("Failed to store idempotent response: %v", err)
+		}
+	})
+}
+
+// captureWriter passes a response through while keeping a copy for replay.
+type captureWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+	overflow    bool
+	streamed    bool
+}
+
+func (c *captureWriter) WriteHeader(status int) {
+	if !c.wroteHeader {
+		c.status, c.wroteHeader = status, true
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *captureWriter) Write(p []byte) (int, error) {
+	c.wroteHeader = true
+	if !c.overflow {
+		if c.body.Len()+len(p) > maxIdempotentBody {
+			c.overflow = true
+			c.body.Reset()
+		} else {
+			c.body.Write(p)
+		}
+	}
+	return c.ResponseWriter.Write(p)
+}
+
+// Flush marks the response as streamed, as the reverse proxy does for chunked
+// upstream responses, and passes the flush on.
+func (c *captureWriter) Flush() {
+	c.streamed = true
+	http.NewResponseController(c.ResponseWriter).Flush()
+}
+
+// newIdempotencyStore builds the store named by kind ("memory" or "redis").
+func newIdempotencyStore(kind, redisAddr string) (idempotencyStore, error) {
+	switch kind {
+	case "", "memory":
+		return newMemoryIdempotencyStore(), nil
+	case "redis":
+		if redisAddr == "" {
+			return nil, errors.New("redis idempotency store requires GATEWAY_REDIS_ADDR")
+		}
+		return newRedisIdempotencyStore(redis.NewClient(&redis.Options{Addr: redisAddr})), nil
+	case "off":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown idempotency store %q", kind)
+	}
+}
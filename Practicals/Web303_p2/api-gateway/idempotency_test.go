@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testIdempotencyStore runs the behaviour every store must share. expire
+// moves the store's clock past ttl.
+func testIdempotencyStore(t *testing.T, store idempotencyStore, expire func(time.Duration)) {
+	ctx := context.Background()
+	ttl := time.Minute
+
+	stored, err := store.Reserve(ctx, "k1", ttl)
+	require.NoError(t, err)
+	assert.Nil(t, stored, "first reservation owns the key")
+
+	_, err = store.Reserve(ctx, "k1", ttl)
+	assert.ErrorIs(t, err, errKeyInFlight)
+
+	resp := &storedResponse{Status: http.StatusCreated, Header: http.Header{"X-Id": {"7"}}, Body: []byte(`{"id":7}`)}
+	require.NoError(t, store.Save(ctx, "k1", resp, ttl))
+
+	stored, err = store.Reserve(ctx, "k1", ttl)
+	require.NoError(t, err)
+	assert.Equal(t, resp, stored)
+
+	_, err = store.Reserve(ctx, "k2", ttl)
+	require.NoError(t, err)
+	require.NoError(t, store.Release(ctx, "k2"))
+	stored, err = store.Reserve(ctx, "k2", ttl)
+	require.NoError(t, err)
+	assert.Nil(t, stored, "released keys can be reserved again")
+
+	expire(2 * ttl)
+	stored, err = store.Reserve(ctx, "k1", ttl)
+	require.NoError(t, err)
+	assert.Nil(t, stored, "expired keys are forgotten")
+}
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	clock := time.Now()
+	store := newMemoryIdempotencyStore()
+	store.now = func() time.Time { return clock }
+
+	testIdempotencyStore(t, store, func(d time.Duration) { clock = clock.Add(d) })
+}
+
+func TestRedisIdempotencyStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	testIdempotencyStore(t, newRedisIdempotencyStore(client), mr.FastForward)
+}
+
+func TestRedisIdempotencyStoreSharedAcrossReplicas(t *testing.T) {
+	mr := miniredis.RunT(t)
+	replicaA := newRedisIdempotencyStore(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	replicaB := newRedisIdempotencyStore(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	ctx := context.Background()
+
+	_, err := replicaA.Reserve(ctx, "order-1", time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, replicaA.Save(ctx, "order-1", &storedResponse{Status: http.StatusCreated}, time.Minute))
+
+	stored, err := replicaB.Reserve(ctx, "order-1", time.Minute)
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, http.StatusCreated, stored.Status)
+}
+
+// expireAfterSetNX fast-forwards miniredis past the key's TTL right after the
+// first SET NX, so the key is gone by the time Reserve reads it.
+type expireAfterSetNX struct {
+	mr    *miniredis.Miniredis
+	ttl   time.Duration
+	fired bool
+}
+
+func (h *expireAfterSetNX) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h *expireAfterSetNX) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func (h *expireAfterSetNX) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		err := next(ctx, cmd)
+		if cmd.Name() == "set" && !h.fired {
+			h.fired = true
+			h.mr.FastForward(h.ttl)
+		}
+		return err
+	}
+}
+
+func TestRedisIdempotencyStoreReclaimsKeyExpiredBeforeGet(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	store := newRedisIdempotencyStore(client)
+	ctx := context.Background()
+
+	_, err := store.Reserve(ctx, "k1", time.Minute)
+	require.NoError(t, err)
+
+	client.AddHook(&expireAfterSetNX{mr: mr, ttl: time.Minute})
+	stored, err := store.Reserve(ctx, "k1", time.Minute)
+	require.NoError(t, err, "nobody holds the key any more")
+	assert.Nil(t, stored, "the retry claims the key")
+
+	_, err = store.Reserve(ctx, "k1", time.Minute)
+	assert.ErrorIs(t, err, errKeyInFlight, "the reclaimed key is held")
+}
+
+func TestIdempotencyMiddlewareReplaysResponses(t *testing.T) {
+	var calls int32
+	handler := (&idempotency{store: newMemoryIdempotencyStore(), ttl: time.Minute}).Wrap(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			n := atomic.AddInt32(&calls, 1)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintf(w, `{"order":%d}`, n)
+		}))
+
+	send := func(method, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/orders/new", nil)
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := send(http.MethodPost, "abc")
+	replay := send(http.MethodPost, "abc")
+	assert.Equal(t, http.StatusCreated, replay.Code)
+	assert.Equal(t, first.Body.String(), replay.Body.String())
+	assert.Equal(t, "true", replay.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, "application/json", replay.Header().Get("Content-Type"))
+	assert.EqualValues(t, 1, atomic.LoadInt32(&calls))
+
+	send(http.MethodPost, "other")
+	send(http.MethodPost, "")
+	send(http.MethodGet, "abc")
+	assert.EqualValues(t, 4, atomic.LoadInt32(&calls), "new keys, missing keys and GETs are not replayed")
+}
+
+func TestIdempotencyMiddlewareDoesNotStoreServerErrors(t *testing.T) {
+	var calls int32
+	handler := (&idempotency{store: newMemoryIdempotencyStore(), ttl: time.Minute}).Wrap(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/api/orders/new", nil)
+		req.Header.Set("Idempotency-Key", "abc")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	assert.EqualValues(t, 2, atomic.LoadInt32(&calls))
+}
+
+func TestIdempotencyKeysAreScopedToTheCaller(t *testing.T) {
+	var calls int32
+	handler := (&idempotency{store: newMemoryIdempotencyStore(), ttl: time.Minute}).Wrap(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, `{"order":%d}`, atomic.AddInt32(&calls, 1))
+		}))
+
+	send := func(header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/orders/new", nil)
+		req.Header.Set("Idempotency-Key", "abc")
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	alice := send("Authorization", "Bearer alice")
+	assert.Empty(t, send("Authorization", "Bearer mallory").Header().Get("Idempotent-Replayed"))
+	assert.Empty(t, send("", "").Header().Get("Idempotent-Replayed"), "anonymous callers get their own scope")
+	replay := send("Authorization", "Bearer alice")
+	assert.Equal(t, "true", replay.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, alice.Body.String(), replay.Body.String())
+	assert.EqualValues(t, 3, atomic.LoadInt32(&calls))
+
+	// A token subject outlives any one token, so a refreshed token still replays
+	send(userIDHeader, "42")
+	assert.Equal(t, "true", send(userIDHeader, "42").Header().Get("Idempotent-Replayed"))
+	assert.EqualValues(t, 4, atomic.LoadInt32(&calls))
+}
+
+func TestIdempotencyRunsAfterAuth(t *testing.T) {
+	withJWTSecret(t)
+	var calls int32
+	withUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"user":%d,"by":%q}`, atomic.AddInt32(&calls, 1), r.Header.Get(userIDHeader))
+	})
+	orig := idempotencyKeys
+	idempotencyKeys = &idempotency{store: newMemoryIdempotencyStore(), ttl: time.Minute}
+	t.Cleanup(func() { idempotencyKeys = orig })
+	router := newRouter()
+
+	send := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/users", nil)
+		req.Header.Set("Idempotency-Key", "abc")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	owner := signToken(t, jwt.MapClaims{"sub": "7", "exp": time.Now().Add(time.Hour).Unix()})
+	first := send(owner)
+	require.Equal(t, http.StatusCreated, first.Code)
+
+	anonymous := send("")
+	assert.Equal(t, http.StatusUnauthorized, anonymous.Code, "unauthenticated callers never see a stored response")
+	assert.NotContains(t, anonymous.Body.String(), `"by":"7"`)
+
+	other := signToken(t, jwt.MapClaims{"sub": "8", "exp": time.Now().Add(time.Hour).Unix()})
+	assert.Contains(t, send(other).Body.String(), `"by":"8"`, "another caller's key is their own")
+	assert.Equal(t, first.Body.String(), send(owner).Body.String())
+	assert.EqualValues(t, 2, atomic.LoadInt32(&calls))
+}
+
+func TestIdempotencyReleasesKeyOnPanic(t *testing.T) {
+	var calls int32
+	handler := (&idempotency{store: newMemoryIdempotencyStore(), ttl: time.Minute}).Wrap(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				panic("boom")
+			}
+			w.WriteHeader(http.StatusCreated)
+		}))
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/orders/new", nil)
+		req.Header.Set("Idempotency-Key", "abc")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.PanicsWithValue(t, "boom", func() { send() }, "the panic still reaches recoverMiddleware")
+	assert.Equal(t, http.StatusCreated, send().Code, "the retry is not refused as in flight")
+	assert.EqualValues(t, 2, atomic.LoadInt32(&calls))
+}
+
+func TestNewIdempotencyStore(t *testing.T) {
+	store, err := newIdempotencyStore("", "")
+	require.NoError(t, err)
+	assert.IsType(t, &memoryIdempotencyStore{}, store)
+
+	_, err = newIdempotencyStore("redis", "")
+	assert.Error(t, err)
+
+	store, err = newIdempotencyStore("off", "")
+	require.NoError(t, err)
+	assert.Nil(t, store)
+
+	_, err = newIdempotencyStore("etcd", "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func captureAccessLog(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	orig := accessLog
+	accessLog = slog.New(slog.NewJSONHandler(&buf, nil))
+	t.Cleanup(func() { accessLog = orig })
+	return &buf
+}
+
+func TestRequestLoggerGeneratesAndPropagatesID(t *testing.T) {
+	logs := captureAccessLog(t)
+	var upstreamID string
+	withUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		upstreamID = r.Header.Get(requestIDHeader)
+		w.WriteHeader(http.StatusAccepted)
+	})
+
+	rec := httptest.NewRecorder()
+	requestLogger(newRouter()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/users/1", nil))
+
+	require.Equal(t, http.StatusAccepted, rec.Code)
+	id := rec.Header().Get(requestIDHeader)
+	assert.Len(t, id, 32)
+	assert.Equal(t, id, upstreamID, "the generated ID is forwarded upstream")
+
+	var lines []map[string]any
+	scanner := bufio.NewScanner(logs)
+	for scanner.Scan() {
+		var line map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		lines = append(lines, line)
+	}
+	require.NotEmpty(t, lines)
+	for _, line := range lines {
+		assert.Equal(t, id, line["request_id"], line["msg"])
+	}
+
+	last := lines[len(lines)-1]
+	assert.Equal(t, "request completed", last["msg"])
+	assert.Equal(t, "GET", last["method"])
+	assert.Equal(t, "/api/users/1", last["path"])
+	assert.Contains(t, last["upstream"], "127.0.0.1")
+	assert.EqualValues(t, http.StatusAccepted, last["status"])
+	assert.Contains(t, last, "duration_ms")
+}
+
+func TestRequestLoggerKeepsClientID(t *testing.T) {
+	captureAccessLog(t)
+	var upstreamID string
+	withUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		upstreamID = r.Header.Get(requestIDHeader)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users/1", nil)
+	req.Header.Set(requestIDHeader, "client-chosen")
+	rec := httptest.NewRecorder()
+	requestLogger(newRouter()).ServeHTTP(rec, req)
+
+	assert.Equal(t, "client-chosen", rec.Header().Get(requestIDHeader))
+	assert.Equal(t, "client-chosen", upstreamID)
+}
@@ -2,87 +2,573 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
+
+	"consulutil"
 
 	consulapi "github.com/hashicorp/consul/api"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const gatewayPort = 8080
 
+// defaultMaxPathLength caps the request path size accepted by the gateway.
+const defaultMaxPathLength = 2048
+
+// maxPathLength is the longest URL path routeRequest will accept before
+// rejecting the request with 414 URI Too Long.
+var maxPathLength = defaultMaxPathLength
+
 func main() {
-	router := http.NewServeMux()
-	router.HandleFunc("/", routeRequest)
+	maxPathLength = envInt("GATEWAY_MAX_PATH_LENGTH", defaultMaxPathLength)
+
+	shutdownTracing, err := setupTracing(context.Background())
+	if err != nil {
+		log.Fatalf("Tracing setup failed: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	lookup := lookupInstances
+	switch source := os.Getenv("GATEWAY_DISCOVERY"); source {
+	case "", "consul":
+		client, err := consulutil.New(consulapi.DefaultConfig())
+		if err != nil {
+			log.Fatalf("Consul client error: %v", err)
+		}
+		consul = client
+	case "static":
+		// Local dev without Consul: poll configured backends directly
+		static, err := loadStaticBackends(os.Getenv("GATEWAY_STATIC_BACKENDS_FILE"))
+		if err != nil {
+			log.Fatalf("Invalid GATEWAY_STATIC_BACKENDS_FILE: %v", err)
+		}
+		static.PollOnce(context.Background())
+		go static.Poll(context.Background(), envDuration("GATEWAY_HEALTH_POLL_INTERVAL", defaultHealthPollInterval))
+		lookup = static.lookup
+		log.Printf("Static discovery for %v", static.Services())
+	default:
+		log.Fatalf("Invalid GATEWAY_DISCOVERY %q, expected consul or static", source)
+	}
+
+	if path := os.Getenv("GATEWAY_ROUTES_FILE"); path != "" {
+		table, err := loadRouteTable(path)
+		if err != nil {
+			log.Fatalf("Invalid GATEWAY_ROUTES_FILE: %v", err)
+		}
+		routeTable.Store(table)
+		go watchRouteReloads(path)
+	}
+
+	aliases, err := parseKeyValues(os.Getenv("GATEWAY_SERVICE_ALIASES"))
+	if err != nil {
+		log.Fatalf("Invalid GATEWAY_SERVICE_ALIASES: %v", err)
+	}
+	for alias, service := range aliases {
+		serviceAliases[alias] = service
+	}
+	consulSlots = newConsulLimiter(
+		envInt("GATEWAY_CONSUL_MAX_CONCURRENT", 0),
+		envDuration("GATEWAY_CONSUL_QUEUE_WAIT", defaultConsulQueueWait),
+	)
+
+	balancer, err = newLoadBalancer(os.Getenv("GATEWAY_LOAD_BALANCER"))
+	if err != nil {
+		log.Fatalf("Invalid GATEWAY_LOAD_BALANCER: %v", err)
+	}
+
+	mode, err := parseCacheMode(os.Getenv("GATEWAY_DISCOVERY_CACHE"))
+	if err != nil {
+		log.Fatalf("Invalid GATEWAY_DISCOVERY_CACHE: %v", err)
+	}
+	discovery = newServiceCache(mode, envDuration("GATEWAY_DISCOVERY_TTL", defaultDiscoveryTTL), lookup)
+	taggedDiscovery = newTaggedCaches(discovery)
+
+	breakers = newBreakerRegistry(
+		envInt("GATEWAY_BREAKER_THRESHOLD", defaultBreakerThreshold),
+		envDuration("GATEWAY_BREAKER_COOLDOWN", defaultBreakerCooldown),
+	)
+
+	upstreamTimeout = envDuration("GATEWAY_UPSTREAM_TIMEOUT", defaultUpstreamTimeout)
+	upstreamTransport = newUpstreamTransport(upstreamTimeout)
+
+	retryAttempts = envInt("GATEWAY_RETRY_ATTEMPTS", defaultRetryAttempts)
+	retryBaseDelay = envDuration("GATEWAY_RETRY_BASE_DELAY", defaultRetryBaseDelay)
+
+	healthServices = splitList(os.Getenv("GATEWAY_HEALTH_SERVICES"))
+	healthCheckTimeout = envDuration("GATEWAY_HEALTH_TIMEOUT", defaultHealthCheckTimeout)
+
+	batchConcurrency = envInt("GATEWAY_BATCH_CONCURRENCY", defaultBatchConcurrency)
+	batchMaxRequests = envInt("GATEWAY_BATCH_MAX_REQUESTS", defaultBatchMaxRequests)
+
+	bufferSizes, err := parseBufferSizes(os.Getenv("GATEWAY_PROXY_BUFFER_SIZES"))
+	if err != nil {
+		log.Fatalf("Invalid GATEWAY_PROXY_BUFFER_SIZES: %v", err)
+	}
+	bufferPools = newProxyBuffers(envInt("GATEWAY_PROXY_BUFFER_SIZE", 0), bufferSizes)
+
+	errorPages, err = loadErrorPages(os.Getenv("GATEWAY_ERROR_PAGES"))
+	if err != nil {
+		log.Fatalf("Invalid GATEWAY_ERROR_PAGES: %v", err)
+	}
+
+	if path := os.Getenv("GATEWAY_TRANSFORMERS_FILE"); path != "" {
+		if err := loadTransformers(path); err != nil {
+			log.Fatalf("Invalid GATEWAY_TRANSFORMERS_FILE: %v", err)
+		}
+	}
+
+	validator := &jwtValidator{hmacSecret: []byte(os.Getenv("GATEWAY_JWT_SECRET"))}
+	if source := os.Getenv("GATEWAY_JWT_JWKS"); source != "" {
+		if validator.rsaKeys, err = loadJWKS(source); err != nil {
+			log.Fatalf("Invalid GATEWAY_JWT_JWKS: %v", err)
+		}
+	}
+	if validator.Enabled() {
+		authValidator = validator
+	}
+	if raw, ok := os.LookupEnv("GATEWAY_AUTH_PUBLIC_PATHS"); ok {
+		publicPaths = splitList(raw)
+	}
+
+	readOnly = newReadOnlySet(strings.Split(os.Getenv("GATEWAY_READ_ONLY_SERVICES"), ",")...)
+
+	limits, err := parseRateLimits(os.Getenv("GATEWAY_RATE_LIMITS"))
+	if err != nil {
+		log.Fatalf("Invalid GATEWAY_RATE_LIMITS: %v", err)
+	}
+	rateLimits = newServiceRateLimiter(limits, envDuration("GATEWAY_RATE_LIMIT_IDLE", defaultRateLimitIdle))
+	if rateLimits != nil {
+		go rateLimits.RunSweeper(context.Background(), rateLimitSweepInterval)
+	}
+
+	shedder := &loadShedder{limit: int64(envInt("GATEWAY_MAX_INFLIGHT", 0))}
+
+	exempt, err := parseCIDRs(os.Getenv("GATEWAY_PER_IP_EXEMPT"))
+	if err != nil {
+		log.Fatalf("Invalid GATEWAY_PER_IP_EXEMPT: %v", err)
+	}
+	perIP := newIPLimiter(envInt("GATEWAY_MAX_PER_IP", 0), exempt)
+
+	store, err := newIdempotencyStore(os.Getenv("GATEWAY_IDEMPOTENCY_STORE"), os.Getenv("GATEWAY_REDIS_ADDR"))
+	if err != nil {
+		log.Fatalf("Invalid GATEWAY_IDEMPOTENCY_STORE: %v", err)
+	}
+	idempotencyKeys = &idempotency{store: store, ttl: envDuration("GATEWAY_IDEMPOTENCY_TTL", defaultIdempotencyTTL)}
+
+	if on, _ := strconv.ParseBool(os.Getenv("DEBUG_BODY_LOG")); on {
+		fields := defaultBodyLogRedactFields
+		if raw, ok := os.LookupEnv("DEBUG_BODY_LOG_REDACT_FIELDS"); ok {
+			fields = raw
+		}
+		bodyLog = newBodyLogger(envInt("DEBUG_BODY_LOG_MAX_BYTES", defaultBodyLogMaxBytes), splitList(fields))
+		log.Printf("DEBUG_BODY_LOG is on: logging up to %d bytes of every proxied body", bodyLog.maxBytes)
+	}
+
+	if webhook := os.Getenv("GATEWAY_PANIC_WEBHOOK_URL"); webhook != "" {
+		reporter = newPanicReporter(webhook)
+	}
 
 	server := &http.Server{
 		Addr:    fmt.Sprintf(":%d", gatewayPort),
-		Handler: router,
+		Handler: trackActive(traceRequests(requestLogger(recoverMiddleware(perIP.Wrap(shedder.Wrap(newRouter())))))),
+	}
+
+	server.TLSConfig, err = loadTLSConfig(os.Getenv("TLS_CERT_FILE"), os.Getenv("TLS_KEY_FILE"), os.Getenv("TLS_MIN_VERSION"))
+	if err != nil {
+		log.Fatalf("Invalid TLS configuration: %v", err)
+	}
+	if server.TLSConfig == nil {
+		log.Printf("TLS_CERT_FILE and TLS_KEY_FILE not set, serving plain HTTP")
 	}
 
-	log.Printf("API Gateway initializing on port %d...", gatewayPort)
-	if err := server.ListenAndServe(); err != nil {
+	ln, err := net.Listen("tcp", server.Addr)
+	if err != nil {
 		log.Fatalf("Gateway startup failed: %v", err)
 	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if port := envInt("TLS_REDIRECT_PORT", 0); port != 0 {
+		if server.TLSConfig == nil {
+			log.Fatalf("TLS_REDIRECT_PORT needs TLS_CERT_FILE and TLS_KEY_FILE")
+		}
+		redirectLn, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+		if err != nil {
+			log.Fatalf("HTTPS redirect listener failed: %v", err)
+		}
+		log.Printf("Redirecting HTTP on port %d to HTTPS", port)
+		go serveRedirects(ctx, redirectLn, gatewayPort)
+	}
+
+	log.Printf("API Gateway initializing on port %d (TLS %t)...", gatewayPort, server.TLSConfig != nil)
+	if err := serveUntilDone(ctx, server, ln, envDuration("GATEWAY_DRAIN_TIMEOUT", defaultDrainTimeout)); err != nil {
+		log.Printf("Gateway stopped: %v", err)
+	}
+}
+
+// newRouter wires the gateway's own endpoints ahead of the catch-all proxy.
+func newRouter() *http.ServeMux {
+	router := http.NewServeMux()
+	router.HandleFunc("GET /gateway/breakers", requireAdmin(handleBreakers))
+	router.HandleFunc("GET /gateway/resolve", requireAdmin(handleResolve))
+	router.HandleFunc("GET /gateway/stats", requireAdmin(handleStats))
+	router.HandleFunc("GET /_gateway/services", requireAdmin(handleServices))
+	router.HandleFunc("GET /_gateway/health", requireAdmin(handleHealth))
+	router.HandleFunc("GET /gateway/read-only", requireAdmin(handleReadOnlyList))
+	router.HandleFunc("PUT /gateway/read-only/{service}", requireAdmin(handleReadOnlySet))
+	router.HandleFunc("GET /metrics", handleMetrics)
+	router.HandleFunc("POST /gateway/batch", handleBatch)
+	router.HandleFunc("GET /gateway/auth/verify", handleAuthVerify)
+	router.HandleFunc("/", instrumentProxy(requireAuth(idempotencyKeys.Wrap(http.HandlerFunc(routeRequest)).ServeHTTP)))
+	return router
 }
 
 // routeRequest forwards HTTP requests to appropriate microservices based on URL path.
 func routeRequest(w http.ResponseWriter, r *http.Request) {
-	log.Printf("Incoming request: %s %s", r.Method, r.URL.Path)
-
-	// Parse the path to extract service name: /api/{service}/{resource}
-	pathParts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
-	if len(pathParts) < 3 || pathParts[0] != "api" {
-		http.Error(w, "Invalid path format", http.StatusBadRequest)
+	// Reject oversized paths before parsing or touching Consul
+	if len(r.URL.Path) > maxPathLength {
+		logFor(r).Warn("rejected oversized path", "path_bytes", len(r.URL.Path), "limit", maxPathLength)
+		writeGatewayError(w, r, "", http.StatusRequestURITooLong, "URI too long")
 		return
 	}
-	serviceName := pathParts[1] + "-service"
 
-	// Locate the service in Consul service registry
-	targetURL, err := discoverService(serviceName)
+	logFor(r).Info("incoming request", "method", r.Method, "path", r.URL.Path)
+
+	serviceName, forwardPath, err := parseRoute(r.URL.EscapedPath())
 	if err != nil {
-		log.Printf("Service discovery failed for '%s': %v", serviceName, err)
-		http.Error(w, "Service not available", http.StatusServiceUnavailable)
+		writeGatewayError(w, r, "", http.StatusBadRequest, "Invalid path format")
 		return
 	}
+	trace.SpanFromContext(r.Context()).SetName(r.Method + " " + serviceName)
 
-	log.Printf("Located service at: %s", targetURL)
+	if readOnly.Blocks(serviceName, r.Method) {
+		logFor(r).Warn("rejected write to read-only service", "method", r.Method, "service", serviceName)
+		rejectReadOnly(w)
+		return
+	}
 
-	// Create reverse proxy and adjust the request path
-	reverseProxy := httputil.NewSingleHostReverseProxy(targetURL)
+	// Throttle before discovery so abusive clients never reach Consul
+	if ok, retryAfter := rateLimits.Allow(clientIP(r).String(), serviceName); !ok {
+		logFor(r).Warn("rate limited", "service", serviceName, "client", r.RemoteAddr)
+		rejectRateLimited(w, retryAfter)
+		return
+	}
 
-	// Remove /api/{service} prefix before forwarding
-	r.URL.Path = "/" + strings.Join(pathParts[2:], "/")
-	log.Printf("Proxying to: %s%s", targetURL, r.URL.Path)
+	// Fail fast while the service's breaker is open
+	breaker := breakers.get(serviceName)
+	if !breaker.Allow() {
+		logFor(r).Warn("circuit open, rejecting request", "service", serviceName)
+		writeGatewayError(w, r, serviceName, http.StatusServiceUnavailable, "Service temporarily unavailable")
+		return
+	}
+
+	// One deadline covers every attempt, so retries can't stretch it
+	ctx, cancel := withUpstreamDeadline(r.Context(), upstreamTimeout)
+	defer cancel()
+	r = r.WithContext(ctx)
+
+	setForwardPath(r.URL, forwardPath)
+	tag := routingTag(r)
+	counter := &countingWriter{ResponseWriter: w}
+	defer func() { addUpstreamBytes(serviceName, counter.n) }()
+
+	// Idempotent requests may be retried on another instance
+	attempts := 1
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		attempts = retryAttempts
+	}
+
+	delay := retryBaseDelay
+	for attempt := 1; ; attempt++ {
+		// Locate the service in Consul service registry
+		targetURL, err := discoverService(serviceName, tag)
+		if errors.Is(err, errConsulBusy) {
+			// Consul is saturated by the gateway itself; not the service's fault
+			logFor(r).Warn("service discovery timed out waiting for a Consul slot", "service", serviceName)
+			writeRejection(w, http.StatusServiceUnavailable, reasonDiscoveryBusy, "service discovery busy", defaultConsulQueueWait)
+			return
+		}
+		if err != nil {
+			breaker.RecordFailure()
+			logFor(r).Error("service discovery failed", "service", serviceName, "error", err)
+			writeGatewayError(w, r, serviceName, http.StatusServiceUnavailable, "Service not available")
+			return
+		}
+
+		noteUpstream(r, targetURL.String())
+		logFor(r).Info("proxying", "service", serviceName, "upstream", targetURL.String(), "forward_path", r.URL.Path)
+		attemptReq, span := startProxySpan(r, serviceName, targetURL.String(), attempt)
+		retry := proxyTo(counter, attemptReq, serviceName, breaker, targetURL, attempt >= attempts)
+		span.End()
+		if !retry {
+			return
+		}
+
+		logFor(r).Warn("retrying request", "service", serviceName, "attempt", attempt+1, "attempts", attempts, "delay", delay.String())
+		select {
+		case <-r.Context().Done():
+			if deadlineExceeded(r.Context()) {
+				rejectUpstreamTimeout(w)
+			}
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+
+		if !breaker.Allow() {
+			writeGatewayError(w, r, serviceName, http.StatusServiceUnavailable, "Service temporarily unavailable")
+			return
+		}
+	}
+}
+
+// Retry defaults for idempotent requests whose upstream is unavailable.
+const (
+	defaultRetryAttempts  = 3
+	defaultRetryBaseDelay = 50 * time.Millisecond
+)
+
+// retryAttempts is the total number of tries for GET/HEAD requests, and
+// retryBaseDelay the first backoff, doubled after each failure.
+var (
+	retryAttempts  = defaultRetryAttempts
+	retryBaseDelay = defaultRetryBaseDelay
+)
+
+// errRetryUpstream aborts a proxied 502/503 so another instance can be tried.
+var errRetryUpstream = errors.New("upstream unavailable")
+
+// proxyTo forwards r to target. Unless last is set, a connection failure or a
+// 502/503 from the upstream writes nothing and returns true so the caller can
+// retry elsewhere.
+func proxyTo(w http.ResponseWriter, r *http.Request, serviceName string, breaker *circuitBreaker, target *url.URL, last bool) (retry bool) {
+	reverseProxy := httputil.NewSingleHostReverseProxy(target)
+	reverseProxy.BufferPool = bufferPools.For(serviceName)
+	reverseProxy.Transport = upstreamTransport
+	chain := transformers[serviceName]
+	director := reverseProxy.Director
+	reverseProxy.Director = func(req *http.Request) {
+		director(req)
+		if r.TLS != nil {
+			req.Header.Set("X-Forwarded-Proto", "https")
+		}
+		injectTraceContext(req)
+		chain.applyRequest(req)
+		if bodyLog != nil {
+			bodyLog.LogRequest(logFor(r), serviceName, req)
+		}
+	}
+	reverseProxy.ModifyResponse = func(resp *http.Response) error {
+		trace.SpanFromContext(resp.Request.Context()).SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+		if isStreamingResponse(resp) {
+			releaseDeadline(resp.Request.Context())
+		} else if bodyLog != nil {
+			bodyLog.LogResponse(logFor(r), serviceName, resp)
+		}
+		if resp.StatusCode >= http.StatusInternalServerError {
+			breaker.RecordFailure()
+		} else {
+			breaker.RecordSuccess()
+		}
+		if !last && (resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusServiceUnavailable) {
+			return errRetryUpstream
+		}
+		return chain.applyResponse(resp)
+	}
+	reverseProxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		span := trace.SpanFromContext(r.Context())
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		if errors.Is(err, errRetryUpstream) {
+			retry = true
+			return
+		}
+		breaker.RecordFailure()
+		logFor(r).Error("upstream failed", "service", serviceName, "upstream", target.String(), "error", err)
+		if !last && r.Context().Err() == nil {
+			retry = true
+			return
+		}
+		if isUpstreamTimeout(r, err) {
+			rejectUpstreamTimeout(w)
+			return
+		}
+		writeGatewayError(w, r, serviceName, http.StatusBadGateway, "Bad gateway")
+	}
 
 	reverseProxy.ServeHTTP(w, r)
+	return retry
+}
+
+// parseRoute maps a gateway path to the Consul service name and the path
+// forwarded to that service. Rules from the route table win; otherwise the
+// path must have the form /api/{service}/{resource...}. path should
+// be the escaped form (URL.EscapedPath) so encoded characters in the resource,
+// such as %2F, reach the service intact; forwardPath is escaped likewise. The
+// prefix and service segment are case-insensitive, the resource is forwarded
+// exactly as sent, and an empty resource forwards "/".
+func parseRoute(path string) (serviceName, forwardPath string, err error) {
+	if service, forward, ok := routeTable.Load().Match(path); ok {
+		return service, forward, nil
+	}
+
+	prefix, rest, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	segment, resource, _ := strings.Cut(rest, "/")
+
+	service, unescapeErr := url.PathUnescape(segment)
+	if !strings.EqualFold(prefix, "api") || unescapeErr != nil || service == "" || strings.Contains(service, "/") {
+		return "", "", fmt.Errorf("path %q does not match /api/{service}/{resource}", path)
+	}
+	if _, err := url.PathUnescape(resource); err != nil {
+		return "", "", fmt.Errorf("path %q has an invalid escape: %w", path, err)
+	}
+
+	return resolveServiceName(strings.ToLower(service)), "/" + resource, nil
+}
+
+// setForwardPath points u at the escaped path returned by parseRoute,
+// keeping Path and RawPath consistent. parseRoute has already validated it.
+func setForwardPath(u *url.URL, escaped string) {
+	u.Path, _ = url.PathUnescape(escaped)
+	u.RawPath = escaped
 }
 
-// discoverService queries Consul to find a healthy instance of a service.
-func discoverService(name string) (*url.URL, error) {
-// discoverService retrieves a service endpoint from Consul.
-func discoverService(serviceName string) (*url.URL, error) {
-	client, err := consulapi.NewClient(consulapi.DefaultConfig())
+// serviceAliases maps friendly path segments to Consul service names that do
+// not follow the {segment}-service convention.
+var serviceAliases = map[string]string{
+	"inventory": "products-service",
+}
+
+// resolveServiceName returns the Consul service for a path segment, checking
+// serviceAliases before falling back to the "-service" suffix rule.
+func resolveServiceName(segment string) string {
+	if name, ok := serviceAliases[segment]; ok {
+		return name
+	}
+	return segment + "-service"
+}
+
+// consul is the shared Consul client created once in main.
+var consul *consulutil.Client
+
+// discovery resolves service names to healthy instances.
+var discovery = newServiceCache(cacheOff, defaultDiscoveryTTL, lookupInstances)
+
+// discoverService retrieves a service endpoint, spreading calls across the
+// healthy instances with the configured load balancer. A non-empty tag
+// prefers instances registered with that Consul tag, falling back to every
+// healthy instance when none are.
+func discoverService(serviceName, tag string) (*url.URL, error) {
+	if tag != "" {
+		instances, err := taggedDiscovery.For(tag).Get(serviceName)
+		switch {
+		case errors.Is(err, errConsulBusy):
+			return nil, err
+		case err == nil && len(instances) > 0:
+			return balancer.Pick(serviceName, instances), nil
+		}
+	}
+
+	instances, err := discovery.Get(serviceName)
 	if err != nil {
-		return nil, fmt.Errorf("consul client error: %w", err)
+		return nil, err
 	}
 
-	// Fetch healthy service entries from Consul
-	healthyInstances, _, err := client.Health().Service(serviceName, "", true, nil)
+	return balancer.Pick(serviceName, instances), nil
+}
+
+// lookupInstances queries Consul for the healthy instances of a service.
+func lookupInstances(serviceName string) ([]*url.URL, error) {
+	return lookupTaggedInstances(serviceName, "")
+}
+
+// lookupTaggedInstances queries Consul for the healthy instances of a
+// service registered with tag. Only untagged lookups, which see the whole
+// service, update the instance gauge and weights, and only they fail when
+// nothing is found.
+func lookupTaggedInstances(serviceName, tag string) ([]*url.URL, error) {
+	if consul == nil {
+		return nil, fmt.Errorf("consul client not initialized")
+	}
+
+	release, err := consulSlots.Acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	healthy, err := consul.TaggedInstances(serviceName, tag)
 	if err != nil {
-		return nil, fmt.Errorf("consul query failed for '%s': %w", serviceName, err)
+		return nil, err
+	}
+	if tag != "" {
+		// No tagged instance is an empty answer rather than an error, so the
+		// cache remembers it and untagged routing takes over
+		return instanceURLs(serviceName, healthy)
+	}
+	healthyInstances.WithLabelValues(serviceName).Set(float64(len(healthy)))
+	if len(healthy) == 0 {
+		return nil, fmt.Errorf("%w for '%s'", consulutil.ErrNoHealthyInstances, serviceName)
 	}
+	instanceWeights.Update(serviceName, healthy)
+	return instanceURLs(serviceName, healthy)
+}
 
-	if len(healthyInstances) == 0 {
-		return nil, fmt.Errorf("no healthy instances available for '%s'", serviceName)
+// instanceURLs returns the base URL of each instance.
+func instanceURLs(serviceName string, healthy []consulutil.Instance) ([]*url.URL, error) {
+	instances := make([]*url.URL, 0, len(healthy))
+	for _, instance := range healthy {
+		endpoint, err := instance.URL()
+		if err != nil {
+			return nil, fmt.Errorf("invalid address for '%s': %w", serviceName, err)
+		}
+		instances = append(instances, endpoint)
 	}
+	return instances, nil
+}
 
-	// Use first available instance
-	instance := healthyInstances[0].Service
-	endpoint := fmt.Sprintf("http://%s:%d", instance.Address, instance.Port)
+// envInt reads a positive integer from the environment, falling back to def
+// when the variable is unset or invalid.
+func envInt(key string, def int) int {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v <= 0 {
+		log.Printf("Ignoring invalid %s=%q, using default %d", key, raw, def)
+		return def
+	}
+	return v
+}
 
-	return url.Parse(endpoint)
-}
\ No newline at end of file
+// envDuration reads a positive time.Duration such as "30s" from the
+// environment, falling back to def when unset or invalid.
+func envDuration(key string, def time.Duration) time.Duration {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def
+	}
+	v, err := time.ParseDuration(raw)
+	if err != nil || v <= 0 {
+		log.Printf("Ignoring invalid %s=%q, using default %s", key, raw, def)
+		return def
+	}
+	return v
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteRequestRejectsLongPath(t *testing.T) {
+	orig := maxPathLength
+	maxPathLength = 64
+	defer func() { maxPathLength = orig }()
+
+	path := "/api/users/" + strings.Repeat("a", 100)
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	rec := httptest.NewRecorder()
+
+	routeRequest(rec, req)
+
+	assert.Equal(t, http.StatusRequestURITooLong, rec.Code)
+}
+
+func TestEnvInt(t *testing.T) {
+	t.Setenv("GATEWAY_TEST_INT", "42")
+	assert.Equal(t, 42, envInt("GATEWAY_TEST_INT", 7))
+
+	t.Setenv("GATEWAY_TEST_INT", "nope")
+	assert.Equal(t, 7, envInt("GATEWAY_TEST_INT", 7))
+
+	t.Setenv("GATEWAY_TEST_INT", "")
+	assert.Equal(t, 7, envInt("GATEWAY_TEST_INT", 7))
+}
+
+func TestParseRouteServiceAliases(t *testing.T) {
+	tests := []struct {
+		path, service, forward string
+	}{
+		{"/api/inventory/items/7", "products-service", "/items/7"},
+		{"/api/users/42", "users-service", "/42"},
+		{"/api/orders/1", "orders-service", "/1"},
+	}
+	for _, tt := range tests {
+		service, forward, err := parseRoute(tt.path)
+		assert.NoError(t, err, tt.path)
+		assert.Equal(t, tt.service, service, tt.path)
+		assert.Equal(t, tt.forward, forward, tt.path)
+	}
+}
+
+func TestParseRouteIsCaseInsensitiveForPrefixAndService(t *testing.T) {
+	tests := []struct {
+		path, service, forward string
+	}{
+		{"/API/Users/1", "users-service", "/1"},
+		{"/Api/USERS/Profile/AbC", "users-service", "/Profile/AbC"},
+		{"/api/Inventory/Items/7", "products-service", "/Items/7"},
+	}
+	for _, tt := range tests {
+		service, forward, err := parseRoute(tt.path)
+		assert.NoError(t, err, tt.path)
+		assert.Equal(t, tt.service, service, tt.path)
+		assert.Equal(t, tt.forward, forward, tt.path)
+	}
+
+	_, _, err := parseRoute("/apis/users/1")
+	assert.Error(t, err)
+}
+
+func TestParseRouteEdgeCases(t *testing.T) {
+	tests := []struct {
+		name, path, service, forward string
+		wantErr                      bool
+	}{
+		{name: "single resource", path: "/api/users/42", service: "users-service", forward: "/42"},
+		{name: "nested resource", path: "/api/users/123/orders/5", service: "users-service", forward: "/123/orders/5"},
+		{name: "trailing slash kept", path: "/api/users/123/orders/", service: "users-service", forward: "/123/orders/"},
+		{name: "empty resource", path: "/api/users/", service: "users-service", forward: "/"},
+		{name: "no resource", path: "/api/users", service: "users-service", forward: "/"},
+		{name: "encoded slash", path: "/api/users/a%2Fb/c", service: "users-service", forward: "/a%2Fb/c"},
+		{name: "encoded space", path: "/api/users/John%20Doe", service: "users-service", forward: "/John%20Doe"},
+		{name: "encoded service", path: "/api/u%73ers/1", service: "users-service", forward: "/1"},
+		{name: "empty service", path: "/api//1", wantErr: true},
+		{name: "service with encoded slash", path: "/api/us%2Fers/1", wantErr: true},
+		{name: "bad escape", path: "/api/users/%zz", wantErr: true},
+		{name: "wrong prefix", path: "/v1/users/1", wantErr: true},
+		{name: "prefix only", path: "/api", wantErr: true},
+		{name: "root", path: "/", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, forward, err := parseRoute(tt.path)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.service, service)
+			assert.Equal(t, tt.forward, forward)
+		})
+	}
+}
+
+func TestRouteRequestPreservesEncodingAndQuery(t *testing.T) {
+	var gotPath, gotRawPath, gotQuery string
+	withUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotRawPath, gotQuery = r.URL.Path, r.URL.EscapedPath(), r.URL.RawQuery
+	})
+
+	rec := httptest.NewRecorder()
+	routeRequest(rec, httptest.NewRequest(http.MethodGet, "/api/users/files/a%2Fb/?sort=name&q=caf%C3%A9", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "/files/a/b/", gotPath)
+	assert.Equal(t, "/files/a%2Fb/", gotRawPath)
+	assert.Equal(t, "sort=name&q=caf%C3%A9", gotQuery)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metricsRegistry holds only the gateway's own metrics; Go runtime metrics
+// are left to the default registry.
+var metricsRegistry = prometheus.NewRegistry()
+
+var (
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_requests_total",
+		Help: "Proxied requests by service and response status code.",
+	}, []string{"service", "code"})
+
+	requestErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "gateway_request_errors_total",
+		Help: "Proxied requests answered with a 5xx status.",
+	}, []string{"service", "code"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gateway_request_duration_seconds",
+		Help:    "Time to serve proxied requests, retries included.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"service", "code"})
+
+	healthyInstances = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gateway_healthy_instances",
+		Help: "Healthy instances per service in the latest Consul lookup.",
+	}, []string{"service"})
+)
+
+func init() {
+	metricsRegistry.MustRegister(
+		requestsTotal, requestErrorsTotal, requestDuration, healthyInstances,
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "gateway_discovery_cache_hits_total",
+			Help: "Service lookups answered from the discovery cache.",
+		}, func() float64 { return float64(discovery.Stats().Hits) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "gateway_discovery_cache_misses_total",
+			Help: "Service lookups that had to query Consul.",
+		}, func() float64 { return float64(discovery.Stats().Misses) }),
+		upstreamBytesCollector{},
+	)
+}
+
+// handleMetrics serves the gateway's metrics for Prometheus to scrape.
+var handleMetrics = promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{}).ServeHTTP
+
+// unknownService labels requests whose path maps to no service.
+const unknownService = "unknown"
+
+// instrumentProxy records count, errors and latency of proxied requests. The
+// service label is resolved up front because routing rewrites the path.
+func instrumentProxy(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		service, _, err := parseRoute(r.URL.EscapedPath())
+		if err != nil {
+			service = unknownService
+		}
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next(rec, r)
+
+		code := strconv.Itoa(rec.Status())
+		requestsTotal.WithLabelValues(service, code).Inc()
+		if rec.Status() >= http.StatusInternalServerError {
+			requestErrorsTotal.WithLabelValues(service, code).Inc()
+		}
+		requestDuration.WithLabelValues(service, code).Observe(time.Since(start).Seconds())
+	}
+}
+
+// upstreamBytes counts response body bytes proxied per service. Bytes are
+// counted as they stream, so chunked responses without a Content-Length are
+// measured correctly.
+var upstreamBytes sync.Map // service name -> *atomic.Uint64
+
+func addUpstreamBytes(service string, n int64) {
+	counter, _ := upstreamBytes.LoadOrStore(service, new(atomic.Uint64))
+	counter.(*atomic.Uint64).Add(uint64(n))
+}
+
+// upstreamBytesDesc describes the counters exported from upstreamBytes.
+var upstreamBytesDesc = prometheus.NewDesc(
+	"gateway_upstream_response_bytes_total",
+	"Response body bytes proxied from each service.",
+	[]string{"service"}, nil,
+)
+
+// upstreamBytesCollector exports upstreamBytes at scrape time.
+type upstreamBytesCollector struct{}
+
+func (upstreamBytesCollector) Describe(ch chan<- *prometheus.Desc) { ch <- upstreamBytesDesc }
+
+func (upstreamBytesCollector) Collect(ch chan<- prometheus.Metric) {
+	upstreamBytes.Range(func(key, value interface{}) bool {
+		ch <- prometheus.MustNewConstMetric(upstreamBytesDesc, prometheus.CounterValue,
+			float64(value.(*atomic.Uint64).Load()), key.(string))
+		return true
+	})
+}
+
+func upstreamBytesFor(service string) uint64 {
+	if counter, ok := upstreamBytes.Load(service); ok {
+		return counter.(*atomic.Uint64).Load()
+	}
+	return 0
+}
+
+// countingWriter tallies the body bytes written through it.
+type countingWriter struct {
+	http.ResponseWriter
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.ResponseWriter.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying Flush, which the
+// reverse proxy relies on to stream chunked responses.
+func (c *countingWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withDiscoveryCache(t *testing.T) {
+	orig := discovery
+	discovery = newServiceCache(cacheLazy, time.Minute, func(string) ([]*url.URL, error) {
+		return []*url.URL{{Scheme: "http", Host: "users:8081"}}, nil
+	})
+	t.Cleanup(func() { discovery = orig })
+}
+
+func TestMetricsExposeDiscoveryCacheCounters(t *testing.T) {
+	withDiscoveryCache(t)
+	discoverService("users-service", "")
+	discoverService("users-service", "")
+	discoverService("users-service", "")
+
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "# TYPE gateway_discovery_cache_hits_total counter\ngateway_discovery_cache_hits_total 2\n")
+	assert.Contains(t, rec.Body.String(), "gateway_discovery_cache_misses_total 1\n")
+}
+
+func TestStatsReportsHitRatio(t *testing.T) {
+	withAdminToken(t, "secret")
+	withDiscoveryCache(t)
+	discoverService("users-service", "")
+	discoverService("users-service", "")
+
+	req := httptest.NewRequest(http.MethodGet, "/gateway/stats", nil)
+	req.Header.Set("X-Admin-Token", "secret")
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var body struct {
+		DiscoveryCache cacheStats `json:"discovery_cache"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.Equal(t, cacheStats{Hits: 1, Misses: 1, HitRatio: 0.5}, body.DiscoveryCache)
+}
+
+func scrapeMetrics(t *testing.T) string {
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	return rec.Body.String()
+}
+
+func TestMetricsCountProxiedRequests(t *testing.T) {
+	withUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	requestsTotal.Reset()
+	requestErrorsTotal.Reset()
+
+	assert.NotContains(t, scrapeMetrics(t), `gateway_requests_total{code="418",service="users-service"}`)
+
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/users/1", nil))
+	require.Equal(t, http.StatusTeapot, rec.Code)
+
+	body := scrapeMetrics(t)
+	assert.Contains(t, body, `gateway_requests_total{code="418",service="users-service"} 1`+"\n")
+	assert.Contains(t, body, `gateway_request_duration_seconds_count{code="418",service="users-service"} 1`+"\n")
+	assert.Contains(t, body, `gateway_healthy_instances{service="users-service"} 1`+"\n")
+	assert.NotContains(t, body, "gateway_request_errors_total{", "4xx is not an error")
+	assert.Equal(t, 1.0, testutil.ToFloat64(requestsTotal.WithLabelValues("users-service", "418")),
+		"scraping /metrics must not count itself as a proxied request")
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Rate limiter housekeeping defaults.
+const (
+	defaultRateLimitIdle   = 10 * time.Minute
+	rateLimitSweepInterval = time.Minute
+)
+
+// rateSpec is a token bucket: rps tokens are added per second, up to burst.
+type rateSpec struct {
+	rps   rate.Limit
+	burst int
+}
+
+// parseRateLimits parses "users-service=10:20,..." into per-service buckets
+// of 10 requests per second with a burst of 20. The burst defaults to the
+// rate, rounded up, when omitted.
+func parseRateLimits(raw string) (map[string]rateSpec, error) {
+	pairs, err := parseKeyValues(raw)
+	if err != nil {
+		return nil, err
+	}
+	specs := make(map[string]rateSpec, len(pairs))
+	for service, value := range pairs {
+		rawRPS, rawBurst, hasBurst := strings.Cut(value, ":")
+		rps, err := strconv.ParseFloat(rawRPS, 64)
+		if err != nil || rps <= 0 {
+			return nil, fmt.Errorf("invalid rate %q for %s", rawRPS, service)
+		}
+		burst := int(rps + 0.999)
+		if hasBurst {
+			if burst, err = strconv.Atoi(rawBurst); err != nil || burst < 1 {
+				return nil, fmt.Errorf("invalid burst %q for %s", rawBurst, service)
+			}
+		}
+		specs[service] = rateSpec{rps: rate.Limit(rps), burst: burst}
+	}
+	return specs, nil
+}
+
+type rateKey struct {
+	ip, service string
+}
+
+type rateBucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// serviceRateLimiter keeps one token bucket per client IP and service.
+// Services without a spec are not limited. Buckets idle for longer than
+// idleAfter are dropped by Sweep, by which time they would be full anyway.
+type serviceRateLimiter struct {
+	specs     map[string]rateSpec
+	idleAfter time.Duration
+	now       func() time.Time
+
+	mu      sync.Mutex
+	buckets map[rateKey]*rateBucket
+}
+
+func newServiceRateLimiter(specs map[string]rateSpec, idleAfter time.Duration) *serviceRateLimiter {
+	if len(specs) == 0 {
+		return nil
+	}
+	return &serviceRateLimiter{specs: specs, idleAfter: idleAfter, now: time.Now, buckets: make(map[rateKey]*rateBucket)}
+}
+
+// Allow takes a token for ip's request to service. When the bucket is empty
+// it reports how long until the next token arrives.
+func (l *serviceRateLimiter) Allow(ip, service string) (ok bool, retryAfter time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+	spec, limited := l.specs[service]
+	if !limited {
+		return true, 0
+	}
+
+	now := l.now()
+	key := rateKey{ip: ip, service: service}
+	l.mu.Lock()
+	bucket, exists := l.buckets[key]
+	if !exists {
+		bucket = &rateBucket{limiter: rate.NewLimiter(spec.rps, spec.burst)}
+		l.buckets[key] = bucket
+	}
+	bucket.lastSeen = now
+	l.mu.Unlock()
+
+	reservation := bucket.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// Sweep drops buckets not used within idleAfter and returns how many went.
+func (l *serviceRateLimiter) Sweep() int {
+	cutoff := l.now().Add(-l.idleAfter)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	removed := 0
+	for key, bucket := range l.buckets {
+		if bucket.lastSeen.Before(cutoff) {
+			delete(l.buckets, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// RunSweeper calls Sweep every interval until ctx is done.
+func (l *serviceRateLimiter) RunSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			l.Sweep()
+		}
+	}
+}
+
+// rateLimits is nil unless GATEWAY_RATE_LIMITS configures some service.
+var rateLimits *serviceRateLimiter
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+// fakeClock is a manually advanced time source.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) Now() time.Time          { return c.t }
+func (c *fakeClock) Advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func withRateLimits(t *testing.T, specs map[string]rateSpec) *fakeClock {
+	clock := &fakeClock{t: time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)}
+	orig := rateLimits
+	rateLimits = newServiceRateLimiter(specs, time.Minute)
+	rateLimits.now = clock.Now
+	t.Cleanup(func() { rateLimits = orig })
+	return clock
+}
+
+func getFrom(handler http.Handler, remoteAddr, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = remoteAddr
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRateLimitRejectsThenRecovers(t *testing.T) {
+	withUpstream(t, func(w http.ResponseWriter, r *http.Request) {})
+	clock := withRateLimits(t, map[string]rateSpec{"users-service": {rps: 2, burst: 3}})
+	router := newRouter()
+
+	for i := 0; i < 3; i++ {
+		require.Equal(t, http.StatusOK, getFrom(router, "10.0.0.1:5000", "/api/users/1").Code, "request %d is within the burst", i+1)
+	}
+
+	rec := getFrom(router, "10.0.0.1:5000", "/api/users/1")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+	assert.Equal(t, reasonRateLimited, rec.Header().Get("X-Gateway-Reason"))
+
+	assert.Equal(t, http.StatusOK, getFrom(router, "10.0.0.2:5000", "/api/users/1").Code, "other clients have their own bucket")
+
+	clock.Advance(500 * time.Millisecond)
+	assert.Equal(t, http.StatusOK, getFrom(router, "10.0.0.1:5000", "/api/users/1").Code, "one token refills every 500ms")
+	assert.Equal(t, http.StatusTooManyRequests, getFrom(router, "10.0.0.1:5000", "/api/users/1").Code)
+}
+
+func TestRateLimitOnlyAppliesToConfiguredServices(t *testing.T) {
+	limiter := newServiceRateLimiter(map[string]rateSpec{"users-service": {rps: 1, burst: 1}}, time.Minute)
+
+	for i := 0; i < 5; i++ {
+		ok, _ := limiter.Allow("10.0.0.1", "products-service")
+		assert.True(t, ok)
+	}
+	ok, _ := newServiceRateLimiter(nil, time.Minute).Allow("10.0.0.1", "users-service")
+	assert.True(t, ok, "a nil limiter allows everything")
+}
+
+func TestRateLimitSweepDropsIdleBuckets(t *testing.T) {
+	clock := withRateLimits(t, map[string]rateSpec{"users-service": {rps: 1, burst: 1}})
+	rateLimits.Allow("10.0.0.1", "users-service")
+	clock.Advance(30 * time.Second)
+	rateLimits.Allow("10.0.0.2", "users-service")
+
+	clock.Advance(45 * time.Second)
+	assert.Equal(t, 1, rateLimits.Sweep())
+	assert.Len(t, rateLimits.buckets, 1)
+}
+
+func TestParseRateLimits(t *testing.T) {
+	specs, err := parseRateLimits("users-service=10:20, products-service=2.5")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]rateSpec{
+		"users-service":    {rps: 10, burst: 20},
+		"products-service": {rps: rate.Limit(2.5), burst: 3},
+	}, specs)
+
+	for _, bad := range []string{"users-service=fast", "users-service=0", "users-service=5:0", "users-service"} {
+		_, err := parseRateLimits(bad)
+		assert.Error(t, err, bad)
+	}
+}
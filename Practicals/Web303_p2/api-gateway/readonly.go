@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// reasonReadOnly marks writes refused while a service is in maintenance.
+const reasonReadOnly = "read_only"
+
+// readOnlyRetryAfter is the back-off suggested for blocked writes.
+const readOnlyRetryAfter = 30 * time.Second
+
+// readOnlySet tracks services that currently accept only GET and HEAD.
+type readOnlySet struct {
+	mu       sync.RWMutex
+	services map[string]bool
+}
+
+func newReadOnlySet(services ...string) *readOnlySet {
+	s := &readOnlySet{services: make(map[string]bool)}
+	for _, name := range services {
+		if name = strings.TrimSpace(name); name != "" {
+			s.services[name] = true
+		}
+	}
+	return s
+}
+
+func (s *readOnlySet) Set(service string, readOnly bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if readOnly {
+		s.services[service] = true
+	} else {
+		delete(s.services, service)
+	}
+}
+
+// Blocks reports whether a request with method must be refused for service.
+func (s *readOnlySet) Blocks(service, method string) bool {
+	if method == http.MethodGet || method == http.MethodHead {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.services[service]
+}
+
+func (s *readOnlySet) List() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]string, 0, len(s.services))
+	for name := range s.services {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// readOnly is seeded from GATEWAY_READ_ONLY_SERVICES and adjusted at
+// runtime through /gateway/read-only.
+var readOnly = newReadOnlySet()
+
+// rejectReadOnly tells the client writes to the service are paused.
+func rejectReadOnly(w http.ResponseWriter) {
+	writeRejection(w, http.StatusServiceUnavailable, reasonReadOnly, "service is read-only for maintenance", readOnlyRetryAfter)
+}
+
+// handleReadOnlyList reports which services are read-only.
+func handleReadOnlyList(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"read_only": readOnly.List()})
+}
+
+// handleReadOnlySet toggles read-only mode with {"read_only": true|false}.
+func handleReadOnlySet(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		ReadOnly *bool `json:"read_only"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.ReadOnly == nil {
+		http.Error(w, `Body must be {"read_only": true|false}`, http.StatusBadRequest)
+		return
+	}
+	readOnly.Set(r.PathValue("service"), *body.ReadOnly)
+	handleReadOnlyList(w, r)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withReadOnly(t *testing.T, services ...string) {
+	orig := readOnly
+	readOnly = newReadOnlySet(services...)
+	t.Cleanup(func() { readOnly = orig })
+}
+
+func TestReadOnlyBlocksWritesAndAllowsReads(t *testing.T) {
+	withUpstream(t, func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	withReadOnly(t, "users-service")
+
+	rec := httptest.NewRecorder()
+	routeRequest(rec, httptest.NewRequest(http.MethodPost, "/api/users/1", strings.NewReader("{}")))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, reasonReadOnly, rec.Header().Get("X-Gateway-Reason"))
+	assert.Contains(t, rec.Body.String(), `"reason":"read_only"`)
+
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		rec = httptest.NewRecorder()
+		routeRequest(rec, httptest.NewRequest(method, "/api/users/1", nil))
+		assert.Equal(t, http.StatusOK, rec.Code, method)
+	}
+}
+
+func TestReadOnlyIsPerService(t *testing.T) {
+	withReadOnly(t, "products-service")
+	assert.False(t, readOnly.Blocks("users-service", http.MethodPost))
+	assert.True(t, readOnly.Blocks("products-service", http.MethodDelete))
+}
+
+func TestReadOnlyAdminToggle(t *testing.T) {
+	withAdminToken(t, "secret")
+	withReadOnly(t)
+	router := newRouter()
+
+	send := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/gateway/read-only/users-service", strings.NewReader(body))
+		req.Header.Set("X-Admin-Token", "secret")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := send(`{"read_only": true}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"read_only":["users-service"]}`, rec.Body.String())
+	assert.True(t, readOnly.Blocks("users-service", http.MethodPost))
+
+	rec = send(`{"read_only": false}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.False(t, readOnly.Blocks("users-service", http.MethodPost))
+
+	assert.Equal(t, http.StatusBadRequest, send(`{}`).Code)
+
+	req := httptest.NewRequest(http.MethodPut, "/gateway/read-only/users-service", strings.NewReader(`{"read_only": true}`))
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// panicReport is the Sentry-style payload posted to the panic webhook.
+type panicReport struct {
+	RequestID string   `json:"request_id"`
+	Method    string   `json:"method"`
+	Path      string   `json:"path"`
+	Error     string   `json:"error"`
+	Stack     []string `json:"stack"`
+	Timestamp string   `json:"timestamp"`
+}
+
+// panicReporter ships panic reports to an external webhook from a background
+// goroutine so request handling never waits on the sink.
+type panicReporter struct {
+	url    string
+	client *http.Client
+	queue  chan panicReport
+}
+
+func newPanicReporter(url string) *panicReporter {
+	r := &panicReporter{
+		url:    url,
+		client: &http.Client{Timeout: 5 * time.Second},
+		queue:  make(chan panicReport, 64),
+	}
+	go r.run()
+	return r
+}
+
+// Report queues a report, dropping it if the queue is full.
+func (r *panicReporter) Report(report panicReport) {
+	select {
+	case r.queue <- report:
+	default:
+		log.Printf("Panic report queue full, dropping report for %s", report.Path)
+	}
+}
+
+func (r *panicReporter) run() {
+	for report := range r.queue {
+		body, err := json.Marshal(report)
+		if err != nil {
+			log.Printf("Failed to encode panic report: %v", err)
+			continue
+		}
+		resp, err := r.client.Post(r.url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			log.Printf("Failed to send panic report: %v", err)
+			continue
+		}
+		resp.Body.Close()
+	}
+}
+
+// reporter receives panic reports when GATEWAY_PANIC_WEBHOOK_URL is set.
+var reporter *panicReporter
+
+// recoverMiddleware turns handler and proxy panics into a JSON 500, logs the
+// stack trace, and forwards a report to the configured sink.
+func recoverMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			// The reverse proxy uses this sentinel to abort a response mid-stream
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+
+			stack := debug.Stack()
+			requestID := r.Header.Get("X-Request-ID")
+			log.Printf("Panic serving %s %s (request %s): %v\n%s", r.Method, r.URL.Path, requestID, rec, stack)
+
+			if reporter != nil {
+				reporter.Report(panicReport{
+					RequestID: requestID,
+					Method:    r.Method,
+					Path:      r.URL.Path,
+					Error:     fmt.Sprint(rec),
+					Stack:     sanitizeStack(stack),
+					Timestamp: time.Now().UTC().Format(time.RFC3339),
+				})
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "internal server error"})
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sanitizeStack reduces a debug.Stack trace to function names and base
+// file:line locations, dropping goroutine headers, directories and offsets.
+func sanitizeStack(stack []byte) []string {
+	var frames []string
+	for _, line := range strings.Split(string(stack), "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "", strings.HasPrefix(trimmed, "goroutine "):
+			continue
+		case strings.HasPrefix(line, "\t"):
+			if i := strings.LastIndex(trimmed, " +0x"); i >= 0 {
+				trimmed = trimmed[:i]
+			}
+			frames = append(frames, "  "+filepath.Base(trimmed))
+		default:
+			if i := strings.LastIndex(trimmed, "("); i > 0 {
+				trimmed = trimmed[:i]
+			}
+			frames = append(frames, trimmed)
+		}
+	}
+	return frames
+}
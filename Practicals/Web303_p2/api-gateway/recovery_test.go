@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecoverMiddlewareReportsPanicToSink(t *testing.T) {
+	received := make(chan panicReport, 1)
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var report panicReport
+		json.NewDecoder(r.Body).Decode(&report)
+		received <- report
+	}))
+	defer sink.Close()
+
+	orig := reporter
+	reporter = newPanicReporter(sink.URL)
+	defer func() { reporter = orig }()
+
+	handler := recoverMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("kaboom")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users/1", nil)
+	req.Header.Set("X-Request-ID", "req-123")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"error":"internal server error"}`, rec.Body.String())
+
+	select {
+	case report := <-received:
+		assert.Equal(t, "req-123", report.RequestID)
+		assert.Equal(t, "/api/users/1", report.Path)
+		assert.Equal(t, "kaboom", report.Error)
+		require.NotEmpty(t, report.Stack)
+		for _, frame := range report.Stack {
+			if strings.HasPrefix(frame, "  ") {
+				assert.NotContains(t, frame, "/", "file frames should not leak directories")
+			}
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("panic report was not delivered to the sink")
+	}
+}
+
+func TestRecoverMiddlewareWithoutReporter(t *testing.T) {
+	orig := reporter
+	reporter = nil
+	defer func() { reporter = orig }()
+
+	handler := recoverMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("kaboom")
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
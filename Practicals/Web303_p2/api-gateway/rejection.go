@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Rejection reasons reported in the JSON body and X-Gateway-Reason header so
+// clients can tell a rate limit from an overloaded gateway.
+const (
+	reasonRateLimited = "rate_limited"
+	reasonOverloaded  = "overloaded"
+
+	// reasonDiscoveryBusy means no Consul query slot freed up in time.
+	reasonDiscoveryBusy = "discovery_busy"
+)
+
+// writeRejection writes a gateway-generated refusal with a Retry-After hint.
+func writeRejection(w http.ResponseWriter, status int, reason, message string, retryAfter time.Duration) {
+	seconds := int(retryAfter.Round(time.Second) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	w.Header().Set("X-Gateway-Reason", reason)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message, "reason": reason})
+}
+
+// rejectRateLimited tells the client it exceeded its request quota.
+func rejectRateLimited(w http.ResponseWriter, retryAfter time.Duration) {
+	writeRejection(w, http.StatusTooManyRequests, reasonRateLimited, "rate limit exceeded", retryAfter)
+}
+
+// rejectOverloaded tells the client the gateway is shedding load.
+func rejectOverloaded(w http.ResponseWriter, retryAfter time.Duration) {
+	writeRejection(w, http.StatusServiceUnavailable, reasonOverloaded, "gateway overloaded", retryAfter)
+}
+
+// loadShedRetryAfter is the back-off suggested to clients when load is shed.
+const loadShedRetryAfter = 2 * time.Second
+
+// loadShedder rejects requests once more than limit are in flight. A limit
+// of zero disables shedding.
+type loadShedder struct {
+	limit    int64
+	inFlight int64
+}
+
+func (s *loadShedder) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.limit <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if atomic.AddInt64(&s.inFlight, 1) > s.limit {
+			atomic.AddInt64(&s.inFlight, -1)
+			rejectOverloaded(w, loadShedRetryAfter)
+			return
+		}
+		defer atomic.AddInt64(&s.inFlight, -1)
+		next.ServeHTTP(w, r)
+	})
+}
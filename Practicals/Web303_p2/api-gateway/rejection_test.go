@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRejectRateLimited(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rejectRateLimited(rec, 3*time.Second)
+
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "3", rec.Header().Get("Retry-After"))
+	assert.Equal(t, "rate_limited", rec.Header().Get("X-Gateway-Reason"))
+	assert.JSONEq(t, `{"error":"rate limit exceeded","reason":"rate_limited"}`, rec.Body.String())
+}
+
+func TestLoadShedderRejectsWhenOverloaded(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-release
+	})
+	handler := (&loadShedder{limit: 1}).Wrap(slow)
+
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/users/1", nil))
+		close(done)
+	}()
+	<-entered
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/users/2", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("Retry-After"))
+	assert.Equal(t, "overloaded", rec.Header().Get("X-Gateway-Reason"))
+	assert.JSONEq(t, `{"error":"gateway overloaded","reason":"overloaded"}`, rec.Body.String())
+
+	close(release)
+	<-done
+}
+
+func TestLoadShedderDisabledByDefault(t *testing.T) {
+	handler := (&loadShedder{}).Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
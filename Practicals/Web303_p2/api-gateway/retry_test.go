@@ -0,0 +1,106 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// refusedAddr returns the address of a server that has already shut down,
+// so every connection to it is refused.
+func refusedAddr(t *testing.T) string {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	return srv.URL
+}
+
+// withInstances registers the given base URLs, in order, as users-service
+// instances behind a fresh round-robin balancer and breaker registry.
+func withInstances(t *testing.T, urls ...string) {
+	var entries []*consulapi.ServiceEntry
+	for i, raw := range urls {
+		u, err := url.Parse(raw)
+		require.NoError(t, err)
+		port, err := strconv.Atoi(u.Port())
+		require.NoError(t, err)
+		entries = append(entries, serviceEntry("users-"+strconv.Itoa(i), u.Hostname(), port))
+	}
+	withFakeConsul(t, &fakeHealth{entries: map[string][]*consulapi.ServiceEntry{"users-service": entries}})
+	withBalancer(t, newRoundRobin())
+
+	origBreakers, origDelay := breakers, retryBaseDelay
+	breakers = newBreakerRegistry(defaultBreakerThreshold, time.Minute)
+	retryBaseDelay = time.Millisecond
+	t.Cleanup(func() { breakers, retryBaseDelay = origBreakers, origDelay })
+}
+
+func TestRetryMovesToNextInstanceOnConnectionFailure(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok "+r.URL.Path)
+	}))
+	defer healthy.Close()
+	withInstances(t, refusedAddr(t), healthy.URL)
+
+	rec := httptest.NewRecorder()
+	routeRequest(rec, httptest.NewRequest(http.MethodGet, "/api/users/1", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "ok /1", rec.Body.String())
+}
+
+func TestRetryMovesToNextInstanceOn503(t *testing.T) {
+	var unavailableHits int32
+	unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&unavailableHits, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		io.WriteString(w, "draining")
+	}))
+	defer unavailable.Close()
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer healthy.Close()
+	withInstances(t, unavailable.URL, healthy.URL)
+
+	rec := httptest.NewRecorder()
+	routeRequest(rec, httptest.NewRequest(http.MethodGet, "/api/users/1", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "ok", rec.Body.String(), "the retried 503 body must not leak into the response")
+	assert.EqualValues(t, 1, atomic.LoadInt32(&unavailableHits))
+}
+
+func TestRetryGivesUpAfterConfiguredAttempts(t *testing.T) {
+	withInstances(t, refusedAddr(t), refusedAddr(t))
+
+	rec := httptest.NewRecorder()
+	routeRequest(rec, httptest.NewRequest(http.MethodGet, "/api/users/1", nil))
+
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+	assert.Equal(t, 1, strings.Count(rec.Body.String(), "Bad gateway"), "only the final failure is written")
+}
+
+func TestRetrySkipsNonIdempotentMethods(t *testing.T) {
+	var posts int32
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&posts, 1)
+	}))
+	defer healthy.Close()
+	withInstances(t, refusedAddr(t), healthy.URL)
+
+	rec := httptest.NewRecorder()
+	routeRequest(rec, httptest.NewRequest(http.MethodPost, "/api/users/1", strings.NewReader("{}")))
+
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+	assert.Zero(t, atomic.LoadInt32(&posts))
+}
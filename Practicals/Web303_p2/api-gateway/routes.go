@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"syscall"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RouteRule sends every path under Prefix to Service. Rewrite is a template
+// for the forwarded path in which {rest} stands for the escaped remainder
+// after the prefix; it defaults to "/{rest}".
+type RouteRule struct {
+	Prefix  string `yaml:"prefix" json:"prefix"`
+	Service string `yaml:"service" json:"service"`
+	Rewrite string `yaml:"rewrite,omitempty" json:"rewrite,omitempty"`
+}
+
+// RouteTable holds configured routes, consulted before the /api/{service}
+// convention. Rules are matched longest prefix first.
+type RouteTable struct {
+	rules []RouteRule
+}
+
+// routesFile is the file format of GATEWAY_ROUTES_FILE. JSON is accepted too,
+// being valid YAML.
+type routesFile struct {
+	Routes []RouteRule `yaml:"routes"`
+}
+
+// loadRouteTable reads and validates a routes.yaml file.
+func loadRouteTable(path string) (*RouteTable, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file routesFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return newRouteTable(file.Routes)
+}
+
+func newRouteTable(rules []RouteRule) (*RouteTable, error) {
+	table := &RouteTable{}
+	for i, rule := range rules {
+		if !strings.HasPrefix(rule.Prefix, "/") || rule.Service == "" {
+			return nil, fmt.Errorf("route %d: prefix must start with / and service is required", i+1)
+		}
+		if rule.Rewrite == "" {
+			rule.Rewrite = "/{rest}"
+		}
+		if !strings.HasPrefix(rule.Rewrite, "/") {
+			return nil, fmt.Errorf("route %d: rewrite %q must start with /", i+1, rule.Rewrite)
+		}
+		rule.Prefix = strings.TrimSuffix(rule.Prefix, "/")
+		table.rules = append(table.rules, rule)
+	}
+	sort.SliceStable(table.rules, func(i, j int) bool {
+		return len(table.rules[i].Prefix) > len(table.rules[j].Prefix)
+	})
+	return table, nil
+}
+
+// Match finds the rule covering the escaped path, matching whole segments
+// only, and returns its service and rewritten forward path.
+func (t *RouteTable) Match(path string) (service, forwardPath string, ok bool) {
+	if t == nil {
+		return "", "", false
+	}
+	for _, rule := range t.rules {
+		if len(path) < len(rule.Prefix) || !strings.EqualFold(path[:len(rule.Prefix)], rule.Prefix) {
+			continue
+		}
+		rest := path[len(rule.Prefix):]
+		if rest != "" && rest[0] != '/' {
+			continue // "/v1/catalogue" must not match prefix "/v1/catalog"
+		}
+		if _, err := url.PathUnescape(rest); err != nil {
+			continue
+		}
+		forward := strings.ReplaceAll(rule.Rewrite, "{rest}", strings.TrimPrefix(rest, "/"))
+		return rule.Service, forward, true
+	}
+	return "", "", false
+}
+
+// routeTable is swapped atomically on SIGHUP so in-flight lookups never see a
+// half-loaded table. Nil means no configured routes.
+var routeTable atomic.Pointer[RouteTable]
+
+// watchRouteReloads re-reads path whenever the process receives SIGHUP. A
+// file that fails to load leaves the previous table in place.
+func watchRouteReloads(path string) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		table, err := loadRouteTable(path)
+		if err != nil {
+			log.Printf("Route reload failed, keeping previous routes: %v", err)
+			continue
+		}
+		routeTable.Store(table)
+		log.Printf("Reloaded %d route(s) from %s", len(table.rules), path)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleRoutes = `
+routes:
+  - prefix: /v1/catalog
+    service: food-catalog-service
+  - prefix: /v1/catalog/admin
+    service: catalog-admin
+    rewrite: /internal/{rest}
+`
+
+func withRouteTable(t *testing.T, yaml string) {
+	path := filepath.Join(t.TempDir(), "routes.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(yaml), 0o600))
+	table, err := loadRouteTable(path)
+	require.NoError(t, err)
+
+	orig := routeTable.Load()
+	routeTable.Store(table)
+	t.Cleanup(func() { routeTable.Store(orig) })
+}
+
+func TestRouteTableMapsPrefixToService(t *testing.T) {
+	withRouteTable(t, sampleRoutes)
+
+	tests := []struct {
+		path, service, forward string
+	}{
+		{"/v1/catalog/items", "food-catalog-service", "/items"},
+		{"/v1/catalog", "food-catalog-service", "/"},
+		{"/V1/Catalog/items/a%2Fb", "food-catalog-service", "/items/a%2Fb"},
+		{"/v1/catalog/admin/reindex", "catalog-admin", "/internal/reindex"},
+		// No rule matches, so the suffix convention still applies.
+		{"/api/users/42", "users-service", "/42"},
+	}
+	for _, tt := range tests {
+		service, forward, err := parseRoute(tt.path)
+		require.NoError(t, err, tt.path)
+		assert.Equal(t, tt.service, service, tt.path)
+		assert.Equal(t, tt.forward, forward, tt.path)
+	}
+
+	_, _, err := parseRoute("/v1/catalogue/items")
+	assert.Error(t, err, "prefixes match whole segments only")
+}
+
+func TestLoadRouteTableRejectsInvalidRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routes.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("routes:\n  - prefix: v1\n    service: x\n"), 0o600))
+
+	_, err := loadRouteTable(path)
+	assert.Error(t, err)
+}
+
+func TestResolveUsesRouteTable(t *testing.T) {
+	withAdminToken(t, "secret")
+	withRouteTable(t, sampleRoutes)
+	withFakeConsul(t, &fakeHealth{entries: map[string][]*consulapi.ServiceEntry{
+		"food-catalog-service": {serviceEntry("catalog-1", "10.0.0.9", 8080)},
+	}})
+
+	req := httptest.NewRequest(http.MethodGet, "/gateway/resolve?path=/v1/catalog/items", nil)
+	req.Header.Set("X-Admin-Token", "secret")
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{
+		"service": "food-catalog-service",
+		"instance": "http://10.0.0.9:8080",
+		"forward_path": "/items"
+	}`, rec.Body.String())
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"time"
+)
+
+// errConsulBusy is returned when a lookup could not get a Consul slot in time.
+var errConsulBusy = errors.New("too many concurrent consul queries")
+
+// defaultConsulQueueWait is how long a lookup waits for a free Consul slot.
+const defaultConsulQueueWait = 250 * time.Millisecond
+
+// consulLimiter bounds the number of Consul queries in flight so that a burst
+// of cache misses cannot flood the agent. A nil limiter is unbounded.
+type consulLimiter struct {
+	slots chan struct{}
+	wait  time.Duration
+}
+
+// newConsulLimiter returns a limiter allowing max concurrent queries, or nil
+// when max is zero.
+func newConsulLimiter(max int, wait time.Duration) *consulLimiter {
+	if max <= 0 {
+		return nil
+	}
+	return &consulLimiter{slots: make(chan struct{}, max), wait: wait}
+}
+
+// Acquire takes a slot, waiting up to l.wait, and returns the func that
+// releases it.
+func (l *consulLimiter) Acquire() (release func(), err error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	timer := time.NewTimer(l.wait)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return func() { <-l.slots }, nil
+	case <-timer.C:
+		return nil, errConsulBusy
+	}
+}
+
+// consulSlots guards every Consul health query made by lookupInstances.
+var consulSlots *consulLimiter
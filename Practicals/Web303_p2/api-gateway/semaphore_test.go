@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingHealth holds every query until release is closed and records the
+// peak number of concurrent queries.
+type blockingHealth struct {
+	release  chan struct{}
+	inFlight int64
+	peak     int64
+}
+
+func (b *blockingHealth) Service(service, tag string, passingOnly bool, q *consulapi.QueryOptions) ([]*consulapi.ServiceEntry, *consulapi.QueryMeta, error) {
+	n := atomic.AddInt64(&b.inFlight, 1)
+	defer atomic.AddInt64(&b.inFlight, -1)
+	for {
+		peak := atomic.LoadInt64(&b.peak)
+		if n <= peak || atomic.CompareAndSwapInt64(&b.peak, peak, n) {
+			break
+		}
+	}
+	<-b.release
+	return []*consulapi.ServiceEntry{serviceEntry(service+"-1", "10.0.0.1", 8080)}, &consulapi.QueryMeta{}, nil
+}
+
+func withConsulSlots(t *testing.T, limiter *consulLimiter) {
+	orig := consulSlots
+	consulSlots = limiter
+	t.Cleanup(func() { consulSlots = orig })
+}
+
+func TestConsulLimiterBoundsConcurrency(t *testing.T) {
+	fake := &blockingHealth{release: make(chan struct{})}
+	withFakeConsul(t, fake)
+	withConsulSlots(t, newConsulLimiter(2, time.Second))
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 6)
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := lookupInstances("users-service")
+			errs <- err
+		}()
+	}
+
+	require.Eventually(t, func() bool { return atomic.LoadInt64(&fake.inFlight) == 2 }, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	assert.EqualValues(t, 2, atomic.LoadInt64(&fake.inFlight), "excess lookups must queue")
+
+	close(fake.release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.EqualValues(t, 2, atomic.LoadInt64(&fake.peak))
+}
+
+func TestConsulLimiterFailsFastWhenSaturated(t *testing.T) {
+	fake := &blockingHealth{release: make(chan struct{})}
+	withFakeConsul(t, fake)
+	withConsulSlots(t, newConsulLimiter(1, 10*time.Millisecond))
+	defer close(fake.release)
+
+	go lookupInstances("users-service")
+	require.Eventually(t, func() bool { return atomic.LoadInt64(&fake.inFlight) == 1 }, time.Second, time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	routeRequest(rec, httptest.NewRequest(http.MethodGet, "/api/users/1", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, reasonDiscoveryBusy, rec.Header().Get("X-Gateway-Reason"))
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+	assert.Equal(t, "closed", breakers.get("users-service").snapshot("users-service").State,
+		"a busy Consul must not trip the service breaker")
+}
+
+func TestNilConsulLimiterIsUnbounded(t *testing.T) {
+	release, err := newConsulLimiter(0, time.Second).Acquire()
+	require.NoError(t, err)
+	release()
+}
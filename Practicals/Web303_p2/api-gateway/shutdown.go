@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// defaultDrainTimeout bounds how long shutdown waits for in-flight requests.
+const defaultDrainTimeout = 15 * time.Second
+
+// activeRequests counts requests the gateway is still serving, so a drain
+// that runs out of time can report what it is abandoning.
+var activeRequests atomic.Int64
+
+// trackActive keeps activeRequests up to date for every request.
+func trackActive(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		activeRequests.Add(1)
+		defer activeRequests.Add(-1)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// serveUntilDone serves on ln until ctx is cancelled, then stops accepting
+// connections and waits up to drainTimeout for in-flight requests to finish.
+// A server with a TLSConfig serves HTTPS using its loaded certificates.
+func serveUntilDone(ctx context.Context, server *http.Server, ln net.Listener, drainTimeout time.Duration) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		if server.TLSConfig != nil {
+			serveErr <- server.ServeTLS(ln, "", "")
+			return
+		}
+		serveErr <- server.Serve(ln)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("Shutting down gateway, draining for up to %s", drainTimeout)
+	drainCtx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(drainCtx); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			log.Printf("Drain timeout exceeded with %d request(s) still active", activeRequests.Load())
+		}
+		return err
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	log.Printf("Gateway drained cleanly")
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServeUntilDoneDrainsInFlightRequests(t *testing.T) {
+	started := make(chan struct{})
+	handler := trackActive(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte("done"))
+	}))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- serveUntilDone(ctx, &http.Server{Handler: handler}, ln, 5*time.Second) }()
+
+	status := make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String() + "/slow")
+		if err != nil {
+			status <- 0
+			return
+		}
+		resp.Body.Close()
+		status <- resp.StatusCode
+	}()
+
+	<-started
+	cancel()
+
+	assert.Equal(t, http.StatusOK, <-status)
+	require.NoError(t, <-served)
+
+	_, err = http.Get("http://" + ln.Addr().String() + "/after")
+	assert.Error(t, err, "new connections are refused after shutdown")
+}
+
+func TestServeUntilDoneReportsDrainTimeout(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	handler := trackActive(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	}))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- serveUntilDone(ctx, &http.Server{Handler: handler}, ln, 50*time.Millisecond) }()
+
+	go http.Get("http://" + ln.Addr().String() + "/stuck")
+	<-started
+	cancel()
+
+	assert.ErrorIs(t, <-served, context.DeadlineExceeded)
+	assert.EqualValues(t, 1, activeRequests.Load())
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"consulutil"
+)
+
+// defaultHealthPollInterval is how often static backends are health-checked.
+const defaultHealthPollInterval = 5 * time.Second
+
+// staticHealthTimeout bounds a single backend health probe.
+const staticHealthTimeout = 2 * time.Second
+
+// staticBackends is the Consul-free discovery source: a fixed list of URLs
+// per service whose /health endpoints the gateway polls itself. Its lookup
+// method has the same shape as lookupInstances, so caching, selection and
+// breakers work the same in both modes.
+type staticBackends struct {
+	services map[string][]*url.URL
+	client   *http.Client
+
+	mu      sync.RWMutex
+	healthy map[string]bool // keyed by backend URL; unknown counts as down
+}
+
+// loadStaticBackends reads {"users-service": ["http://localhost:8081"]}.
+func loadStaticBackends(path string) (*staticBackends, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string][]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return newStaticBackends(raw)
+}
+
+func newStaticBackends(raw map[string][]string) (*staticBackends, error) {
+	s := &staticBackends{
+		services: make(map[string][]*url.URL, len(raw)),
+		client:   &http.Client{Timeout: staticHealthTimeout},
+		healthy:  make(map[string]bool),
+	}
+	for service, addrs := range raw {
+		for _, addr := range addrs {
+			u, err := url.Parse(addr)
+			if err != nil || u.Scheme == "" || u.Host == "" {
+				return nil, fmt.Errorf("invalid backend %q for %s", addr, service)
+			}
+			s.services[service] = append(s.services[service], u)
+		}
+	}
+	return s, nil
+}
+
+// lookup returns the healthy configured backends for serviceName.
+func (s *staticBackends) lookup(serviceName string) ([]*url.URL, error) {
+	backends, ok := s.services[serviceName]
+	if !ok {
+		return nil, fmt.Errorf("no static backends configured for '%s'", serviceName)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var instances []*url.URL
+	for _, u := range backends {
+		if s.healthy[u.String()] {
+			instances = append(instances, u)
+		}
+	}
+	if len(instances) == 0 {
+		return nil, fmt.Errorf("%w for '%s'", consulutil.ErrNoHealthyInstances, serviceName)
+	}
+	return instances, nil
+}
+
+// PollOnce probes every backend's /health endpoint concurrently; only a 200
+// marks a backend healthy.
+func (s *staticBackends) PollOnce(ctx context.Context) {
+	var wg sync.WaitGroup
+	results := make(map[string]bool)
+	var resultsMu sync.Mutex
+	for _, backends := range s.services {
+		for _, u := range backends {
+			wg.Add(1)
+			go func(u *url.URL) {
+				defer wg.Done()
+				ok := s.probe(ctx, u)
+				resultsMu.Lock()
+				results[u.String()] = ok
+				resultsMu.Unlock()
+			}(u)
+		}
+	}
+	wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for backend, ok := range results {
+		if s.healthy[backend] != ok {
+			log.Printf("Static backend %s is now %s", backend, map[bool]string{true: "healthy", false: "unhealthy"}[ok])
+		}
+		s.healthy[backend] = ok
+	}
+}
+
+func (s *staticBackends) probe(ctx context.Context, backend *url.URL) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, backend.JoinPath("health").String(), nil)
+	if err != nil {
+		return false
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK
+}
+
+// Poll runs PollOnce every interval until ctx is cancelled.
+func (s *staticBackends) Poll(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.PollOnce(ctx)
+		}
+	}
+}
+
+// Services lists the configured service names.
+func (s *staticBackends) Services() []string {
+	names := make([]string, 0, len(s.services))
+	for name := range s.services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
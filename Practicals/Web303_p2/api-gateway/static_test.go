@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// backend serves /health with healthStatus and answers everything else with
+// its name.
+func backend(t *testing.T, name string, healthStatus int) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			w.WriteHeader(healthStatus)
+			return
+		}
+		io.WriteString(w, name+" "+r.URL.Path)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func withStaticDiscovery(t *testing.T, static *staticBackends) {
+	origDiscovery, origBreakers := discovery, breakers
+	discovery = newServiceCache(cacheOff, defaultDiscoveryTTL, static.lookup)
+	breakers = newBreakerRegistry(defaultBreakerThreshold, time.Minute)
+	t.Cleanup(func() { discovery, breakers = origDiscovery, origBreakers })
+}
+
+func TestStaticModeRoutesToHealthyBackend(t *testing.T) {
+	down := backend(t, "down", http.StatusServiceUnavailable)
+	up := backend(t, "up", http.StatusOK)
+	static, err := newStaticBackends(map[string][]string{"users-service": {down.URL, up.URL}})
+	require.NoError(t, err)
+	static.PollOnce(context.Background())
+	withStaticDiscovery(t, static)
+
+	rec := httptest.NewRecorder()
+	routeRequest(rec, httptest.NewRequest(http.MethodGet, "/api/users/42", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "up /42", rec.Body.String())
+}
+
+func TestStaticModeUnavailableUntilHealthy(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			w.WriteHeader(status)
+		}
+	}))
+	defer srv.Close()
+	static, err := newStaticBackends(map[string][]string{"users-service": {srv.URL}})
+	require.NoError(t, err)
+	withStaticDiscovery(t, static)
+
+	_, err = discoverService("users-service", "")
+	assert.Error(t, err, "backends are down until their first successful probe")
+
+	static.PollOnce(context.Background())
+	_, err = discoverService("users-service", "")
+	assert.Error(t, err)
+
+	status = http.StatusOK
+	static.PollOnce(context.Background())
+	target, err := discoverService("users-service", "")
+	require.NoError(t, err)
+	assert.Equal(t, srv.URL, target.String())
+
+	_, err = discoverService("orders-service", "")
+	assert.ErrorContains(t, err, "no static backends configured")
+}
+
+func TestLoadStaticBackends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "backends.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"users-service": ["http://localhost:8081"], "products-service": ["http://localhost:8082"]}`), 0o600))
+
+	static, err := loadStaticBackends(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"products-service", "users-service"}, static.Services())
+
+	_, err = newStaticBackends(map[string][]string{"users-service": {"localhost:8081"}})
+	assert.Error(t, err, "backends need a scheme")
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gatewayServer serves the full gateway router on a real listener, since
+// upgrades need a connection that can be hijacked.
+func gatewayServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(requestLogger(newRouter()))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// echoUpgrade is a minimal WebSocket-style upstream: it completes the
+// handshake and then echoes raw bytes back until the client hangs up.
+func echoUpgrade(seen chan<- http.Header) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		seen <- r.Header.Clone()
+		conn, buf, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprint(buf, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: accept-token\r\n\r\n")
+		buf.Flush()
+		io.Copy(conn, buf)
+	}
+}
+
+func TestWebSocketUpgradeThroughGateway(t *testing.T) {
+	// Streams must outlive the per-request deadline
+	withUpstreamTimeout(t, 50*time.Millisecond)
+	seen := make(chan http.Header, 1)
+	withUpstream(t, echoUpgrade(seen))
+	gateway := gatewayServer(t)
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(gateway.URL, "http://"))
+	require.NoError(t, err)
+	defer conn.Close()
+	fmt.Fprint(conn, "GET /api/users/socket HTTP/1.1\r\nHost: gateway\r\n"+
+		"Connection: Upgrade\r\nUpgrade: websocket\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\nSec-WebSocket-Protocol: chat\r\n\r\n")
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Equal(t, "websocket", resp.Header.Get("Upgrade"))
+	assert.Equal(t, "Upgrade", resp.Header.Get("Connection"))
+	assert.Equal(t, "accept-token", resp.Header.Get("Sec-WebSocket-Accept"))
+
+	upstream := <-seen
+	assert.Equal(t, "websocket", upstream.Get("Upgrade"))
+	assert.Equal(t, "Upgrade", upstream.Get("Connection"))
+	assert.Equal(t, "dGhlIHNhbXBsZSBub25jZQ==", upstream.Get("Sec-WebSocket-Key"))
+	assert.Equal(t, "13", upstream.Get("Sec-WebSocket-Version"))
+	assert.Equal(t, "chat", upstream.Get("Sec-WebSocket-Protocol"))
+
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+	for _, msg := range []string{"ping", "pong after the deadline"} {
+		time.Sleep(60 * time.Millisecond)
+		_, err := io.WriteString(conn, msg)
+		require.NoError(t, err)
+		got := make([]byte, len(msg))
+		_, err = io.ReadFull(reader, got)
+		require.NoError(t, err)
+		assert.Equal(t, msg, string(got))
+	}
+}
+
+func TestServerSentEventsAreFlushedAsTheyArrive(t *testing.T) {
+	withUpstreamTimeout(t, 50*time.Millisecond)
+	next := make(chan struct{})
+	withUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 1; i <= 2; i++ {
+			fmt.Fprintf(w, "data: event %d\n\n", i)
+			http.NewResponseController(w).Flush()
+			select {
+			case <-next:
+			case <-r.Context().Done():
+				return
+			}
+		}
+	})
+	gateway := gatewayServer(t)
+
+	resp, err := http.Get(gateway.URL + "/api/users/events")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	reader := bufio.NewReader(resp.Body)
+	readEvent := func() string {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		_, err = reader.ReadString('\n') // blank separator
+		require.NoError(t, err)
+		return strings.TrimSpace(line)
+	}
+
+	// The first event arrives while the upstream is still blocked
+	assert.Equal(t, "data: event 1", readEvent())
+	time.Sleep(100 * time.Millisecond) // past the request deadline
+	next <- struct{}{}
+	assert.Equal(t, "data: event 2", readEvent())
+	close(next)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Upstream timeout defaults. defaultUpstreamTimeout bounds a whole proxied
+// request, retries included.
+const (
+	defaultUpstreamTimeout = 30 * time.Second
+	upstreamDialTimeout    = 5 * time.Second
+)
+
+// upstreamTimeout is the deadline routeRequest puts on each request.
+var upstreamTimeout = defaultUpstreamTimeout
+
+type deadlineKey struct{}
+
+// withUpstreamDeadline cancels the returned context with
+// context.DeadlineExceeded once timeout passes, unless releaseDeadline is
+// called first. Unlike context.WithTimeout the deadline can be lifted, which
+// streaming responses need: a WebSocket or SSE stream legitimately outlives
+// any request timeout.
+func withUpstreamDeadline(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+	timer := time.AfterFunc(timeout, func() { cancel(context.DeadlineExceeded) })
+	return context.WithValue(ctx, deadlineKey{}, timer), func() {
+		timer.Stop()
+		cancel(context.Canceled)
+	}
+}
+
+// releaseDeadline lifts the deadline installed by withUpstreamDeadline.
+func releaseDeadline(ctx context.Context) {
+	if timer, ok := ctx.Value(deadlineKey{}).(*time.Timer); ok {
+		timer.Stop()
+	}
+}
+
+// deadlineExceeded reports whether ctx was cancelled by its upstream deadline.
+func deadlineExceeded(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), context.DeadlineExceeded)
+}
+
+// isStreamingResponse reports whether resp is a protocol upgrade or a
+// server-sent event stream, which are exempt from the request deadline.
+func isStreamingResponse(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusSwitchingProtocols {
+		return true
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return mediaType == "text/event-stream"
+}
+
+// reasonUpstreamTimeout means the upstream did not answer within the deadline.
+const reasonUpstreamTimeout = "upstream_timeout"
+
+// upstreamTransport is shared by every reverse proxy so connections are
+// pooled across requests.
+var upstreamTransport = newUpstreamTransport(defaultUpstreamTimeout)
+
+// newUpstreamTransport bounds connection setup and waiting for response
+// headers; bodies may stream for as long as the request deadline allows.
+func newUpstreamTransport(headerTimeout time.Duration) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = (&net.Dialer{Timeout: upstreamDialTimeout, KeepAlive: 30 * time.Second}).DialContext
+	t.TLSHandshakeTimeout = upstreamDialTimeout
+	t.ResponseHeaderTimeout = headerTimeout
+	return t
+}
+
+// isUpstreamTimeout reports whether a proxy error means the upstream was too
+// slow rather than unreachable or broken.
+func isUpstreamTimeout(r *http.Request, err error) bool {
+	if deadlineExceeded(r.Context()) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// rejectUpstreamTimeout answers 504 with a JSON body.
+func rejectUpstreamTimeout(w http.ResponseWriter) {
+	w.Header().Set("X-Gateway-Reason", reasonUpstreamTimeout)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusGatewayTimeout)
+	json.NewEncoder(w).Encode(map[string]string{"error": "upstream timed out", "reason": reasonUpstreamTimeout})
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withUpstreamTimeout(t *testing.T, timeout time.Duration) {
+	orig := upstreamTimeout
+	upstreamTimeout = timeout
+	t.Cleanup(func() { upstreamTimeout = orig })
+}
+
+func TestSlowUpstreamTimesOut(t *testing.T) {
+	withUpstreamTimeout(t, 50*time.Millisecond)
+	withUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(2 * time.Second):
+		case <-r.Context().Done():
+		}
+	})
+
+	start := time.Now()
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/users/1", nil))
+
+	require.Equal(t, http.StatusGatewayTimeout, rec.Code)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"error": "upstream timed out", "reason": "upstream_timeout"}`, rec.Body.String())
+}
+
+func TestFastUpstreamWithinTimeout(t *testing.T) {
+	withUpstreamTimeout(t, time.Second)
+	withUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	rec := httptest.NewRecorder()
+	newRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/users/1", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "ok", rec.Body.String())
+}
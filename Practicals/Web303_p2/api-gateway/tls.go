@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// tlsVersions are the accepted TLS_MIN_VERSION values.
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// loadTLSConfig builds the listener TLS config from a PEM certificate and key
+// pair. minVersion is "1.2" or "1.3"; empty means 1.2. It returns nil, nil
+// when neither file is configured, so the gateway serves plain HTTP.
+func loadTLSConfig(certFile, keyFile, minVersion string) (*tls.Config, error) {
+	if certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+
+	if minVersion == "" {
+		minVersion = "1.2"
+	}
+	version, ok := tlsVersions[minVersion]
+	if !ok {
+		return nil, fmt.Errorf("invalid TLS_MIN_VERSION %q, expected 1.2 or 1.3", minVersion)
+	}
+
+	// Loaded up front so a bad pair fails startup rather than every handshake
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading certificate: %w", err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: version}, nil
+}
+
+// redirectToHTTPS answers every request with a permanent redirect to the same
+// URL on the HTTPS listener at httpsPort.
+func redirectToHTTPS(httpsPort int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(httpsPort))
+		}
+		target := "https://" + host + r.URL.RequestURI()
+
+		// 301 lets clients turn a POST into a GET, so other methods get 308
+		status := http.StatusPermanentRedirect
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			status = http.StatusMovedPermanently
+		}
+		http.Redirect(w, r, target, status)
+	}
+}
+
+// serveRedirects runs the plain HTTP redirect listener on ln until ctx is
+// cancelled. Redirects are instant, so it closes without draining.
+func serveRedirects(ctx context.Context, ln net.Listener, httpsPort int) {
+	server := &http.Server{Handler: redirectToHTTPS(httpsPort)}
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("HTTPS redirect listener stopped: %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeSelfSignedCert writes a certificate for 127.0.0.1 and its key as PEM
+// files, returning their paths and a pool trusting the certificate.
+func writeSelfSignedCert(t *testing.T) (certFile, keyFile string, roots *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "gateway test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	roots = x509.NewCertPool()
+	roots.AddCert(cert)
+	return certFile, keyFile, roots
+}
+
+// serveTLS starts the gateway router over TLS and returns its address.
+func serveTLS(t *testing.T, config *tls.Config) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- serveUntilDone(ctx, &http.Server{Handler: newRouter(), TLSConfig: config}, ln, time.Second)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-served
+	})
+	return ln.Addr().String()
+}
+
+func TestTLSListenerProxiesOverHTTPS(t *testing.T) {
+	var forwardedProto string
+	withUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		forwardedProto = r.Header.Get("X-Forwarded-Proto")
+		w.Write([]byte(`{"id": 1}`))
+	})
+
+	certFile, keyFile, roots := writeSelfSignedCert(t)
+	config, err := loadTLSConfig(certFile, keyFile, "")
+	require.NoError(t, err)
+	addr := serveTLS(t, config)
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	resp, err := client.Get("https://" + addr + "/api/users/1")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.JSONEq(t, `{"id": 1}`, string(body))
+	require.NotNil(t, resp.TLS)
+	assert.GreaterOrEqual(t, resp.TLS.Version, uint16(tls.VersionTLS12))
+	assert.Equal(t, "https", forwardedProto)
+
+	plain, err := http.Get("http://" + addr + "/api/users/1")
+	require.NoError(t, err)
+	plain.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, plain.StatusCode, "plain HTTP is not proxied on the TLS port")
+}
+
+func TestTLSMinVersionRejectsOlderClients(t *testing.T) {
+	certFile, keyFile, roots := writeSelfSignedCert(t)
+	config, err := loadTLSConfig(certFile, keyFile, "1.3")
+	require.NoError(t, err)
+	addr := serveTLS(t, config)
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:    roots,
+		MaxVersion: tls.VersionTLS12,
+	}}}
+	_, err = client.Get("https://" + addr + "/gateway/breakers")
+	assert.Error(t, err)
+}
+
+func TestLoadTLSConfig(t *testing.T) {
+	config, err := loadTLSConfig("", "", "")
+	require.NoError(t, err)
+	assert.Nil(t, config, "no certificate means plain HTTP")
+
+	certFile, keyFile, _ := writeSelfSignedCert(t)
+	config, err = loadTLSConfig(certFile, keyFile, "1.3")
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), config.MinVersion)
+	assert.Len(t, config.Certificates, 1)
+
+	_, err = loadTLSConfig(certFile, "", "")
+	assert.Error(t, err, "both files are required")
+	_, err = loadTLSConfig(certFile, keyFile, "1.0")
+	assert.ErrorContains(t, err, "TLS_MIN_VERSION")
+	_, err = loadTLSConfig(certFile, filepath.Join(t.TempDir(), "missing.pem"), "")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestRedirectToHTTPS(t *testing.T) {
+	tests := []struct {
+		method, target string
+		port           int
+		status         int
+		location       string
+	}{
+		{http.MethodGet, "http://cafe.example:8000/api/users/1?x=y", 8080, http.StatusMovedPermanently, "https://cafe.example:8080/api/users/1?x=y"},
+		{http.MethodGet, "http://cafe.example/", 443, http.StatusMovedPermanently, "https://cafe.example/"},
+		{http.MethodPost, "http://cafe.example/api/users", 443, http.StatusPermanentRedirect, "https://cafe.example/api/users"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		redirectToHTTPS(tt.port)(rec, httptest.NewRequest(tt.method, tt.target, nil))
+		assert.Equal(t, tt.status, rec.Code, tt.target)
+		assert.Equal(t, tt.location, rec.Header().Get("Location"), tt.target)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the gateway's instrumentation.
+const tracerName = "api-gateway"
+
+// tracer is looked up on each use so providers installed later, including
+// test recorders, take effect.
+func tracer() trace.Tracer {
+	return otel.GetTracerProvider().Tracer(tracerName)
+}
+
+// setupTracing installs W3C trace context propagation and, when
+// OTEL_EXPORTER_OTLP_ENDPOINT is set, an OTLP exporter for the gateway's
+// spans. The returned function flushes buffered spans on shutdown.
+func setupTracing(ctx context.Context) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	// The exporter reads the endpoint and headers from the OTEL_* variables
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName("api-gateway"))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// traceRequests starts the root span for each request, continuing a trace
+// the client already started. routeRequest renames it once the target
+// service is known.
+func traceRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer().Start(ctx, r.Method+" gateway",
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+			))
+		defer span.End()
+
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		span.SetAttributes(semconv.HTTPResponseStatusCode(rec.Status()))
+		if rec.Status() >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rec.Status()))
+		}
+	})
+}
+
+// startProxySpan opens the client span for one attempt at an upstream.
+func startProxySpan(r *http.Request, serviceName, target string, attempt int) (*http.Request, trace.Span) {
+	ctx, span := tracer().Start(r.Context(), "proxy "+serviceName,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("gateway.service", serviceName),
+			attribute.String("gateway.upstream", target),
+			attribute.Int("gateway.attempt", attempt),
+		))
+	return r.WithContext(ctx), span
+}
+
+// injectTraceContext writes the current span into the outbound headers.
+func injectTraceContext(req *http.Request) {
+	otel.GetTextMapPropagator().Inject(req.Context(), propagation.HeaderCarrier(req.Header))
+}
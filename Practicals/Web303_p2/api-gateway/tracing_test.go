@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func withSpanRecorder(t *testing.T) *tracetest.InMemoryExporter {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	origProvider, origPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(origProvider)
+		otel.SetTextMapPropagator(origPropagator)
+	})
+	return exporter
+}
+
+func TestProxiedRequestRecordsParentAndChildSpans(t *testing.T) {
+	exporter := withSpanRecorder(t)
+	var traceparent string
+	withUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	})
+
+	rec := httptest.NewRecorder()
+	traceRequests(newRouter()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/users/1", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	child, root := spans[0], spans[1] // ended innermost first
+	assert.Equal(t, "GET users-service", root.Name)
+	assert.Equal(t, trace.SpanKindServer, root.SpanKind)
+	assert.Equal(t, "proxy users-service", child.Name)
+	assert.Equal(t, trace.SpanKindClient, child.SpanKind)
+
+	assert.Equal(t, root.SpanContext.SpanID(), child.Parent.SpanID())
+	assert.Equal(t, root.SpanContext.TraceID(), child.SpanContext.TraceID())
+
+	want := "00-" + child.SpanContext.TraceID().String() + "-" + child.SpanContext.SpanID().String() + "-01"
+	assert.Equal(t, want, traceparent, "upstream continues the proxy span")
+}
+
+func TestTraceRequestsContinuesClientTrace(t *testing.T) {
+	exporter := withSpanRecorder(t)
+	withUpstream(t, func(w http.ResponseWriter, r *http.Request) {})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	traceRequests(newRouter()).ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	require.NotEmpty(t, spans)
+	root := spans[len(spans)-1]
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", root.SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", root.Parent.SpanID().String())
+}
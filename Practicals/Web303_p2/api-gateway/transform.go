@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// RequestTransformer mutates a request just before it is proxied upstream.
+type RequestTransformer interface {
+	TransformRequest(r *http.Request)
+}
+
+// ResponseTransformer mutates an upstream response before it reaches the
+// client. Returning an error turns the response into a 502.
+type ResponseTransformer interface {
+	TransformResponse(resp *http.Response) error
+}
+
+// transformerChain is the ordered set of transformers for one service.
+type transformerChain struct {
+	requests  []RequestTransformer
+	responses []ResponseTransformer
+}
+
+func (c *transformerChain) applyRequest(r *http.Request) {
+	if c == nil {
+		return
+	}
+	for _, t := range c.requests {
+		t.TransformRequest(r)
+	}
+}
+
+func (c *transformerChain) applyResponse(resp *http.Response) error {
+	if c == nil {
+		return nil
+	}
+	for _, t := range c.responses {
+		if err := t.TransformResponse(resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// transformers holds the chains keyed by Consul service name.
+var transformers = map[string]*transformerChain{}
+
+// registerTransformer adds t to service's chain as a request and/or response
+// transformer, depending on which interfaces it implements.
+func registerTransformer(service string, t interface{}) error {
+	req, isReq := t.(RequestTransformer)
+	resp, isResp := t.(ResponseTransformer)
+	if !isReq && !isResp {
+		return fmt.Errorf("%T is neither a RequestTransformer nor a ResponseTransformer", t)
+	}
+
+	chain := transformers[service]
+	if chain == nil {
+		chain = &transformerChain{}
+		transformers[service] = chain
+	}
+	if isReq {
+		chain.requests = append(chain.requests, req)
+	}
+	if isResp {
+		chain.responses = append(chain.responses, resp)
+	}
+	return nil
+}
+
+// transformerFactories builds transformers by the "type" named in config.
+// New built-ins only need an entry here.
+var transformerFactories = map[string]func(json.RawMessage) (interface{}, error){
+	"inject_headers": func(raw json.RawMessage) (interface{}, error) {
+		var h headerInjector
+		err := json.Unmarshal(raw, &h)
+		return &h, err
+	},
+}
+
+// loadTransformers reads a JSON file of the form
+//
+//	{"users-service": [{"type": "inject_headers", "request": {"X-Tenant": "cafe"}}]}
+//
+// and registers each entry in order.
+func loadTransformers(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var config map[string][]json.RawMessage
+	if err := json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	for service, entries := range config {
+		for _, raw := range entries {
+			var head struct {
+				Type string `json:"type"`
+			}
+			if err := json.Unmarshal(raw, &head); err != nil {
+				return fmt.Errorf("%s: %w", service, err)
+			}
+			factory, ok := transformerFactories[head.Type]
+			if !ok {
+				return fmt.Errorf("%s: unknown transformer type %q", service, head.Type)
+			}
+			t, err := factory(raw)
+			if err != nil {
+				return fmt.Errorf("%s: %s: %w", service, head.Type, err)
+			}
+			if err := registerTransformer(service, t); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// headerInjector is the built-in transformer that sets fixed headers on the
+// upstream request and/or the client response.
+type headerInjector struct {
+	Request  map[string]string `json:"request"`
+	Response map[string]string `json:"response"`
+}
+
+func (h *headerInjector) TransformRequest(r *http.Request) {
+	for name, value := range h.Request {
+		r.Header.Set(name, value)
+	}
+}
+
+func (h *headerInjector) TransformResponse(resp *http.Response) error {
+	for name, value := range h.Response {
+		resp.Header.Set(name, value)
+	}
+	return nil
+}
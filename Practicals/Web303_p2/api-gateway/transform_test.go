@@ -0,0 +1,95 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withUpstream registers an httptest backend as the only users-service
+// instance and resets the transformer registry.
+func withUpstream(t *testing.T, handler http.HandlerFunc) {
+	upstream := httptest.NewServer(handler)
+	t.Cleanup(upstream.Close)
+
+	u, err := url.Parse(upstream.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(u.Port())
+	require.NoError(t, err)
+	withFakeConsul(t, &fakeHealth{entries: map[string][]*consulapi.ServiceEntry{
+		"users-service": {serviceEntry("users-1", u.Hostname(), port)},
+	}})
+
+	orig := transformers
+	transformers = map[string]*transformerChain{}
+	t.Cleanup(func() { transformers = orig })
+}
+
+// renameStatus is a test transformer touching both directions.
+type renameStatus struct{}
+
+func (renameStatus) TransformRequest(r *http.Request) { r.Header.Set("X-Renamed", "yes") }
+
+func (renameStatus) TransformResponse(resp *http.Response) error {
+	resp.Header.Set("X-Upstream-Status", resp.Header.Get("X-Status"))
+	resp.Header.Del("X-Status")
+	return nil
+}
+
+func TestTransformersMutateRequestAndResponse(t *testing.T) {
+	var seen http.Header
+	withUpstream(t, func(w http.ResponseWriter, r *http.Request) {
+		seen = r.Header.Clone()
+		w.Header().Set("X-Status", "fresh")
+	})
+	require.NoError(t, registerTransformer("users-service", &headerInjector{
+		Request:  map[string]string{"X-Tenant": "cafe"},
+		Response: map[string]string{"X-Served-By": "gateway"},
+	}))
+	require.NoError(t, registerTransformer("users-service", renameStatus{}))
+
+	rec := httptest.NewRecorder()
+	routeRequest(rec, httptest.NewRequest(http.MethodGet, "/api/users/1", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "cafe", seen.Get("X-Tenant"))
+	assert.Equal(t, "yes", seen.Get("X-Renamed"))
+	assert.Equal(t, "gateway", rec.Header().Get("X-Served-By"))
+	assert.Equal(t, "fresh", rec.Header().Get("X-Upstream-Status"))
+	assert.Empty(t, rec.Header().Get("X-Status"))
+}
+
+func TestTransformersAreScopedToService(t *testing.T) {
+	var seen http.Header
+	withUpstream(t, func(w http.ResponseWriter, r *http.Request) { seen = r.Header.Clone() })
+	require.NoError(t, registerTransformer("products-service", &headerInjector{Request: map[string]string{"X-Tenant": "cafe"}}))
+
+	rec := httptest.NewRecorder()
+	routeRequest(rec, httptest.NewRequest(http.MethodGet, "/api/users/1", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, seen.Get("X-Tenant"))
+}
+
+func TestLoadTransformers(t *testing.T) {
+	withUpstream(t, func(w http.ResponseWriter, r *http.Request) {})
+	path := filepath.Join(t.TempDir(), "transformers.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"users-service": [{"type": "inject_headers", "response": {"X-Cafe": "open"}}]}`), 0o600))
+
+	require.NoError(t, loadTransformers(path))
+	require.Contains(t, transformers, "users-service")
+	assert.Len(t, transformers["users-service"].responses, 1)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"users-service": [{"type": "teleport"}]}`), 0o600))
+	assert.ErrorContains(t, loadTransformers(path), "unknown transformer type")
+
+	assert.Error(t, registerTransformer("users-service", 42))
+}
@@ -0,0 +1,44 @@
+package consulutil
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// ResponseBuffer is an http.ResponseWriter that keeps the response in
+// memory so it can be inspected before anything reaches the client.
+type ResponseBuffer struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+// NewResponseBuffer returns an empty buffer whose status defaults to 200,
+// as it would for a handler that never calls WriteHeader.
+func NewResponseBuffer() *ResponseBuffer {
+	return &ResponseBuffer{header: make(http.Header), status: http.StatusOK}
+}
+
+func (b *ResponseBuffer) Header() http.Header { return b.header }
+
+func (b *ResponseBuffer) WriteHeader(status int) {
+	if !b.wroteHeader {
+		b.status, b.wroteHeader = status, true
+	}
+}
+
+func (b *ResponseBuffer) Write(p []byte) (int, error) {
+	b.wroteHeader = true
+	return b.body.Write(p)
+}
+
+// Flush lets streaming handlers such as a reverse proxy run; the buffered
+// body is still only read once the handler returns.
+func (b *ResponseBuffer) Flush() {}
+
+// Status is the status the handler wrote.
+func (b *ResponseBuffer) Status() int { return b.status }
+
+// Body is everything the handler wrote.
+func (b *ResponseBuffer) Body() string { return b.body.String() }
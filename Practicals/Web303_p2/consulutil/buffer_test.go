@@ -0,0 +1,24 @@
+package consulutil
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseBuffer(t *testing.T) {
+	buf := NewResponseBuffer()
+	fmt.Fprint(buf, "hello")
+	buf.WriteHeader(http.StatusTeapot)
+	assert.Equal(t, http.StatusOK, buf.Status(), "writing the body commits the default status")
+	assert.Equal(t, "hello", buf.Body())
+
+	buf = NewResponseBuffer()
+	buf.Header().Set("X-Probe", "1")
+	buf.WriteHeader(http.StatusCreated)
+	buf.WriteHeader(http.StatusConflict)
+	assert.Equal(t, http.StatusCreated, buf.Status(), "only the first WriteHeader counts")
+	assert.Equal(t, "1", buf.Header().Get("X-Probe"))
+}
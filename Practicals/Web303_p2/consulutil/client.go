@@ -0,0 +1,185 @@
+// Package consulutil wraps the Consul agent and health APIs shared by the
+// gateway and the backend services: registering an instance with its health
+// checks, deregistering it on shutdown, and discovering healthy instances.
+package consulutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+// Agent is the subset of the Consul agent API used to register instances.
+type Agent interface {
+	Services() (map[string]*consulapi.AgentService, error)
+	ServiceRegister(reg *consulapi.AgentServiceRegistration) error
+	ServiceDeregister(serviceID string) error
+	UpdateTTL(checkID, output, status string) error
+}
+
+// Health is the subset of the Consul health API used for discovery.
+type Health interface {
+	Service(service, tag string, passingOnly bool, q *consulapi.QueryOptions) ([]*consulapi.ServiceEntry, *consulapi.QueryMeta, error)
+}
+
+// Catalog is the subset of the Consul catalog API used to list services.
+type Catalog interface {
+	Services(q *consulapi.QueryOptions) (map[string][]string, *consulapi.QueryMeta, error)
+}
+
+// ErrNoHealthyInstances is returned by Discover when Consul knows no passing
+// instance of a service.
+var ErrNoHealthyInstances = errors.New("no healthy instances available")
+
+// Client is a Consul client shared by everything in a process.
+type Client struct {
+	agent   Agent
+	health  Health
+	catalog Catalog
+
+	// DeregisterAttempts and DeregisterBaseDelay bound Deregister's retries;
+	// the delay doubles after every failed attempt.
+	DeregisterAttempts  int
+	DeregisterBaseDelay time.Duration
+
+	mu         sync.Mutex
+	heartbeats map[string]context.CancelFunc
+}
+
+// New connects to the Consul agent described by config.
+func New(config *consulapi.Config) (*Client, error) {
+	client, err := consulapi.NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+	return NewFromAPI(client.Agent(), client.Health(), client.Catalog()), nil
+}
+
+// NewFromAPI builds a Client on existing API implementations. Any may be nil
+// when the caller only registers or only discovers.
+func NewFromAPI(agent Agent, health Health, catalog Catalog) *Client {
+	return &Client{
+		agent:               agent,
+		health:              health,
+		catalog:             catalog,
+		DeregisterAttempts:  5,
+		DeregisterBaseDelay: 200 * time.Millisecond,
+		heartbeats:          make(map[string]context.CancelFunc),
+	}
+}
+
+// Instance is one passing instance of a service.
+type Instance struct {
+	ID      string            `json:"id"`
+	Address string            `json:"address"`
+	Port    int               `json:"port"`
+	Meta    map[string]string `json:"meta,omitempty"`
+	Tags    []string          `json:"tags,omitempty"`
+}
+
+// URL is the base URL requests to the instance are sent to.
+func (i Instance) URL() (*url.URL, error) {
+	return url.Parse(fmt.Sprintf("http://%s:%d", i.Address, i.Port))
+}
+
+// Services lists the names of every service registered in the catalog,
+// sorted alphabetically.
+func (c *Client) Services() ([]string, error) {
+	if c.catalog == nil {
+		return nil, errors.New("consul catalog API not configured")
+	}
+
+	services, _, err := c.catalog.Services(nil)
+	if err != nil {
+		return nil, fmt.Errorf("consul catalog query failed: %w", err)
+	}
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Instances returns every passing instance of name, one per service ID, in
+// the order Consul reported them. An empty result is not an error.
+func (c *Client) Instances(name string) ([]Instance, error) {
+	return c.TaggedInstances(name, "")
+}
+
+// TaggedInstances is Instances restricted to instances registered with tag.
+// An empty tag matches every instance.
+func (c *Client) TaggedInstances(name, tag string) ([]Instance, error) {
+	if c.health == nil {
+		return nil, errors.New("consul health API not configured")
+	}
+
+	entries, _, err := c.health.Service(name, tag, true, nil)
+	if err != nil {
+		return nil, fmt.Errorf("consul query failed for '%s': %w", name, err)
+	}
+
+	healthy := dedupeInstances(name, entries)
+	instances := make([]Instance, 0, len(healthy))
+	for _, entry := range healthy {
+		instances = append(instances, Instance{
+			ID:      entry.Service.ID,
+			Address: entry.Service.Address,
+			Port:    entry.Service.Port,
+			Meta:    entry.Service.Meta,
+			Tags:    entry.Service.Tags,
+		})
+	}
+	return instances, nil
+}
+
+// Discover returns the base URL of every passing instance of name, failing
+// with ErrNoHealthyInstances when there are none.
+func (c *Client) Discover(name string) ([]*url.URL, error) {
+	instances, err := c.Instances(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(instances) == 0 {
+		return nil, fmt.Errorf("%w for '%s'", ErrNoHealthyInstances, name)
+	}
+
+	urls := make([]*url.URL, 0, len(instances))
+	for _, instance := range instances {
+		endpoint, err := instance.URL()
+		if err != nil {
+			return nil, fmt.Errorf("invalid address for '%s': %w", name, err)
+		}
+		urls = append(urls, endpoint)
+	}
+	return urls, nil
+}
+
+// dedupeInstances collapses entries sharing a service ID, which Consul can
+// briefly report after a crash-restart, keeping the most recently modified
+// one. Order of first appearance is preserved.
+func dedupeInstances(name string, entries []*consulapi.ServiceEntry) []*consulapi.ServiceEntry {
+	byID := make(map[string]int, len(entries))
+	out := make([]*consulapi.ServiceEntry, 0, len(entries))
+	for _, entry := range entries {
+		idx, seen := byID[entry.Service.ID]
+		if !seen {
+			byID[entry.Service.ID] = len(out)
+			out = append(out, entry)
+			continue
+		}
+
+		log.Printf("Collapsing duplicate Consul entry %q for '%s'", entry.Service.ID, name)
+		if entry.Service.ModifyIndex >= out[idx].Service.ModifyIndex {
+			out[idx] = entry
+		}
+	}
+	return out
+}
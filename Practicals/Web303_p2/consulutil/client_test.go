@@ -0,0 +1,126 @@
+package consulutil
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeHealth is an in-memory stand-in for the Consul health API.
+type fakeHealth struct {
+	entries map[string][]*consulapi.ServiceEntry
+	err     error
+}
+
+func (f *fakeHealth) Service(service, tag string, passingOnly bool, q *consulapi.QueryOptions) ([]*consulapi.ServiceEntry, *consulapi.QueryMeta, error) {
+	if f.err != nil {
+		return nil, nil, f.err
+	}
+	return filterTag(f.entries[service], tag), &consulapi.QueryMeta{}, nil
+}
+
+// filterTag keeps the entries registered with tag, as Consul's tag filter does.
+func filterTag(entries []*consulapi.ServiceEntry, tag string) []*consulapi.ServiceEntry {
+	if tag == "" {
+		return entries
+	}
+	var tagged []*consulapi.ServiceEntry
+	for _, entry := range entries {
+		if slices.Contains(entry.Service.Tags, tag) {
+			tagged = append(tagged, entry)
+		}
+	}
+	return tagged
+}
+
+func serviceEntry(id, address string, port int) *consulapi.ServiceEntry {
+	return &consulapi.ServiceEntry{
+		Service: &consulapi.AgentService{ID: id, Address: address, Port: port},
+	}
+}
+
+func TestDiscoverReturnsHealthyInstances(t *testing.T) {
+	stale := serviceEntry("users-1", "10.0.0.5", 8081)
+	stale.Service.ModifyIndex = 10
+	fresh := serviceEntry("users-1", "10.0.0.9", 8081)
+	fresh.Service.ModifyIndex = 42
+	client := NewFromAPI(nil, &fakeHealth{entries: map[string][]*consulapi.ServiceEntry{
+		"users-service": {fresh, serviceEntry("users-2", "10.0.0.6", 8081), stale},
+	}}, nil)
+
+	instances, err := client.Discover("users-service")
+	require.NoError(t, err)
+	require.Len(t, instances, 2)
+	assert.Equal(t, "http://10.0.0.9:8081", instances[0].String())
+	assert.Equal(t, "http://10.0.0.6:8081", instances[1].String())
+}
+
+// fakeCatalog lists a fixed set of service names.
+type fakeCatalog []string
+
+func (f fakeCatalog) Services(q *consulapi.QueryOptions) (map[string][]string, *consulapi.QueryMeta, error) {
+	out := make(map[string][]string, len(f))
+	for _, name := range f {
+		out[name] = nil
+	}
+	return out, &consulapi.QueryMeta{}, nil
+}
+
+func TestServicesAndInstances(t *testing.T) {
+	entry := serviceEntry("users-1", "10.0.0.5", 8081)
+	entry.Service.Meta = map[string]string{"weight": "3"}
+	client := NewFromAPI(nil, &fakeHealth{entries: map[string][]*consulapi.ServiceEntry{
+		"users-service": {entry},
+	}}, fakeCatalog{"users-service", "consul", "products-service"})
+
+	names, err := client.Services()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"consul", "products-service", "users-service"}, names)
+
+	instances, err := client.Instances("users-service")
+	require.NoError(t, err)
+	assert.Equal(t, []Instance{{ID: "users-1", Address: "10.0.0.5", Port: 8081, Meta: map[string]string{"weight": "3"}}}, instances)
+
+	instances, err = client.Instances("products-service")
+	require.NoError(t, err)
+	assert.Empty(t, instances)
+
+	_, err = NewFromAPI(nil, nil, nil).Services()
+	assert.Error(t, err)
+}
+
+func TestTaggedInstances(t *testing.T) {
+	canary := serviceEntry("users-2", "10.0.0.6", 8081)
+	canary.Service.Tags = []string{"canary"}
+	client := NewFromAPI(nil, &fakeHealth{entries: map[string][]*consulapi.ServiceEntry{
+		"users-service": {serviceEntry("users-1", "10.0.0.5", 8081), canary},
+	}}, nil)
+
+	instances, err := client.TaggedInstances("users-service", "canary")
+	require.NoError(t, err)
+	assert.Equal(t, []Instance{{ID: "users-2", Address: "10.0.0.6", Port: 8081, Tags: []string{"canary"}}}, instances)
+
+	instances, err = client.TaggedInstances("users-service", "")
+	require.NoError(t, err)
+	assert.Len(t, instances, 2, "no tag matches every instance")
+
+	instances, err = client.TaggedInstances("users-service", "beta")
+	require.NoError(t, err)
+	assert.Empty(t, instances)
+}
+
+func TestDiscoverErrors(t *testing.T) {
+	_, err := NewFromAPI(nil, &fakeHealth{}, nil).Discover("users-service")
+	assert.ErrorIs(t, err, ErrNoHealthyInstances)
+	assert.EqualError(t, err, "no healthy instances available for 'users-service'")
+
+	_, err = NewFromAPI(nil, &fakeHealth{err: errors.New("connection refused")}, nil).Discover("users-service")
+	assert.ErrorContains(t, err, "consul query failed for 'users-service'")
+
+	_, err = NewFromAPI(&fakeAgent{}, nil, nil).Discover("users-service")
+	assert.Error(t, err)
+}
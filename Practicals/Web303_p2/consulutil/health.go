@@ -0,0 +1,26 @@
+package consulutil
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// AssertHealthBody runs the health handler and reports 503 unless it
+// answered 200 with a body containing expect, so a "degraded" 200 fails a
+// Consul HTTP check pointed at it.
+func AssertHealthBody(health http.HandlerFunc, expect string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		buf := NewResponseBuffer()
+		health(buf, r)
+
+		body := buf.Body()
+		w.Header().Set("Content-Type", "text/plain")
+		if buf.Status() != http.StatusOK || !strings.Contains(body, expect) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintf(w, "health assertion failed: status %d, body %q", buf.Status(), body)
+			return
+		}
+		fmt.Fprint(w, body)
+	}
+}
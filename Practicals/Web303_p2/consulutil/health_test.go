@@ -0,0 +1,48 @@
+package consulutil
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssertHealthBody(t *testing.T) {
+	respond := func(status int, body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Probe", "1")
+			w.WriteHeader(status)
+			fmt.Fprint(w, body)
+		}
+	}
+	tests := []struct {
+		name   string
+		health http.HandlerFunc
+		want   int
+	}{
+		{"healthy", respond(http.StatusOK, "OK"), http.StatusOK},
+		{"degraded 200", respond(http.StatusOK, "DEGRADED: database unreachable"), http.StatusServiceUnavailable},
+		{"failing status", respond(http.StatusInternalServerError, "OK"), http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			AssertHealthBody(tt.health, "OK")(rec, httptest.NewRequest(http.MethodGet, "/health/assert", nil))
+
+			assert.Equal(t, tt.want, rec.Code)
+			assert.Empty(t, rec.Header().Get("X-Probe"), "the health handler's headers are not forwarded")
+		})
+	}
+}
+
+func TestAssertHealthBodyReportsTheFailure(t *testing.T) {
+	rec := httptest.NewRecorder()
+	AssertHealthBody(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusBadGateway)
+	}, "OK")(rec, httptest.NewRequest(http.MethodGet, "/health/assert", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, `health assertion failed: status 502, body "down\n"`, rec.Body.String())
+}
@@ -0,0 +1,268 @@
+package consulutil
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+// Check defaults used when a CheckConfig field is left zero.
+const (
+	DefaultHealthPath    = "/health"
+	DefaultCheckInterval = 10 * time.Second
+	DefaultCheckTimeout  = time.Second
+	DefaultTTL           = 15 * time.Second
+	// Safety net in case deregistration on shutdown never succeeds
+	DefaultDeregisterCriticalAfter = time.Minute
+)
+
+// CheckConfig tunes the health checks attached to a registration.
+type CheckConfig struct {
+	// Path is polled by the http check.
+	Path     string
+	Interval time.Duration
+	Timeout  time.Duration
+	// TTL is how long a ttl check stays passing without a heartbeat.
+	TTL                     time.Duration
+	DeregisterCriticalAfter time.Duration
+}
+
+// Config describes one service instance to register.
+type Config struct {
+	ID      string
+	Name    string
+	Address string
+	Port    int
+	// Checks lists "http", "grpc" and "ttl"; the instance is only healthy
+	// while every listed check passes. An empty list means "http".
+	Checks []string
+	// GRPCAddr is the host:port probed by a grpc check.
+	GRPCAddr string
+	Check    CheckConfig
+}
+
+// ChecksFromEnv reads HEALTH_CHECK_PATH, HEALTH_CHECK_INTERVAL and
+// HEALTH_CHECK_TIMEOUT, leaving unset fields at their defaults.
+func ChecksFromEnv() (CheckConfig, error) {
+	var cfg CheckConfig
+	cfg.Path = strings.TrimSpace(os.Getenv("HEALTH_CHECK_PATH"))
+	for key, dst := range map[string]*time.Duration{
+		"HEALTH_CHECK_INTERVAL": &cfg.Interval,
+		"HEALTH_CHECK_TIMEOUT":  &cfg.Timeout,
+	} {
+		raw := strings.TrimSpace(os.Getenv(key))
+		if raw == "" {
+			continue
+		}
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			return CheckConfig{}, fmt.Errorf("%s %q is not a positive duration", key, raw)
+		}
+		*dst = d
+	}
+	return cfg, nil
+}
+
+func (c CheckConfig) withDefaults() CheckConfig {
+	if c.Path == "" {
+		c.Path = DefaultHealthPath
+	}
+	if !strings.HasPrefix(c.Path, "/") {
+		c.Path = "/" + c.Path
+	}
+	if c.Interval == 0 {
+		c.Interval = DefaultCheckInterval
+	}
+	if c.Timeout == 0 {
+		c.Timeout = DefaultCheckTimeout
+	}
+	if c.TTL == 0 {
+		c.TTL = DefaultTTL
+	}
+	if c.DeregisterCriticalAfter == 0 {
+		c.DeregisterCriticalAfter = DefaultDeregisterCriticalAfter
+	}
+	return c
+}
+
+// Registration builds the Consul registration for cfg. ID defaults to
+// Name-Address.
+func (cfg Config) Registration() (*consulapi.AgentServiceRegistration, error) {
+	id := cfg.ID
+	if id == "" {
+		id = cfg.Name + "-" + cfg.Address
+	}
+	kinds := cfg.Checks
+	if len(kinds) == 0 {
+		kinds = []string{"http"}
+	}
+	opts := cfg.Check.withDefaults()
+
+	var checks consulapi.AgentServiceChecks
+	for _, kind := range kinds {
+		kind = strings.TrimSpace(kind)
+		check := &consulapi.AgentServiceCheck{
+			CheckID:                        id + ":" + kind,
+			Name:                           cfg.Name + " " + kind + " check",
+			DeregisterCriticalServiceAfter: consulDuration(opts.DeregisterCriticalAfter),
+		}
+		switch kind {
+		case "http":
+			check.HTTP = fmt.Sprintf("http://%s:%d%s", cfg.Address, cfg.Port, opts.Path)
+			check.Interval = consulDuration(opts.Interval)
+			check.Timeout = consulDuration(opts.Timeout)
+		case "grpc":
+			if cfg.GRPCAddr == "" {
+				return nil, fmt.Errorf("grpc health check requires a gRPC health address")
+			}
+			check.GRPC = cfg.GRPCAddr
+			check.Interval = consulDuration(opts.Interval)
+			check.Timeout = consulDuration(opts.Timeout)
+		case "ttl":
+			check.TTL = consulDuration(opts.TTL)
+		default:
+			return nil, fmt.Errorf("unknown health check type %q", kind)
+		}
+		checks = append(checks, check)
+	}
+
+	return &consulapi.AgentServiceRegistration{
+		ID:      id,
+		Name:    cfg.Name,
+		Port:    cfg.Port,
+		Address: cfg.Address,
+		Checks:  checks,
+	}, nil
+}
+
+// consulDuration formats d the way Consul configs usually spell durations,
+// dropping zero trailing units so a minute is "1m" rather than "1m0s".
+func consulDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// Register registers cfg with Consul, keeps any TTL checks passing until the
+// instance is deregistered, and returns the registration ID.
+func (c *Client) Register(cfg Config) (string, error) {
+	if c.agent == nil {
+		return "", fmt.Errorf("consul agent API not configured")
+	}
+	reg, err := cfg.Registration()
+	if err != nil {
+		return "", err
+	}
+	if err := registerIdempotent(c.agent, reg); err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	for _, check := range reg.Checks {
+		if check.TTL != "" {
+			ttl, _ := time.ParseDuration(check.TTL)
+			go keepTTLPassing(ctx, c.agent, check.CheckID, ttl)
+		}
+	}
+	c.mu.Lock()
+	if stop, ok := c.heartbeats[reg.ID]; ok {
+		stop()
+	}
+	c.heartbeats[reg.ID] = cancel
+	c.mu.Unlock()
+
+	log.Printf("Registered %s on %s:%d with %d check(s)", reg.Name, reg.Address, reg.Port, len(reg.Checks))
+	return reg.ID, nil
+}
+
+// registerIdempotent registers reg under its ID, which Consul treats as an
+// upsert, then removes any other entry for this service at the same address
+// and port, such as one left behind under a previous ID.
+func registerIdempotent(agent Agent, reg *consulapi.AgentServiceRegistration) error {
+	existing, err := agent.Services()
+	if err != nil {
+		return fmt.Errorf("listing registered services failed: %w", err)
+	}
+	if current, ok := existing[reg.ID]; ok {
+		log.Printf("Updating existing Consul entry %s (was %s:%d)", reg.ID, current.Address, current.Port)
+	}
+
+	if err := agent.ServiceRegister(reg); err != nil {
+		return fmt.Errorf("registration failed: %w", err)
+	}
+
+	for id, svc := range existing {
+		if id == reg.ID || svc.Service != reg.Name || svc.Address != reg.Address || svc.Port != reg.Port {
+			continue
+		}
+		if err := agent.ServiceDeregister(id); err != nil {
+			log.Printf("Failed to remove stale Consul entry %s: %v", id, err)
+			continue
+		}
+		log.Printf("Removed stale Consul entry %s", id)
+	}
+	return nil
+}
+
+// keepTTLPassing heartbeats a TTL check at a third of its interval until ctx
+// is cancelled.
+func keepTTLPassing(ctx context.Context, agent Agent, checkID string, ttl time.Duration) {
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := agent.UpdateTTL(checkID, "", consulapi.HealthPassing); err != nil {
+				log.Printf("TTL heartbeat for %s failed: %v", checkID, err)
+			}
+		}
+	}
+}
+
+// Deregister stops the TTL heartbeats for id and removes it from Consul,
+// retrying with exponential backoff until it succeeds, attempts run out, or
+// ctx expires.
+func (c *Client) Deregister(ctx context.Context, id string) error {
+	if c.agent == nil {
+		return fmt.Errorf("consul agent API not configured")
+	}
+	c.mu.Lock()
+	if stop, ok := c.heartbeats[id]; ok {
+		stop()
+		delete(c.heartbeats, id)
+	}
+	c.mu.Unlock()
+
+	delay := c.DeregisterBaseDelay
+	var err error
+	for attempt := 1; attempt <= c.DeregisterAttempts; attempt++ {
+		if err = c.agent.ServiceDeregister(id); err == nil {
+			log.Printf("Deregistered %s from Consul (attempt %d)", id, attempt)
+			return nil
+		}
+		log.Printf("Deregistration attempt %d/%d for %s failed: %v", attempt, c.DeregisterAttempts, id, err)
+		if attempt == c.DeregisterAttempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("deregistration abandoned after %d attempts: %w", attempt, err)
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+	return fmt.Errorf("deregistration failed after %d attempts: %w", c.DeregisterAttempts, err)
+}
@@ -0,0 +1,199 @@
+package consulutil
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAgent stores registrations the way the Consul agent does: keyed by ID,
+// with re-registration replacing the previous entry. The first failDeregister
+// deregistrations fail.
+type fakeAgent struct {
+	mu             sync.Mutex
+	registry       map[string]*consulapi.AgentServiceRegistration
+	failDeregister int
+	deregistered   []string
+	ttlUpdates     int
+}
+
+func (f *fakeAgent) Services() (map[string]*consulapi.AgentService, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make(map[string]*consulapi.AgentService, len(f.registry))
+	for id, reg := range f.registry {
+		out[id] = &consulapi.AgentService{ID: id, Service: reg.Name, Address: reg.Address, Port: reg.Port}
+	}
+	return out, nil
+}
+
+func (f *fakeAgent) ServiceRegister(reg *consulapi.AgentServiceRegistration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.registry == nil {
+		f.registry = make(map[string]*consulapi.AgentServiceRegistration)
+	}
+	f.registry[reg.ID] = reg
+	return nil
+}
+
+func (f *fakeAgent) ServiceDeregister(serviceID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deregistered = append(f.deregistered, serviceID)
+	if len(f.deregistered) <= f.failDeregister {
+		return errors.New("consul unreachable")
+	}
+	delete(f.registry, serviceID)
+	return nil
+}
+
+func (f *fakeAgent) UpdateTTL(checkID, output, status string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ttlUpdates++
+	return nil
+}
+
+func (f *fakeAgent) updates() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.ttlUpdates
+}
+
+func usersConfig(checks ...string) Config {
+	return Config{ID: "users-service-users-host", Name: "users-service", Address: "users-host", Port: 8081, Checks: checks}
+}
+
+func TestRegistrationDefaults(t *testing.T) {
+	reg, err := Config{Name: "users-service", Address: "users-host", Port: 8081}.Registration()
+	require.NoError(t, err)
+
+	assert.Equal(t, "users-service-users-host", reg.ID)
+	require.Len(t, reg.Checks, 1)
+	check := reg.Checks[0]
+	assert.Equal(t, "http://users-host:8081/health", check.HTTP)
+	assert.Equal(t, "10s", check.Interval)
+	assert.Equal(t, "1s", check.Timeout)
+	assert.Equal(t, "1m", check.DeregisterCriticalServiceAfter)
+}
+
+func TestRegistrationHonoursCheckConfig(t *testing.T) {
+	cfg := usersConfig("http", "grpc", "ttl")
+	cfg.GRPCAddr = "users-host:9091"
+	cfg.Check = CheckConfig{Path: "ready", Interval: 3 * time.Second, Timeout: 500 * time.Millisecond, TTL: 30 * time.Second}
+
+	reg, err := cfg.Registration()
+	require.NoError(t, err)
+	require.Len(t, reg.Checks, 3)
+	assert.Equal(t, "http://users-host:8081/ready", reg.Checks[0].HTTP)
+	assert.Equal(t, "3s", reg.Checks[0].Interval)
+	assert.Equal(t, "500ms", reg.Checks[0].Timeout)
+	assert.Equal(t, "users-host:9091", reg.Checks[1].GRPC)
+	assert.Equal(t, "users-service-users-host:ttl", reg.Checks[2].CheckID)
+	assert.Equal(t, "30s", reg.Checks[2].TTL)
+
+	_, err = usersConfig("grpc").Registration()
+	assert.Error(t, err, "grpc check without an address")
+	_, err = usersConfig("http", "carrier-pigeon").Registration()
+	assert.Error(t, err)
+}
+
+func TestChecksFromEnv(t *testing.T) {
+	t.Setenv("HEALTH_CHECK_PATH", "/health/assert")
+	t.Setenv("HEALTH_CHECK_INTERVAL", "5s")
+	t.Setenv("HEALTH_CHECK_TIMEOUT", "")
+
+	cfg, err := ChecksFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, CheckConfig{Path: "/health/assert", Interval: 5 * time.Second}, cfg)
+
+	t.Setenv("HEALTH_CHECK_TIMEOUT", "soon")
+	_, err = ChecksFromEnv()
+	assert.ErrorContains(t, err, "HEALTH_CHECK_TIMEOUT")
+}
+
+func TestRegisterUpdatesInPlace(t *testing.T) {
+	agent := &fakeAgent{}
+	client := NewFromAPI(agent, nil, nil)
+
+	_, err := client.Register(usersConfig("http"))
+	require.NoError(t, err)
+	id, err := client.Register(usersConfig("http", "ttl"))
+	require.NoError(t, err)
+	defer client.Deregister(context.Background(), id)
+
+	require.Len(t, agent.registry, 1)
+	entry := agent.registry["users-service-users-host"]
+	require.NotNil(t, entry)
+	assert.Len(t, entry.Checks, 2, "latest details win")
+}
+
+func TestRegisterRemovesStaleIDs(t *testing.T) {
+	agent := &fakeAgent{registry: map[string]*consulapi.AgentServiceRegistration{
+		"users-service-old-id": {ID: "users-service-old-id", Name: "users-service", Address: "users-host", Port: 8081},
+		"users-service-peer":   {ID: "users-service-peer", Name: "users-service", Address: "peer-host", Port: 8081},
+	}}
+	cfg := usersConfig()
+	cfg.ID = "users-service-stable"
+
+	_, err := NewFromAPI(agent, nil, nil).Register(cfg)
+	require.NoError(t, err)
+
+	assert.Contains(t, agent.registry, "users-service-stable")
+	assert.NotContains(t, agent.registry, "users-service-old-id")
+	assert.Contains(t, agent.registry, "users-service-peer", "other instances are left alone")
+}
+
+func TestDeregisterStopsHeartbeats(t *testing.T) {
+	agent := &fakeAgent{}
+	client := NewFromAPI(agent, nil, nil)
+	cfg := usersConfig("ttl")
+	cfg.Check.TTL = 30 * time.Millisecond
+
+	id, err := client.Register(cfg)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return agent.updates() > 0 }, time.Second, time.Millisecond)
+
+	require.NoError(t, client.Deregister(context.Background(), id))
+	assert.Empty(t, agent.registry)
+	after := agent.updates()
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, after, agent.updates(), "heartbeats stop once deregistered")
+}
+
+func TestDeregisterUsesRegistrationIDOnce(t *testing.T) {
+	agent := &fakeAgent{}
+	client := NewFromAPI(agent, nil, nil)
+
+	id, err := client.Register(usersConfig("http"))
+	require.NoError(t, err)
+	require.NoError(t, client.Deregister(context.Background(), id))
+	assert.Equal(t, []string{id}, agent.deregistered)
+	assert.Empty(t, agent.registry)
+}
+
+func TestDeregisterRetriesUntilSuccess(t *testing.T) {
+	agent := &fakeAgent{failDeregister: 1}
+	client := NewFromAPI(agent, nil, nil)
+	client.DeregisterBaseDelay = time.Millisecond
+
+	require.NoError(t, client.Deregister(context.Background(), "users-service-host"))
+	assert.Equal(t, []string{"users-service-host", "users-service-host"}, agent.deregistered)
+}
+
+func TestDeregisterStopsAtDeadline(t *testing.T) {
+	agent := &fakeAgent{failDeregister: 100}
+	client := NewFromAPI(agent, nil, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	require.Error(t, client.Deregister(ctx, "users-service-host"))
+	assert.Less(t, len(agent.deregistered), client.DeregisterAttempts)
+}
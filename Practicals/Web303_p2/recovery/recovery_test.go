@@ -0,0 +1,32 @@
+package recovery
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecoverMiddlewareReturnsJSON500(t *testing.T) {
+	var logs bytes.Buffer
+	orig := log.Writer()
+	log.SetOutput(&logs)
+	t.Cleanup(func() { log.SetOutput(orig) })
+
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("index out of range")
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/boom", nil)
+	req.Header.Set("X-Request-ID", "req-123")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"error": "internal server error"}`, rec.Body.String())
+	assert.Contains(t, logs.String(), "Panic serving GET /boom (request req-123): index out of range")
+	assert.Contains(t, logs.String(), "recovery_test.go", "the stack trace is logged")
+}
@@ -1,32 +1,92 @@
-// services/products-service/main.go
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"consulutil"
+	"recovery"
 
 	"github.com/go-chi/chi/v5"
 	consulapi "github.com/hashicorp/consul/api"
 )
 
 const serviceName = "products-service"
-const servicePort = 8082
+const defaultServicePort = 8082
+
+// servicePort and serviceAddress are set from SERVICE_PORT and SERVICE_ADDRESS
+// at startup. An empty serviceAddress advertises the hostname.
+var (
+	servicePort    = defaultServicePort
+	serviceAddress string
+)
+
+// shutdownGracePeriod bounds how long draining in-flight requests may take.
+const shutdownGracePeriod = 10 * time.Second
+
+// deregisterTimeout bounds the Consul deregistration that precedes draining.
+const deregisterTimeout = 3 * time.Second
 
 func main() {
-	if err := registerWithConsul(); err != nil {
+	port, address, err := loadServiceConfig(os.Getenv("SERVICE_PORT"), os.Getenv("SERVICE_ADDRESS"))
+	if err != nil {
+		log.Fatalf("Invalid service config: %v", err)
+	}
+	servicePort, serviceAddress = port, address
+
+	consul, err := consulutil.New(consulapi.DefaultConfig())
+	if err != nil {
+		log.Fatalf("Failed to create consul client: %v", err)
+	}
+
+	serviceID, err := registerWithConsul(consul)
+	if err != nil {
 		log.Fatalf("Service registration failed: %v", err)
 	}
 
 	mux := chi.NewRouter()
+	mux.Use(recovery.Middleware)
 	mux.Get("/health", handleHealthStatus)
+	if expect := os.Getenv("HEALTH_EXPECT_BODY"); expect != "" {
+		mux.Get(healthAssertPath, consulutil.AssertHealthBody(handleHealthStatus, expect))
+	}
 	mux.Get("/products/{id}", handleProductRequest)
 
-	log.Printf("%s is starting on port %d", serviceName, servicePort)
+	server := &http.Server{Addr: fmt.Sprintf(":%d", servicePort), Handler: mux}
+
+	go func() {
+		log.Printf("%s is starting on port %d", serviceName, servicePort)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server startup error: %v", err)
+		}
+	}()
 
-	if err := http.ListenAndServe(fmt.Sprintf(":%d", servicePort), mux); err != nil {
-		log.Fatalf("Server startup error: %v", err)
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	<-stop
+
+	log.Printf("%s is shutting down", serviceName)
+	// Deregister first so Consul stops advertising the instance before it
+	// stops accepting connections
+	deregisterCtx, cancelDeregister := context.WithTimeout(context.Background(), deregisterTimeout)
+	defer cancelDeregister()
+	if err := consul.Deregister(deregisterCtx, serviceID); err != nil {
+		log.Printf("Leaving %s for Consul to reap: %v", serviceID, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownGracePeriod)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("HTTP shutdown error: %v", err)
 	}
 }
 
@@ -44,33 +104,77 @@ func handleHealthStatus(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprint(w, "Healthy")
 }
 
-func registerWithConsul() error {
-	client, err := consulapi.NewClient(consulapi.DefaultConfig())
-	if err != nil {
-		return fmt.Errorf("failed to create consul client: %w", err)
+// loadServiceConfig validates the listen port and advertised address, falling
+// back to defaultServicePort and the hostname when they are unset.
+func loadServiceConfig(rawPort, address string) (int, string, error) {
+	port := defaultServicePort
+	if rawPort = strings.TrimSpace(rawPort); rawPort != "" {
+		p, err := strconv.Atoi(rawPort)
+		if err != nil || p < 1 || p > 65535 {
+			return 0, "", fmt.Errorf("SERVICE_PORT %q is not a valid port number", rawPort)
+		}
+		port = p
 	}
+	return port, strings.TrimSpace(address), nil
+}
 
+func registerWithConsul(consul *consulutil.Client) (string, error) {
 	hostname, err := os.Hostname()
 	if err != nil {
-		return fmt.Errorf("unable to get hostname: %w", err)
+		return "", fmt.Errorf("unable to get hostname: %w", err)
 	}
 
-	serviceReg := &consulapi.AgentServiceRegistration{
-		ID:      serviceName + "-" + hostname,
-		Name:    serviceName,
-		Port:    servicePort,
-		Address: hostname,
-		Check: &consulapi.AgentServiceCheck{
-			HTTP:     fmt.Sprintf("http://%s:%d/health", hostname, servicePort),
-			Interval: "10s",
-			Timeout:  "1s",
-		},
+	cfg, err := serviceConfig(hostname, os.Getenv("HEALTH_CHECKS"))
+	if err != nil {
+		return "", err
 	}
+	return consul.Register(cfg)
+}
+
+// healthAssertPath serves /health filtered through the HEALTH_EXPECT_BODY
+// assertion; Consul only inspects status codes, so this is what it polls.
+const healthAssertPath = "/health/assert"
 
-	if err := client.Agent().ServiceRegister(serviceReg); err != nil {
-		return fmt.Errorf("service registration error: %w", err)
+// serviceConfig describes this instance to Consul. checkKinds is a
+// comma-separated list of "http", "grpc" and "ttl"; the service is only
+// healthy while every listed check passes. An empty list means "http".
+// HEALTH_CHECK_PATH, HEALTH_CHECK_INTERVAL and HEALTH_CHECK_TIMEOUT tune the
+// probes.
+func serviceConfig(hostname, checkKinds string) (consulutil.Config, error) {
+	checks, err := consulutil.ChecksFromEnv()
+	if err != nil {
+		return consulutil.Config{}, err
+	}
+	if checks.Path == "" && os.Getenv("HEALTH_EXPECT_BODY") != "" {
+		checks.Path = healthAssertPath
 	}
 
-	log.Printf("Service %s registered successfully", serviceName)
-	return nil
-}
\ No newline at end of file
+	address := hostname
+	if serviceAddress != "" {
+		address = serviceAddress
+	}
+	var kinds []string
+	if strings.TrimSpace(checkKinds) != "" {
+		kinds = strings.Split(checkKinds, ",")
+	}
+
+	return consulutil.Config{
+		ID:       instanceID(hostname),
+		Name:     serviceName,
+		Address:  address,
+		Port:     servicePort,
+		Checks:   kinds,
+		GRPCAddr: os.Getenv("GRPC_HEALTH_ADDR"),
+		Check:    checks,
+	}, nil
+}
+
+// instanceID is the Consul ID for this instance. SERVICE_INSTANCE_ID pins it
+// across hostname changes; otherwise it is derived from the hostname so that
+// re-registering the same instance always reuses the same ID.
+func instanceID(hostname string) string {
+	if id := strings.TrimSpace(os.Getenv("SERVICE_INSTANCE_ID")); id != "" {
+		return id
+	}
+	return serviceName + "-" + hostname
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"consulutil"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildRegistration is the Consul registration serviceConfig produces.
+func buildRegistration(hostname, checkKinds string) (*consulapi.AgentServiceRegistration, error) {
+	cfg, err := serviceConfig(hostname, checkKinds)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Registration()
+}
+
+func TestBuildRegistrationWithMultipleChecks(t *testing.T) {
+	reg, err := buildRegistration("products-host", "http, ttl")
+	require.NoError(t, err)
+
+	require.Len(t, reg.Checks, 2)
+	assert.Equal(t, "http://products-host:8082/health", reg.Checks[0].HTTP)
+	assert.Equal(t, "products-service-products-host:ttl", reg.Checks[1].CheckID)
+	assert.Equal(t, "15s", reg.Checks[1].TTL)
+}
+
+func TestLoadServiceConfig(t *testing.T) {
+	port, address, err := loadServiceConfig("", "")
+	require.NoError(t, err)
+	assert.Equal(t, defaultServicePort, port)
+	assert.Empty(t, address)
+
+	port, address, err = loadServiceConfig("9090", " products.internal ")
+	require.NoError(t, err)
+	assert.Equal(t, 9090, port)
+	assert.Equal(t, "products.internal", address)
+
+	for _, bad := range []string{"http", "0", "70000", "80a"} {
+		_, _, err := loadServiceConfig(bad, "")
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestBuildRegistrationAdvertisesConfiguredAddress(t *testing.T) {
+	origPort, origAddr := servicePort, serviceAddress
+	servicePort, serviceAddress = 9090, "10.1.2.3"
+	defer func() { servicePort, serviceAddress = origPort, origAddr }()
+
+	reg, err := buildRegistration("container-id", "")
+	require.NoError(t, err)
+
+	assert.Equal(t, "products-service-container-id", reg.ID)
+	assert.Equal(t, "10.1.2.3", reg.Address)
+	assert.Equal(t, 9090, reg.Port)
+	assert.Equal(t, "http://10.1.2.3:9090/health", reg.Checks[0].HTTP)
+}
+
+func TestServiceConfigKeepsInstanceIDAcrossHostnames(t *testing.T) {
+	t.Setenv("SERVICE_INSTANCE_ID", "products-service-stable")
+
+	cfg, err := serviceConfig("products-host", "")
+	require.NoError(t, err)
+	assert.Equal(t, "products-service-stable", cfg.ID)
+}
+
+func TestServiceConfigReadsCheckTuning(t *testing.T) {
+	t.Setenv("HEALTH_CHECK_INTERVAL", "30s")
+	t.Setenv("HEALTH_CHECK_PATH", "/ready")
+
+	reg, err := buildRegistration("products-host", "http")
+	require.NoError(t, err)
+	assert.Equal(t, "http://products-host:8082/ready", reg.Checks[0].HTTP)
+	assert.Equal(t, "30s", reg.Checks[0].Interval)
+	assert.Equal(t, "1s", reg.Checks[0].Timeout)
+}
+
+func TestAssertHealthBodyExcludesDegradedInstance(t *testing.T) {
+	degraded := func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "DEGRADED: database unreachable")
+	}
+	check := consulutil.AssertHealthBody(degraded, "Healthy")
+
+	rec := httptest.NewRecorder()
+	check(rec, httptest.NewRequest(http.MethodGet, healthAssertPath, nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	rec = httptest.NewRecorder()
+	consulutil.AssertHealthBody(handleHealthStatus, "Healthy")(rec, httptest.NewRequest(http.MethodGet, healthAssertPath, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestBuildRegistrationUsesHealthAssertion(t *testing.T) {
+	t.Setenv("HEALTH_EXPECT_BODY", "Healthy")
+
+	reg, err := buildRegistration("products-host", "http")
+	require.NoError(t, err)
+	assert.Equal(t, "http://products-host:8082/health/assert", reg.Checks[0].HTTP)
+}
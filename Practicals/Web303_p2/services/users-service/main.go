@@ -1,43 +1,112 @@
-// services/users-service/main.go
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"consulutil"
+	"recovery"
 
 	"github.com/go-chi/chi/v5"
 	consulapi "github.com/hashicorp/consul/api"
 )
 
 const serviceName = "users-service"
-const servicePort = 8081
+const defaultServicePort = 8081
+
+// servicePort and serviceAddress are set from SERVICE_PORT and SERVICE_ADDRESS
+// at startup. An empty serviceAddress advertises the hostname.
+var (
+	servicePort    = defaultServicePort
+	serviceAddress string
+)
+
+// shutdownGracePeriod bounds how long draining in-flight requests may take.
+const shutdownGracePeriod = 10 * time.Second
+
+// deregisterTimeout bounds the Consul deregistration that precedes draining.
+const deregisterTimeout = 3 * time.Second
 
 func main() {
+	port, address, err := loadServiceConfig(os.Getenv("SERVICE_PORT"), os.Getenv("SERVICE_ADDRESS"))
+	if err != nil {
+		log.Fatalf("Invalid service config: %v", err)
+	}
+	servicePort, serviceAddress = port, address
+
+	consul, err := consulutil.New(consulapi.DefaultConfig())
+	if err != nil {
+		log.Fatalf("Consul client init failed: %v", err)
+	}
+
 	// Register with service discovery
-	if err := registerWithConsul(); err != nil {
+	serviceID, err := registerWithConsul(consul)
+	if err != nil {
 		log.Fatalf("Registration error: %v", err)
 	}
 
 	// Setup router
 	router := chi.NewRouter()
+	router.Use(recovery.Middleware)
 	router.Get("/health", handleHealthCheck)
+	if expect := os.Getenv("HEALTH_EXPECT_BODY"); expect != "" {
+		router.Get(healthAssertPath, consulutil.AssertHealthBody(handleHealthCheck, expect))
+	}
 	router.Get("/users/{id}", handleGetUser)
 
 	addr := fmt.Sprintf(":%d", servicePort)
-	log.Printf("Starting %s on %s", serviceName, addr)
+	server := &http.Server{Addr: addr, Handler: router}
+
+	go func() {
+		log.Printf("Starting %s on %s", serviceName, addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server error: %v", err)
+		}
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	<-stop
 
-	if err := http.ListenAndServe(addr, router); err != nil {
-		log.Fatalf("Server error: %v", err)
+	log.Printf("Shutting down %s", serviceName)
+	// Deregister first so Consul stops advertising the instance before it
+	// stops accepting connections
+	deregisterCtx, cancelDeregister := context.WithTimeout(context.Background(), deregisterTimeout)
+	defer cancelDeregister()
+	if err := consul.Deregister(deregisterCtx, serviceID); err != nil {
+		log.Printf("Leaving %s for Consul to reap: %v", serviceID, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownGracePeriod)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("HTTP shutdown error: %v", err)
 	}
 }
 
 // handleGetUser retrieves user information by ID.
 func handleGetUser(w http.ResponseWriter, r *http.Request) {
-	id := chi.URLParam(r, "id")
-	w.Header().Set("Content-Type", "text/plain")
-	fmt.Fprintf(w, "User Data - Service: %s, ID: %s\n", serviceName, id)
+	user, err := users.Get(chi.URLParam(r, "id"))
+	if errors.Is(err, errUserNotFound) {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "user lookup failed", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
 }
 
 // handleHealthCheck returns service health status.
@@ -45,36 +114,81 @@ func handleHealthCheck(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/plain")
 	w.WriteHeader(http.StatusOK)
 	fmt.Fprint(w, "OK")
-// registerWithConsul registers the service instance with Consul.
-func registerWithConsul() error {
-	client, err := consulapi.NewClient(consulapi.DefaultConfig())
-	if err != nil {
-		return fmt.Errorf("consul client init failed: %w", err)
+}
+
+// loadServiceConfig validates the listen port and advertised address, falling
+// back to defaultServicePort and the hostname when they are unset.
+func loadServiceConfig(rawPort, address string) (int, string, error) {
+	port := defaultServicePort
+	if rawPort = strings.TrimSpace(rawPort); rawPort != "" {
+		p, err := strconv.Atoi(rawPort)
+		if err != nil || p < 1 || p > 65535 {
+			return 0, "", fmt.Errorf("SERVICE_PORT %q is not a valid port number", rawPort)
+		}
+		port = p
 	}
+	return port, strings.TrimSpace(address), nil
+}
 
+// registerWithConsul registers the service instance with Consul and returns
+// the registration ID used to deregister it later.
+func registerWithConsul(consul *consulutil.Client) (string, error) {
 	hostname, err := os.Hostname()
 	if err != nil {
-		return fmt.Errorf("hostname lookup failed: %w", err)
+		return "", fmt.Errorf("hostname lookup failed: %w", err)
+	}
+
+	cfg, err := serviceConfig(hostname, os.Getenv("HEALTH_CHECKS"))
+	if err != nil {
+		return "", err
 	}
+	return consul.Register(cfg)
+}
+
+// healthAssertPath serves /health filtered through the HEALTH_EXPECT_BODY
+// assertion; Consul only inspects status codes, so this is what it polls.
+const healthAssertPath = "/health/assert"
 
-	reg := &consulapi.AgentServiceRegistration{
-		ID:      serviceName + "-" + hostname,
-		Name:    serviceName,
-		Port:    servicePort,
-		Address: hostname,
-		Check: &consulapi.AgentServiceCheck{
-			HTTP:     fmt.Sprintf("http://%s:%d/health", hostname, servicePort),
-			Interval: "10s",
-			Timeout:  "1s",
-		},
+// serviceConfig describes this instance to Consul. checkKinds is a
+// comma-separated list of "http", "grpc" and "ttl"; the service is only
+// healthy while every listed check passes. An empty list means "http".
+// HEALTH_CHECK_PATH, HEALTH_CHECK_INTERVAL and HEALTH_CHECK_TIMEOUT tune the
+// probes.
+func serviceConfig(hostname, checkKinds string) (consulutil.Config, error) {
+	checks, err := consulutil.ChecksFromEnv()
+	if err != nil {
+		return consulutil.Config{}, err
+	}
+	if checks.Path == "" && os.Getenv("HEALTH_EXPECT_BODY") != "" {
+		checks.Path = healthAssertPath
 	}
 
-	if err := client.Agent().ServiceRegister(reg); err != nil {
-		return fmt.Errorf("registration failed: %w", err)
+	address := hostname
+	if serviceAddress != "" {
+		address = serviceAddress
+	}
+	var kinds []string
+	if strings.TrimSpace(checkKinds) != "" {
+		kinds = strings.Split(checkKinds, ",")
 	}
 
-	log.Printf("Registered %s on %s:%d", serviceName, hostname, servicePort)
-	return nil
-}log.Printf("Successfully registered '%s' with Consul", serviceName)
-	return nil
-}
\ No newline at end of file
+	return consulutil.Config{
+		ID:       instanceID(hostname),
+		Name:     serviceName,
+		Address:  address,
+		Port:     servicePort,
+		Checks:   kinds,
+		GRPCAddr: os.Getenv("GRPC_HEALTH_ADDR"),
+		Check:    checks,
+	}, nil
+}
+
+// instanceID is the Consul ID for this instance. SERVICE_INSTANCE_ID pins it
+// across hostname changes; otherwise it is derived from the hostname so that
+// re-registering the same instance always reuses the same ID.
+func instanceID(hostname string) string {
+	if id := strings.TrimSpace(os.Getenv("SERVICE_INSTANCE_ID")); id != "" {
+		return id
+	}
+	return serviceName + "-" + hostname
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"consulutil"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildRegistration is the Consul registration serviceConfig produces.
+func buildRegistration(hostname, checkKinds string) (*consulapi.AgentServiceRegistration, error) {
+	cfg, err := serviceConfig(hostname, checkKinds)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Registration()
+}
+
+func TestBuildRegistrationWithMultipleChecks(t *testing.T) {
+	t.Setenv("GRPC_HEALTH_ADDR", "users-host:9091")
+
+	reg, err := buildRegistration("users-host", "http,grpc,ttl")
+	require.NoError(t, err)
+
+	assert.Equal(t, "users-service-users-host", reg.ID)
+	assert.Nil(t, reg.Check)
+	require.Len(t, reg.Checks, 3)
+	assert.Equal(t, "http://users-host:8081/health", reg.Checks[0].HTTP)
+	assert.Equal(t, "users-host:9091", reg.Checks[1].GRPC)
+	assert.Equal(t, "15s", reg.Checks[2].TTL)
+	for _, check := range reg.Checks {
+		assert.Equal(t, "1m", check.DeregisterCriticalServiceAfter)
+	}
+}
+
+func TestBuildRegistrationDefaultsToHTTP(t *testing.T) {
+	reg, err := buildRegistration("users-host", "")
+	require.NoError(t, err)
+	require.Len(t, reg.Checks, 1)
+	assert.NotEmpty(t, reg.Checks[0].HTTP)
+
+	_, err = buildRegistration("users-host", "http,carrier-pigeon")
+	assert.Error(t, err)
+}
+
+func TestLoadServiceConfig(t *testing.T) {
+	port, address, err := loadServiceConfig("", "")
+	require.NoError(t, err)
+	assert.Equal(t, defaultServicePort, port)
+	assert.Empty(t, address)
+
+	port, address, err = loadServiceConfig("9090", " users.internal ")
+	require.NoError(t, err)
+	assert.Equal(t, 9090, port)
+	assert.Equal(t, "users.internal", address)
+
+	for _, bad := range []string{"http", "0", "70000", "80a"} {
+		_, _, err := loadServiceConfig(bad, "")
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestBuildRegistrationAdvertisesConfiguredAddress(t *testing.T) {
+	origPort, origAddr := servicePort, serviceAddress
+	servicePort, serviceAddress = 9090, "10.1.2.3"
+	defer func() { servicePort, serviceAddress = origPort, origAddr }()
+
+	reg, err := buildRegistration("container-id", "")
+	require.NoError(t, err)
+
+	assert.Equal(t, "users-service-container-id", reg.ID)
+	assert.Equal(t, "10.1.2.3", reg.Address)
+	assert.Equal(t, 9090, reg.Port)
+	assert.Equal(t, "http://10.1.2.3:9090/health", reg.Checks[0].HTTP)
+}
+
+func TestServiceConfigKeepsInstanceIDAcrossHostnames(t *testing.T) {
+	t.Setenv("SERVICE_INSTANCE_ID", "users-service-stable")
+
+	cfg, err := serviceConfig("users-host", "")
+	require.NoError(t, err)
+	assert.Equal(t, "users-service-stable", cfg.ID)
+	assert.Equal(t, "users-host", cfg.Address)
+}
+
+func TestServiceConfigReadsCheckTuning(t *testing.T) {
+	t.Setenv("HEALTH_CHECK_INTERVAL", "3s")
+	t.Setenv("HEALTH_CHECK_TIMEOUT", "250ms")
+	t.Setenv("HEALTH_CHECK_PATH", "/ready")
+
+	reg, err := buildRegistration("users-host", "http")
+	require.NoError(t, err)
+	assert.Equal(t, "http://users-host:8081/ready", reg.Checks[0].HTTP)
+	assert.Equal(t, "3s", reg.Checks[0].Interval)
+	assert.Equal(t, "250ms", reg.Checks[0].Timeout)
+
+	t.Setenv("HEALTH_CHECK_INTERVAL", "often")
+	_, err = serviceConfig("users-host", "http")
+	assert.Error(t, err)
+}
+
+func TestAssertHealthBodyExcludesDegradedInstance(t *testing.T) {
+	degraded := func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "DEGRADED: database unreachable")
+	}
+	check := consulutil.AssertHealthBody(degraded, "OK")
+
+	rec := httptest.NewRecorder()
+	check(rec, httptest.NewRequest(http.MethodGet, healthAssertPath, nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	rec = httptest.NewRecorder()
+	consulutil.AssertHealthBody(handleHealthCheck, "OK")(rec, httptest.NewRequest(http.MethodGet, healthAssertPath, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestBuildRegistrationUsesHealthAssertion(t *testing.T) {
+	t.Setenv("HEALTH_EXPECT_BODY", "OK")
+
+	reg, err := buildRegistration("users-host", "http")
+	require.NoError(t, err)
+	assert.Equal(t, "http://users-host:8081/health/assert", reg.Checks[0].HTTP)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"sync"
+)
+
+// User is the record served by GET /users/{id}.
+type User struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// errUserNotFound is returned by a userStore for an unknown ID.
+var errUserNotFound = errors.New("user not found")
+
+// userStore looks users up by ID. The in-memory store stands in until a
+// database-backed implementation exists.
+type userStore interface {
+	Get(id string) (User, error)
+}
+
+// memoryUserStore is a userStore held in a map.
+type memoryUserStore struct {
+	mu    sync.RWMutex
+	users map[string]User
+}
+
+func newMemoryUserStore(seed ...User) *memoryUserStore {
+	s := &memoryUserStore{users: make(map[string]User, len(seed))}
+	for _, u := range seed {
+		s.users[u.ID] = u
+	}
+	return s
+}
+
+func (s *memoryUserStore) Get(id string) (User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	u, ok := s.users[id]
+	if !ok {
+		return User{}, errUserNotFound
+	}
+	return u, nil
+}
+
+// seedUsers gives integration tests something to fetch.
+var seedUsers = []User{
+	{ID: "1", Name: "Pema Choden", Email: "pema@example.com"},
+	{ID: "2", Name: "Karma Dorji", Email: "karma@example.com"},
+	{ID: "3", Name: "Sonam Wangmo", Email: "sonam@example.com"},
+}
+
+// users backs handleGetUser.
+var users userStore = newMemoryUserStore(seedUsers...)
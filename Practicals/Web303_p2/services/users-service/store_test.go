@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getUser(t *testing.T, id string) *httptest.ResponseRecorder {
+	router := chi.NewRouter()
+	router.Get("/users/{id}", handleGetUser)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/"+id, nil))
+	return rec
+}
+
+func TestHandleGetUserFound(t *testing.T) {
+	rec := getUser(t, "2")
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var user User
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&user))
+	assert.Equal(t, User{ID: "2", Name: "Karma Dorji", Email: "karma@example.com"}, user)
+}
+
+func TestHandleGetUserNotFound(t *testing.T) {
+	rec := getUser(t, "999")
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// corsConfig controls which browser origins may call the catalog.
+type corsConfig struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	AllowCredentials bool
+	MaxAge           int
+}
+
+// defaultCORS is a permissive development setup: any origin may read the
+// catalog, without credentials.
+var defaultCORS = corsConfig{
+	AllowedOrigins: []string{"*"},
+	AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodOptions},
+	AllowedHeaders: []string{"Accept", "Content-Type", "Authorization"},
+	MaxAge:         600,
+}
+
+// loadCORSConfig starts from defaultCORS and applies any CORS_ALLOWED_ORIGINS,
+// CORS_ALLOWED_METHODS, CORS_ALLOWED_HEADERS (comma-separated),
+// CORS_ALLOW_CREDENTIALS and CORS_MAX_AGE overrides. Credentials cannot be
+// combined with the "*" origin, which would let any site read responses
+// with the user's cookies.
+func loadCORSConfig() (corsConfig, error) {
+	cfg := defaultCORS
+	if v, ok := os.LookupEnv("CORS_ALLOWED_ORIGINS"); ok {
+		cfg.AllowedOrigins = splitList(v)
+	}
+	if v, ok := os.LookupEnv("CORS_ALLOWED_METHODS"); ok {
+		cfg.AllowedMethods = splitList(strings.ToUpper(v))
+	}
+	if v, ok := os.LookupEnv("CORS_ALLOWED_HEADERS"); ok {
+		cfg.AllowedHeaders = splitList(v)
+	}
+	if v, err := strconv.ParseBool(os.Getenv("CORS_ALLOW_CREDENTIALS")); err == nil {
+		cfg.AllowCredentials = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("CORS_MAX_AGE")); err == nil && v >= 0 {
+		cfg.MaxAge = v
+	}
+	if cfg.AllowCredentials {
+		for _, origin := range cfg.AllowedOrigins {
+			if origin == "*" {
+				return corsConfig{}, errors.New("CORS_ALLOW_CREDENTIALS needs explicit CORS_ALLOWED_ORIGINS, not *")
+			}
+		}
+	}
+	return cfg, nil
+}
+
+func splitList(raw string) []string {
+	var out []string
+	for _, part := range strings.Split(raw, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin value for origin, or
+// "" when it is not allowed.
+func (c corsConfig) allowOrigin(origin string) string {
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" {
+			return "*"
+		}
+		if strings.EqualFold(allowed, origin) {
+			return origin
+		}
+	}
+	return ""
+}
+
+// Handler adds CORS headers to responses for allowed origins and answers
+// preflight OPTIONS requests itself. Requests from other origins are served
+// without CORS headers, so browsers will refuse to expose the response.
+func (c corsConfig) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		allowed := c.allowOrigin(origin)
+		if allowed != "" {
+			w.Header().Set("Access-Control-Allow-Origin", allowed)
+			// Browsers refuse credentials with the wildcard anyway
+			if c.AllowCredentials && allowed != "*" {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+		}
+		if !preflight {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+		w.Header().Add("Vary", "Access-Control-Request-Headers")
+		if allowed != "" {
+			w.Header().Set("Access-Control-Allow-Methods", strings.Join(c.AllowedMethods, ", "))
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(c.AllowedHeaders, ", "))
+			if c.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(c.MaxAge))
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
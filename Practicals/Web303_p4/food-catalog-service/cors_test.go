@@ -0,0 +1,85 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var lockedCORS = corsConfig{
+	AllowedOrigins:   []string{"https://cafe.example"},
+	AllowedMethods:   []string{http.MethodGet},
+	AllowedHeaders:   []string{"Content-Type"},
+	AllowCredentials: true,
+	MaxAge:           60,
+}
+
+func corsRequest(cfg corsConfig, method, origin string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/items", nil)
+	req.Header.Set("Origin", origin)
+	if method == http.MethodOptions {
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	}
+	rec := httptest.NewRecorder()
+	newRouter(cfg).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCORSPreflight(t *testing.T) {
+	rec := corsRequest(lockedCORS, http.MethodOptions, "https://cafe.example")
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "https://cafe.example", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET", rec.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Content-Type", rec.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "true", rec.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "60", rec.Header().Get("Access-Control-Max-Age"))
+
+	rec = corsRequest(lockedCORS, http.MethodOptions, "https://evil.example")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Methods"))
+}
+
+func TestCORSSimpleGet(t *testing.T) {
+	rec := corsRequest(lockedCORS, http.MethodGet, "https://cafe.example")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "https://cafe.example", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, rec.Header().Values("Vary"), "Origin")
+
+	rec = corsRequest(lockedCORS, http.MethodGet, "https://evil.example")
+	assert.Equal(t, http.StatusOK, rec.Code, "the response is served; the browser withholds it")
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+
+	rec = corsRequest(defaultCORS, http.MethodGet, "https://anywhere.example")
+	assert.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestLoadCORSConfigFromEnv(t *testing.T) {
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://cafe.example, https://admin.example")
+	t.Setenv("CORS_ALLOW_CREDENTIALS", "true")
+
+	cfg, err := loadCORSConfig()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://cafe.example", "https://admin.example"}, cfg.AllowedOrigins)
+	assert.True(t, cfg.AllowCredentials)
+	assert.Equal(t, defaultCORS.AllowedMethods, cfg.AllowedMethods)
+}
+
+func TestLoadCORSConfigRejectsCredentialedWildcard(t *testing.T) {
+	t.Setenv("CORS_ALLOW_CREDENTIALS", "true")
+	_, err := loadCORSConfig()
+	assert.ErrorContains(t, err, "CORS_ALLOW_CREDENTIALS", "the default * origin cannot carry credentials")
+
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://cafe.example, *")
+	_, err = loadCORSConfig()
+	assert.Error(t, err)
+
+	// A config built in code still never pairs * with credentials
+	rec := corsRequest(corsConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}, http.MethodGet, "https://evil.example")
+	assert.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Credentials"))
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withCatalog restores the seeded catalog after a test that adds items.
+func withCatalog(t *testing.T) {
+	orig := slices.Clone(foodItems)
+	t.Cleanup(func() {
+		foodItemsMu.Lock()
+		foodItems = orig
+		foodItemsMu.Unlock()
+	})
+}
+
+func postItem(body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	newRouter(defaultCORS).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(body)))
+	return rec
+}
+
+func TestCreateItem(t *testing.T) {
+	withCatalog(t)
+
+	rec := postItem(`{"name": " Flat White ", "price": 3.25}`)
+
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var created FoodItem
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&created))
+	assert.Equal(t, FoodItem{ID: "6", Name: "Flat White", Price: 3.25}, created)
+	assert.Equal(t, "/items/6", rec.Header().Get("Location"))
+
+	found, ok := findItem("6")
+	require.True(t, ok)
+	assert.Equal(t, created, found)
+
+	assert.Equal(t, http.StatusCreated, postItem(`{"id": "latte", "name": "Latte", "price": 3.5}`).Code)
+	assert.Equal(t, http.StatusConflict, postItem(`{"id": "latte", "name": "Latte", "price": 3.5}`).Code)
+}
+
+func TestCreateItemValidation(t *testing.T) {
+	withCatalog(t)
+
+	for _, body := range []string{
+		`{"name": "", "price": 2}`,
+		`{"name": "   ", "price": 2}`,
+		`{"name": "Water", "price": 0}`,
+		`{"name": "Water", "price": -1}`,
+		`{"name": "Water", "price": "free"}`,
+		`not json`,
+	} {
+		rec := postItem(body)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+		assert.Contains(t, rec.Body.String(), `"error"`, body)
+	}
+	assert.Len(t, snapshotItems(), 5)
+}
+
+func TestCreateItemConcurrentAppends(t *testing.T) {
+	withCatalog(t)
+
+	const n = 50
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			postItem(fmt.Sprintf(`{"name": "Special %d", "price": 4}`, i))
+			getItems("")
+		}(i)
+	}
+	wg.Wait()
+
+	items := snapshotItems()
+	require.Len(t, items, 5+n)
+	ids := make(map[string]bool, len(items))
+	for _, item := range items {
+		ids[item.ID] = true
+	}
+	assert.Len(t, ids, len(items), "every item gets a unique ID")
+}
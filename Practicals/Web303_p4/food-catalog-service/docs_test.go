@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openAPIDoc is the part of an OpenAPI 3 document the tests check.
+type openAPIDoc struct {
+	OpenAPI string `json:"openapi"`
+	Info    struct {
+		Title   string `json:"title"`
+		Version string `json:"version"`
+	} `json:"info"`
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components map[string]map[string]json.RawMessage `json:"components"`
+}
+
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// parseOpenAPI decodes raw and checks what makes it a valid OpenAPI 3
+// document: the version and info fields, responses on every operation and
+// every $ref resolving within the document.
+func parseOpenAPI(t *testing.T, raw []byte) openAPIDoc {
+	var doc openAPIDoc
+	require.NoError(t, json.Unmarshal(raw, &doc))
+	assert.True(t, strings.HasPrefix(doc.OpenAPI, "3."), "openapi version %q", doc.OpenAPI)
+	assert.NotEmpty(t, doc.Info.Title)
+	assert.NotEmpty(t, doc.Info.Version)
+
+	for path, item := range doc.Paths {
+		assert.True(t, strings.HasPrefix(path, "/"), "path %q", path)
+		for _, method := range openAPIMethods {
+			if op, ok := item[method]; ok {
+				var operation struct {
+					Responses map[string]json.RawMessage `json:"responses"`
+				}
+				require.NoError(t, json.Unmarshal(op, &operation))
+				assert.NotEmpty(t, operation.Responses, "%s %s has no responses", method, path)
+			}
+		}
+	}
+
+	var generic interface{}
+	require.NoError(t, json.Unmarshal(raw, &generic))
+	walkRefs(generic, func(ref string) {
+		parts := strings.Split(strings.TrimPrefix(ref, "#/components/"), "/")
+		require.Len(t, parts, 2, "unsupported $ref %q", ref)
+		assert.Contains(t, doc.Components[parts[0]], parts[1], "$ref %q does not resolve", ref)
+	})
+	return doc
+}
+
+func walkRefs(v interface{}, visit func(string)) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			if ref, ok := child.(string); ok && k == "$ref" {
+				visit(ref)
+			}
+			walkRefs(child, visit)
+		}
+	case []interface{}:
+		for _, child := range v {
+			walkRefs(child, visit)
+		}
+	}
+}
+
+func TestOpenAPISpecDescribesTheRoutes(t *testing.T) {
+	router := newRouter(defaultCORS)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	doc := parseOpenAPI(t, rec.Body.Bytes())
+	for _, path := range []string{"/health", "/items", "/items/{id}"} {
+		assert.Contains(t, doc.Paths, path)
+	}
+
+	// Every routed endpoint apart from the docs themselves is described
+	require.NoError(t, chi.Walk(router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if route == "/openapi.json" || route == "/docs" {
+			return nil
+		}
+		assert.Contains(t, doc.Paths[route], strings.ToLower(method), "%s %s is missing from openapi.json", method, route)
+		return nil
+	}))
+}
+
+func TestDocsServesSwaggerUI(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newRouter(defaultCORS).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "swagger-ui-bundle.js")
+	assert.Contains(t, rec.Body.String(), "openapi.json")
+}
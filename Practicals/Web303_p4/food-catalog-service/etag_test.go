@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func conditionalGet(path, ifNoneMatch string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	rec := httptest.NewRecorder()
+	newRouter(defaultCORS).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestItemConditionalGet(t *testing.T) {
+	rec := conditionalGet("/items/2", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	etag := rec.Header().Get("ETag")
+	assert.Regexp(t, `^W/"[0-9a-f]{32}"$`, etag)
+
+	rec = conditionalGet("/items/2", etag)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Equal(t, etag, rec.Header().Get("ETag"))
+	assert.Zero(t, rec.Body.Len())
+
+	assert.Equal(t, http.StatusOK, conditionalGet("/items/3", etag).Code, "other items have their own ETag")
+}
+
+func TestItemsConditionalGetSeesNewItems(t *testing.T) {
+	withCatalog(t)
+
+	etag := conditionalGet("/items", "").Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	assert.Equal(t, http.StatusNotModified, conditionalGet("/items", etag).Code)
+
+	require.Equal(t, http.StatusCreated, postItem(`{"name": "Chai", "price": 2}`).Code)
+	rec := conditionalGet("/items", etag)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+}
+
+func TestETagMatches(t *testing.T) {
+	assert.True(t, etagMatches(`"a", W/"b"`, `W/"b"`))
+	assert.True(t, etagMatches(`*`, `W/"b"`))
+	assert.False(t, etagMatches(`W/"c"`, `W/"b"`))
+}
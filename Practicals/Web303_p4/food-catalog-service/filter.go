@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// itemQuery holds the ?name=, ?min_price=, ?max_price=, ?sort= and ?order=
+// options of the /items listing.
+type itemQuery struct {
+	Name     string
+	MinPrice float64
+	MaxPrice float64
+	Sort     string
+	Desc     bool
+}
+
+// parseItemQuery validates the filter and sort parameters. Absent bounds
+// leave the price range open.
+func parseItemQuery(r *http.Request) (itemQuery, error) {
+	q := r.URL.Query()
+	query := itemQuery{
+		Name:     strings.ToLower(strings.TrimSpace(q.Get("name"))),
+		MinPrice: math.Inf(-1),
+		MaxPrice: math.Inf(1),
+	}
+
+	var err error
+	if raw := q.Get("min_price"); raw != "" {
+		if query.MinPrice, err = parsePrice(raw); err != nil {
+			return itemQuery{}, fmt.Errorf("min_price %w", err)
+		}
+	}
+	if raw := q.Get("max_price"); raw != "" {
+		if query.MaxPrice, err = parsePrice(raw); err != nil {
+			return itemQuery{}, fmt.Errorf("max_price %w", err)
+		}
+	}
+	if query.MinPrice > query.MaxPrice {
+		return itemQuery{}, fmt.Errorf("min_price must not exceed max_price")
+	}
+
+	switch query.Sort = q.Get("sort"); query.Sort {
+	case "", "price", "name":
+	default:
+		return itemQuery{}, fmt.Errorf("sort must be price or name")
+	}
+	switch q.Get("order") {
+	case "", "asc":
+	case "desc":
+		query.Desc = true
+	default:
+		return itemQuery{}, fmt.Errorf("order must be asc or desc")
+	}
+	return query, nil
+}
+
+func parsePrice(raw string) (float64, error) {
+	price, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(price) || math.IsInf(price, 0) || price < 0 {
+		return 0, fmt.Errorf("must be a non-negative number")
+	}
+	return price, nil
+}
+
+// apply returns the matching items in the requested order. The input slice
+// is never modified.
+func (q itemQuery) apply(items []FoodItem) []FoodItem {
+	out := make([]FoodItem, 0, len(items))
+	for _, item := range items {
+		if q.Name != "" && !strings.Contains(strings.ToLower(item.Name), q.Name) {
+			continue
+		}
+		if item.Price < q.MinPrice || item.Price > q.MaxPrice {
+			continue
+		}
+		out = append(out, item)
+	}
+
+	var cmp func(a, b FoodItem) int
+	switch q.Sort {
+	case "price":
+		cmp = func(a, b FoodItem) int {
+			if a.Price < b.Price {
+				return -1
+			}
+			if a.Price > b.Price {
+				return 1
+			}
+			return 0
+		}
+	case "name":
+		cmp = func(a, b FoodItem) int { return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)) }
+	default:
+		return out
+	}
+	slices.SortStableFunc(out, func(a, b FoodItem) int {
+		if q.Desc {
+			return cmp(b, a)
+		}
+		return cmp(a, b)
+	})
+	return out
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func itemIDs(t *testing.T, query string) []string {
+	rec := getItemsPage(query)
+	require.Equal(t, http.StatusOK, rec.Code, query)
+	var items []FoodItem
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&items))
+	ids := make([]string, len(items))
+	for i, item := range items {
+		ids[i] = item.ID
+	}
+	return ids
+}
+
+func TestItemsFilterAndSort(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"no filters", "", []string{"1", "2", "3", "4", "5"}},
+		{"name substring is case-insensitive", "name=TEA", []string{"4"}},
+		{"name matches several", "name=e", []string{"1", "2", "3", "4", "5"}},
+		{"min price", "min_price=5.5", []string{"2", "5"}},
+		{"max price", "max_price=2.75", []string{"1", "4"}},
+		{"price range", "min_price=3&max_price=6", []string{"2", "3", "5"}},
+		{"sort by price", "sort=price", []string{"4", "1", "3", "2", "5"}},
+		{"sort by price desc", "sort=price&order=desc", []string{"5", "2", "3", "1", "4"}},
+		{"sort by name", "sort=name", []string{"3", "5", "1", "4", "2"}},
+		{"combined", "name=a&max_price=5.5&sort=price&order=desc", []string{"2", "4"}},
+		{"filtered then paged", "sort=price&limit=2&offset=1", []string{"1", "3"}},
+		{"no matches", "name=pizza", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, itemIDs(t, tt.query))
+		})
+	}
+}
+
+func TestItemsRejectBadParametersWithJSONErrors(t *testing.T) {
+	for _, query := range []string{
+		"min_price=cheap",
+		"max_price=-1",
+		"max_price=NaN",
+		"min_price=5&max_price=2",
+		"sort=calories",
+		"order=sideways",
+		"limit=0",
+	} {
+		rec := getItemsPage(query)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"), query)
+		var body errorResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&body), query)
+		assert.NotEmpty(t, body.Error, query)
+	}
+}
+
+func TestItemsFilterTotalCountReflectsMatches(t *testing.T) {
+	rec := getItemsPage("max_price=3&limit=1")
+	assert.Equal(t, "2", rec.Header().Get("X-Total-Count"))
+}
@@ -1,12 +1,21 @@
 package main
 
 import (
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"log"
+	"math"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+
+	"recovery"
 )
 
 type FoodItem struct {
@@ -15,27 +24,205 @@ type FoodItem struct {
 	Price float64 `json:"price"`
 }
 
-var foodItems = []FoodItem{
-	{ID: "1", Name: "Espresso", Price: 2.75},
-	{ID: "2", Name: "Turkey Sandwich", Price: 5.50},
-	{ID: "3", Name: "Blueberry Muffin", Price: 3.50},
-	{ID: "4", Name: "Iced Tea", Price: 2.25},
-	{ID: "5", Name: "Caesar Salad", Price: 6.00},
-}
+// foodItems is the catalog; foodItemsMu guards it now that POST /items
+// appends at runtime.
+var (
+	foodItemsMu sync.RWMutex
+	foodItems   = []FoodItem{
+		{ID: "1", Name: "Espresso", Price: 2.75},
+		{ID: "2", Name: "Turkey Sandwich", Price: 5.50},
+		{ID: "3", Name: "Blueberry Muffin", Price: 3.50},
+		{ID: "4", Name: "Iced Tea", Price: 2.25},
+		{ID: "5", Name: "Caesar Salad", Price: 6.00},
+	}
+)
 
 func main() {
+	cors, err := loadCORSConfig()
+	if err != nil {
+		log.Fatalf("Invalid CORS configuration: %v", err)
+	}
+	r := newRouter(cors)
+	log.Println("Food Catalog Service starting on port 8080...")
+	http.ListenAndServe(":8080", r)
+}
+
+// newRouter wires the catalog routes behind the CORS middleware.
+func newRouter(cors corsConfig) chi.Router {
 	r := chi.NewRouter()
 	r.Use(middleware.Logger)
+	r.Use(recovery.Middleware)
+	r.Use(cors.Handler)
 
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
 
-	r.Get("/items", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(foodItems)
-	})
+	r.Get("/items", handleItems)
+	r.Post("/items", handleCreateItem)
+	r.Get("/items/{id}", handleItem)
 
-	log.Println("Food Catalog Service starting on port 8080...")
-	http.ListenAndServe(":8080", r)
-}
\ No newline at end of file
+	r.Get("/openapi.json", handleOpenAPI)
+	r.Get("/docs", handleDocs)
+	return r
+}
+
+// itemTypes are the representations /items can be served in, preferred first.
+var itemTypes = []string{"application/json", "text/csv"}
+
+// handleItems lists the catalog as JSON or CSV depending on the Accept header.
+func handleItems(w http.ResponseWriter, r *http.Request) {
+	contentType, ok := negotiate(r, itemTypes...)
+	if !ok {
+		writeNotAcceptable(w, itemTypes)
+		return
+	}
+
+	query, err := parseItemQuery(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	items := query.apply(snapshotItems())
+
+	page, paged, err := parsePage(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if paged {
+		setPageHeaders(w, r, page, len(items))
+		start, end := page.bounds(len(items))
+		items = items[start:end]
+	}
+
+	w.Header().Add("Vary", "Accept")
+	w.Header().Set("Content-Type", contentType)
+	if contentType == "text/csv" {
+		// Attachment makes browsers save the export instead of showing it
+		w.Header().Set("Content-Disposition", `attachment; filename="items.csv"`)
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"id", "name", "price"})
+		for _, item := range items {
+			cw.Write([]string{csvCell(item.ID), csvCell(item.Name), strconv.FormatFloat(item.Price, 'f', 2, 64)})
+		}
+		cw.Flush()
+		return
+	}
+	writeCachedJSON(w, r, items)
+}
+
+// csvCell prefixes text a spreadsheet would evaluate as a formula with a
+// quote, so an exported catalog cannot run in the reader's spreadsheet.
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// snapshotItems copies the catalog so it can be read without holding the lock.
+func snapshotItems() []FoodItem {
+	foodItemsMu.RLock()
+	defer foodItemsMu.RUnlock()
+	return slices.Clone(foodItems)
+}
+
+// findItem looks up a catalog entry by its ID.
+func findItem(id string) (FoodItem, bool) {
+	foodItemsMu.RLock()
+	defer foodItemsMu.RUnlock()
+	for _, item := range foodItems {
+		if item.ID == id {
+			return item, true
+		}
+	}
+	return FoodItem{}, false
+}
+
+// errorResponse is the JSON body of catalog errors.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+var errDuplicateID = errors.New("an item with this id already exists")
+
+// addItem appends item to the catalog, assigning the next free numeric ID
+// when it has none.
+func addItem(item FoodItem) (FoodItem, error) {
+	foodItemsMu.Lock()
+	defer foodItemsMu.Unlock()
+
+	taken := make(map[string]bool, len(foodItems))
+	next := 1
+	for _, existing := range foodItems {
+		taken[existing.ID] = true
+		if n, err := strconv.Atoi(existing.ID); err == nil && n >= next {
+			next = n + 1
+		}
+	}
+	if item.ID == "" {
+		for taken[strconv.Itoa(next)] {
+			next++
+		}
+		item.ID = strconv.Itoa(next)
+	} else if taken[item.ID] {
+		return FoodItem{}, errDuplicateID
+	}
+	foodItems = append(foodItems, item)
+	return item, nil
+}
+
+// validateItem rejects items that could not be sold.
+func validateItem(item FoodItem) error {
+	if strings.TrimSpace(item.Name) == "" {
+		return errors.New("name is required")
+	}
+	if !(item.Price > 0) || math.IsInf(item.Price, 0) {
+		return errors.New("price must be positive")
+	}
+	return nil
+}
+
+// writeError sends a JSON error body with the given status.
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(errorResponse{Error: message})
+}
+
+// handleCreateItem adds an item to the in-memory catalog and returns it
+// with 201.
+func handleCreateItem(w http.ResponseWriter, r *http.Request) {
+	var item FoodItem
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid item: "+err.Error())
+		return
+	}
+	item.Name = strings.TrimSpace(item.Name)
+	if err := validateItem(item); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	created, err := addItem(item)
+	if err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/items/"+created.ID)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// handleItem returns a single catalog entry, or a JSON 404. Like the JSON
+// listing it carries an ETag for conditional GETs.
+func handleItem(w http.ResponseWriter, r *http.Request) {
+	item, ok := findItem(chi.URLParam(r, "id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "item not found")
+		return
+	}
+	writeCachedJSON(w, r, item)
+}
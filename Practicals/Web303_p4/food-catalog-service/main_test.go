@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getItems(accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/items", nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	rec := httptest.NewRecorder()
+	handleItems(rec, req)
+	return rec
+}
+
+func TestItemsNegotiatesRepresentation(t *testing.T) {
+	for _, accept := range []string{"", "*/*", "application/json", "application/*", "text/csv;q=0.5, application/json"} {
+		rec := getItems(accept)
+		require.Equal(t, http.StatusOK, rec.Code, accept)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"), accept)
+	}
+
+	rec := getItems("text/csv")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/csv", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="items.csv"`, rec.Header().Get("Content-Disposition"))
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, len(foodItems)+1)
+	assert.Equal(t, []string{"id", "name", "price"}, rows[0])
+	assert.Equal(t, []string{"1", "Espresso", "2.75"}, rows[1])
+
+	rec = getItems("text/*, application/json;q=0.1")
+	assert.Equal(t, "text/csv", rec.Header().Get("Content-Type"))
+}
+
+func TestItemsCSVMatchesJSON(t *testing.T) {
+	withCatalog(t)
+	_, err := addItem(FoodItem{Name: "=1+1", Price: 1})
+	require.NoError(t, err)
+
+	var items []FoodItem
+	require.NoError(t, json.NewDecoder(getItems("application/json").Body).Decode(&items))
+	rows, err := csv.NewReader(getItems("text/csv").Body).ReadAll()
+	require.NoError(t, err)
+
+	require.Len(t, rows, len(items)+1)
+	for i, item := range items[:len(items)-1] {
+		assert.Equal(t, []string{item.ID, item.Name, strconv.FormatFloat(item.Price, 'f', 2, 64)}, rows[i+1])
+	}
+	assert.Equal(t, "'=1+1", rows[len(items)][1], "formulas are neutralised")
+}
+
+func TestItemsRejectsUnsupportedAccept(t *testing.T) {
+	for _, accept := range []string{"application/xml", "text/html, image/*", "application/json;q=0, text/csv;q=0"} {
+		rec := getItems(accept)
+
+		require.Equal(t, http.StatusNotAcceptable, rec.Code, accept)
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		var body notAcceptableResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+		assert.Equal(t, "not acceptable", body.Error)
+		assert.Equal(t, []string{"application/json", "text/csv"}, body.Supported)
+	}
+}
+
+func getItemsPage(query string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handleItems(rec, httptest.NewRequest(http.MethodGet, "/items?"+query, nil))
+	return rec
+}
+
+func TestItemsLinkHeaderOnMiddlePage(t *testing.T) {
+	rec := getItemsPage("offset=2&limit=2")
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "5", rec.Header().Get("X-Total-Count"))
+	assert.Equal(t, `</items?limit=2&offset=4>; rel="next", </items?limit=2&offset=0>; rel="prev"`, rec.Header().Get("Link"))
+	var items []FoodItem
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&items))
+	require.Len(t, items, 2)
+	assert.Equal(t, "3", items[0].ID)
+}
+
+func TestItemsLinkHeaderAtEdges(t *testing.T) {
+	first := getItemsPage("limit=2")
+	assert.Equal(t, `</items?limit=2&offset=2>; rel="next"`, first.Header().Get("Link"), "no prev on the first page")
+
+	last := getItemsPage("offset=4&limit=2")
+	assert.Equal(t, `</items?limit=2&offset=2>; rel="prev"`, last.Header().Get("Link"), "no next on the last page")
+
+	all := getItemsPage("limit=10")
+	assert.Empty(t, all.Header().Get("Link"))
+
+	unpaged := getItems("")
+	assert.Empty(t, unpaged.Header().Get("Link"))
+	assert.Empty(t, unpaged.Header().Get("X-Total-Count"))
+
+	assert.Equal(t, http.StatusBadRequest, getItemsPage("limit=0").Code)
+	assert.Equal(t, http.StatusBadRequest, getItemsPage("offset=-1").Code)
+}
+
+func TestItemsHugeOffsetReturnsEmptyPage(t *testing.T) {
+	rec := getItemsPage("offset=9223372036854775807&limit=20")
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "5", rec.Header().Get("X-Total-Count"))
+	assert.Equal(t, `</items?limit=20&offset=9223372036854775787>; rel="prev"`, rec.Header().Get("Link"), "no next past the end")
+	var items []FoodItem
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&items))
+	assert.Empty(t, items)
+}
+
+func TestFindItem(t *testing.T) {
+	item, ok := findItem("3")
+	require.True(t, ok)
+	assert.Equal(t, "Blueberry Muffin", item.Name)
+
+	_, ok = findItem("99")
+	assert.False(t, ok)
+}
+
+func TestGetItemByID(t *testing.T) {
+	router := newRouter(defaultCORS)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items/2", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var item FoodItem
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&item))
+	assert.Equal(t, FoodItem{ID: "2", Name: "Turkey Sandwich", Price: 5.50}, item)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items/99", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"error":"item not found"}`, rec.Body.String())
+}
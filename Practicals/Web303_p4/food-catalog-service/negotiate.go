@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// negotiate returns the entry of supported the client most prefers according
+// to its Accept header, honouring q-values and type/* or */* wildcards. Ties
+// go to the earlier entry in supported. A missing Accept header accepts the
+// first supported type.
+//
+// Web303_p5/httputil and the p6 gateway's httputil carry the same algorithm.
+// Each practical is built and handed in on its own, with its Docker context
+// limited to its own directory, so the copies are kept in step by hand rather
+// than importing another practical's module.
+func negotiate(r *http.Request, supported ...string) (string, bool) {
+	accept := r.Header.Get("Accept")
+	if strings.TrimSpace(accept) == "" {
+		return supported[0], true
+	}
+
+	best, bestQ := "", 0.0
+	for _, candidate := range supported {
+		if q := acceptQuality(accept, candidate); q > bestQ {
+			best, bestQ = candidate, q
+		}
+	}
+	return best, best != ""
+}
+
+// acceptQuality is the q-value the Accept header assigns to mediaType, using
+// the most specific matching range.
+func acceptQuality(accept, mediaType string) float64 {
+	typ, _, _ := strings.Cut(mediaType, "/")
+	q, specificity := 0.0, -1
+	for _, part := range strings.Split(accept, ",") {
+		rangeType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		rank := -1
+		switch {
+		case rangeType == mediaType:
+			rank = 2
+		case rangeType == typ+"/*":
+			rank = 1
+		case rangeType == "*/*":
+			rank = 0
+		}
+		if rank <= specificity {
+			continue
+		}
+
+		rangeQ := 1.0
+		if raw, ok := params["q"]; ok {
+			if rangeQ, err = strconv.ParseFloat(raw, 64); err != nil {
+				continue
+			}
+		}
+		q, specificity = rangeQ, rank
+	}
+	return q
+}
+
+// notAcceptableResponse is the 406 body listing what the endpoint can produce.
+type notAcceptableResponse struct {
+	Error     string   `json:"error"`
+	Supported []string `json:"supported"`
+}
+
+// writeNotAcceptable rejects a request whose Accept header matched none of
+// supported. The body is always JSON so every client can read it.
+func writeNotAcceptable(w http.ResponseWriter, supported []string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotAcceptable)
+	json.NewEncoder(w).Encode(notAcceptableResponse{Error: "not acceptable", Supported: supported})
+}
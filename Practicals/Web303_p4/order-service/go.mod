@@ -5,4 +5,7 @@ go 1.23
 require (
 	github.com/go-chi/chi/v5 v5.2.3
 	github.com/hashicorp/go-uuid v1.0.3
+	recovery v0.0.0
 )
+
+replace recovery => ../recovery
@@ -0,0 +1,33 @@
+// Package recovery holds the panic middleware the p4 services share.
+package recovery
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"runtime/debug"
+)
+
+// Middleware turns a panicking handler into a JSON 500, logging the panic
+// and its stack trace with the caller's X-Request-ID. The client only sees
+// a generic message.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			// net/http uses this sentinel to abort a response on purpose
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+			log.Printf("Panic serving %s %s (request %s): %v\n%s", r.Method, r.URL.Path, r.Header.Get("X-Request-ID"), rec, debug.Stack())
+
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "internal server error"})
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
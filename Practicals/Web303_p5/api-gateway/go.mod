@@ -2,4 +2,9 @@ module api-gateway
 
 go 1.23
 
-require github.com/go-chi/chi/v5 v5.0.11
+require (
+	github.com/go-chi/chi/v5 v5.0.11
+	httputil v0.0.0
+)
+
+replace httputil => ../httputil
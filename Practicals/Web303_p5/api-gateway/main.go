@@ -9,11 +9,14 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+
+	cafehttp "httputil"
 )
 
 func main() {
 	r := chi.NewRouter()
 	r.Use(middleware.Logger)
+	r.Use(cafehttp.Recover)
 
 	// Route /api/users/* to user-service
 	r.HandleFunc("/api/users*", proxyTo("http://user-service:8081", "/users"))
@@ -0,0 +1,130 @@
+package dbutil
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// IndexAdvisorAllowed reports whether env (APP_ENV) permits running the
+// index advisor. It issues extra queries, so it never runs in production.
+func IndexAdvisorAllowed(env string) bool {
+	switch strings.ToLower(strings.TrimSpace(env)) {
+	case "development", "dev", "test":
+		return true
+	}
+	return false
+}
+
+// IndexAdvice is the outcome of explaining one slow query.
+type IndexAdvice struct {
+	SQL       string
+	Plan      []string
+	UsesIndex bool
+}
+
+// indexAdvisor wraps the query logger and runs EXPLAIN on every slow SELECT.
+type indexAdvisor struct {
+	logger.Interface
+	db        *gorm.DB
+	threshold time.Duration
+	report    func(IndexAdvice)
+}
+
+// EnableIndexAdvisor makes db explain queries slower than threshold and log
+// whether the plan used an index. Callers must gate it on IndexAdvisorAllowed.
+func EnableIndexAdvisor(db *gorm.DB, threshold time.Duration) {
+	db.Logger = &indexAdvisor{
+		Interface: db.Logger,
+		db:        db,
+		threshold: threshold,
+		report:    logAdvice,
+	}
+}
+
+func (a *indexAdvisor) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	a.Interface.Trace(ctx, begin, fc, err)
+	if err != nil || time.Since(begin) < a.threshold {
+		return
+	}
+
+	sql, _ := fc()
+	if !strings.HasPrefix(strings.ToUpper(strings.TrimSpace(sql)), "SELECT") {
+		return
+	}
+
+	plan, explainErr := a.explain(ctx, sql)
+	if explainErr != nil {
+		log.Printf("Index advisor could not explain query: %v", explainErr)
+		return
+	}
+	a.report(IndexAdvice{SQL: sql, Plan: plan, UsesIndex: planUsesIndex(plan)})
+}
+
+// explain returns the query plan one line per row. The session discards its
+// own logging so EXPLAIN statements are never themselves explained.
+func (a *indexAdvisor) explain(ctx context.Context, sql string) ([]string, error) {
+	prefix := "EXPLAIN "
+	if a.db.Dialector.Name() == "sqlite" {
+		prefix = "EXPLAIN QUERY PLAN "
+	}
+
+	rows, err := a.db.Session(&gorm.Session{Logger: logger.Discard, NewDB: true}).
+		WithContext(ctx).Raw(prefix + sql).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var plan []string
+	for rows.Next() {
+		values := make([]interface{}, len(cols))
+		ptrs := make([]interface{}, len(cols))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		// The plan text is the last column for both Postgres and SQLite
+		plan = append(plan, fmt.Sprint(asText(values[len(values)-1])))
+	}
+	return plan, rows.Err()
+}
+
+func asText(v interface{}) interface{} {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return v
+}
+
+// planUsesIndex recognises index access in Postgres ("Index Scan",
+// "Bitmap Index Scan") and SQLite ("USING INDEX", "USING INTEGER PRIMARY KEY").
+func planUsesIndex(plan []string) bool {
+	for _, line := range plan {
+		upper := strings.ToUpper(line)
+		if strings.Contains(upper, "INDEX") || strings.Contains(upper, "PRIMARY KEY") {
+			return true
+		}
+	}
+	return false
+}
+
+func logAdvice(advice IndexAdvice) {
+	verdict := "FULL SCAN"
+	if advice.UsesIndex {
+		verdict = "index used"
+	}
+	log.Printf("Index advisor [%s]: %s\n  plan: %s", verdict, advice.SQL, strings.Join(advice.Plan, " | "))
+}
@@ -0,0 +1,73 @@
+package dbutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type advisedItem struct {
+	ID     uint
+	MenuID uint `gorm:"index"`
+	Name   string
+}
+
+func setupAdvisedDB(t *testing.T) (*gorm.DB, *[]IndexAdvice) {
+	db, err := gorm.Open(sqlite.Open("file:advisor?mode=memory"), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&advisedItem{}))
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+
+	// A zero threshold treats every query as slow
+	EnableIndexAdvisor(db, 0)
+	var captured []IndexAdvice
+	db.Logger.(*indexAdvisor).report = func(a IndexAdvice) { captured = append(captured, a) }
+	return db, &captured
+}
+
+func TestIndexAdvisorCapturesExplainOutput(t *testing.T) {
+	db, captured := setupAdvisedDB(t)
+
+	var items []advisedItem
+	require.NoError(t, db.Where("menu_id = ?", 1).Find(&items).Error)
+	require.NoError(t, db.Where("name = ?", "Momo").Find(&items).Error)
+
+	require.Len(t, *captured, 2)
+	indexed, scanned := (*captured)[0], (*captured)[1]
+
+	assert.Contains(t, indexed.SQL, "menu_id")
+	assert.NotEmpty(t, indexed.Plan)
+	assert.True(t, indexed.UsesIndex, "plan: %v", indexed.Plan)
+
+	assert.Contains(t, scanned.SQL, "name")
+	assert.NotEmpty(t, scanned.Plan)
+	assert.False(t, scanned.UsesIndex, "plan: %v", scanned.Plan)
+}
+
+func TestIndexAdvisorIgnoresWritesAndFastQueries(t *testing.T) {
+	db, captured := setupAdvisedDB(t)
+	require.NoError(t, db.Create(&advisedItem{MenuID: 1, Name: "Momo"}).Error)
+	assert.Empty(t, *captured, "writes are never explained")
+
+	db.Logger.(*indexAdvisor).threshold = time.Hour
+	var items []advisedItem
+	require.NoError(t, db.Find(&items).Error)
+	assert.Empty(t, *captured)
+}
+
+func TestIndexAdvisorAllowed(t *testing.T) {
+	for _, env := range []string{"development", "dev", "TEST"} {
+		assert.True(t, IndexAdvisorAllowed(env), env)
+	}
+	for _, env := range []string{"", "production", "staging"} {
+		assert.False(t, IndexAdvisorAllowed(env), env)
+	}
+}
@@ -0,0 +1,53 @@
+package dbutil
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// flakyOpen fails its first failures calls, then opens db.
+func flakyOpen(db *gorm.DB, failures int, calls *int) func() (*gorm.DB, error) {
+	return func() (*gorm.DB, error) {
+		*calls++
+		if *calls <= failures {
+			return nil, errors.New("connection refused")
+		}
+		return db, nil
+	}
+}
+
+var testPool = PoolConfig{MaxOpenConns: 3, MaxIdleConns: 1}
+
+func TestConnectRetriesUntilTheDatabaseIsUp(t *testing.T) {
+	db := openTestDB(t)
+	calls := 0
+
+	got, err := connect(flakyOpen(db, 3, &calls), time.Second, time.Millisecond, testPool)
+	require.NoError(t, err)
+	assert.Equal(t, 4, calls)
+	assert.Same(t, db, got)
+
+	sqlDB, err := got.DB()
+	require.NoError(t, err)
+	assert.Equal(t, 3, sqlDB.Stats().MaxOpenConnections, "the pool is sized once connected")
+}
+
+func TestConnectGivesUpAfterItsBudget(t *testing.T) {
+	calls := 0
+
+	start := time.Now()
+	_, err := connect(flakyOpen(nil, 1000, &calls), 50*time.Millisecond, 5*time.Millisecond, testPool)
+	assert.ErrorContains(t, err, "connection refused")
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Greater(t, calls, 1)
+
+	calls = 0
+	_, err = connect(flakyOpen(nil, 1, &calls), 0, time.Millisecond, testPool)
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls, "no budget means a single attempt")
+}
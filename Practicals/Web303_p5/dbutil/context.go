@@ -0,0 +1,16 @@
+package dbutil
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WithTimeout returns db bound to ctx and cut off after timeout, so a
+// disconnected client or a stuck query stops holding a connection. Call
+// cancel once the queries are done.
+func WithTimeout(ctx context.Context, db *gorm.DB, timeout time.Duration) (*gorm.DB, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return db.WithContext(ctx), cancel
+}
@@ -0,0 +1,22 @@
+package dbutil
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithTimeoutBoundsTheStatementContext(t *testing.T) {
+	db := openTestDB(t)
+
+	scoped, cancel := WithTimeout(context.Background(), db, time.Minute)
+	deadline, ok := scoped.Statement.Context.Deadline()
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+
+	cancel()
+	var n int
+	assert.ErrorIs(t, scoped.Raw("SELECT 1").Scan(&n).Error, context.Canceled)
+}
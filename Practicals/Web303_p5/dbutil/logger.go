@@ -0,0 +1,31 @@
+package dbutil
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"gorm.io/gorm/logger"
+)
+
+// NewQueryLogger mirrors GORM's default logger at level, logging queries
+// slower than slow.
+func NewQueryLogger(level logger.LogLevel, slow time.Duration) logger.Interface {
+	return logger.New(log.New(os.Stdout, "\r\n", log.LstdFlags), logger.Config{
+		SlowThreshold: slow,
+		LogLevel:      level,
+		Colorful:      true,
+	})
+}
+
+// QueryLogLevelFor maps a service log level onto GORM's. Only debug logs
+// every statement; info and warn report slow queries and errors.
+func QueryLogLevelFor(level string) logger.LogLevel {
+	switch level {
+	case "debug":
+		return logger.Info
+	case "error":
+		return logger.Error
+	}
+	return logger.Warn
+}
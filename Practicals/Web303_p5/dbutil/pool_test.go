@@ -0,0 +1,58 @@
+package dbutil
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func openTestDB(t *testing.T) *gorm.DB {
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", strings.ReplaceAll(t.Name(), "/", "_"))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		sqlDB, err := db.DB()
+		require.NoError(t, err)
+		sqlDB.Close()
+	})
+	return db
+}
+
+func TestConfigurePoolAppliesSettings(t *testing.T) {
+	db := openTestDB(t)
+	require.NoError(t, ConfigurePool(db, PoolConfig{MaxOpenConns: 3, MaxIdleConns: 1, ConnMaxLifetime: time.Hour}))
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	assert.Equal(t, 3, sqlDB.Stats().MaxOpenConnections)
+
+	// Release three busy connections: only one may stay idle
+	ctx := context.Background()
+	var conns []*sql.Conn
+	for i := 0; i < 3; i++ {
+		conn, err := sqlDB.Conn(ctx)
+		require.NoError(t, err)
+		conns = append(conns, conn)
+	}
+	for _, conn := range conns {
+		require.NoError(t, conn.Close())
+	}
+	stats := sqlDB.Stats()
+	assert.Equal(t, 1, stats.Idle)
+	assert.EqualValues(t, 2, stats.MaxIdleClosed)
+
+	// Connections past their lifetime are closed when returned
+	require.NoError(t, ConfigurePool(db, PoolConfig{MaxOpenConns: 3, MaxIdleConns: 1, ConnMaxLifetime: time.Nanosecond}))
+	conn, err := sqlDB.Conn(ctx)
+	require.NoError(t, err)
+	time.Sleep(time.Millisecond)
+	require.NoError(t, conn.Close())
+	assert.NotZero(t, sqlDB.Stats().MaxLifetimeClosed)
+}
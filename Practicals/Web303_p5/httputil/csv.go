@@ -0,0 +1,45 @@
+package httputil
+
+import (
+	"encoding/csv"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+)
+
+// RespondCSV writes header and rows as a 200 CSV download saved as filename.
+// Text cells a spreadsheet would evaluate as a formula are prefixed with a
+// quote so exported data cannot run in the reader's spreadsheet.
+func RespondCSV(w http.ResponseWriter, filename string, header []string, rows [][]string) {
+	w.Header().Set("Content-Type", MediaCSV+"; charset=utf-8")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.WriteHeader(http.StatusOK)
+
+	cw := csv.NewWriter(w)
+	cw.Write(header)
+	for _, row := range rows {
+		for i, cell := range row {
+			row[i] = escapeFormula(cell)
+		}
+		cw.Write(row)
+	}
+	if cw.Flush(); cw.Error() != nil {
+		log.Printf("Failed to write CSV response: %v", cw.Error())
+	}
+}
+
+// escapeFormula neutralises cells starting with a formula trigger, leaving
+// numbers such as -1.5 alone.
+func escapeFormula(cell string) string {
+	if cell == "" {
+		return cell
+	}
+	switch cell[0] {
+	case '=', '+', '-', '@', '\t', '\r':
+		if _, err := strconv.ParseFloat(cell, 64); err != nil {
+			return "'" + cell
+		}
+	}
+	return cell
+}
@@ -0,0 +1,28 @@
+package httputil
+
+import (
+	"encoding/csv"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRespondCSVEscapesFormulas(t *testing.T) {
+	rec := httptest.NewRecorder()
+	RespondCSV(rec, "things.csv", []string{"name", "n"}, [][]string{
+		{"=HYPERLINK(\"http://evil\")", "-1.5"},
+		{"plain, with comma", "@2"},
+	})
+
+	assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename=things.csv`, rec.Header().Get("Content-Disposition"))
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"name", "n"},
+		{"'=HYPERLINK(\"http://evil\")", "-1.5"},
+		{"plain, with comma", "'@2"},
+	}, rows)
+}
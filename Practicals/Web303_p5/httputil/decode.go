@@ -0,0 +1,71 @@
+package httputil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxBodyBytes caps request bodies read by DecodeJSON unless
+// MaxBodyBytes is changed at startup.
+const DefaultMaxBodyBytes = 1 << 20
+
+// MaxBodyBytes is the largest request body DecodeJSON accepts.
+var MaxBodyBytes int64 = DefaultMaxBodyBytes
+
+// DecodeError is a request body DecodeJSON rejected, with the status the
+// client should get for it.
+type DecodeError struct {
+	Status int
+	Msg    string
+}
+
+func (e *DecodeError) Error() string { return e.Msg }
+
+// DecodeJSON decodes a single JSON value from r's body into v. Bodies over
+// MaxBodyBytes fail with 413; malformed JSON, unknown fields and anything
+// after the value fail with 400.
+func DecodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxBodyBytes))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(v); err != nil {
+		return decodeError(err)
+	}
+	if err := dec.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return decodeError(err)
+		}
+		return &DecodeError{Status: http.StatusBadRequest, Msg: "body must contain a single JSON value"}
+	}
+	return nil
+}
+
+// RespondDecodeError writes err from DecodeJSON, prefixed with msg.
+func RespondDecodeError(w http.ResponseWriter, msg string, err error) {
+	var de *DecodeError
+	if errors.As(err, &de) {
+		RespondError(w, de.Status, msg+": "+de.Msg)
+		return
+	}
+	RespondError(w, http.StatusBadRequest, msg+": "+err.Error())
+}
+
+func decodeError(err error) *DecodeError {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return &DecodeError{Status: http.StatusRequestEntityTooLarge, Msg: fmt.Sprintf("body exceeds %d bytes", tooLarge.Limit)}
+	}
+	// encoding/json reports these as `json: unknown field "name"`
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return &DecodeError{Status: http.StatusBadRequest, Msg: "unknown field " + field}
+	}
+	if errors.Is(err, io.EOF) {
+		return &DecodeError{Status: http.StatusBadRequest, Msg: "body is empty"}
+	}
+	return &DecodeError{Status: http.StatusBadRequest, Msg: err.Error()}
+}
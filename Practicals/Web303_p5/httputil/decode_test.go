@@ -0,0 +1,54 @@
+package httputil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeBody(body string) (struct{ Name string }, error) {
+	var v struct{ Name string }
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	err := DecodeJSON(httptest.NewRecorder(), req, &v)
+	return v, err
+}
+
+func TestDecodeJSON(t *testing.T) {
+	v, err := decodeBody(`{"Name": "Tashi"}` + "\n")
+	require.NoError(t, err)
+	assert.Equal(t, "Tashi", v.Name)
+
+	for body, want := range map[string]DecodeError{
+		``:                           {http.StatusBadRequest, "body is empty"},
+		`{"Name": `:                  {http.StatusBadRequest, "unexpected EOF"},
+		`{"Name": "a", "Nmae": "b"}`: {http.StatusBadRequest, `unknown field "Nmae"`},
+		`{"Name": "a"} {}`:           {http.StatusBadRequest, "body must contain a single JSON value"},
+		`{"Name": "a"} garbage`:      {http.StatusBadRequest, "body must contain a single JSON value"},
+	} {
+		_, err := decodeBody(body)
+		assert.Equal(t, &want, err, body)
+	}
+}
+
+func TestDecodeJSONRejectsOversizedBodies(t *testing.T) {
+	old := MaxBodyBytes
+	MaxBodyBytes = 32
+	t.Cleanup(func() { MaxBodyBytes = old })
+
+	_, err := decodeBody(`{"Name": "` + strings.Repeat("x", 64) + `"}`)
+	assert.Equal(t, &DecodeError{http.StatusRequestEntityTooLarge, "body exceeds 32 bytes"}, err)
+
+	// A small value followed by a long tail is caught while checking for
+	// trailing data
+	_, err = decodeBody(`{"Name": "a"}` + strings.Repeat(" ", 64))
+	assert.Equal(t, &DecodeError{http.StatusRequestEntityTooLarge, "body exceeds 32 bytes"}, err)
+
+	rec := httptest.NewRecorder()
+	RespondDecodeError(rec, "Invalid request body", err)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.JSONEq(t, `{"error": "Invalid request body: body exceeds 32 bytes"}`, rec.Body.String())
+}
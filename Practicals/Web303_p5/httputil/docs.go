@@ -0,0 +1,48 @@
+package httputil
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+)
+
+// SwaggerUIVersion pins the Swagger UI release the docs page loads from the
+// CDN, so the page doesn't change under us.
+const SwaggerUIVersion = "5.17.14"
+
+var docsPage = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>{{.Title}}</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@{{.Version}}/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@{{.Version}}/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.onload = () => { window.ui = SwaggerUIBundle({url: {{.SpecURL}}, dom_id: "#swagger-ui"}); };
+  </script>
+</body>
+</html>
+`))
+
+// ServeSpec answers with the OpenAPI document spec.
+func ServeSpec(spec []byte) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(spec)
+	}
+}
+
+// SwaggerUI serves a Swagger UI page titled title that renders the OpenAPI
+// document at specURL.
+func SwaggerUI(title, specURL string) http.HandlerFunc {
+	data := struct{ Title, Version, SpecURL string }{title, SwaggerUIVersion, specURL}
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := docsPage.Execute(w, data); err != nil {
+			log.Printf("Failed to render docs page: %v", err)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package httputil
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// RespondCachedJSON writes v as a 200 JSON response carrying a weak ETag
+// derived from the body. When the request's If-None-Match already names that
+// ETag it answers 304 Not Modified with no body instead.
+func RespondCachedJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(v); err != nil {
+		log.Printf("Failed to encode response: %v", err)
+		RespondError(w, http.StatusInternalServerError, "failed to encode response")
+		return
+	}
+
+	etag := WeakETag(body.Bytes())
+	w.Header().Set("ETag", etag)
+	if MatchesETag(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body.Bytes())
+}
+
+// WeakETag returns a weak entity tag for body.
+func WeakETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// MatchesETag reports whether an If-None-Match header names etag, using the
+// weak comparison RFC 9110 prescribes for that header.
+func MatchesETag(ifNoneMatch, etag string) bool {
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	want := strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == want {
+			return true
+		}
+	}
+	return false
+}
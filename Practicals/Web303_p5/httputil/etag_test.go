@@ -0,0 +1,41 @@
+package httputil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRespondCachedJSON(t *testing.T) {
+	rec := httptest.NewRecorder()
+	RespondCachedJSON(rec, httptest.NewRequest(http.MethodGet, "/", nil), map[string]int{"id": 7})
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	etag := rec.Header().Get("ETag")
+	assert.Regexp(t, `^W/"[0-9a-f]{32}"$`, etag)
+	assert.JSONEq(t, `{"id": 7}`, rec.Body.String())
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-None-Match", `"stale", `+etag)
+	rec = httptest.NewRecorder()
+	RespondCachedJSON(rec, req, map[string]int{"id": 7})
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Equal(t, etag, rec.Header().Get("ETag"))
+	assert.Zero(t, rec.Body.Len())
+
+	rec = httptest.NewRecorder()
+	RespondCachedJSON(rec, req, map[string]int{"id": 8})
+	assert.Equal(t, http.StatusOK, rec.Code, "a changed body gets a new ETag")
+	assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+}
+
+func TestMatchesETag(t *testing.T) {
+	assert.True(t, MatchesETag(`W/"abc"`, `W/"abc"`))
+	assert.True(t, MatchesETag(`"abc"`, `W/"abc"`), "weak comparison ignores the W/ prefix")
+	assert.True(t, MatchesETag(`*`, `W/"abc"`))
+	assert.False(t, MatchesETag(``, `W/"abc"`))
+	assert.False(t, MatchesETag(`"abcd"`, `W/"abc"`))
+}
@@ -0,0 +1,14 @@
+module httputil
+
+go 1.23
+
+require (
+	github.com/go-chi/chi/v5 v5.0.11
+	github.com/stretchr/testify v1.11.1
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
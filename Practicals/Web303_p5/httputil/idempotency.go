@@ -0,0 +1,196 @@
+package httputil
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultIdempotencyTTL is how long a completed response stays replayable.
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// maxIdempotentResponse caps the response size kept for replay; larger
+// responses are passed through without being stored.
+const maxIdempotentResponse = 1 << 20
+
+// replayedHeaders are the response headers stored with a replayable
+// response. Others, such as Vary, are set afresh by outer middleware.
+var replayedHeaders = []string{"Content-Type", "Location"}
+
+// Idempotency replays the response of a request carrying an Idempotency-Key
+// header when the same key is sent again, so a retried create does not
+// insert twice. Keys are scoped to the method and path and kept in memory;
+// they are lost on restart and not shared between replicas.
+type Idempotency struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]idempotencyEntry
+	ops     int
+}
+
+type idempotencyEntry struct {
+	// bodyHash fingerprints the request, so a key reused for a different
+	// body is caught rather than answered with an unrelated response.
+	bodyHash  [sha256.Size]byte
+	resp      *storedResponse // nil while the request is in flight
+	expiresAt time.Time
+}
+
+type storedResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// idempotencySweepEvery is how many reserve calls pass between expiry sweeps.
+const idempotencySweepEvery = 1024
+
+// NewIdempotency keeps completed responses replayable for ttl.
+func NewIdempotency(ttl time.Duration) *Idempotency {
+	return &Idempotency{ttl: ttl, now: time.Now, entries: make(map[string]idempotencyEntry)}
+}
+
+// Wrap applies idempotency keys to next. Requests without the header are
+// served normally.
+func (m *Idempotency) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// One byte past the limit is enough for the handler to answer 413
+		body, err := io.ReadAll(io.LimitReader(r.Body, MaxBodyBytes+1))
+		if err != nil {
+			RespondError(w, http.StatusBadRequest, "Failed to read request body: "+err.Error())
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		scoped := r.Method + " " + r.URL.Path + " " + key
+		hash := sha256.Sum256(body)
+		stored, status := m.reserve(scoped, hash)
+		switch {
+		case status != 0:
+			msg := "Idempotency-Key was already used with a different request body"
+			if status == http.StatusConflict {
+				msg = "A request with this Idempotency-Key is still in progress"
+			}
+			RespondError(w, status, msg)
+			return
+		case stored != nil:
+			for name, values := range stored.header {
+				w.Header()[name] = values
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(stored.status)
+			w.Write(stored.body)
+			return
+		}
+
+		completed := false
+		defer func() {
+			// A panic is on its way to Recover; free the key so retries are
+			// not refused as in flight until the TTL runs out
+			if !completed {
+				m.release(scoped)
+			}
+		}()
+
+		capture := &captureWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(capture, r)
+		completed = true
+
+		// Server errors and oversized bodies are not replayed, so a retry
+		// runs the handler again
+		if capture.status >= http.StatusInternalServerError || capture.overflow {
+			m.release(scoped)
+			return
+		}
+		header := make(http.Header)
+		for _, name := range replayedHeaders {
+			if values := w.Header().Values(name); len(values) > 0 {
+				header[name] = values
+			}
+		}
+		m.save(scoped, hash, &storedResponse{status: capture.status, header: header, body: capture.body.Bytes()})
+	})
+}
+
+// reserve claims key for a new request. It returns the stored response when
+// the key has completed, or the error status for a key that is in flight
+// (409) or was used with another body (422). Neither means the caller now
+// owns the key.
+func (m *Idempotency) reserve(key string, hash [sha256.Size]byte) (*storedResponse, int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	if m.ops++; m.ops%idempotencySweepEvery == 0 {
+		for k, e := range m.entries {
+			if !now.Before(e.expiresAt) {
+				delete(m.entries, k)
+			}
+		}
+	}
+
+	if entry, ok := m.entries[key]; ok && now.Before(entry.expiresAt) {
+		switch {
+		case entry.bodyHash != hash:
+			return nil, http.StatusUnprocessableEntity
+		case entry.resp == nil:
+			return nil, http.StatusConflict
+		default:
+			return entry.resp, 0
+		}
+	}
+	m.entries[key] = idempotencyEntry{bodyHash: hash, expiresAt: now.Add(m.ttl)}
+	return nil, 0
+}
+
+func (m *Idempotency) save(key string, hash [sha256.Size]byte, resp *storedResponse) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[key] = idempotencyEntry{bodyHash: hash, resp: resp, expiresAt: m.now().Add(m.ttl)}
+}
+
+func (m *Idempotency) release(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+}
+
+// captureWriter passes a response through while keeping a copy for replay.
+type captureWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+	overflow    bool
+}
+
+func (c *captureWriter) WriteHeader(status int) {
+	if !c.wroteHeader {
+		c.status, c.wroteHeader = status, true
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *captureWriter) Write(p []byte) (int, error) {
+	c.wroteHeader = true
+	if !c.overflow {
+		if c.body.Len()+len(p) > maxIdempotentResponse {
+			c.overflow = true
+			c.body.Reset()
+		} else {
+			c.body.Write(p)
+		}
+	}
+	return c.ResponseWriter.Write(p)
+}
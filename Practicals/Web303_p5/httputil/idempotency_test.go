@@ -0,0 +1,148 @@
+package httputil
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingCreate answers every POST with a new ID, failing while fail is set.
+type countingCreate struct {
+	calls int
+	fail  bool
+}
+
+func (h *countingCreate) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	io.Copy(io.Discard, r.Body)
+	h.calls++
+	if h.fail {
+		RespondError(w, http.StatusInternalServerError, "database unavailable")
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/things/%d", h.calls))
+	RespondJSON(w, http.StatusCreated, map[string]int{"id": h.calls})
+}
+
+func postWithKey(h http.Handler, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/things", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIdempotencyReplaysCompletedRequests(t *testing.T) {
+	create := &countingCreate{}
+	h := NewIdempotency(time.Hour).Wrap(create)
+
+	first := postWithKey(h, "k1", `{"name": "a"}`)
+	require.Equal(t, http.StatusCreated, first.Code)
+	assert.Empty(t, first.Header().Get("Idempotent-Replayed"))
+
+	replay := postWithKey(h, "k1", `{"name": "a"}`)
+	assert.Equal(t, http.StatusCreated, replay.Code)
+	assert.Equal(t, "true", replay.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, "/things/1", replay.Header().Get("Location"))
+	assert.Equal(t, "application/json", replay.Header().Get("Content-Type"))
+	assert.Equal(t, first.Body.String(), replay.Body.String())
+	assert.Equal(t, 1, create.calls, "the replay never reaches the handler")
+
+	assert.Equal(t, http.StatusCreated, postWithKey(h, "k2", `{"name": "a"}`).Code)
+	assert.Equal(t, http.StatusCreated, postWithKey(h, "", `{"name": "a"}`).Code)
+	assert.Equal(t, 3, create.calls, "other keys and unkeyed requests run normally")
+}
+
+func TestIdempotencyRejectsKeyReuseWithAnotherBody(t *testing.T) {
+	create := &countingCreate{}
+	h := NewIdempotency(time.Hour).Wrap(create)
+
+	require.Equal(t, http.StatusCreated, postWithKey(h, "k1", `{"name": "a"}`).Code)
+	rec := postWithKey(h, "k1", `{"name": "b"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, rec.Body.String(), "different request body")
+	assert.Equal(t, 1, create.calls)
+}
+
+func TestIdempotencyForgetsFailuresAndExpiredKeys(t *testing.T) {
+	create := &countingCreate{fail: true}
+	m := NewIdempotency(time.Minute)
+	clock := time.Now()
+	m.now = func() time.Time { return clock }
+	h := m.Wrap(create)
+
+	assert.Equal(t, http.StatusInternalServerError, postWithKey(h, "k1", `{}`).Code)
+	create.fail = false
+	assert.Equal(t, http.StatusCreated, postWithKey(h, "k1", `{}`).Code, "a failed request can be retried")
+	assert.Equal(t, 2, create.calls)
+
+	clock = clock.Add(2 * time.Minute)
+	assert.Equal(t, http.StatusCreated, postWithKey(h, "k1", `{"name": "new"}`).Code, "expired keys are free again")
+	assert.Equal(t, 3, create.calls)
+}
+
+func TestIdempotencyRejectsConcurrentUse(t *testing.T) {
+	m := NewIdempotency(time.Hour)
+	inside := make(chan struct{})
+	release := make(chan struct{})
+	h := m.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(inside)
+		<-release
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		postWithKey(h, "k1", `{}`)
+		close(done)
+	}()
+	<-inside
+	assert.Equal(t, http.StatusConflict, postWithKey(h, "k1", `{}`).Code)
+	close(release)
+	<-done
+}
+
+func TestIdempotencyReleasesKeyOnPanic(t *testing.T) {
+	m := NewIdempotency(time.Hour)
+	create := &countingCreate{}
+	panicking := true
+	h := m.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if panicking {
+			panic("handler bug")
+		}
+		create.ServeHTTP(w, r)
+	}))
+
+	assert.Panics(t, func() { postWithKey(h, "k1", `{}`) })
+	panicking = false
+	assert.Equal(t, http.StatusCreated, postWithKey(h, "k1", `{}`).Code, "the retry is not refused as in flight")
+	assert.Equal(t, 1, create.calls)
+}
+
+func TestIdempotencySweepsExpiredKeysPeriodically(t *testing.T) {
+	m := NewIdempotency(time.Minute)
+	clock := time.Now()
+	m.now = func() time.Time { return clock }
+	h := m.Wrap(&countingCreate{})
+
+	for i := 0; i < 10; i++ {
+		postWithKey(h, fmt.Sprintf("old-%d", i), `{}`)
+	}
+	clock = clock.Add(2 * time.Minute)
+	postWithKey(h, "fresh", `{}`)
+	assert.Len(t, m.entries, 11, "expired keys are not swept on every request")
+
+	for m.ops%idempotencySweepEvery != idempotencySweepEvery-1 {
+		m.ops++
+	}
+	postWithKey(h, "fresh", `{}`)
+	assert.Len(t, m.entries, 1, "the periodic sweep drops expired keys")
+}
@@ -0,0 +1,83 @@
+package httputil
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Media types the list endpoints negotiate between.
+const (
+	MediaJSON = "application/json"
+	MediaCSV  = "text/csv"
+)
+
+// ListTypes are the representations of a list endpoint, preferred first.
+var ListTypes = []string{MediaJSON, MediaCSV}
+
+// NotAcceptableBody is the 406 response listing what an endpoint can produce.
+type NotAcceptableBody struct {
+	Error     string   `json:"error"`
+	Supported []string `json:"supported"`
+}
+
+// Negotiate returns the entry of supported the client most prefers according
+// to its Accept header, honouring q-values and type/* or */* wildcards. Ties
+// go to the earlier entry in supported. A missing Accept header accepts the
+// first supported type.
+func Negotiate(r *http.Request, supported ...string) (string, bool) {
+	accept := r.Header.Get("Accept")
+	if strings.TrimSpace(accept) == "" {
+		return supported[0], true
+	}
+
+	best, bestQ := "", 0.0
+	for _, candidate := range supported {
+		if q := acceptQuality(accept, candidate); q > bestQ {
+			best, bestQ = candidate, q
+		}
+	}
+	return best, best != ""
+}
+
+// acceptQuality is the q-value the Accept header assigns to mediaType, using
+// the most specific matching range.
+func acceptQuality(accept, mediaType string) float64 {
+	typ, _, _ := strings.Cut(mediaType, "/")
+	q, specificity := 0.0, -1
+	for _, part := range strings.Split(accept, ",") {
+		rangeType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		rank := -1
+		switch {
+		case rangeType == mediaType:
+			rank = 2
+		case rangeType == typ+"/*":
+			rank = 1
+		case rangeType == "*/*":
+			rank = 0
+		}
+		if rank <= specificity {
+			continue
+		}
+
+		rangeQ := 1.0
+		if raw, ok := params["q"]; ok {
+			if rangeQ, err = strconv.ParseFloat(raw, 64); err != nil {
+				continue
+			}
+		}
+		q, specificity = rangeQ, rank
+	}
+	return q
+}
+
+// RespondNotAcceptable rejects a request whose Accept header matched none of
+// supported.
+func RespondNotAcceptable(w http.ResponseWriter, supported []string) {
+	RespondJSON(w, http.StatusNotAcceptable, NotAcceptableBody{Error: "not acceptable", Supported: supported})
+}
@@ -0,0 +1,33 @@
+package httputil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiate(t *testing.T) {
+	for accept, want := range map[string]string{
+		"":                                 MediaJSON,
+		"*/*":                              MediaJSON,
+		"text/csv":                         MediaCSV,
+		"text/*":                           MediaCSV,
+		"text/csv;q=0.5, application/json": MediaJSON,
+		"application/json;q=0.1, text/csv": MediaCSV,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", accept)
+		got, ok := Negotiate(req, ListTypes...)
+		assert.True(t, ok, accept)
+		assert.Equal(t, want, got, accept)
+	}
+
+	for _, accept := range []string{"application/xml", "text/csv;q=0, application/json;q=0"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", accept)
+		_, ok := Negotiate(req, ListTypes...)
+		assert.False(t, ok, accept)
+	}
+}
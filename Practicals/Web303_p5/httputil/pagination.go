@@ -0,0 +1,81 @@
+package httputil
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Pagination defaults for list endpoints.
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// Page is the window of a list requested with ?offset= and ?limit=.
+type Page struct {
+	Offset int
+	Limit  int
+}
+
+// Bounds returns the slice bounds of the page within a list of n items. An
+// offset past the end yields an empty window, and offset is never added to
+// limit, so a huge offset cannot overflow.
+func (p Page) Bounds(n int) (start, end int) {
+	start = min(p.Offset, n)
+	return start, start + min(p.Limit, n-start)
+}
+
+// ParsePage reads offset/limit from the query string. ok is false when the
+// client asked for neither; the caller decides whether that means the full
+// list or a first page of DefaultPageLimit.
+func ParsePage(r *http.Request) (page Page, ok bool, err error) {
+	q := r.URL.Query()
+	if !q.Has("offset") && !q.Has("limit") {
+		return Page{}, false, nil
+	}
+
+	page = Page{Limit: DefaultPageLimit}
+	if raw := q.Get("offset"); raw != "" {
+		if page.Offset, err = strconv.Atoi(raw); err != nil || page.Offset < 0 {
+			return Page{}, true, fmt.Errorf("offset must be a non-negative integer")
+		}
+	}
+	if raw := q.Get("limit"); raw != "" {
+		if page.Limit, err = strconv.Atoi(raw); err != nil || page.Limit < 1 || page.Limit > MaxPageLimit {
+			return Page{}, true, fmt.Errorf("limit must be between 1 and %d", MaxPageLimit)
+		}
+	}
+	return page, true, nil
+}
+
+// SetPageHeaders sets X-Total-Count and an RFC 5988 Link header with next and
+// prev relations, omitting whichever falls outside [0, total).
+func SetPageHeaders(w http.ResponseWriter, r *http.Request, page Page, total int) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+
+	var links []string
+	// Compared as a difference so offset+limit cannot overflow
+	if page.Offset < total-page.Limit {
+		links = append(links, pageLink(r, page.Offset+page.Limit, page.Limit, "next"))
+	}
+	if page.Offset > 0 {
+		prev := page.Offset - page.Limit
+		if prev < 0 {
+			prev = 0
+		}
+		links = append(links, pageLink(r, prev, page.Limit, "prev"))
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+}
+
+// pageLink keeps the request's other query parameters so filters carry over.
+func pageLink(r *http.Request, offset, limit int, rel string) string {
+	q := r.URL.Query()
+	q.Set("offset", strconv.Itoa(offset))
+	q.Set("limit", strconv.Itoa(limit))
+	return fmt.Sprintf("<%s?%s>; rel=%q", r.URL.Path, q.Encode(), rel)
+}
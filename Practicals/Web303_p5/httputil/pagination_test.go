@@ -0,0 +1,61 @@
+package httputil
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePage(t *testing.T) {
+	page, ok, err := ParsePage(httptest.NewRequest(http.MethodGet, "/items", nil))
+	require.NoError(t, err)
+	assert.False(t, ok, "no paging parameters")
+	assert.Equal(t, Page{}, page)
+
+	page, ok, err = ParsePage(httptest.NewRequest(http.MethodGet, "/items?offset=40", nil))
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, Page{Offset: 40, Limit: DefaultPageLimit}, page)
+
+	for _, query := range []string{"offset=-1", "offset=x", "limit=0", "limit=101"} {
+		_, _, err := ParsePage(httptest.NewRequest(http.MethodGet, "/items?"+query, nil))
+		assert.Error(t, err, query)
+	}
+}
+
+func TestPageBounds(t *testing.T) {
+	for _, tt := range []struct {
+		page       Page
+		start, end int
+	}{
+		{Page{Offset: 0, Limit: 2}, 0, 2},
+		{Page{Offset: 4, Limit: 2}, 4, 5},
+		{Page{Offset: 9, Limit: 2}, 5, 5},
+		{Page{Offset: math.MaxInt, Limit: MaxPageLimit}, 5, 5},
+	} {
+		start, end := tt.page.Bounds(5)
+		assert.Equal(t, tt.start, start, "%+v", tt.page)
+		assert.Equal(t, tt.end, end, "%+v", tt.page)
+	}
+}
+
+func TestSetPageHeaders(t *testing.T) {
+	links := func(query string, page Page) string {
+		rec := httptest.NewRecorder()
+		SetPageHeaders(rec, httptest.NewRequest(http.MethodGet, "/items?"+query, nil), page, 5)
+		assert.Equal(t, "5", rec.Header().Get("X-Total-Count"))
+		return rec.Header().Get("Link")
+	}
+
+	assert.Equal(t, `</items?limit=2&offset=2>; rel="next"`, links("", Page{Limit: 2}))
+	assert.Equal(t, `</items?limit=2&offset=4&q=x>; rel="next", </items?limit=2&offset=0&q=x>; rel="prev"`,
+		links("q=x", Page{Offset: 2, Limit: 2}), "other parameters carry over")
+	assert.Equal(t, `</items?limit=2&offset=2>; rel="prev"`, links("", Page{Offset: 4, Limit: 2}))
+	assert.Empty(t, links("", Page{Limit: 10}))
+	assert.Equal(t, `</items?limit=20&offset=9223372036854775787>; rel="prev"`,
+		links("", Page{Offset: math.MaxInt, Limit: 20}), "no next past the end")
+}
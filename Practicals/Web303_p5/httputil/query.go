@@ -0,0 +1,31 @@
+package httputil
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// StatusClientClosedRequest is the non-standard status nginx uses for a
+// client that went away before the response was ready.
+const StatusClientClosedRequest = 499
+
+// QueryErrorStatus is the status for a failed query: 499 when the client
+// cancelled the request, 504 when the query deadline passed, and 500
+// otherwise.
+func QueryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return StatusClientClosedRequest
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// RespondQueryError writes err from a failed query, prefixed with msg, with
+// the status QueryErrorStatus picks.
+func RespondQueryError(w http.ResponseWriter, msg string, err error) {
+	RespondError(w, QueryErrorStatus(err), msg+err.Error())
+}
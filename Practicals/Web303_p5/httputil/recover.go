@@ -0,0 +1,39 @@
+package httputil
+
+import (
+	"log"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Recover turns a panicking handler into a JSON 500. The panic value and
+// stack trace are logged with the request ID; the client only sees a
+// generic message.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			// net/http uses this sentinel to abort a response on purpose
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+			log.Printf("Panic serving %s %s (request %s): %v\n%s", r.Method, r.URL.Path, requestID(r), rec, debug.Stack())
+			RespondError(w, http.StatusInternalServerError, "internal server error")
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestID is the ID chi's RequestID middleware assigned, or else the
+// caller's X-Request-ID.
+func requestID(r *http.Request) string {
+	if id := middleware.GetReqID(r.Context()); id != "" {
+		return id
+	}
+	return r.Header.Get(middleware.RequestIDHeader)
+}
@@ -0,0 +1,92 @@
+// Package config loads service settings from the environment, falling back
+// to a .env file for local development and then to the defaults in the
+// struct tags.
+package config
+
+import (
+	"envconfig"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Config holds the settings main needs to start the service. Each field
+// names its environment variable and default in struct tags.
+type Config struct {
+	ServicePort int    `env:"PORT" default:"8082"`
+	DatabaseDSN string `env:"DATABASE_URL" default:"host=localhost user=postgres password=postgres dbname=menu_db port=5432 sslmode=disable"`
+	LogLevel    string `env:"LOG_LEVEL" default:"info"`
+	// MigrateOnBoot applies pending schema migrations at startup; when
+	// false they must be run with the migrate subcommand.
+	MigrateOnBoot bool `env:"MIGRATE_ON_BOOT" default:"true"`
+	// The DB* fields size the database connection pool.
+	DBMaxOpenConns    int           `env:"DB_MAX_OPEN_CONNS" default:"25"`
+	DBMaxIdleConns    int           `env:"DB_MAX_IDLE_CONNS" default:"5"`
+	DBConnMaxLifetime time.Duration `env:"DB_CONN_MAX_LIFETIME" default:"30m"`
+	// DBConnectRetry is how long startup keeps retrying a database that is
+	// not accepting connections yet; 0 tries once.
+	DBConnectRetry time.Duration `env:"DB_CONNECT_RETRY" default:"60s"`
+	// QueryTimeout bounds the database work of a single request.
+	QueryTimeout time.Duration `env:"QUERY_TIMEOUT" default:"5s"`
+	// MaxBodyBytes caps the size of JSON request bodies.
+	MaxBodyBytes int `env:"MAX_BODY_BYTES" default:"1048576"`
+	// SeedData loads SeedFile into an empty database at startup.
+	SeedData bool   `env:"SEED_DATA" default:"false"`
+	SeedFile string `env:"SEED_FILE" default:"seed.json"`
+	// IdempotencyTTL is how long a create stays replayable under its
+	// Idempotency-Key.
+	IdempotencyTTL time.Duration `env:"IDEMPOTENCY_TTL" default:"24h"`
+}
+
+// Load reads the configuration from the environment and
+// envconfig.DotEnvFile.
+func Load() (Config, error) {
+	lookup, err := envconfig.Lookup()
+	if err != nil {
+		return Config{}, err
+	}
+	return load(lookup)
+}
+
+// load fills a Config using lookup, then validates it.
+func load(lookup func(string) (string, bool)) (Config, error) {
+	var cfg Config
+	if err := envconfig.Fill(&cfg, lookup); err != nil {
+		return Config{}, err
+	}
+	cfg.LogLevel = strings.ToLower(cfg.LogLevel)
+	return cfg, cfg.validate()
+}
+
+func (c Config) validate() error {
+	if c.ServicePort < 1 || c.ServicePort > 65535 {
+		return fmt.Errorf("PORT: %d is out of range", c.ServicePort)
+	}
+	if c.MaxBodyBytes < 1 {
+		return fmt.Errorf("MAX_BODY_BYTES: %d must be positive", c.MaxBodyBytes)
+	}
+	if c.DBMaxOpenConns < 1 {
+		return fmt.Errorf("DB_MAX_OPEN_CONNS: %d must be positive", c.DBMaxOpenConns)
+	}
+	if c.DBMaxIdleConns < 0 || c.DBMaxIdleConns > c.DBMaxOpenConns {
+		return fmt.Errorf("DB_MAX_IDLE_CONNS: %d must be between 0 and DB_MAX_OPEN_CONNS", c.DBMaxIdleConns)
+	}
+	if c.DBConnMaxLifetime < 0 {
+		return fmt.Errorf("DB_CONN_MAX_LIFETIME: %s must not be negative", c.DBConnMaxLifetime)
+	}
+	if c.DBConnectRetry < 0 {
+		return fmt.Errorf("DB_CONNECT_RETRY: %s must not be negative", c.DBConnectRetry)
+	}
+	if c.QueryTimeout <= 0 {
+		return fmt.Errorf("QUERY_TIMEOUT: %s must be positive", c.QueryTimeout)
+	}
+	if c.IdempotencyTTL <= 0 {
+		return fmt.Errorf("IDEMPOTENCY_TTL: %s must be positive", c.IdempotencyTTL)
+	}
+	switch c.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("LOG_LEVEL: %q must be debug, info, warn or error", c.LogLevel)
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package database
+
+import (
+	"context"
+	"dbutil"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// QueryTimeout bounds the database work done for one request; main sets it
+// from QUERY_TIMEOUT.
+var QueryTimeout = 5 * time.Second
+
+// WithContext returns DB bound to ctx and cut off after QueryTimeout. Call
+// cancel once the queries are done.
+func WithContext(ctx context.Context) (*gorm.DB, context.CancelFunc) {
+	return dbutil.WithTimeout(ctx, DB, QueryTimeout)
+}
@@ -0,0 +1,19 @@
+package database
+
+import (
+	"dbutil"
+	"time"
+
+	"gorm.io/gorm/logger"
+)
+
+// SlowQueryThreshold is the duration above which GORM logs a query as slow.
+var SlowQueryThreshold = 200 * time.Millisecond
+
+// QueryLogLevel is the GORM logger level; main sets it from LOG_LEVEL.
+var QueryLogLevel = logger.Warn
+
+// newQueryLogger mirrors GORM's default logger with our slow-query threshold.
+func newQueryLogger() logger.Interface {
+	return dbutil.NewQueryLogger(QueryLogLevel, SlowQueryThreshold)
+}
@@ -0,0 +1,39 @@
+package database
+
+import (
+	"menu-service/models"
+	"migrate"
+
+	"gorm.io/gorm"
+)
+
+// Migration and SchemaMigration are the shared runner's types, named here so
+// callers keep using database.Migration.
+type (
+	Migration       = migrate.Migration
+	SchemaMigration = migrate.SchemaMigration
+)
+
+// migrations is the menu service schema history, oldest first.
+var migrations = []Migration{
+	{Version: 1, Name: "create_menus_and_items", Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&models.Menu{}, &models.MenuItem{})
+	}},
+}
+
+func runner() migrate.Runner {
+	return migrate.Runner{Migrations: migrations, Command: "menu-service migrate"}
+}
+
+// Migrate applies every migration not yet recorded in schema_migrations.
+func Migrate(db *gorm.DB) error { return runner().Migrate(db) }
+
+// Pending lists the migrations that have not been applied yet.
+func Pending(db *gorm.DB) ([]Migration, error) { return runner().Pending(db) }
+
+// EnsureSchema runs pending migrations when migrateOnBoot is set. Otherwise
+// it refuses to start against an outdated schema, leaving the upgrade to an
+// explicit `menu-service migrate`.
+func EnsureSchema(db *gorm.DB, migrateOnBoot bool) error {
+	return runner().EnsureSchema(db, migrateOnBoot)
+}
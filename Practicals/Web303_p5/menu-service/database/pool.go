@@ -0,0 +1,16 @@
+package database
+
+import (
+	"dbutil"
+	"time"
+)
+
+// PoolConfig sizes the database/sql connection pool under DB.
+type PoolConfig = dbutil.PoolConfig
+
+// Pool is applied by Connect; main sets it from the environment.
+var Pool = PoolConfig{
+	MaxOpenConns:    25,
+	MaxIdleConns:    5,
+	ConnMaxLifetime: 30 * time.Minute,
+}
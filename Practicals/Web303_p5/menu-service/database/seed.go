@@ -0,0 +1,50 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"menu-service/models"
+	"os"
+
+	"gorm.io/gorm"
+)
+
+// SeedData is the shape of a seed file. Each service reads only its own key,
+// so one file can seed the whole stack.
+type SeedData struct {
+	Menus []models.Menu `json:"menus"`
+}
+
+// Seed inserts the menus in the JSON file at path, together with their
+// menu_items, all or none, and returns how many menus were inserted. It does
+// nothing when the menus table already has rows, so restarting a seeded
+// service never duplicates them.
+func Seed(db *gorm.DB, path string) (int, error) {
+	var count int64
+	if err := db.Model(&models.Menu{}).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("counting menus: %w", err)
+	}
+	if count > 0 {
+		return 0, nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	var seed SeedData
+	if err := json.Unmarshal(raw, &seed); err != nil {
+		return 0, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if len(seed.Menus) == 0 {
+		return 0, nil
+	}
+
+	// Creating a menu also creates its MenuItems association
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		return tx.Create(&seed.Menus).Error
+	}); err != nil {
+		return 0, fmt.Errorf("inserting menus: %w", err)
+	}
+	return len(seed.Menus), nil
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"menu-service/models"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func writeSeedFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "seed.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestSeedLoadsMenusAndItemsIntoEmptyTables(t *testing.T) {
+	db := openTestDB(t)
+	require.NoError(t, Migrate(db))
+	path := writeSeedFile(t, `{
+		"users": [{"name": "read by the user service only"}],
+		"menus": [
+			{"name": "Breakfast", "owner_id": 1, "menu_items": [
+				{"name": "Toast", "price": 2, "category": "mains"},
+				{"name": "Suja", "price": 1.5, "category": "drinks"}
+			]},
+			{"name": "Dinner", "owner_id": 2}
+		]
+	}`)
+
+	inserted, err := Seed(db, path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, inserted)
+
+	var menus []models.Menu
+	require.NoError(t, db.Preload("MenuItems", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Order("id").Find(&menus).Error)
+	require.Len(t, menus, 2)
+	assert.Equal(t, "Breakfast", menus[0].Name)
+	require.Len(t, menus[0].MenuItems, 2)
+	assert.Equal(t, "Suja", menus[0].MenuItems[1].Name)
+	assert.Empty(t, menus[1].MenuItems)
+
+	// A second boot finds the table populated and leaves it alone
+	inserted, err = Seed(db, path)
+	require.NoError(t, err)
+	assert.Zero(t, inserted)
+	var count int64
+	require.NoError(t, db.Model(&models.MenuItem{}).Count(&count).Error)
+	assert.EqualValues(t, 2, count)
+}
+
+func TestSeedRejectsBadFiles(t *testing.T) {
+	db := openTestDB(t)
+	require.NoError(t, Migrate(db))
+
+	_, err := Seed(db, filepath.Join(t.TempDir(), "missing.json"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	_, err = Seed(db, writeSeedFile(t, `{"menus": [`))
+	assert.Error(t, err)
+
+	var count int64
+	require.NoError(t, db.Model(&models.Menu{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
@@ -3,17 +3,34 @@ module menu-service
 go 1.23
 
 require (
+	dbutil v0.0.0
+	envconfig v0.0.0
 	github.com/go-chi/chi/v5 v5.0.11
+	github.com/stretchr/testify v1.11.1
 	gorm.io/driver/postgres v1.5.4
+	gorm.io/driver/sqlite v1.5.4
 	gorm.io/gorm v1.25.5
+	httputil v0.0.0
+	migrate v0.0.0
 )
 
 require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/jackc/pgpassfile v1.0.0 // indirect
 	github.com/jackc/pgservicefile v0.0.0-20221227161230-091c0ba34f0a // indirect
 	github.com/jackc/pgx/v5 v5.4.3 // indirect
 	github.com/jinzhu/inflection v1.0.0 // indirect
 	github.com/jinzhu/now v1.1.5 // indirect
+	github.com/mattn/go-sqlite3 v1.14.17 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
 	golang.org/x/crypto v0.14.0 // indirect
 	golang.org/x/text v0.13.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace (
+	dbutil => ../dbutil
+	envconfig => ../envconfig
+	httputil => ../httputil
+	migrate => ../migrate
 )
@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+)
+
+// AdminToken is the shared secret admin callers present in X-Admin-Token.
+// Admin-only behaviour is disabled while it is empty.
+var AdminToken = os.Getenv("ADMIN_TOKEN")
+
+// isAdmin reports whether the request carries the configured admin token.
+func isAdmin(r *http.Request) bool {
+	presented := r.Header.Get("X-Admin-Token")
+	return AdminToken != "" && subtle.ConstantTimeCompare([]byte(presented), []byte(AdminToken)) == 1
+}
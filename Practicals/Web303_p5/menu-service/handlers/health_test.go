@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLiveness(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Liveness(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestReadinessCheck(t *testing.T) {
+	db := setupTestDB(t)
+
+	rec := httptest.NewRecorder()
+	ReadinessCheck(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var ready Readiness
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&ready))
+	assert.Equal(t, "ready", ready.Status)
+	assert.GreaterOrEqual(t, ready.DBLatencyMS, 0.0)
+
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	require.NoError(t, sqlDB.Close())
+
+	rec = httptest.NewRecorder()
+	ReadinessCheck(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	var down Readiness
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&down))
+	assert.Equal(t, "unavailable", down.Status)
+	assert.NotEmpty(t, down.Error)
+}
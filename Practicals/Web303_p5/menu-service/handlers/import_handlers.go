@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"httputil"
+	"io"
+	"math"
+	"menu-service/database"
+	"menu-service/models"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// maxImportBytes bounds the size of an uploaded CSV file.
+const maxImportBytes = 5 << 20
+
+// importColumns is the expected column order of an import file. A header
+// row naming these columns is optional.
+var importColumns = []string{"name", "description", "price", "category"}
+
+// ImportRowResult reports what happened to one CSV row.
+type ImportRowResult struct {
+	Line   int    `json:"line"`
+	Status string `json:"status"`
+	ID     uint   `json:"id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ImportReport is the response body of ImportMenuItems.
+type ImportReport struct {
+	MenuID   uint              `json:"menu_id"`
+	Imported int               `json:"imported"`
+	Rejected int               `json:"rejected"`
+	Rows     []ImportRowResult `json:"rows"`
+}
+
+// ImportMenuItems handles POST /items/import?menu_id={id} with a text/csv
+// body of name,description,price,category rows. Valid rows are inserted in a
+// single transaction; invalid rows are reported and skipped. A file that is
+// not well-formed CSV is rejected as a whole.
+func ImportMenuItems(w http.ResponseWriter, r *http.Request) {
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "text/csv" {
+		httputil.RespondError(w, http.StatusUnsupportedMediaType, "Content-Type must be text/csv")
+		return
+	}
+
+	menuID, err := strconv.ParseUint(r.URL.Query().Get("menu_id"), 10, 64)
+	if err != nil || menuID == 0 {
+		httputil.RespondError(w, http.StatusBadRequest, "menu_id query parameter is required")
+		return
+	}
+	db, cancel := database.WithContext(r.Context())
+	defer cancel()
+	var menu models.Menu
+	if err := db.First(&menu, menuID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			httputil.RespondError(w, http.StatusNotFound, "Menu not found")
+			return
+		}
+		httputil.RespondQueryError(w, "", err)
+		return
+	}
+
+	items, report, err := parseImport(http.MaxBytesReader(w, r.Body, maxImportBytes), menu.ID)
+	if err != nil {
+		httputil.RespondError(w, http.StatusBadRequest, "Malformed CSV: "+err.Error())
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for i := range report.Rows {
+			item, ok := items[i]
+			if !ok {
+				continue
+			}
+			if err := tx.Create(&item).Error; err != nil {
+				return fmt.Errorf("line %d: %w", report.Rows[i].Line, err)
+			}
+			report.Rows[i].ID = item.ID
+		}
+		return nil
+	})
+	if err != nil {
+		httputil.RespondQueryError(w, "Failed to import menu items: ", err)
+		return
+	}
+
+	httputil.RespondJSON(w, http.StatusOK, report)
+}
+
+// parseImport validates every row, returning the valid items keyed by their
+// index in report.Rows. Only unreadable CSV produces an error.
+func parseImport(body io.Reader, menuID uint) (map[int]models.MenuItem, *ImportReport, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1 // column count is validated per row
+	reader.TrimLeadingSpace = true
+
+	report := &ImportReport{MenuID: menuID, Rows: []ImportRowResult{}}
+	items := make(map[int]models.MenuItem)
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		if first && isImportHeader(record) {
+			continue
+		}
+
+		item, err := parseImportRow(record, menuID)
+		if err != nil {
+			report.Rows = append(report.Rows, ImportRowResult{Line: line, Status: "rejected", Error: err.Error()})
+			report.Rejected++
+			continue
+		}
+		items[len(report.Rows)] = item
+		report.Rows = append(report.Rows, ImportRowResult{Line: line, Status: "imported"})
+		report.Imported++
+	}
+	return items, report, nil
+}
+
+func isImportHeader(record []string) bool {
+	if len(record) != len(importColumns) {
+		return false
+	}
+	for i, col := range importColumns {
+		if !strings.EqualFold(strings.TrimSpace(record[i]), col) {
+			return false
+		}
+	}
+	return true
+}
+
+func parseImportRow(record []string, menuID uint) (models.MenuItem, error) {
+	if len(record) != len(importColumns) {
+		return models.MenuItem{}, fmt.Errorf("expected %d columns (%s), got %d",
+			len(importColumns), strings.Join(importColumns, ","), len(record))
+	}
+
+	name := strings.TrimSpace(record[0])
+	if name == "" {
+		return models.MenuItem{}, errors.New("name is required")
+	}
+	price, err := strconv.ParseFloat(strings.TrimSpace(record[2]), 64)
+	if err != nil || math.IsNaN(price) || math.IsInf(price, 0) {
+		return models.MenuItem{}, fmt.Errorf("invalid price %q", record[2])
+	}
+	if price < 0 {
+		return models.MenuItem{}, errors.New("price must not be negative")
+	}
+
+	return models.MenuItem{
+		MenuID:      menuID,
+		Name:        name,
+		Description: strings.TrimSpace(record[1]),
+		Price:       price,
+		Category:    strings.TrimSpace(record[3]),
+	}, nil
+}
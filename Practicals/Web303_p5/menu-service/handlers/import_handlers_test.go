@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"menu-service/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func importRequest(t *testing.T, menuID uint, body string) *httptest.ResponseRecorder {
+	r := chi.NewRouter()
+	r.Post("/items/import", ImportMenuItems)
+
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/items/import?menu_id=%d", menuID), strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/csv; charset=utf-8")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestImportMenuItemsClean(t *testing.T) {
+	db := setupTestDB(t)
+	menu := models.Menu{Name: "Lunch"}
+	require.NoError(t, db.Create(&menu).Error)
+
+	rec := importRequest(t, menu.ID, "name,description,price,category\n"+
+		"Momo,Steamed dumplings,4.50,mains\n"+
+		"\"Ema Datshi\",\"Chilli, cheese\",6,mains\n")
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var report ImportReport
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&report))
+	assert.Equal(t, 2, report.Imported)
+	assert.Equal(t, 0, report.Rejected)
+	require.Len(t, report.Rows, 2)
+	assert.Equal(t, 2, report.Rows[0].Line)
+	assert.Equal(t, "imported", report.Rows[1].Status)
+	assert.NotZero(t, report.Rows[1].ID)
+
+	var items []models.MenuItem
+	require.NoError(t, db.Order("id").Find(&items).Error)
+	require.Len(t, items, 2)
+	assert.Equal(t, menu.ID, items[1].MenuID)
+	assert.Equal(t, "Chilli, cheese", items[1].Description)
+	assert.Equal(t, "mains", items[1].Category)
+}
+
+func TestImportMenuItemsReportsInvalidRows(t *testing.T) {
+	db := setupTestDB(t)
+	menu := models.Menu{Name: "Lunch"}
+	require.NoError(t, db.Create(&menu).Error)
+
+	rec := importRequest(t, menu.ID, "Momo,Dumplings,4.50,mains\n"+
+		",No name,3,mains\n"+
+		"Tea,Milk tea,cheap,drinks\n"+
+		"Coffee,Too few columns\n"+
+		"Juice,Fresh,-1,drinks\n"+
+		"Suja,Butter tea,2,drinks\n")
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var report ImportReport
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&report))
+	assert.Equal(t, 2, report.Imported)
+	assert.Equal(t, 4, report.Rejected)
+
+	statuses := make(map[int]ImportRowResult)
+	for _, row := range report.Rows {
+		statuses[row.Line] = row
+	}
+	assert.Equal(t, "imported", statuses[1].Status)
+	assert.Equal(t, "name is required", statuses[2].Error)
+	assert.Contains(t, statuses[3].Error, "invalid price")
+	assert.Contains(t, statuses[4].Error, "expected 4 columns")
+	assert.Contains(t, statuses[5].Error, "negative")
+	assert.Equal(t, "imported", statuses[6].Status)
+
+	var count int64
+	db.Model(&models.MenuItem{}).Count(&count)
+	assert.EqualValues(t, 2, count)
+}
+
+func TestImportMenuItemsRejectsMalformedFile(t *testing.T) {
+	db := setupTestDB(t)
+	menu := models.Menu{Name: "Lunch"}
+	require.NoError(t, db.Create(&menu).Error)
+
+	rec := importRequest(t, menu.ID, "Momo,Dumplings,4.50,mains\n\"Tea,unterminated quote,2,drinks\n")
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	var count int64
+	db.Model(&models.MenuItem{}).Count(&count)
+	assert.Zero(t, count, "nothing is imported from a malformed file")
+}
+
+func TestImportMenuItemsRequiresCSVAndMenu(t *testing.T) {
+	setupTestDB(t)
+
+	assert.Equal(t, http.StatusNotFound, importRequest(t, 42, "Momo,Dumplings,4.50,mains\n").Code)
+
+	req := httptest.NewRequest(http.MethodPost, "/items/import?menu_id=1", strings.NewReader("{}"))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	ImportMenuItems(rec, req)
+	assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+}
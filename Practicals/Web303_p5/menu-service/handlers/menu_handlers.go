@@ -1,82 +1,347 @@
 package handlers
 
 import (
-	"encoding/json"
-	"net/http"
+	"errors"
+	"fmt"
+	"httputil"
 	"menu-service/database"
 	"menu-service/models"
+	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
 )
 
+// GetMenu returns a menu together with its items. The response carries an
+// ETag, and a matching If-None-Match gets 304 Not Modified. Accept: text/csv
+// returns just the item list as a CSV download.
 func GetMenu(w http.ResponseWriter, r *http.Request) {
+	contentType, ok := httputil.Negotiate(r, httputil.ListTypes...)
+	w.Header().Add("Vary", "Accept")
+	if !ok {
+		httputil.RespondNotAcceptable(w, httputil.ListTypes)
+		return
+	}
+
 	menuID := chi.URLParam(r, "id")
+	db, cancel := database.WithContext(r.Context())
+	defer cancel()
 	var menu models.Menu
-	if err := database.DB.Where("id = ?", menuID).First(&menu).Error; err != nil {
-		http.Error(w, "Menu item not found", http.StatusNotFound)
+	err := db.Preload("MenuItems", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Where("id = ?", menuID).First(&menu).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		httputil.RespondError(w, http.StatusNotFound, "Menu not found")
+		return
+	}
+	if err != nil {
+		httputil.RespondQueryError(w, "", err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(menu)
+	if contentType == httputil.MediaCSV {
+		respondMenuItemsCSV(w, menu.ID, models.MenuItemsToResponse(menu.MenuItems, isAdmin(r)))
+		return
+	}
+	httputil.RespondCachedJSON(w, r, menu.ToResponse(isAdmin(r)))
+}
+
+// respondMenuItemsCSV writes a menu's items as a menu-<id>-items.csv
+// download, one row per item under the JSON field names.
+func respondMenuItemsCSV(w http.ResponseWriter, menuID uint, items []models.MenuItemResponse) {
+	rows := make([][]string, len(items))
+	for i, item := range items {
+		rows[i] = []string{
+			strconv.FormatUint(uint64(item.ID), 10), item.Name, item.Description,
+			strconv.FormatFloat(item.Price, 'f', 2, 64), item.Category,
+			item.AvailableFrom, item.AvailableUntil, item.CreatedAt, item.UpdatedAt,
+		}
+	}
+	httputil.RespondCSV(w, fmt.Sprintf("menu-%d-items.csv", menuID),
+		[]string{"id", "name", "description", "price", "category", "available_from", "available_until", "created_at", "updated_at"}, rows)
 }
 
 func CreateMenu(w http.ResponseWriter, r *http.Request) {
 	var menuData models.Menu
-	if err := json.NewDecoder(r.Body).Decode(&menuData); err != nil {
-		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+	if err := httputil.DecodeJSON(w, r, &menuData); err != nil {
+		httputil.RespondDecodeError(w, "Invalid request body", err)
+		return
+	}
+	if errs := validateMenu(menuData); len(errs) > 0 {
+		writeValidationErrors(w, errs)
 		return
 	}
 
-	result := database.DB.Create(&menuData)
-	if result.Error != nil {
-		http.Error(w, "Failed to create menu: "+result.Error.Error(), http.StatusInternalServerError)
+	db, cancel := database.WithContext(r.Context())
+	defer cancel()
+	// The menu and its items persist together or not at all
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("MenuItems").Create(&menuData).Error; err != nil {
+			return fmt.Errorf("menu: %w", err)
+		}
+		for i := range menuData.MenuItems {
+			menuData.MenuItems[i].MenuID = menuData.ID
+			if err := tx.Create(&menuData.MenuItems[i]).Error; err != nil {
+				return fmt.Errorf("menu_items[%d]: %w", i, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		httputil.RespondQueryError(w, "Failed to create menu: ", err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(menuData)
+	httputil.RespondJSON(w, http.StatusCreated, menuData.ToResponse(isAdmin(r)))
 }
 
-// func GetMenuItems(w http.ResponseWriter, r *http.Request) {
-// 	var items []models.MenuItem
-// 	if err := database.DB.Find(&items).Error; err != nil {
-// 		http.Error(w, err.Error(), http.StatusInternalServerError)
-// 		return
-// 	}
-
-// 	w.Header().Set("Content-Type", "application/json")
-// 	json.NewEncoder(w).Encode(items)
-// }
-
-// func CreateMenuItem(w http.ResponseWriter, r *http.Request) {
-// 	var item models.MenuItem
-// 	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
-// 		http.Error(w, err.Error(), http.StatusBadRequest)
-// 		return
-// 	}
-
-// 	if err := database.DB.Create(&item).Error; err != nil {
-// 		http.Error(w, err.Error(), http.StatusInternalServerError)
-// 		return
-// 	}
-
-// 	w.Header().Set("Content-Type", "application/json")
-// 	w.WriteHeader(http.StatusCreated)
-// 	json.NewEncoder(w).Encode(item)
-// }
-
-// func GetMenuItem(w http.ResponseWriter, r *http.Request) {
-// 	id := chi.URLParam(r, "id")
-
-// 	var item models.MenuItem
-// 	if err := database.DB.First(&item, id).Error; err != nil {
-// 		http.Error(w, "Menu item not found", http.StatusNotFound)
-// 		return
-// 	}
-
-// 	w.Header().Set("Content-Type", "application/json")
-// 	json.NewEncoder(w).Encode(item)
-// }
+// now is the clock used for availability filtering, swappable in tests.
+var now = time.Now
+
+// GetMenuItems lists menu items. With ?available_now=true only items whose
+// availability window contains the current time of day are returned.
+func GetMenuItems(w http.ResponseWriter, r *http.Request) {
+	availableNow := false
+	if raw := r.URL.Query().Get("available_now"); raw != "" {
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			httputil.RespondError(w, http.StatusBadRequest, "available_now must be true or false")
+			return
+		}
+		availableNow = v
+	}
+
+	page, paged, err := httputil.ParsePage(r)
+	if err != nil {
+		httputil.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	db, cancel := database.WithContext(r.Context())
+	defer cancel()
+	// Availability is evaluated in Go, so filtered listings page in memory;
+	// otherwise the database does the windowing
+	query := db.Model(&models.MenuItem{}).Order("id")
+	if paged && !availableNow {
+		var total int64
+		if err := query.Count(&total).Error; err != nil {
+			httputil.RespondQueryError(w, "", err)
+			return
+		}
+		httputil.SetPageHeaders(w, r, page, int(total))
+		query = query.Offset(page.Offset).Limit(page.Limit)
+	}
+
+	var items []models.MenuItem
+	if err := query.Find(&items).Error; err != nil {
+		httputil.RespondQueryError(w, "", err)
+		return
+	}
+
+	if availableNow {
+		current := now()
+		filtered := items[:0]
+		for _, item := range items {
+			if item.AvailableAt(current) {
+				filtered = append(filtered, item)
+			}
+		}
+		items = filtered
+
+		if paged {
+			httputil.SetPageHeaders(w, r, page, len(items))
+			start, end := page.Bounds(len(items))
+			items = items[start:end]
+		}
+	}
+
+	httputil.RespondJSON(w, http.StatusOK, models.MenuItemsToResponse(items, isAdmin(r)))
+}
+
+// CreateMenuItem adds a single item to the menu named by its menu_id.
+func CreateMenuItem(w http.ResponseWriter, r *http.Request) {
+	var item models.MenuItem
+	if err := httputil.DecodeJSON(w, r, &item); err != nil {
+		httputil.RespondDecodeError(w, "Invalid request body", err)
+		return
+	}
+	errs := validateMenuItem(item, "")
+	if item.MenuID == 0 {
+		errs = append(errs, FieldError{Field: "menu_id", Message: "is required"})
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	db, cancel := database.WithContext(r.Context())
+	defer cancel()
+	var menu models.Menu
+	if err := db.Select("id").First(&menu, item.MenuID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			httputil.RespondError(w, http.StatusNotFound, "Menu not found")
+			return
+		}
+		httputil.RespondQueryError(w, "", err)
+		return
+	}
+
+	if err := db.Create(&item).Error; err != nil {
+		httputil.RespondQueryError(w, "Failed to create menu item: ", err)
+		return
+	}
+
+	httputil.RespondJSON(w, http.StatusCreated, item.ToResponse(isAdmin(r)))
+}
+
+// Batch limits for CreateMenuItemsBatch.
+const (
+	maxBatchItems   = 500
+	insertBatchSize = 100
+)
+
+// CreateMenuItemsBatch handles POST /menus/{id}/items/batch with a JSON array
+// of items. Every item is validated first and problems are reported per
+// index, e.g. "[2].price"; otherwise all items are inserted under the menu in
+// one transaction and returned with their IDs.
+func CreateMenuItemsBatch(w http.ResponseWriter, r *http.Request) {
+	menuID, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+	if err != nil || menuID == 0 {
+		httputil.RespondError(w, http.StatusBadRequest, "Invalid menu ID")
+		return
+	}
+
+	var items []models.MenuItem
+	if err := httputil.DecodeJSON(w, r, &items); err != nil {
+		httputil.RespondDecodeError(w, "Invalid request body", err)
+		return
+	}
+	if len(items) == 0 || len(items) > maxBatchItems {
+		httputil.RespondError(w, http.StatusBadRequest, fmt.Sprintf("batch must contain between 1 and %d items", maxBatchItems))
+		return
+	}
+
+	var errs []FieldError
+	for i := range items {
+		errs = append(errs, validateMenuItem(items[i], fmt.Sprintf("[%d].", i))...)
+		if items[i].MenuID != 0 && items[i].MenuID != uint(menuID) {
+			errs = append(errs, FieldError{Field: fmt.Sprintf("[%d].menu_id", i), Message: "must match the menu in the path"})
+		}
+		items[i].MenuID = uint(menuID)
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return
+	}
+
+	db, cancel := database.WithContext(r.Context())
+	defer cancel()
+	var menu models.Menu
+	if err := db.Select("id").First(&menu, menuID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			httputil.RespondError(w, http.StatusNotFound, "Menu not found")
+			return
+		}
+		httputil.RespondQueryError(w, "", err)
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(&items, insertBatchSize).Error
+	})
+	if err != nil {
+		httputil.RespondQueryError(w, "Failed to create menu items: ", err)
+		return
+	}
+
+	httputil.RespondJSON(w, http.StatusCreated, models.MenuItemsToResponse(items, isAdmin(r)))
+}
+
+// GetMenuItem returns a single menu item by ID.
+func GetMenuItem(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	db, cancel := database.WithContext(r.Context())
+	defer cancel()
+
+	var item models.MenuItem
+	if err := db.First(&item, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			httputil.RespondError(w, http.StatusNotFound, "Menu item not found")
+			return
+		}
+		httputil.RespondQueryError(w, "", err)
+		return
+	}
+
+	httputil.RespondJSON(w, http.StatusOK, item.ToResponse(isAdmin(r)))
+}
+
+var (
+	errMenuNotFound  = errors.New("menu not found")
+	errNotCafeOwner  = errors.New("target user is not a cafe owner")
+	errOwnerNotFound = errors.New("target user not found")
+)
+
+// TransferMenu reassigns a menu to another café owner (admin only).
+func TransferMenu(w http.ResponseWriter, r *http.Request) {
+	if !isAdmin(r) {
+		httputil.RespondError(w, http.StatusForbidden, "Admin privileges required")
+		return
+	}
+
+	var body struct {
+		NewOwnerID uint `json:"new_owner_id"`
+	}
+	if err := httputil.DecodeJSON(w, r, &body); err != nil {
+		httputil.RespondDecodeError(w, "Invalid request body", err)
+		return
+	}
+	if body.NewOwnerID == 0 {
+		httputil.RespondError(w, http.StatusBadRequest, "Invalid request body: new_owner_id is required")
+		return
+	}
+
+	// The owner lives in the user-service; look them up before the
+	// transaction so no database connection is held across the remote call
+	owner, err := Users.GetUser(r.Context(), body.NewOwnerID)
+	switch {
+	case errors.Is(err, ErrUserNotFound):
+		httputil.RespondError(w, http.StatusNotFound, errOwnerNotFound.Error())
+		return
+	case err != nil:
+		httputil.RespondQueryError(w, "Failed to transfer menu: ", err)
+		return
+	case !owner.IsCafeOwner:
+		httputil.RespondError(w, http.StatusConflict, errNotCafeOwner.Error())
+		return
+	}
+
+	db, cancel := database.WithContext(r.Context())
+	defer cancel()
+	var menu models.Menu
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Preload("MenuItems", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id")
+		}).First(&menu, "id = ?", chi.URLParam(r, "id")).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errMenuNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Model(&menu).Update("owner_id", owner.ID).Error
+	})
+
+	switch {
+	case errors.Is(err, errMenuNotFound):
+		httputil.RespondError(w, http.StatusNotFound, err.Error())
+		return
+	case err != nil:
+		httputil.RespondQueryError(w, "Failed to transfer menu: ", err)
+		return
+	}
+
+	httputil.RespondJSON(w, http.StatusOK, menu.ToResponse(true))
+}
@@ -0,0 +1,664 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"httputil"
+	"menu-service/database"
+	"menu-service/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestDB points database.DB at an isolated in-memory SQLite database.
+func setupTestDB(t *testing.T) *gorm.DB {
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", strings.ReplaceAll(t.Name(), "/", "_"))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err, "Failed to open test database")
+	require.NoError(t, db.AutoMigrate(&models.Menu{}, &models.MenuItem{}))
+
+	database.DB = db
+	t.Cleanup(func() {
+		sqlDB, err := db.DB()
+		require.NoError(t, err)
+		sqlDB.Close()
+	})
+	return db
+}
+
+// fakeUsers is an in-memory UserDirectory.
+type fakeUsers map[uint]RemoteUser
+
+func (f fakeUsers) GetUser(ctx context.Context, id uint) (*RemoteUser, error) {
+	u, ok := f[id]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return &u, nil
+}
+
+func withUsers(t *testing.T, dir UserDirectory) {
+	orig := Users
+	Users = dir
+	t.Cleanup(func() { Users = orig })
+}
+
+func withAdmin(t *testing.T) {
+	orig := AdminToken
+	AdminToken = "admin-secret"
+	t.Cleanup(func() { AdminToken = orig })
+}
+
+func newTestRouter() http.Handler {
+	r := chi.NewRouter()
+	r.Post("/menus/{id}/transfer", TransferMenu)
+	return r
+}
+
+func transferRequest(menuID uint, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/menus/%d/transfer", menuID), strings.NewReader(body))
+	req.Header.Set("X-Admin-Token", "admin-secret")
+	return req
+}
+
+func TestTransferMenu(t *testing.T) {
+	db := setupTestDB(t)
+	withAdmin(t)
+	withUsers(t, fakeUsers{
+		2: {ID: 2, IsCafeOwner: true},
+		3: {ID: 3, IsCafeOwner: false},
+	})
+
+	menu := models.Menu{Name: "Breakfast", OwnerID: 1, MenuItems: []models.MenuItem{{Name: "Suja"}, {Name: "Khapse"}}}
+	require.NoError(t, db.Create(&menu).Error)
+
+	t.Run("successful transfer", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newTestRouter().ServeHTTP(rec, transferRequest(menu.ID, `{"new_owner_id":2}`))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		var resp models.MenuResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		assert.Equal(t, uint(2), resp.OwnerID)
+		require.Len(t, resp.MenuItems, 2, "the response includes the menu's items")
+		assert.Equal(t, "Suja", resp.MenuItems[0].Name)
+
+		var stored models.Menu
+		require.NoError(t, db.First(&stored, menu.ID).Error)
+		assert.Equal(t, uint(2), stored.OwnerID)
+	})
+
+	t.Run("target is not a cafe owner", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newTestRouter().ServeHTTP(rec, transferRequest(menu.ID, `{"new_owner_id":3}`))
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		var stored models.Menu
+		require.NoError(t, db.First(&stored, menu.ID).Error)
+		assert.Equal(t, uint(2), stored.OwnerID)
+	})
+
+	t.Run("unknown target user", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newTestRouter().ServeHTTP(rec, transferRequest(menu.ID, `{"new_owner_id":99}`))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("unknown menu", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newTestRouter().ServeHTTP(rec, transferRequest(menu.ID+100, `{"new_owner_id":2}`))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Contains(t, rec.Body.String(), "menu not found")
+	})
+
+	t.Run("requires admin", func(t *testing.T) {
+		req := transferRequest(menu.ID, `{"new_owner_id":2}`)
+		req.Header.Del("X-Admin-Token")
+		rec := httptest.NewRecorder()
+		newTestRouter().ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
+
+func TestGetMenuItemsAvailableNow(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.Create(&[]models.MenuItem{
+		{Name: "Pancakes", AvailableFrom: "07:00", AvailableUntil: "11:00"},
+		{Name: "Late Momo", AvailableFrom: "22:00", AvailableUntil: "02:00"},
+		{Name: "Tea"},
+	}).Error)
+
+	origNow := now
+	defer func() { now = origNow }()
+
+	names := func(query string) []string {
+		rec := httptest.NewRecorder()
+		GetMenuItems(rec, httptest.NewRequest(http.MethodGet, "/items"+query, nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		var items []models.MenuItemResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&items))
+		out := make([]string, len(items))
+		for i, item := range items {
+			out[i] = item.Name
+		}
+		return out
+	}
+
+	now = func() time.Time { return time.Date(2025, 9, 1, 8, 0, 0, 0, time.Local) }
+	assert.Equal(t, []string{"Pancakes", "Tea"}, names("?available_now=true"))
+
+	now = func() time.Time { return time.Date(2025, 9, 1, 1, 30, 0, 0, time.Local) }
+	assert.Equal(t, []string{"Late Momo", "Tea"}, names("?available_now=true"))
+
+	assert.Len(t, names(""), 3)
+
+	rec := httptest.NewRecorder()
+	GetMenuItems(rec, httptest.NewRequest(http.MethodGet, "/items?available_now=maybe", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestGetMenuItemsLinkHeaders(t *testing.T) {
+	db := setupTestDB(t)
+	for i := 1; i <= 5; i++ {
+		require.NoError(t, db.Create(&models.MenuItem{Name: fmt.Sprintf("Item %d", i)}).Error)
+	}
+
+	get := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		GetMenuItems(rec, httptest.NewRequest(http.MethodGet, "/items"+query, nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		return rec
+	}
+
+	middle := get("?offset=2&limit=2")
+	assert.Equal(t, "5", middle.Header().Get("X-Total-Count"))
+	assert.Equal(t, `</items?limit=2&offset=4>; rel="next", </items?limit=2&offset=0>; rel="prev"`, middle.Header().Get("Link"))
+	var items []models.MenuItemResponse
+	require.NoError(t, json.NewDecoder(middle.Body).Decode(&items))
+	require.Len(t, items, 2)
+	assert.Equal(t, "Item 3", items[0].Name)
+
+	assert.NotContains(t, get("?limit=2").Header().Get("Link"), `rel="prev"`)
+	assert.Equal(t, `</items?limit=2&offset=2>; rel="prev"`, get("?offset=4&limit=2").Header().Get("Link"))
+	assert.Empty(t, get("").Header().Get("Link"))
+
+	filtered := get("?available_now=true&limit=2&offset=2")
+	assert.Equal(t, `</items?available_now=true&limit=2&offset=4>; rel="next", </items?available_now=true&limit=2&offset=0>; rel="prev"`,
+		filtered.Header().Get("Link"), "filters carry over into page links")
+
+	huge := get("?available_now=true&limit=20&offset=9223372036854775807")
+	assert.Equal(t, `</items?available_now=true&limit=20&offset=9223372036854775787>; rel="prev"`, huge.Header().Get("Link"))
+	require.NoError(t, json.NewDecoder(huge.Body).Decode(&items))
+	assert.Empty(t, items, "an offset past the end pages to nothing")
+}
+
+func newItemsRouter() http.Handler {
+	r := chi.NewRouter()
+	r.Get("/items", GetMenuItems)
+	r.Post("/items", CreateMenuItem)
+	r.Get("/items/{id}", GetMenuItem)
+	return r
+}
+
+func TestCreateAndGetMenuItem(t *testing.T) {
+	db := setupTestDB(t)
+	menu := models.Menu{Name: "Lunch"}
+	require.NoError(t, db.Create(&menu).Error)
+	router := newItemsRouter()
+
+	rec := httptest.NewRecorder()
+	body := fmt.Sprintf(`{"menu_id": %d, "name": "Momo", "price": 4.5}`, menu.ID)
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(body)))
+	require.Equal(t, http.StatusCreated, rec.Code)
+	var created models.MenuItemResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&created))
+	assert.NotZero(t, created.ID)
+	assert.Equal(t, menu.ID, created.MenuID)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/items/%d", created.ID), nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var fetched models.MenuItemResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&fetched))
+	assert.Equal(t, "Momo", fetched.Name)
+	assert.Equal(t, 4.5, fetched.Price)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var listed []models.MenuItemResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&listed))
+	require.Len(t, listed, 1)
+	assert.Equal(t, created.ID, listed[0].ID)
+
+	var stored models.Menu
+	require.NoError(t, db.Preload("MenuItems").First(&stored, menu.ID).Error)
+	assert.Len(t, stored.MenuItems, 1, "item is linked to its menu")
+}
+
+func TestMenuItemErrors(t *testing.T) {
+	setupTestDB(t)
+	router := newItemsRouter()
+
+	status := func(method, path, body string) int {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusNotFound, status(http.MethodGet, "/items/99", ""))
+	assert.Equal(t, http.StatusNotFound, status(http.MethodPost, "/items", `{"menu_id": 99, "name": "Momo"}`))
+	assert.Equal(t, http.StatusUnprocessableEntity, status(http.MethodPost, "/items", `{"name": "Momo"}`))
+	assert.Equal(t, http.StatusBadRequest, status(http.MethodPost, "/items", `{"name": `))
+}
+
+func TestGetMenuIncludesItems(t *testing.T) {
+	db := setupTestDB(t)
+	menu := models.Menu{Name: "Lunch", MenuItems: []models.MenuItem{
+		{Name: "Momo", Price: 4.5},
+		{Name: "Ema datshi", Price: 6},
+	}}
+	require.NoError(t, db.Create(&menu).Error)
+
+	r := chi.NewRouter()
+	r.Get("/menu/{id}", GetMenu)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/menu/%d", menu.ID), nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var got models.MenuResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+	require.Len(t, got.MenuItems, 2)
+	assert.Equal(t, "Momo", got.MenuItems[0].Name)
+	assert.Equal(t, "Ema datshi", got.MenuItems[1].Name)
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/menu/99", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestGetMenuAsJSONAndCSV(t *testing.T) {
+	db := setupTestDB(t)
+	menu := models.Menu{Name: "Lunch", MenuItems: []models.MenuItem{
+		{Name: "Momo", Description: "Steamed, with chilli", Price: 4.5, Category: "mains"},
+		{Name: "Suja", Price: 1.25, AvailableFrom: "07:00", AvailableUntil: "11:00"},
+	}}
+	require.NoError(t, db.Create(&menu).Error)
+	r := chi.NewRouter()
+	r.Get("/menu/{id}", GetMenu)
+	get := func(accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/menu/%d", menu.ID), nil)
+		req.Header.Set("Accept", accept)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("application/json")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var asJSON models.MenuResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&asJSON))
+	require.Len(t, asJSON.MenuItems, 2)
+
+	rec = get("text/csv")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, fmt.Sprintf("attachment; filename=menu-%d-items.csv", menu.ID), rec.Header().Get("Content-Disposition"))
+	assert.Contains(t, rec.Header().Values("Vary"), "Accept")
+	assert.Empty(t, rec.Header().Get("ETag"), "only the JSON representation is cached")
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, []string{"id", "name", "description", "price", "category", "available_from", "available_until", "created_at", "updated_at"}, rows[0])
+	for i, item := range asJSON.MenuItems {
+		assert.Equal(t, []string{
+			fmt.Sprint(item.ID), item.Name, item.Description, fmt.Sprintf("%.2f", item.Price), item.Category,
+			item.AvailableFrom, item.AvailableUntil, item.CreatedAt, item.UpdatedAt,
+		}, rows[i+1])
+	}
+
+	rec = get("application/xml")
+	assert.Equal(t, http.StatusNotAcceptable, rec.Code)
+	assert.JSONEq(t, `{"error": "not acceptable", "supported": ["application/json", "text/csv"]}`, rec.Body.String())
+	assert.Equal(t, http.StatusOK, get("").Code)
+}
+
+func TestCreateMenuRollsBackOnItemFailure(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, db.Callback().Create().Before("gorm:create").Register("fail_item", func(tx *gorm.DB) {
+		if item, ok := tx.Statement.Dest.(*models.MenuItem); ok && item.Name == "Broken" {
+			tx.AddError(errors.New("constraint violated"))
+		}
+	}))
+	r := chi.NewRouter()
+	r.Post("/menu", CreateMenu)
+
+	body := `{"name": "Dinner", "owner_id": 1, "menu_items": [
+		{"name": "Momo", "price": 4.5, "category": "mains"},
+		{"name": "Broken", "price": 1, "category": "mains"}]}`
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/menu", strings.NewReader(body)))
+
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, rec.Body.String(), "menu_items[1]")
+	var menus, items int64
+	require.NoError(t, db.Unscoped().Model(&models.Menu{}).Count(&menus).Error)
+	require.NoError(t, db.Unscoped().Model(&models.MenuItem{}).Count(&items).Error)
+	assert.Zero(t, menus, "no orphan menu is left behind")
+	assert.Zero(t, items)
+}
+
+func TestCreateMenuWithItems(t *testing.T) {
+	db := setupTestDB(t)
+	r := chi.NewRouter()
+	r.Post("/menu", CreateMenu)
+
+	body := `{"name": "Breakfast", "owner_id": 1, "menu_items": [{"name": "Toast", "price": 2, "category": "mains"}]}`
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/menu", strings.NewReader(body)))
+
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var created models.MenuResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&created))
+	require.Len(t, created.MenuItems, 1)
+
+	var item models.MenuItem
+	require.NoError(t, db.First(&item).Error)
+	assert.Equal(t, created.ID, item.MenuID)
+}
+
+func TestCreateMenuRejectsMalformedBodies(t *testing.T) {
+	db := setupTestDB(t)
+	r := chi.NewRouter()
+	r.Post("/menu", CreateMenu)
+	old := httputil.MaxBodyBytes
+	httputil.MaxBodyBytes = 256
+	t.Cleanup(func() { httputil.MaxBodyBytes = old })
+
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/menu", strings.NewReader(body)))
+		return rec
+	}
+
+	// Unknown fields are caught inside nested items too
+	rec := post(`{"name": "Lunch", "owner_id": 1, "menu_items": [{"name": "Momo", "price": 4.5, "category": "mains", "prise": 5}]}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.JSONEq(t, `{"error": "Invalid request body: unknown field \"prise\""}`, rec.Body.String())
+
+	rec = post(`{"name": "Lunch", "owner_id": 1}garbage`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "single JSON value")
+
+	rec = post(`{"name": "Lunch", "owner_id": 1, "description": "` + strings.Repeat("d", 512) + `"}`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+	var menus int64
+	require.NoError(t, db.Model(&models.Menu{}).Count(&menus).Error)
+	assert.Zero(t, menus, "rejected bodies must not be stored")
+}
+
+func TestGetMenuCompressesLargeMenus(t *testing.T) {
+	db := setupTestDB(t)
+	menu := models.Menu{Name: "All day"}
+	for i := 0; i < 30; i++ {
+		menu.MenuItems = append(menu.MenuItems, models.MenuItem{Name: fmt.Sprintf("Item %d", i), Price: 3})
+	}
+	require.NoError(t, db.Create(&menu).Error)
+
+	r := chi.NewRouter()
+	r.Use(httputil.Compress(httputil.DefaultCompressMinSize))
+	r.Get("/menu/{id}", GetMenu)
+	path := fmt.Sprintf("/menu/%d", menu.ID)
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+	zr, err := gzip.NewReader(rec.Body)
+	require.NoError(t, err)
+	var got models.MenuResponse
+	require.NoError(t, json.NewDecoder(zr).Decode(&got))
+	assert.Len(t, got.MenuItems, 30)
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+	assert.Len(t, got.MenuItems, 30)
+}
+
+func TestGetMenuConditional(t *testing.T) {
+	db := setupTestDB(t)
+	menu := models.Menu{Name: "Lunch", MenuItems: []models.MenuItem{{Name: "Momo", Price: 4.5}}}
+	require.NoError(t, db.Create(&menu).Error)
+
+	r := chi.NewRouter()
+	r.Get("/menu/{id}", GetMenu)
+	path := fmt.Sprintf("/menu/%d", menu.ID)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Equal(t, etag, rec.Header().Get("ETag"))
+	assert.Zero(t, rec.Body.Len())
+
+	require.NoError(t, db.Create(&models.MenuItem{MenuID: menu.ID, Name: "Ema datshi", Price: 6}).Error)
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code, "a new item changes the ETag")
+}
+
+func postBatch(menuID uint, body string) *httptest.ResponseRecorder {
+	r := chi.NewRouter()
+	r.Post("/menus/{id}/items/batch", CreateMenuItemsBatch)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, fmt.Sprintf("/menus/%d/items/batch", menuID), strings.NewReader(body)))
+	return rec
+}
+
+func TestCreateMenuItemsBatch(t *testing.T) {
+	db := setupTestDB(t)
+	menu := models.Menu{Name: "Lunch"}
+	require.NoError(t, db.Create(&menu).Error)
+
+	rec := postBatch(menu.ID, `[
+		{"name": "Momo", "price": 4.5, "category": "mains"},
+		{"name": "Ema datshi", "price": 6, "category": "mains"},
+		{"name": "Suja", "price": 1.5, "category": "drinks"}]`)
+
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var created []models.MenuItemResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&created))
+	require.Len(t, created, 3)
+	for i, item := range created {
+		assert.NotZero(t, item.ID, i)
+		assert.Equal(t, menu.ID, item.MenuID, i)
+	}
+	assert.Equal(t, "Suja", created[2].Name)
+
+	var stored int64
+	require.NoError(t, db.Model(&models.MenuItem{}).Where("menu_id = ?", menu.ID).Count(&stored).Error)
+	assert.EqualValues(t, 3, stored)
+}
+
+func TestCreateMenuItemsBatchRejectsInvalidItems(t *testing.T) {
+	db := setupTestDB(t)
+	menu := models.Menu{Name: "Lunch"}
+	require.NoError(t, db.Create(&menu).Error)
+
+	rec := postBatch(menu.ID, `[
+		{"name": "Momo", "price": 4.5},
+		{"name": "", "price": 2},
+		{"name": "Suja", "price": -1}]`)
+
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	var errs []FieldError
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&errs))
+	assert.Equal(t, []FieldError{
+		{Field: "[1].name", Message: "must not be empty"},
+		{Field: "[2].price", Message: "must not be negative"},
+	}, errs)
+
+	var stored int64
+	require.NoError(t, db.Model(&models.MenuItem{}).Count(&stored).Error)
+	assert.Zero(t, stored, "valid items are not inserted when any item is invalid")
+}
+
+func TestCreateMenuItemsBatchErrors(t *testing.T) {
+	setupTestDB(t)
+
+	rec := postBatch(99, `[{"name": "Momo", "price": 4.5}]`)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	assert.Equal(t, http.StatusBadRequest, postBatch(99, `[]`).Code)
+	assert.Equal(t, http.StatusBadRequest, postBatch(99, `{"name": "Momo"}`).Code)
+}
+
+func TestMenuItemCreatesRejectMalformedBodies(t *testing.T) {
+	db := setupTestDB(t)
+	menu := models.Menu{Name: "Lunch"}
+	require.NoError(t, db.Create(&menu).Error)
+	router := newItemsRouter()
+	old := httputil.MaxBodyBytes
+	httputil.MaxBodyBytes = 256
+	t.Cleanup(func() { httputil.MaxBodyBytes = old })
+
+	postItem := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(body)))
+		return rec
+	}
+	item := fmt.Sprintf(`{"menu_id": %d, "name": "Momo", "price": 4.5}`, menu.ID)
+
+	rec := postItem(item + `{"name": "Suja"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "single JSON value")
+	rec = postItem(fmt.Sprintf(`{"menu_id": %d, "name": "Momo", "prise": 5}`, menu.ID))
+	assert.JSONEq(t, `{"error": "Invalid request body: unknown field \"prise\""}`, rec.Body.String())
+	assert.Equal(t, http.StatusRequestEntityTooLarge, postItem(fmt.Sprintf(`{"menu_id": %d, "name": "%s"}`, menu.ID, strings.Repeat("m", 512))).Code)
+
+	rec = postBatch(menu.ID, `[{"name": "Momo", "price": 4.5}] [{"name": "Suja"}]`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "single JSON value")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, postBatch(menu.ID, `[`+strings.Repeat(`{"name": "Momo", "price": 4.5},`, 20)+`{}]`).Code)
+
+	var stored int64
+	require.NoError(t, db.Model(&models.MenuItem{}).Count(&stored).Error)
+	assert.Zero(t, stored, "rejected bodies must not be stored")
+}
+
+func TestWrongMethodGets405WithAllow(t *testing.T) {
+	r := chi.NewRouter()
+	r.NotFound(httputil.NotFound)
+	r.MethodNotAllowed(httputil.MethodNotAllowed(r))
+	r.Get("/menu/{id}", GetMenu)
+	r.Post("/menu", CreateMenu)
+	r.Get("/items", GetMenuItems)
+	r.Post("/items", CreateMenuItem)
+	r.Post("/menus/{id}/items/batch", CreateMenuItemsBatch)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/menu/1", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, "GET", rec.Header().Get("Allow"))
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/items", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, "GET, POST", rec.Header().Get("Allow"))
+	assert.Contains(t, rec.Body.String(), `"error"`)
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/menus/1/items/batch", nil))
+	assert.Equal(t, "POST", rec.Header().Get("Allow"))
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/menus", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+}
+
+func TestGetMenuStopsAtTheQueryTimeout(t *testing.T) {
+	db := setupTestDB(t)
+	menu := models.Menu{Name: "Lunch", OwnerID: 1}
+	require.NoError(t, db.Create(&menu).Error)
+	// Stand in for a query stuck on a lock
+	require.NoError(t, db.Callback().Query().Before("gorm:query").Register("slow_query", func(tx *gorm.DB) {
+		select {
+		case <-tx.Statement.Context.Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	old := database.QueryTimeout
+	database.QueryTimeout = 20 * time.Millisecond
+	t.Cleanup(func() { database.QueryTimeout = old })
+	r := chi.NewRouter()
+	r.Get("/menus/{id}", GetMenu)
+
+	start := time.Now()
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/menus/%d", menu.ID), nil))
+	assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+	assert.Less(t, time.Since(start), 500*time.Millisecond, "the handler does not wait for the query")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/menus/%d", menu.ID), nil).WithContext(ctx))
+	assert.Equal(t, httputil.StatusClientClosedRequest, rec.Code)
+}
+
+func TestCreateMenuIdempotencyKey(t *testing.T) {
+	db := setupTestDB(t)
+	r := chi.NewRouter()
+	r.With(httputil.NewIdempotency(httputil.DefaultIdempotencyTTL).Wrap).Post("/menu", CreateMenu)
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/menu", strings.NewReader(body))
+		req.Header.Set("Idempotency-Key", "retry-1")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	body := `{"name": "Breakfast", "owner_id": 1, "menu_items": [{"name": "Toast", "price": 2, "category": "mains"}]}`
+
+	first := post(body)
+	require.Equal(t, http.StatusCreated, first.Code, first.Body.String())
+	replay := post(body)
+	assert.Equal(t, http.StatusCreated, replay.Code)
+	assert.Equal(t, "true", replay.Header().Get("Idempotent-Replayed"))
+	assert.JSONEq(t, first.Body.String(), replay.Body.String())
+
+	assert.Equal(t, http.StatusUnprocessableEntity, post(`{"name": "Dinner", "owner_id": 1}`).Code)
+
+	var menus, items int64
+	require.NoError(t, db.Model(&models.Menu{}).Count(&menus).Error)
+	require.NoError(t, db.Model(&models.MenuItem{}).Count(&items).Error)
+	assert.EqualValues(t, 1, menus, "only the first request inserts")
+	assert.EqualValues(t, 1, items)
+}
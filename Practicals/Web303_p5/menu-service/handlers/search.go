@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"fmt"
+	"httputil"
+	"menu-service/database"
+	"menu-service/models"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Search limits: shorter terms match nearly everything, and each of the
+// menu and item queries returns at most maxSearchResults rows.
+const (
+	minSearchLength  = 2
+	maxSearchResults = 50
+)
+
+// SearchMatch is one menu in a search result. MenuItems holds only the items
+// whose name matched; MenuMatched is set when the menu's own name or
+// description matched.
+type SearchMatch struct {
+	models.MenuResponse
+	MenuMatched bool `json:"menu_matched"`
+}
+
+// SearchResult is the response body of Search.
+type SearchResult struct {
+	Query string        `json:"query"`
+	Menus []SearchMatch `json:"menus"`
+	// Truncated reports that more menus or items matched than were returned.
+	Truncated bool `json:"truncated"`
+}
+
+// likeEscaper escapes LIKE wildcards so the search term matches literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// Search handles GET /search?q= with a case-insensitive substring match on
+// menu names and descriptions and on item names, grouping matching items
+// under their menus.
+func Search(w http.ResponseWriter, r *http.Request) {
+	term := strings.TrimSpace(r.URL.Query().Get("q"))
+	if utf8.RuneCountInString(term) < minSearchLength {
+		httputil.RespondError(w, http.StatusBadRequest, fmt.Sprintf("q must be at least %d characters", minSearchLength))
+		return
+	}
+	pattern := "%" + likeEscaper.Replace(strings.ToLower(term)) + "%"
+
+	db, cancel := database.WithContext(r.Context())
+	defer cancel()
+
+	var menus []models.Menu
+	err := db.
+		Where(`LOWER(name) LIKE ? ESCAPE '\' OR LOWER(description) LIKE ? ESCAPE '\'`, pattern, pattern).
+		Order("id").Limit(maxSearchResults + 1).Find(&menus).Error
+	if err != nil {
+		httputil.RespondQueryError(w, "Search failed: ", err)
+		return
+	}
+	var items []models.MenuItem
+	err = db.Where(`LOWER(name) LIKE ? ESCAPE '\'`, pattern).
+		Order("menu_id, id").Limit(maxSearchResults + 1).Find(&items).Error
+	if err != nil {
+		httputil.RespondQueryError(w, "Search failed: ", err)
+		return
+	}
+
+	result := SearchResult{Query: term, Menus: []SearchMatch{}}
+	if len(menus) > maxSearchResults {
+		menus, result.Truncated = menus[:maxSearchResults], true
+	}
+	if len(items) > maxSearchResults {
+		items, result.Truncated = items[:maxSearchResults], true
+	}
+
+	byID := make(map[uint]*SearchMatch, len(menus))
+	for _, menu := range menus {
+		byID[menu.ID] = &SearchMatch{MenuResponse: menu.ToResponse(isAdmin(r)), MenuMatched: true}
+	}
+
+	// Matching items may belong to menus that did not match themselves
+	var missing []uint
+	for _, item := range items {
+		if _, ok := byID[item.MenuID]; !ok {
+			missing = append(missing, item.MenuID)
+		}
+	}
+	if len(missing) > 0 {
+		var parents []models.Menu
+		if err := db.Where("id IN ?", missing).Find(&parents).Error; err != nil {
+			httputil.RespondQueryError(w, "Search failed: ", err)
+			return
+		}
+		for _, menu := range parents {
+			byID[menu.ID] = &SearchMatch{MenuResponse: menu.ToResponse(isAdmin(r))}
+		}
+	}
+
+	for _, item := range items {
+		// Items of soft-deleted menus have no parent to list them under
+		if match, ok := byID[item.MenuID]; ok {
+			match.MenuItems = append(match.MenuItems, item.ToResponse(isAdmin(r)))
+		}
+	}
+	for _, match := range byID {
+		result.Menus = append(result.Menus, *match)
+	}
+	sort.Slice(result.Menus, func(i, j int) bool { return result.Menus[i].ID < result.Menus[j].ID })
+
+	httputil.RespondJSON(w, http.StatusOK, result)
+}
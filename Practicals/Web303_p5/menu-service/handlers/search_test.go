@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"menu-service/models"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func search(t *testing.T, q string) (*httptest.ResponseRecorder, SearchResult) {
+	rec := httptest.NewRecorder()
+	Search(rec, httptest.NewRequest(http.MethodGet, "/search?q="+url.QueryEscape(q), nil))
+	var result SearchResult
+	if rec.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	}
+	return rec, result
+}
+
+func seedSearchMenus(t *testing.T, db *gorm.DB) (breakfast, lunch, drinks models.Menu) {
+	breakfast = models.Menu{Name: "Breakfast", Description: "Morning MOMO specials", MenuItems: []models.MenuItem{
+		{Name: "Toast", Price: 2},
+	}}
+	lunch = models.Menu{Name: "Lunch", MenuItems: []models.MenuItem{
+		{Name: "Veg Momo", Price: 4.5},
+		{Name: "Ema datshi", Price: 6},
+		{Name: "Chicken momo", Price: 5},
+	}}
+	drinks = models.Menu{Name: "Drinks", MenuItems: []models.MenuItem{
+		{Name: "Suja", Price: 1.5},
+	}}
+	for _, menu := range []*models.Menu{&breakfast, &lunch, &drinks} {
+		require.NoError(t, db.Create(menu).Error)
+	}
+	return breakfast, lunch, drinks
+}
+
+func TestSearchGroupsItemsUnderMenus(t *testing.T) {
+	db := setupTestDB(t)
+	breakfast, lunch, _ := seedSearchMenus(t, db)
+
+	rec, result := search(t, "moMo")
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	assert.Equal(t, "moMo", result.Query)
+	assert.False(t, result.Truncated)
+	require.Len(t, result.Menus, 2)
+
+	assert.Equal(t, breakfast.ID, result.Menus[0].ID)
+	assert.True(t, result.Menus[0].MenuMatched, "matched on description")
+	assert.Empty(t, result.Menus[0].MenuItems, "Toast does not match")
+
+	assert.Equal(t, lunch.ID, result.Menus[1].ID)
+	assert.False(t, result.Menus[1].MenuMatched)
+	require.Len(t, result.Menus[1].MenuItems, 2)
+	assert.Equal(t, "Veg Momo", result.Menus[1].MenuItems[0].Name)
+	assert.Equal(t, "Chicken momo", result.Menus[1].MenuItems[1].Name)
+}
+
+func TestSearchNoMatches(t *testing.T) {
+	db := setupTestDB(t)
+	seedSearchMenus(t, db)
+
+	rec, result := search(t, "pizza")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, result.Menus)
+	assert.JSONEq(t, `{"query": "pizza", "menus": [], "truncated": false}`, rec.Body.String())
+
+	_, result = search(t, "100%")
+	assert.Empty(t, result.Menus, "LIKE wildcards in the term match literally")
+}
+
+func TestSearchValidatesAndCapsResults(t *testing.T) {
+	db := setupTestDB(t)
+
+	rec, _ := search(t, " m ")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	menu := models.Menu{Name: "Everything"}
+	for i := 0; i < maxSearchResults+5; i++ {
+		menu.MenuItems = append(menu.MenuItems, models.MenuItem{Name: fmt.Sprintf("Tea %d", i), Price: 1})
+	}
+	require.NoError(t, db.Create(&menu).Error)
+
+	rec, result := search(t, "tea")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, result.Truncated)
+	require.Len(t, result.Menus, 1)
+	assert.Len(t, result.Menus[0].MenuItems, maxSearchResults)
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"httputil"
+	"menu-service/database"
+	"menu-service/models"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+// MenuStats summarises the prices of a menu's items. All values are zero for
+// a menu without items.
+type MenuStats struct {
+	MenuID     uint    `json:"menu_id"`
+	ItemCount  int64   `json:"item_count"`
+	MinPrice   float64 `json:"min_price"`
+	MaxPrice   float64 `json:"max_price"`
+	AvgPrice   float64 `json:"avg_price"`
+	TotalPrice float64 `json:"total_price"`
+}
+
+// GetMenuStats handles GET /menus/{id}/stats, computing the figures in a
+// single aggregate query instead of loading the items.
+func GetMenuStats(w http.ResponseWriter, r *http.Request) {
+	menuID := chi.URLParam(r, "id")
+	db, cancel := database.WithContext(r.Context())
+	defer cancel()
+
+	var menu models.Menu
+	if err := db.Select("id").Where("id = ?", menuID).First(&menu).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			httputil.RespondError(w, http.StatusNotFound, "Menu not found")
+			return
+		}
+		httputil.RespondQueryError(w, "", err)
+		return
+	}
+
+	stats := MenuStats{MenuID: menu.ID}
+	err := db.Model(&models.MenuItem{}).
+		Select(`COUNT(*) AS item_count,
+			COALESCE(MIN(price), 0) AS min_price,
+			COALESCE(MAX(price), 0) AS max_price,
+			COALESCE(AVG(price), 0) AS avg_price,
+			COALESCE(SUM(price), 0) AS total_price`).
+		Where("menu_id = ?", menu.ID).
+		Scan(&stats).Error
+	if err != nil {
+		httputil.RespondQueryError(w, "Failed to compute menu stats: ", err)
+		return
+	}
+
+	httputil.RespondJSON(w, http.StatusOK, stats)
+}
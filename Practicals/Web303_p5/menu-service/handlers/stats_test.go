@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"menu-service/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getStats(menuID uint) *httptest.ResponseRecorder {
+	r := chi.NewRouter()
+	r.Get("/menus/{id}/stats", GetMenuStats)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/menus/%d/stats", menuID), nil))
+	return rec
+}
+
+func TestGetMenuStats(t *testing.T) {
+	db := setupTestDB(t)
+	menu := models.Menu{Name: "Lunch", MenuItems: []models.MenuItem{
+		{Name: "Momo", Price: 4.5},
+		{Name: "Ema datshi", Price: 6},
+		{Name: "Suja", Price: 1.5},
+		{Name: "Retired", Price: 100},
+	}}
+	require.NoError(t, db.Create(&menu).Error)
+	require.NoError(t, db.Delete(&menu.MenuItems[3]).Error)
+	require.NoError(t, db.Create(&models.Menu{Name: "Other", MenuItems: []models.MenuItem{{Name: "Toast", Price: 50}}}).Error)
+
+	rec := getStats(menu.ID)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var stats MenuStats
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&stats))
+
+	assert.Equal(t, menu.ID, stats.MenuID)
+	assert.EqualValues(t, 3, stats.ItemCount, "deleted items and other menus are excluded")
+	assert.Equal(t, 1.5, stats.MinPrice)
+	assert.Equal(t, 6.0, stats.MaxPrice)
+	assert.InDelta(t, 4.0, stats.AvgPrice, 1e-9)
+	assert.Equal(t, 12.0, stats.TotalPrice)
+}
+
+func TestGetMenuStatsEmptyAndMissing(t *testing.T) {
+	db := setupTestDB(t)
+	menu := models.Menu{Name: "Empty"}
+	require.NoError(t, db.Create(&menu).Error)
+
+	rec := getStats(menu.ID)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, fmt.Sprintf(`{"menu_id": %d, "item_count": 0, "min_price": 0, "max_price": 0, "avg_price": 0, "total_price": 0}`, menu.ID), rec.Body.String())
+
+	assert.Equal(t, http.StatusNotFound, getStats(99).Code)
+}
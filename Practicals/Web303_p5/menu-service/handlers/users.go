@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// ErrUserNotFound is returned by a UserDirectory when the user does not exist.
+var ErrUserNotFound = errors.New("user not found")
+
+// RemoteUser is the subset of a user-service record the menu-service needs.
+type RemoteUser struct {
+	ID          uint `json:"ID"`
+	IsCafeOwner bool `json:"is_cafe_owner"`
+}
+
+// UserDirectory looks up users owned by the user-service.
+type UserDirectory interface {
+	GetUser(ctx context.Context, id uint) (*RemoteUser, error)
+}
+
+// Users is the directory used by handlers that need to verify users.
+var Users UserDirectory = NewHTTPUserDirectory(userServiceURL())
+
+func userServiceURL() string {
+	if u := os.Getenv("USER_SERVICE_URL"); u != "" {
+		return u
+	}
+	return "http://user-service:8081"
+}
+
+// HTTPUserDirectory resolves users through the user-service REST API.
+type HTTPUserDirectory struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewHTTPUserDirectory creates a directory backed by the user-service at baseURL.
+func NewHTTPUserDirectory(baseURL string) *HTTPUserDirectory {
+	return &HTTPUserDirectory{baseURL: baseURL, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+// GetUser fetches a user by ID, returning ErrUserNotFound on a 404.
+func (d *HTTPUserDirectory) GetUser(ctx context.Context, id uint) (*RemoteUser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/users/%d", d.baseURL, id), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("user-service request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, ErrUserNotFound
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("user-service returned %d", resp.StatusCode)
+	}
+
+	var user RemoteUser
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return nil, fmt.Errorf("invalid user-service response: %w", err)
+	}
+	return &user, nil
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"fmt"
+	"httputil"
+	"menu-service/models"
+	"net/http"
+	"strings"
+)
+
+// FieldError describes one invalid field in a request body. Field uses the
+// JSON names, e.g. "menu_items[2].price".
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// validateMenu checks a menu and its embedded items, returning every problem
+// found rather than stopping at the first.
+func validateMenu(menu models.Menu) []FieldError {
+	var errs []FieldError
+	if strings.TrimSpace(menu.Name) == "" {
+		errs = append(errs, FieldError{Field: "name", Message: "must not be empty"})
+	}
+	for i, item := range menu.MenuItems {
+		errs = append(errs, validateMenuItem(item, fmt.Sprintf("menu_items[%d].", i))...)
+	}
+	return errs
+}
+
+// validateMenuItem checks one item, prefixing field names with prefix.
+func validateMenuItem(item models.MenuItem, prefix string) []FieldError {
+	var errs []FieldError
+	if strings.TrimSpace(item.Name) == "" {
+		errs = append(errs, FieldError{Field: prefix + "name", Message: "must not be empty"})
+	}
+	if item.Price < 0 {
+		errs = append(errs, FieldError{Field: prefix + "price", Message: "must not be negative"})
+	}
+	if err := item.ValidateAvailability(); err != nil {
+		errs = append(errs, FieldError{Field: prefix + "availability", Message: err.Error()})
+	}
+	return errs
+}
+
+// writeValidationErrors responds 422 with the field errors as a JSON array.
+func writeValidationErrors(w http.ResponseWriter, errs []FieldError) {
+	httputil.RespondJSON(w, http.StatusUnprocessableEntity, errs)
+}
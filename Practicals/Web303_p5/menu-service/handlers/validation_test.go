@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createMenu(body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	CreateMenu(rec, httptest.NewRequest(http.MethodPost, "/menus", strings.NewReader(body)))
+	return rec
+}
+
+func TestCreateMenuValidation(t *testing.T) {
+	setupTestDB(t)
+
+	tests := []struct {
+		name, body string
+		want       []FieldError
+	}{
+		{"empty menu name", `{"name": " "}`,
+			[]FieldError{{"name", "must not be empty"}}},
+		{"blank item name", `{"name": "Lunch", "menu_items": [{"name": "", "price": 5}]}`,
+			[]FieldError{{"menu_items[0].name", "must not be empty"}}},
+		{"negative price", `{"name": "Lunch", "menu_items": [{"name": "Ema datshi", "price": 5}, {"name": "Momo", "price": -1}]}`,
+			[]FieldError{{"menu_items[1].price", "must not be negative"}}},
+		{"bad availability", `{"name": "Lunch", "menu_items": [{"name": "Momo", "available_from": "noon", "available_until": "14:00"}]}`,
+			[]FieldError{{"menu_items[0].availability", `invalid time of day "noon", expected HH:MM`}}},
+		{"empty availability window", `{"name": "Lunch", "menu_items": [{"name": "Momo", "available_from": "12:00", "available_until": "12:00"}]}`,
+			[]FieldError{{"menu_items[0].availability", "available_from and available_until must differ; leave both empty for all-day items"}}},
+		{"every error reported", `{"menu_items": [{"price": -2}]}`,
+			[]FieldError{
+				{"name", "must not be empty"},
+				{"menu_items[0].name", "must not be empty"},
+				{"menu_items[0].price", "must not be negative"},
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := createMenu(tt.body)
+			require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+			var got []FieldError
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCreateMenuValidInput(t *testing.T) {
+	setupTestDB(t)
+
+	rec := createMenu(`{"name": "Lunch", "menu_items": [{"name": "Momo", "price": 0}]}`)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"name":"Lunch"`)
+}
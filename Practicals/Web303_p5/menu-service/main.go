@@ -1,40 +1,108 @@
 package main
 
 import (
+	"dbutil"
+	"fmt"
+	"httputil"
 	"log"
-	"net/http"
-	"os"
+	"menu-service/config"
 	"menu-service/database"
 	"menu-service/handlers"
+	"menu-service/models"
+	"net/http"
+	"os"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
 func main() {
-	// Connect to dedicated menu database
-	dsn := os.Getenv("DATABASE_URL")
-	if dsn == "" {
-		dsn = "host=localhost user=postgres password=postgres dbname=menu_db port=5432 sslmode=disable"
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	database.QueryLogLevel = dbutil.QueryLogLevelFor(cfg.LogLevel)
+
+	if raw := os.Getenv("SLOW_QUERY_THRESHOLD"); raw != "" {
+		threshold, err := time.ParseDuration(raw)
+		if err != nil {
+			log.Fatalf("Invalid SLOW_QUERY_THRESHOLD: %v", err)
+		}
+		database.SlowQueryThreshold = threshold
+	}
+
+	database.Pool = database.PoolConfig{
+		MaxOpenConns:    cfg.DBMaxOpenConns,
+		MaxIdleConns:    cfg.DBMaxIdleConns,
+		ConnMaxLifetime: cfg.DBConnMaxLifetime,
 	}
+	database.ConnectRetry = cfg.DBConnectRetry
+	database.QueryTimeout = cfg.QueryTimeout
 
-	if err := database.Connect(dsn); err != nil {
+	// Connect to dedicated menu database
+	if err := database.Connect(cfg.DatabaseDSN); err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
+	// `menu-service migrate` applies pending schema migrations and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := database.Migrate(database.DB); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+	if err := database.EnsureSchema(database.DB, cfg.MigrateOnBoot); err != nil {
+		log.Fatalf("Database schema not ready: %v", err)
+	}
+	if cfg.SeedData {
+		inserted, err := database.Seed(database.DB, cfg.SeedFile)
+		if err != nil {
+			log.Fatalf("Seeding from %s failed: %v", cfg.SeedFile, err)
+		}
+		log.Printf("Seeded %d menus from %s", inserted, cfg.SeedFile)
+	}
+
+	// Dev/test only: explain slow queries to spot missing indexes
+	if dbutil.IndexAdvisorAllowed(os.Getenv("APP_ENV")) {
+		dbutil.EnableIndexAdvisor(database.DB, database.SlowQueryThreshold)
+		log.Println("Index advisor enabled")
+	}
+
+	if format := os.Getenv("JSON_TIME_FORMAT"); format != "" {
+		models.TimeFormat = format
+	}
+
+	httputil.MaxBodyBytes = int64(cfg.MaxBodyBytes)
+
 	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
 	r.Use(middleware.Logger)
+	r.Use(httputil.Recover)
+	r.Use(httputil.Compress(httputil.DefaultCompressMinSize))
+	r.NotFound(httputil.NotFound)
+	r.MethodNotAllowed(httputil.MethodNotAllowed(r))
+
+	r.Get("/healthz", handlers.Liveness)
+	r.Get("/readyz", handlers.ReadinessCheck)
+	r.Get("/openapi.json", handlers.OpenAPI)
+	r.Get("/docs", handlers.Docs)
+
+	// Retried creates replay their first response
+	idempotency := httputil.NewIdempotency(cfg.IdempotencyTTL)
 
 	// Menu endpoints (note: no /api prefix)
 	r.Get("/menu/{id}", handlers.GetMenu)
-	r.Post("/menu", handlers.CreateMenu)
-	// r.Get("/menu/{id}", handlers.GetMenuItem)
-
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8082"
-	}
+	r.With(idempotency.Wrap).Post("/menu", handlers.CreateMenu)
+	r.Post("/menus/{id}/transfer", handlers.TransferMenu)
+	r.Post("/menus/{id}/items/batch", handlers.CreateMenuItemsBatch)
+	r.Get("/menus/{id}/stats", handlers.GetMenuStats)
+	r.Get("/items", handlers.GetMenuItems)
+	r.Post("/items", handlers.CreateMenuItem)
+	r.Get("/items/{id}", handlers.GetMenuItem)
+	r.Post("/items/import", handlers.ImportMenuItems)
+	r.Get("/search", handlers.Search)
 
-	log.Printf("Menu service starting on :%s", port)
-	http.ListenAndServe(":"+port, r)
+	log.Printf("Menu service starting on :%d", cfg.ServicePort)
+	http.ListenAndServe(fmt.Sprintf(":%d", cfg.ServicePort), r)
 }
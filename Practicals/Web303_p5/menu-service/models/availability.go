@@ -0,0 +1,62 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// availabilityLayout is the time-of-day format for availability windows.
+const availabilityLayout = "15:04"
+
+// parseTimeOfDay converts "HH:MM" into minutes since midnight.
+func parseTimeOfDay(s string) (int, error) {
+	t, err := time.Parse(availabilityLayout, s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// ValidateAvailability checks that any configured window uses HH:MM times
+// and is not empty. A window that starts and ends at the same minute would
+// never be open; all-day items leave both bounds unset instead.
+func (i MenuItem) ValidateAvailability() error {
+	for _, s := range []string{i.AvailableFrom, i.AvailableUntil} {
+		if s == "" {
+			continue
+		}
+		if _, err := parseTimeOfDay(s); err != nil {
+			return err
+		}
+	}
+	if i.AvailableFrom != "" && i.AvailableUntil != "" {
+		from, _ := parseTimeOfDay(i.AvailableFrom)
+		until, _ := parseTimeOfDay(i.AvailableUntil)
+		if from == until {
+			return errors.New("available_from and available_until must differ; leave both empty for all-day items")
+		}
+	}
+	return nil
+}
+
+// AvailableAt reports whether the item is served at the time of day of t.
+// Windows where AvailableFrom is later than AvailableUntil wrap past
+// midnight (e.g. 22:00-02:00). Missing or malformed bounds mean the item is
+// always available.
+func (i MenuItem) AvailableAt(t time.Time) bool {
+	if i.AvailableFrom == "" || i.AvailableUntil == "" {
+		return true
+	}
+	from, errFrom := parseTimeOfDay(i.AvailableFrom)
+	until, errUntil := parseTimeOfDay(i.AvailableUntil)
+	if errFrom != nil || errUntil != nil {
+		return true
+	}
+
+	current := t.Hour()*60 + t.Minute()
+	if from <= until {
+		return current >= from && current < until
+	}
+	return current >= from || current < until
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func at(hour, minute int) time.Time {
+	return time.Date(2025, 9, 1, hour, minute, 0, 0, time.Local)
+}
+
+func TestMenuItemAvailableAt(t *testing.T) {
+	breakfast := MenuItem{AvailableFrom: "07:00", AvailableUntil: "11:00"}
+	lateNight := MenuItem{AvailableFrom: "22:00", AvailableUntil: "02:00"}
+
+	tests := []struct {
+		name string
+		item MenuItem
+		when time.Time
+		want bool
+	}{
+		{"in window", breakfast, at(8, 30), true},
+		{"window start is inclusive", breakfast, at(7, 0), true},
+		{"window end is exclusive", breakfast, at(11, 0), false},
+		{"out of window", breakfast, at(15, 0), false},
+		{"overnight before midnight", lateNight, at(23, 15), true},
+		{"overnight after midnight", lateNight, at(1, 59), true},
+		{"overnight out of window", lateNight, at(12, 0), false},
+		{"empty window always available", MenuItem{}, at(3, 0), true},
+		{"half-open window always available", MenuItem{AvailableFrom: "09:00"}, at(3, 0), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.item.AvailableAt(tt.when))
+		})
+	}
+}
+
+func TestMenuItemValidateAvailability(t *testing.T) {
+	assert.NoError(t, MenuItem{AvailableFrom: "07:00", AvailableUntil: "23:59"}.ValidateAvailability())
+	assert.Error(t, MenuItem{AvailableFrom: "7am"}.ValidateAvailability())
+	assert.ErrorContains(t, MenuItem{AvailableFrom: "09:00", AvailableUntil: "09:00"}.ValidateAvailability(), "must differ")
+}
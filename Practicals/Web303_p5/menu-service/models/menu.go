@@ -4,14 +4,92 @@ import "gorm.io/gorm"
 
 type Menu struct {
 	gorm.Model
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	MenuItems   []MenuItem `json:"menu_items" gorm:"references:MenuID"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	OwnerID     uint       `json:"owner_id" gorm:"index"`
+	MenuItems   []MenuItem `json:"menu_items" gorm:"foreignKey:MenuID"`
 }
 
 type MenuItem struct {
 	gorm.Model
+	MenuID      uint    `json:"menu_id" gorm:"index"`
 	Name        string  `json:"name"`
 	Description string  `json:"description"`
 	Price       float64 `json:"price"`
+	Category    string  `json:"category"`
+	// AvailableFrom and AvailableUntil bound the "15:04" time-of-day window
+	// the item is served in; leaving either empty means always available.
+	AvailableFrom  string `json:"available_from"`
+	AvailableUntil string `json:"available_until"`
+}
+
+// MenuResponse is the JSON shape of a Menu returned by the API.
+// ID and the timestamps keep the keys gorm.Model serialised to, which
+// clients already read.
+type MenuResponse struct {
+	ID          uint               `json:"ID"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	OwnerID     uint               `json:"owner_id"`
+	MenuItems   []MenuItemResponse `json:"menu_items"`
+	CreatedAt   string             `json:"CreatedAt"`
+	UpdatedAt   string             `json:"UpdatedAt"`
+	DeletedAt   *string            `json:"DeletedAt,omitempty"`
+}
+
+// MenuItemResponse is the JSON shape of a MenuItem returned by the API,
+// with the same gorm.Model keys as MenuResponse.
+type MenuItemResponse struct {
+	ID             uint    `json:"ID"`
+	MenuID         uint    `json:"menu_id"`
+	Name           string  `json:"name"`
+	Description    string  `json:"description"`
+	Price          float64 `json:"price"`
+	Category       string  `json:"category,omitempty"`
+	AvailableFrom  string  `json:"available_from,omitempty"`
+	AvailableUntil string  `json:"available_until,omitempty"`
+	CreatedAt      string  `json:"CreatedAt"`
+	UpdatedAt      string  `json:"UpdatedAt"`
+	DeletedAt      *string `json:"DeletedAt,omitempty"`
+}
+
+// ToResponse converts the menu and its items to their API representation.
+// DeletedAt is only included when includeDeleted is set (admin callers).
+func (m Menu) ToResponse(includeDeleted bool) MenuResponse {
+	return MenuResponse{
+		ID:          m.ID,
+		Name:        m.Name,
+		Description: m.Description,
+		OwnerID:     m.OwnerID,
+		MenuItems:   MenuItemsToResponse(m.MenuItems, includeDeleted),
+		CreatedAt:   formatTime(m.CreatedAt),
+		UpdatedAt:   formatTime(m.UpdatedAt),
+		DeletedAt:   formatDeletedAt(m.DeletedAt, includeDeleted),
+	}
+}
+
+// ToResponse converts the item to its API representation.
+func (i MenuItem) ToResponse(includeDeleted bool) MenuItemResponse {
+	return MenuItemResponse{
+		ID:             i.ID,
+		MenuID:         i.MenuID,
+		Name:           i.Name,
+		Description:    i.Description,
+		Price:          i.Price,
+		Category:       i.Category,
+		AvailableFrom:  i.AvailableFrom,
+		AvailableUntil: i.AvailableUntil,
+		CreatedAt:      formatTime(i.CreatedAt),
+		UpdatedAt:      formatTime(i.UpdatedAt),
+		DeletedAt:      formatDeletedAt(i.DeletedAt, includeDeleted),
+	}
+}
+
+// MenuItemsToResponse converts a slice of items to their API representation.
+func MenuItemsToResponse(items []MenuItem, includeDeleted bool) []MenuItemResponse {
+	out := make([]MenuItemResponse, len(items))
+	for i, item := range items {
+		out[i] = item.ToResponse(includeDeleted)
+	}
+	return out
 }
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestMenuToResponseFormatsNestedTimestamps(t *testing.T) {
+	local := time.FixedZone("BTT", 6*60*60)
+	stamp := time.Date(2025, 9, 1, 12, 0, 0, 0, local)
+
+	item := MenuItem{Name: "Ema Datshi", Price: 4.5}
+	item.CreatedAt, item.UpdatedAt = stamp, stamp
+	item.DeletedAt = gorm.DeletedAt{Time: stamp, Valid: true}
+
+	menu := Menu{Name: "Lunch", MenuItems: []MenuItem{item}}
+	menu.CreatedAt, menu.UpdatedAt = stamp, stamp
+
+	resp := menu.ToResponse(false)
+	assert.Equal(t, "2025-09-01T06:00:00Z", resp.CreatedAt)
+	require.Len(t, resp.MenuItems, 1)
+	assert.Equal(t, "2025-09-01T06:00:00Z", resp.MenuItems[0].UpdatedAt)
+
+	body, err := json.Marshal(resp)
+	require.NoError(t, err)
+	assert.NotContains(t, string(body), "DeletedAt")
+	assert.Contains(t, string(body), `"CreatedAt":"2025-09-01T06:00:00Z"`, "gorm.Model keys are kept for existing clients")
+	assert.Contains(t, string(body), `"ID":0`)
+
+	admin := menu.ToResponse(true)
+	require.NotNil(t, admin.MenuItems[0].DeletedAt)
+	assert.Nil(t, admin.DeletedAt)
+}
@@ -0,0 +1,104 @@
+// Package migrate runs the ordered schema migrations of the p5 services.
+package migrate
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration is one ordered schema change. Versions are never reused: add a
+// new entry to a service's migrations rather than editing an applied one.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+}
+
+// SchemaMigration records an applied migration in schema_migrations.
+type SchemaMigration struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (SchemaMigration) TableName() string { return "schema_migrations" }
+
+// Runner applies one service's schema history.
+type Runner struct {
+	// Migrations is the schema history, in any order.
+	Migrations []Migration
+	// Command is the CLI invocation that applies them, named in the error
+	// EnsureSchema returns, e.g. "user-service migrate".
+	Command string
+}
+
+// Migrate applies every migration not yet recorded in schema_migrations, in
+// version order and each in its own transaction, so re-runs are no-ops.
+func (r Runner) Migrate(db *gorm.DB) error {
+	pending, err := r.Pending(db)
+	if err != nil {
+		return err
+	}
+	for _, m := range pending {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+		log.Printf("Applied migration %d: %s", m.Version, m.Name)
+	}
+	return nil
+}
+
+// Pending lists the migrations that have not been applied yet.
+func (r Runner) Pending(db *gorm.DB) ([]Migration, error) {
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, fmt.Errorf("creating schema_migrations: %w", err)
+	}
+	var applied []int
+	if err := db.Model(&SchemaMigration{}).Pluck("version", &applied).Error; err != nil {
+		return nil, err
+	}
+	done := make(map[int]bool, len(applied))
+	for _, v := range applied {
+		done[v] = true
+	}
+
+	ordered := append([]Migration(nil), r.Migrations...)
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].Version < ordered[j].Version })
+	var pending []Migration
+	for i, m := range ordered {
+		if i > 0 && ordered[i-1].Version == m.Version {
+			return nil, fmt.Errorf("duplicate migration version %d", m.Version)
+		}
+		if !done[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// EnsureSchema runs pending migrations when migrateOnBoot is set. Otherwise
+// it refuses to start against an outdated schema, leaving the upgrade to an
+// explicit r.Command.
+func (r Runner) EnsureSchema(db *gorm.DB, migrateOnBoot bool) error {
+	if migrateOnBoot {
+		return r.Migrate(db)
+	}
+	pending, err := r.Pending(db)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%d pending migrations, run `%s`", len(pending), r.Command)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"envconfig"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lookupFrom(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := load(lookupFrom(nil))
+	require.NoError(t, err)
+
+	assert.Equal(t, 8083, cfg.ServicePort)
+	assert.Contains(t, cfg.DatabaseDSN, "dbname=order_db")
+	assert.Equal(t, "info", cfg.LogLevel)
+	assert.True(t, cfg.MigrateOnBoot)
+	assert.Equal(t, 25, cfg.DBMaxOpenConns)
+	assert.Equal(t, 5, cfg.DBMaxIdleConns)
+	assert.Equal(t, 30*time.Minute, cfg.DBConnMaxLifetime)
+	assert.Equal(t, time.Minute, cfg.DBConnectRetry)
+	assert.Equal(t, 5*time.Second, cfg.QueryTimeout)
+}
+
+func TestLoadEnvOverrides(t *testing.T) {
+	cfg, err := load(lookupFrom(map[string]string{
+		"PORT":                 "9090",
+		"DATABASE_URL":         "postgres://users@db/order_db",
+		"LOG_LEVEL":            "DEBUG",
+		"MIGRATE_ON_BOOT":      "false",
+		"DB_MAX_OPEN_CONNS":    "10",
+		"DB_MAX_IDLE_CONNS":    "2",
+		"DB_CONN_MAX_LIFETIME": "5m",
+		"DB_CONNECT_RETRY":     "2m",
+		"QUERY_TIMEOUT":        "2s",
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, Config{
+		ServicePort:       9090,
+		DatabaseDSN:       "postgres://users@db/order_db",
+		LogLevel:          "debug",
+		DBMaxOpenConns:    10,
+		DBMaxIdleConns:    2,
+		DBConnMaxLifetime: 5 * time.Minute,
+		DBConnectRetry:    2 * time.Minute,
+		QueryTimeout:      2 * time.Second,
+	}, cfg)
+}
+
+func TestLoadRejectsInvalidValues(t *testing.T) {
+	for _, env := range []map[string]string{
+		{"PORT": "http"},
+		{"PORT": "70000"},
+		{"PORT": "0"},
+		{"LOG_LEVEL": "verbose"},
+		{"MIGRATE_ON_BOOT": "sometimes"},
+		{"DB_MAX_OPEN_CONNS": "0"},
+		{"DB_MAX_IDLE_CONNS": "-1"},
+		{"DB_MAX_OPEN_CONNS": "4", "DB_MAX_IDLE_CONNS": "8"},
+		{"DB_CONN_MAX_LIFETIME": "forever"},
+		{"DB_CONNECT_RETRY": "-1s"},
+		{"QUERY_TIMEOUT": "0s"},
+	} {
+		_, err := load(lookupFrom(env))
+		assert.Error(t, err, env)
+	}
+}
+
+func TestDotEnvFallback(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, envconfig.DotEnvFile), []byte(
+		"# local development\nPORT=9191\nexport LOG_LEVEL='warn'\n\nDATABASE_URL=\"host=db dbname=order_db\"\n"), 0o600))
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { os.Chdir(wd) })
+	t.Setenv("PORT", "9292")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, 9292, cfg.ServicePort, "the environment wins over .env")
+	assert.Equal(t, "warn", cfg.LogLevel)
+	assert.Equal(t, "host=db dbname=order_db", cfg.DatabaseDSN)
+
+	require.NoError(t, os.WriteFile(envconfig.DotEnvFile, []byte("not a pair\n"), 0o600))
+	_, err = Load()
+	assert.ErrorContains(t, err, ".env:1")
+}
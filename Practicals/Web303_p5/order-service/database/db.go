@@ -1,27 +1,36 @@
 package database
 
 import (
+	"dbutil"
 	"log"
-	"order-service/models"
+	"time"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
 var DB *gorm.DB
 
-func Connect(dsn string) error {
-	var err error
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
-	if err != nil {
-		return err
-	}
+// QueryLogLevel is the GORM logger level; main sets it from LOG_LEVEL.
+var QueryLogLevel = logger.Warn
+
+// ConnectRetry is how long Connect keeps retrying a database that is not
+// accepting connections yet, as when it is still starting under Docker
+// Compose; 0 tries once. main sets it from DB_CONNECT_RETRY.
+var ConnectRetry = time.Minute
 
-	// Only migrate order-related tables
-	err = DB.AutoMigrate(&models.Order{}, &models.OrderItem{})
+// Connect opens dsn as DB, retrying for up to ConnectRetry.
+func Connect(dsn string) error {
+	db, err := dbutil.Connect(func() (*gorm.DB, error) {
+		return gorm.Open(postgres.Open(dsn), &gorm.Config{
+			Logger: logger.Default.LogMode(QueryLogLevel),
+		})
+	}, ConnectRetry, Pool)
 	if err != nil {
 		return err
 	}
+	DB = db
 
 	log.Println("Order database connected")
 	return nil
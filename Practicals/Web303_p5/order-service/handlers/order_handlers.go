@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"order-service/database"
@@ -60,8 +62,10 @@ func CreateOrder(w http.ResponseWriter, r *http.Request) {
 		order.OrderItems = append(order.OrderItems, orderItem)
 	}
 
-	if err := database.DB.Create(&order).Error; err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	db, cancel := database.WithContext(r.Context())
+	defer cancel()
+	if err := db.Create(&order).Error; err != nil {
+		http.Error(w, err.Error(), queryErrorStatus(err))
 		return
 	}
 
@@ -71,10 +75,12 @@ func CreateOrder(w http.ResponseWriter, r *http.Request) {
 }
 
 func GetOrders(w http.ResponseWriter, r *http.Request) {
+	db, cancel := database.WithContext(r.Context())
+	defer cancel()
 	var orders []models.Order
-	result := database.DB.Preload("OrderItems").Find(&orders)
+	result := db.Preload("OrderItems").Find(&orders)
 	if result.Error != nil {
-		http.Error(w, "Failed to fetch orders: "+result.Error.Error(), http.StatusInternalServerError)
+		http.Error(w, "Failed to fetch orders: "+result.Error.Error(), queryErrorStatus(result.Error))
 		return
 	}
 
@@ -82,3 +88,16 @@ func GetOrders(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(orders)
 }
+
+// queryErrorStatus is the status for a failed query: 499 when the client
+// cancelled the request, 504 when QueryTimeout passed, and 500 otherwise.
+func queryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return 499
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -1,39 +1,62 @@
 package main
 
 import (
+	"fmt"
+	"httputil"
 	"log"
 	"net/http"
-	"os"
+	"order-service/config"
 	"order-service/database"
 	"order-service/handlers"
+	"os"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"gorm.io/gorm/logger"
 )
 
 func main() {
-	// Connect to dedicated order database
-	dsn := os.Getenv("DATABASE_URL")
-	if dsn == "" {
-		dsn = "host=localhost user=postgres password=postgres dbname=order_db port=5432 sslmode=disable"
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	if cfg.LogLevel == "debug" {
+		database.QueryLogLevel = logger.Info
+	}
+
+	database.Pool = database.PoolConfig{
+		MaxOpenConns:    cfg.DBMaxOpenConns,
+		MaxIdleConns:    cfg.DBMaxIdleConns,
+		ConnMaxLifetime: cfg.DBConnMaxLifetime,
 	}
+	database.ConnectRetry = cfg.DBConnectRetry
+	database.QueryTimeout = cfg.QueryTimeout
 
-	if err := database.Connect(dsn); err != nil {
+	// Connect to dedicated order database
+	if err := database.Connect(cfg.DatabaseDSN); err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
+	// `order-service migrate` applies pending schema migrations and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := database.Migrate(database.DB); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+	if err := database.EnsureSchema(database.DB, cfg.MigrateOnBoot); err != nil {
+		log.Fatalf("Database schema not ready: %v", err)
+	}
+
 	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
 	r.Use(middleware.Logger)
+	r.Use(httputil.Recover)
 
 	// Order endpoints
 	r.Post("/orders", handlers.CreateOrder)
 	r.Get("/orders", handlers.GetOrders)
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8083"
-	}
-
-	log.Printf("Order service starting on :%s", port)
-	http.ListenAndServe(":"+port, r)
+	log.Printf("Order service starting on :%d", cfg.ServicePort)
+	http.ListenAndServe(fmt.Sprintf(":%d", cfg.ServicePort), r)
 }
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"envconfig"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lookupFrom(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := load(lookupFrom(nil))
+	require.NoError(t, err)
+
+	assert.Equal(t, 8081, cfg.ServicePort)
+	assert.Equal(t, 9091, cfg.GRPCPort)
+	assert.Contains(t, cfg.DatabaseDSN, "dbname=user_db")
+	assert.Equal(t, "info", cfg.LogLevel)
+	assert.True(t, cfg.MigrateOnBoot)
+	assert.Equal(t, 25, cfg.DBMaxOpenConns)
+	assert.Equal(t, 5, cfg.DBMaxIdleConns)
+	assert.Equal(t, 30*time.Minute, cfg.DBConnMaxLifetime)
+	assert.Equal(t, time.Minute, cfg.DBConnectRetry)
+	assert.Equal(t, 5*time.Second, cfg.QueryTimeout)
+	assert.Equal(t, 1<<20, cfg.MaxBodyBytes)
+	assert.False(t, cfg.SeedData)
+	assert.Equal(t, "seed.json", cfg.SeedFile)
+	assert.Equal(t, 24*time.Hour, cfg.IdempotencyTTL)
+}
+
+func TestLoadEnvOverrides(t *testing.T) {
+	cfg, err := load(lookupFrom(map[string]string{
+		"PORT":                 "9090",
+		"GRPC_PORT":            "9292",
+		"DATABASE_URL":         "postgres://users@db/user_db",
+		"LOG_LEVEL":            "DEBUG",
+		"MIGRATE_ON_BOOT":      "false",
+		"DB_MAX_OPEN_CONNS":    "10",
+		"DB_MAX_IDLE_CONNS":    "2",
+		"DB_CONN_MAX_LIFETIME": "5m",
+		"DB_CONNECT_RETRY":     "2m",
+		"QUERY_TIMEOUT":        "2s",
+		"MAX_BODY_BYTES":       "4096",
+		"SEED_DATA":            "true",
+		"SEED_FILE":            "/seed/demo.json",
+		"IDEMPOTENCY_TTL":      "1h",
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, Config{
+		ServicePort:       9090,
+		GRPCPort:          9292,
+		DatabaseDSN:       "postgres://users@db/user_db",
+		LogLevel:          "debug",
+		DBMaxOpenConns:    10,
+		DBMaxIdleConns:    2,
+		DBConnMaxLifetime: 5 * time.Minute,
+		DBConnectRetry:    2 * time.Minute,
+		QueryTimeout:      2 * time.Second,
+		MaxBodyBytes:      4096,
+		SeedData:          true,
+		SeedFile:          "/seed/demo.json",
+		IdempotencyTTL:    time.Hour,
+	}, cfg)
+}
+
+func TestLoadRejectsInvalidValues(t *testing.T) {
+	for _, env := range []map[string]string{
+		{"PORT": "http"},
+		{"PORT": "70000"},
+		{"PORT": "0"},
+		{"GRPC_PORT": "-1"},
+		{"LOG_LEVEL": "verbose"},
+		{"MIGRATE_ON_BOOT": "sometimes"},
+		{"DB_MAX_OPEN_CONNS": "0"},
+		{"DB_MAX_IDLE_CONNS": "-1"},
+		{"DB_MAX_OPEN_CONNS": "4", "DB_MAX_IDLE_CONNS": "8"},
+		{"DB_CONN_MAX_LIFETIME": "forever"},
+		{"DB_CONNECT_RETRY": "-1s"},
+		{"QUERY_TIMEOUT": "0s"},
+		{"IDEMPOTENCY_TTL": "-1h"},
+		{"MAX_BODY_BYTES": "0"},
+	} {
+		_, err := load(lookupFrom(env))
+		assert.Error(t, err, env)
+	}
+}
+
+func TestDotEnvFallback(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, envconfig.DotEnvFile), []byte(
+		"# local development\nPORT=9191\nexport LOG_LEVEL='warn'\n\nDATABASE_URL=\"host=db dbname=user_db\"\n"), 0o600))
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { os.Chdir(wd) })
+	t.Setenv("PORT", "9292")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, 9292, cfg.ServicePort, "the environment wins over .env")
+	assert.Equal(t, "warn", cfg.LogLevel)
+	assert.Equal(t, "host=db dbname=user_db", cfg.DatabaseDSN)
+
+	require.NoError(t, os.WriteFile(envconfig.DotEnvFile, []byte("not a pair\n"), 0o600))
+	_, err = Load()
+	assert.ErrorContains(t, err, ".env:1")
+}
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"user-service/models"
+
+	"gorm.io/gorm"
+)
+
+// SeedData is the shape of a seed file. Each service reads only its own key,
+// so one file can seed the whole stack.
+type SeedData struct {
+	Users []models.User `json:"users"`
+}
+
+// Seed inserts the users in the JSON file at path, all or none, and returns
+// how many were inserted. It does nothing when the users table already has
+// rows, so restarting a seeded service never duplicates them.
+func Seed(db *gorm.DB, path string) (int, error) {
+	var count int64
+	if err := db.Model(&models.User{}).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("counting users: %w", err)
+	}
+	if count > 0 {
+		return 0, nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	var seed SeedData
+	if err := json.Unmarshal(raw, &seed); err != nil {
+		return 0, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if len(seed.Users) == 0 {
+		return 0, nil
+	}
+	for i, user := range seed.Users {
+		if err := user.Validate(); err != nil {
+			return 0, fmt.Errorf("users[%d]: %w", i, err)
+		}
+	}
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		return tx.Create(&seed.Users).Error
+	}); err != nil {
+		return 0, fmt.Errorf("inserting users: %w", err)
+	}
+	return len(seed.Users), nil
+}
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"user-service/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeSeedFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "seed.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestSeedLoadsUsersIntoEmptyTable(t *testing.T) {
+	db := openTestDB(t)
+	require.NoError(t, Migrate(db))
+	path := writeSeedFile(t, `{
+		"users": [
+			{"name": "Tashi", "email": "tashi@example.com", "is_cafe_owner": true},
+			{"name": "Pema", "email": "pema@example.com"}
+		],
+		"menus": [{"name": "read by the menu service only"}]
+	}`)
+
+	inserted, err := Seed(db, path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, inserted)
+
+	var users []models.User
+	require.NoError(t, db.Order("id").Find(&users).Error)
+	require.Len(t, users, 2)
+	assert.Equal(t, "tashi@example.com", users[0].Email)
+	assert.True(t, users[0].IsCafeOwner)
+
+	// A second boot finds the table populated and leaves it alone
+	inserted, err = Seed(db, path)
+	require.NoError(t, err)
+	assert.Zero(t, inserted)
+	var count int64
+	require.NoError(t, db.Model(&models.User{}).Count(&count).Error)
+	assert.EqualValues(t, 2, count)
+}
+
+func TestSeedRejectsBadFiles(t *testing.T) {
+	db := openTestDB(t)
+	require.NoError(t, Migrate(db))
+
+	_, err := Seed(db, filepath.Join(t.TempDir(), "missing.json"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	_, err = Seed(db, writeSeedFile(t, `{"users": [`))
+	assert.Error(t, err)
+
+	_, err = Seed(db, writeSeedFile(t, `{"users": [{"name": "Tashi", "email": "tashi@example.com"}, {"name": "Pema"}]}`))
+	assert.ErrorContains(t, err, "users[1]")
+
+	var count int64
+	require.NoError(t, db.Model(&models.User{}).Count(&count).Error)
+	assert.Zero(t, count, "a bad seed file inserts nothing")
+}
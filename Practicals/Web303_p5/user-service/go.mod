@@ -1,19 +1,43 @@
 module user-service
 
-go 1.23
+go 1.23.0
 
 require (
+	dbutil v0.0.0
+	envconfig v0.0.0
+	github.com/douglasswm/student-cafe-protos v0.0.0
 	github.com/go-chi/chi/v5 v5.0.11
+	github.com/stretchr/testify v1.11.1
+	google.golang.org/grpc v1.75.0
 	gorm.io/driver/postgres v1.5.4
+	gorm.io/driver/sqlite v1.5.4
 	gorm.io/gorm v1.25.5
+	httputil v0.0.0
+	migrate v0.0.0
 )
 
 require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/jackc/pgpassfile v1.0.0 // indirect
 	github.com/jackc/pgservicefile v0.0.0-20221227161230-091c0ba34f0a // indirect
 	github.com/jackc/pgx/v5 v5.4.3 // indirect
 	github.com/jinzhu/inflection v1.0.0 // indirect
 	github.com/jinzhu/now v1.1.5 // indirect
-	golang.org/x/crypto v0.14.0 // indirect
-	golang.org/x/text v0.13.0 // indirect
+	github.com/mattn/go-sqlite3 v1.14.17 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/crypto v0.39.0 // indirect
+	golang.org/x/net v0.41.0 // indirect
+	golang.org/x/sys v0.33.0 // indirect
+	golang.org/x/text v0.26.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250707201910-8d1bb00bc6a7 // indirect
+	google.golang.org/protobuf v1.36.6 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace (
+	dbutil => ../dbutil
+	envconfig => ../envconfig
+	github.com/douglasswm/student-cafe-protos => ../student-cafe-protos
+	httputil => ../httputil
+	migrate => ../migrate
 )
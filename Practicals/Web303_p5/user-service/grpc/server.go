@@ -0,0 +1,106 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"time"
+	"user-service/database"
+	"user-service/handlers"
+	"user-service/models"
+
+	userv1 "github.com/douglasswm/student-cafe-protos/gen/go/user/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// UserServer serves the shared student-cafe UserService contract (the same
+// user.proto the p6 gateway is generated from) from the GORM store.
+type UserServer struct {
+	userv1.UnimplementedUserServiceServer
+}
+
+// NewUserServer returns a UserServer backed by database.DB.
+func NewUserServer() *UserServer {
+	return &UserServer{}
+}
+
+// adminMetadataKey carries the admin token on gRPC calls, like the
+// X-Admin-Token header does over HTTP.
+const adminMetadataKey = "x-admin-token"
+
+// isAdmin reports whether the call carries the configured admin token.
+func isAdmin(ctx context.Context) bool {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, presented := range md.Get(adminMetadataKey) {
+		if handlers.IsAdminToken(presented) {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateUser validates and stores a new user. As over HTTP, only admins may
+// create a cafe owner.
+func (s *UserServer) CreateUser(ctx context.Context, req *userv1.CreateUserRequest) (*userv1.CreateUserResponse, error) {
+	if req.GetIsCafeOwner() && !isAdmin(ctx) {
+		return nil, status.Error(codes.PermissionDenied, "admin privileges required to create a cafe owner")
+	}
+	user := models.User{
+		Name:        req.GetName(),
+		Email:       req.GetEmail(),
+		IsCafeOwner: req.GetIsCafeOwner(),
+	}
+	if err := user.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	err := database.DB.WithContext(ctx).Create(&user).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, status.Error(codes.AlreadyExists, "email already registered")
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create user: %v", err)
+	}
+	return &userv1.CreateUserResponse{User: toProto(user)}, nil
+}
+
+// GetUser looks a user up by ID.
+func (s *UserServer) GetUser(ctx context.Context, req *userv1.GetUserRequest) (*userv1.GetUserResponse, error) {
+	var user models.User
+	err := database.DB.WithContext(ctx).First(&user, req.GetId()).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, status.Errorf(codes.NotFound, "user %d not found", req.GetId())
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get user: %v", err)
+	}
+	return &userv1.GetUserResponse{User: toProto(user)}, nil
+}
+
+// GetUsers lists every user in ID order. It is the ListUsers operation of
+// the contract.
+func (s *UserServer) GetUsers(ctx context.Context, req *userv1.GetUsersRequest) (*userv1.GetUsersResponse, error) {
+	var users []models.User
+	if err := database.DB.WithContext(ctx).Order("id").Find(&users).Error; err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list users: %v", err)
+	}
+
+	out := make([]*userv1.User, len(users))
+	for i, user := range users {
+		out[i] = toProto(user)
+	}
+	return &userv1.GetUsersResponse{Users: out}, nil
+}
+
+func toProto(user models.User) *userv1.User {
+	return &userv1.User{
+		Id:          uint32(user.ID),
+		Name:        user.Name,
+		Email:       user.Email,
+		IsCafeOwner: user.IsCafeOwner,
+		CreatedAt:   user.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:   user.UpdatedAt.Format(time.RFC3339),
+	}
+}
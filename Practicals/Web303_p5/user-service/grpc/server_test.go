@@ -0,0 +1,129 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"user-service/database"
+	"user-service/handlers"
+	"user-service/models"
+
+	userv1 "github.com/douglasswm/student-cafe-protos/gen/go/user/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// newTestClient serves UserServer over an in-memory bufconn listener backed
+// by an isolated SQLite database.
+func newTestClient(t *testing.T) userv1.UserServiceClient {
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", strings.ReplaceAll(t.Name(), "/", "_"))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{TranslateError: true})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.User{}))
+	database.DB = db
+
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	userv1.RegisterUserServiceServer(server, NewUserServer())
+	go server.Serve(lis)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+		server.Stop()
+		sqlDB, _ := db.DB()
+		sqlDB.Close()
+	})
+	return userv1.NewUserServiceClient(conn)
+}
+
+// withAdmin configures the admin token and returns a context carrying it.
+func withAdmin(t *testing.T) context.Context {
+	orig := handlers.AdminToken
+	handlers.AdminToken = "admin-secret"
+	t.Cleanup(func() { handlers.AdminToken = orig })
+	return metadata.AppendToOutgoingContext(context.Background(), adminMetadataKey, "admin-secret")
+}
+
+func TestCreateAndGetUser(t *testing.T) {
+	client := newTestClient(t)
+	ctx := withAdmin(t)
+
+	created, err := client.CreateUser(ctx, &userv1.CreateUserRequest{Name: "Tashi", Email: "tashi@example.com", IsCafeOwner: true})
+	require.NoError(t, err)
+	assert.NotZero(t, created.User.Id)
+	assert.True(t, created.User.IsCafeOwner)
+
+	got, err := client.GetUser(ctx, &userv1.GetUserRequest{Id: created.User.Id})
+	require.NoError(t, err)
+	assert.Equal(t, "tashi@example.com", got.User.Email)
+}
+
+func TestGetUserNotFound(t *testing.T) {
+	client := newTestClient(t)
+
+	_, err := client.GetUser(context.Background(), &userv1.GetUserRequest{Id: 99})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestCreateUserErrors(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := client.CreateUser(ctx, &userv1.CreateUserRequest{Name: "", Email: "a@example.com"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.CreateUser(ctx, &userv1.CreateUserRequest{Name: "A", Email: "not-an-email"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.CreateUser(ctx, &userv1.CreateUserRequest{Name: "A", Email: "a@example.com"})
+	require.NoError(t, err)
+	_, err = client.CreateUser(ctx, &userv1.CreateUserRequest{Name: "B", Email: "a@example.com"})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+}
+
+func TestCreateUserRequiresAdminForCafeOwners(t *testing.T) {
+	client := newTestClient(t)
+	withAdmin(t)
+	req := &userv1.CreateUserRequest{Name: "Karma", Email: "karma@example.com", IsCafeOwner: true}
+
+	_, err := client.CreateUser(context.Background(), req)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	wrong := metadata.AppendToOutgoingContext(context.Background(), adminMetadataKey, "guess")
+	_, err = client.CreateUser(wrong, req)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	var count int64
+	require.NoError(t, database.DB.Model(&models.User{}).Count(&count).Error)
+	assert.Zero(t, count, "no user is stored")
+
+	_, err = client.CreateUser(context.Background(), &userv1.CreateUserRequest{Name: "Karma", Email: "karma@example.com"})
+	assert.NoError(t, err, "customers need no token")
+}
+
+func TestListUsers(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+	for _, name := range []string{"Pema", "Dawa"} {
+		_, err := client.CreateUser(ctx, &userv1.CreateUserRequest{Name: name, Email: strings.ToLower(name) + "@example.com"})
+		require.NoError(t, err)
+	}
+
+	list, err := client.GetUsers(ctx, &userv1.GetUsersRequest{})
+	require.NoError(t, err)
+	require.Len(t, list.Users, 2)
+	assert.Equal(t, "Pema", list.Users[0].Name)
+	assert.Equal(t, "Dawa", list.Users[1].Name)
+}
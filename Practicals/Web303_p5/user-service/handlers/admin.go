@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+)
+
+// AdminToken is the shared secret admin callers present in X-Admin-Token.
+// Admin-only behaviour is disabled while it is empty.
+var AdminToken = os.Getenv("ADMIN_TOKEN")
+
+// isAdmin reports whether the request carries the configured admin token.
+func isAdmin(r *http.Request) bool {
+	return IsAdminToken(r.Header.Get("X-Admin-Token"))
+}
+
+// IsAdminToken reports whether presented is the configured admin token. The
+// gRPC server checks the same secret, sent as x-admin-token metadata.
+func IsAdminToken(presented string) bool {
+	return AdminToken != "" && subtle.ConstantTimeCompare([]byte(presented), []byte(AdminToken)) == 1
+}
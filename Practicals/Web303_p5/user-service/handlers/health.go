@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"context"
+	"httputil"
+	"net/http"
+	"time"
+	"user-service/database"
+)
+
+// readinessTimeout bounds the database ping behind /readyz.
+const readinessTimeout = 2 * time.Second
+
+// Liveness reports that the process is up. It never touches the database, so
+// a database outage doesn't get the service restarted.
+func Liveness(w http.ResponseWriter, r *http.Request) {
+	httputil.RespondJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Readiness is the JSON body of /readyz.
+type Readiness struct {
+	Status      string  `json:"status"`
+	DBLatencyMS float64 `json:"db_latency_ms"`
+	Error       string  `json:"error,omitempty"`
+}
+
+// ReadinessCheck pings the database and answers 503 when it is unreachable.
+func ReadinessCheck(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := pingDB(ctx)
+	report := Readiness{Status: "ready", DBLatencyMS: float64(time.Since(start).Microseconds()) / 1000}
+
+	status := http.StatusOK
+	if err != nil {
+		status = http.StatusServiceUnavailable
+		report.Status = "unavailable"
+		report.Error = err.Error()
+	}
+
+	httputil.RespondJSON(w, status, report)
+}
+
+func pingDB(ctx context.Context) error {
+	sqlDB, err := database.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
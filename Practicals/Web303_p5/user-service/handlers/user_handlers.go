@@ -2,54 +2,442 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"httputil"
+	"log"
 	"net/http"
+	"strconv"
 	"user-service/database"
 	"user-service/models"
 
 	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
 )
 
+// UserCreate is the body of CreateUser. Only admins may create a cafe owner.
+type UserCreate struct {
+	Name        string `json:"name"`
+	Email       string `json:"email"`
+	IsCafeOwner bool   `json:"is_cafe_owner"`
+}
+
 func CreateUser(w http.ResponseWriter, r *http.Request) {
-	var userData models.User
-	if err := json.NewDecoder(r.Body).Decode(&userData); err != nil {
-		http.Error(w, "Invalid user data: "+err.Error(), http.StatusBadRequest)
+	var body UserCreate
+	if err := httputil.DecodeJSON(w, r, &body); err != nil {
+		httputil.RespondDecodeError(w, "Invalid user data", err)
+		return
+	}
+	if body.IsCafeOwner && !isAdmin(r) {
+		httputil.RespondError(w, http.StatusForbidden, "Admin privileges required to create a cafe owner")
 		return
 	}
 
-	result := database.DB.Create(&userData)
+	userData := models.User{Name: body.Name, Email: body.Email, IsCafeOwner: body.IsCafeOwner}
+	if err := userData.Validate(); err != nil {
+		httputil.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	db, cancel := database.WithContext(r.Context())
+	defer cancel()
+	result := db.Create(&userData)
+	if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
+		httputil.RespondError(w, http.StatusConflict, "email already registered")
+		return
+	}
 	if result.Error != nil {
-		http.Error(w, "Failed to create user: "+result.Error.Error(), http.StatusInternalServerError)
+		httputil.RespondQueryError(w, "Failed to create user: ", result.Error)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(userData)
+	httputil.RespondJSON(w, http.StatusCreated, userData.ToResponse(isAdmin(r)))
 }
 
+// GetUser returns one user. The response carries an ETag, and a matching
+// If-None-Match gets 304 Not Modified.
 func GetUser(w http.ResponseWriter, r *http.Request) {
 	userID := chi.URLParam(r, "id")
+	db, cancel := database.WithContext(r.Context())
+	defer cancel()
 
 	var user models.User
-	if err := database.DB.Where("id = ?", userID).First(&user).Error; err != nil {
-		http.Error(w, "User not found with ID: "+userID, http.StatusNotFound)
+	if err := db.Where("id = ?", userID).First(&user).Error; err != nil {
+		respondLoadError(w, userID, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(user)
+	httputil.RespondCachedJSON(w, r, user.ToResponse(isAdmin(r)))
+}
+
+// maxBatchUserIDs caps the distinct IDs one GetUsersBatch request may ask for.
+const maxBatchUserIDs = 100
+
+// BatchUsersRequest is the body of POST /users/batch.
+type BatchUsersRequest struct {
+	IDs []uint `json:"ids"`
+}
+
+// BatchUsersResponse holds the users found, in the order their IDs were
+// first requested, and the requested IDs that matched no user.
+type BatchUsersResponse struct {
+	Data    []models.UserResponse `json:"data"`
+	Missing []uint                `json:"missing"`
+}
+
+// GetUsersBatch handles POST /users/batch, loading every requested user with
+// a single WHERE id IN query so callers joining users onto other records
+// need not fetch them one at a time. Duplicate IDs are collapsed before the
+// maxBatchUserIDs cap applies; soft-deleted users are reported as missing.
+func GetUsersBatch(w http.ResponseWriter, r *http.Request) {
+	var req BatchUsersRequest
+	if err := httputil.DecodeJSON(w, r, &req); err != nil {
+		httputil.RespondDecodeError(w, "Invalid request body", err)
+		return
+	}
+
+	ids := make([]uint, 0, len(req.IDs))
+	seen := make(map[uint]bool, len(req.IDs))
+	for _, id := range req.IDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 || len(ids) > maxBatchUserIDs {
+		httputil.RespondError(w, http.StatusBadRequest, fmt.Sprintf("ids must contain between 1 and %d distinct IDs", maxBatchUserIDs))
+		return
+	}
+
+	db, cancel := database.WithContext(r.Context())
+	defer cancel()
+	var users []models.User
+	if err := db.Where("id IN ?", ids).Find(&users).Error; err != nil {
+		httputil.RespondQueryError(w, "Failed to load users: ", err)
+		return
+	}
+
+	byID := make(map[uint]models.User, len(users))
+	for _, u := range users {
+		byID[u.ID] = u
+	}
+	resp := BatchUsersResponse{Data: []models.UserResponse{}, Missing: []uint{}}
+	for _, id := range ids {
+		if u, ok := byID[id]; ok {
+			resp.Data = append(resp.Data, u.ToResponse(isAdmin(r)))
+		} else {
+			resp.Missing = append(resp.Missing, id)
+		}
+	}
+	httputil.RespondJSON(w, http.StatusOK, resp)
 }
+
+// UserList is the envelope returned by GetUsers.
+type UserList struct {
+	Data   []models.UserResponse `json:"data"`
+	Total  int64                 `json:"total"`
+	Limit  int                   `json:"limit"`
+	Offset int                   `json:"offset"`
+}
+
+// GetUsers lists users a page at a time, defaulting to the first
+// httputil.DefaultPageLimit. ?email= keeps only users whose email contains
+// it, and admins may pass ?include_deleted=true to list soft-deleted users too.
+// Accept: text/csv returns the page as a CSV download instead of JSON.
 func GetUsers(w http.ResponseWriter, r *http.Request) {
+	stream := r.URL.Query().Get("stream") == "true"
+	supported := httputil.ListTypes
+	if stream {
+		supported = []string{httputil.MediaJSON}
+	}
+	contentType, ok := httputil.Negotiate(r, supported...)
+	w.Header().Add("Vary", "Accept")
+	if !ok {
+		httputil.RespondNotAcceptable(w, supported)
+		return
+	}
+	if stream {
+		StreamUsers(w, r)
+		return
+	}
+
+	page, paged, err := httputil.ParsePage(r)
+	if err != nil {
+		httputil.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !paged {
+		page = httputil.Page{Limit: httputil.DefaultPageLimit}
+	}
+
+	db, cancel := database.WithContext(r.Context())
+	defer cancel()
+	query := db.Model(&models.User{})
+	if r.URL.Query().Get("include_deleted") == "true" {
+		if !isAdmin(r) {
+			httputil.RespondError(w, http.StatusForbidden, "include_deleted requires admin access")
+			return
+		}
+		query = query.Unscoped()
+	}
+	if email := r.URL.Query().Get("email"); email != "" {
+		query = query.Where("email LIKE ?", "%"+email+"%")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		httputil.RespondQueryError(w, "Failed to count users: ", err)
+		return
+	}
+	httputil.SetPageHeaders(w, r, page, int(total))
+
 	var users []models.User
-	result := database.DB.Find(&users)
+	result := query.Order("id").Offset(page.Offset).Limit(page.Limit).Find(&users)
 	if result.Error != nil {
-		http.Error(w, "Failed to retrieve users: "+result.Error.Error(), http.StatusInternalServerError)
+		httputil.RespondQueryError(w, "Failed to retrieve users: ", result.Error)
 		return
 	}
 
+	if contentType == httputil.MediaCSV {
+		respondUsersCSV(w, models.UsersToResponse(users, isAdmin(r)))
+		return
+	}
+	httputil.RespondJSON(w, http.StatusOK, UserList{
+		Data:   models.UsersToResponse(users, isAdmin(r)),
+		Total:  total,
+		Limit:  page.Limit,
+		Offset: page.Offset,
+	})
+}
+
+// respondUsersCSV writes a page of users as a users.csv download, one row
+// per user under the JSON field names.
+func respondUsersCSV(w http.ResponseWriter, users []models.UserResponse) {
+	rows := make([][]string, len(users))
+	for i, u := range users {
+		deletedAt := ""
+		if u.DeletedAt != nil {
+			deletedAt = *u.DeletedAt
+		}
+		rows[i] = []string{
+			strconv.FormatUint(uint64(u.ID), 10), u.Name, u.Email,
+			strconv.FormatBool(u.IsCafeOwner), u.CreatedAt, u.UpdatedAt, deletedAt,
+		}
+	}
+	httputil.RespondCSV(w, "users.csv",
+		[]string{"id", "name", "email", "is_cafe_owner", "created_at", "updated_at", "deleted_at"}, rows)
+}
+
+// StreamUsers writes every user as a JSON array, scanning and encoding one
+// row at a time so memory stays flat however large the table is.
+func StreamUsers(w http.ResponseWriter, r *http.Request) {
+	// A stream may legitimately outlast QueryTimeout, so only the client
+	// going away stops it
+	db := database.DB.WithContext(r.Context())
+	rows, err := db.Model(&models.User{}).Order("id").Rows()
+	if err != nil {
+		httputil.RespondQueryError(w, "Failed to retrieve users: ", err)
+		return
+	}
+	defer rows.Close()
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(users)
-}json.NewEncoder(w).Encode(users)
+
+	admin := isAdmin(r)
+	enc := json.NewEncoder(w)
+	w.Write([]byte("["))
+	for first := true; rows.Next(); first = false {
+		var user models.User
+		if err := db.ScanRows(rows, &user); err != nil {
+			// Headers are already sent; truncating the array is all we can do
+			log.Printf("Aborting user stream: %v", err)
+			return
+		}
+		if !first {
+			w.Write([]byte(","))
+		}
+		if err := enc.Encode(user.ToResponse(admin)); err != nil {
+			log.Printf("Aborting user stream: %v", err)
+			return
+		}
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Aborting user stream: %v", err)
+		return
+	}
+	w.Write([]byte("]"))
+}
+
+// UserUpdate is the body of UpdateUser. Only fields present in the body are
+// changed, so a PATCH of {"name": "x"} leaves the email alone. IsCafeOwner
+// is only accepted from admins, as with SetUserRole.
+type UserUpdate struct {
+	Name        *string `json:"name"`
+	Email       *string `json:"email"`
+	IsCafeOwner *bool   `json:"is_cafe_owner"`
+}
+
+func (u UserUpdate) changes() map[string]interface{} {
+	changes := map[string]interface{}{}
+	if u.Name != nil {
+		changes["name"] = *u.Name
+	}
+	if u.Email != nil {
+		changes["email"] = *u.Email
+	}
+	if u.IsCafeOwner != nil {
+		changes["is_cafe_owner"] = *u.IsCafeOwner
+	}
+	return changes
+}
+
+// apply copies the fields present in the update onto user.
+func (u UserUpdate) apply(user *models.User) {
+	if u.Name != nil {
+		user.Name = *u.Name
+	}
+	if u.Email != nil {
+		user.Email = *u.Email
+	}
+	if u.IsCafeOwner != nil {
+		user.IsCafeOwner = *u.IsCafeOwner
+	}
+}
+
+// UpdateUser applies a partial update to a user and returns the result.
+func UpdateUser(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "id")
+
+	var update UserUpdate
+	if err := httputil.DecodeJSON(w, r, &update); err != nil {
+		httputil.RespondDecodeError(w, "Invalid user data", err)
+		return
+	}
+	if update.IsCafeOwner != nil && !isAdmin(r) {
+		httputil.RespondError(w, http.StatusForbidden, "Admin privileges required to change is_cafe_owner")
+		return
+	}
+
+	db, cancel := database.WithContext(r.Context())
+	defer cancel()
+	var user models.User
+	if err := db.Where("id = ?", userID).First(&user).Error; err != nil {
+		respondLoadError(w, userID, err)
+		return
+	}
+
+	// Check the user as it would be stored, so a PATCH cannot save a name
+	// or email that CreateUser would have refused
+	merged := user
+	update.apply(&merged)
+	if err := merged.Validate(); err != nil {
+		httputil.RespondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if changes := update.changes(); len(changes) > 0 {
+		err := db.Model(&user).Updates(changes).Error
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			httputil.RespondError(w, http.StatusConflict, "email already registered")
+			return
+		}
+		if err != nil {
+			httputil.RespondQueryError(w, "Failed to update user: ", err)
+			return
+		}
+	}
+
+	httputil.RespondJSON(w, http.StatusOK, user.ToResponse(isAdmin(r)))
+}
+
+// DeleteUser soft-deletes a user; it can be brought back with RestoreUser.
+func DeleteUser(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "id")
+	db, cancel := database.WithContext(r.Context())
+	defer cancel()
+
+	result := db.Where("id = ?", userID).Delete(&models.User{})
+	if result.Error != nil {
+		httputil.RespondQueryError(w, "Failed to delete user: ", result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		httputil.RespondError(w, http.StatusNotFound, "User not found with ID: "+userID)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RestoreUser clears DeletedAt on a soft-deleted user. Unknown users are
+// reported as 404 and users that are not deleted as 409.
+func RestoreUser(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "id")
+	db, cancel := database.WithContext(r.Context())
+	defer cancel()
+
+	var user models.User
+	if err := db.Unscoped().Where("id = ?", userID).First(&user).Error; err != nil {
+		respondLoadError(w, userID, err)
+		return
+	}
+	if !user.DeletedAt.Valid {
+		httputil.RespondError(w, http.StatusConflict, "User is not deleted: "+userID)
+		return
+	}
+
+	if err := db.Unscoped().Model(&user).Update("deleted_at", nil).Error; err != nil {
+		httputil.RespondQueryError(w, "Failed to restore user: ", err)
+		return
+	}
+
+	httputil.RespondJSON(w, http.StatusOK, user.ToResponse(isAdmin(r)))
+}
+
+// SetUserRole handles PATCH /users/{id}/role (admin only), which changes
+// just the is_cafe_owner flag and returns the updated user.
+func SetUserRole(w http.ResponseWriter, r *http.Request) {
+	if !isAdmin(r) {
+		httputil.RespondError(w, http.StatusForbidden, "Admin privileges required")
+		return
+	}
+	userID := chi.URLParam(r, "id")
+
+	var body struct {
+		IsCafeOwner *bool `json:"is_cafe_owner"`
+	}
+	if err := httputil.DecodeJSON(w, r, &body); err != nil {
+		httputil.RespondDecodeError(w, "Invalid role data", err)
+		return
+	}
+	if body.IsCafeOwner == nil {
+		httputil.RespondError(w, http.StatusBadRequest, "Invalid role data: is_cafe_owner is required")
+		return
+	}
+
+	db, cancel := database.WithContext(r.Context())
+	defer cancel()
+	var user models.User
+	if err := db.Where("id = ?", userID).First(&user).Error; err != nil {
+		respondLoadError(w, userID, err)
+		return
+	}
+
+	if err := db.Model(&user).Update("is_cafe_owner", *body.IsCafeOwner).Error; err != nil {
+		httputil.RespondQueryError(w, "Failed to update role: ", err)
+		return
+	}
+
+	httputil.RespondJSON(w, http.StatusOK, user.ToResponse(true))
+}
+
+// respondLoadError writes err from loading the user userID: 404 when there
+// is no such user, otherwise whatever RespondQueryError picks.
+func respondLoadError(w http.ResponseWriter, userID string, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		httputil.RespondError(w, http.StatusNotFound, "User not found with ID: "+userID)
+		return
+	}
+	httputil.RespondQueryError(w, "Failed to load user: ", err)
 }
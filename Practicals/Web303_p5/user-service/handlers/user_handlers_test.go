@@ -0,0 +1,768 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"httputil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"user-service/database"
+	"user-service/models"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestDB points database.DB at an isolated in-memory SQLite database.
+func setupTestDB(t *testing.T) *gorm.DB {
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", strings.ReplaceAll(t.Name(), "/", "_"))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{TranslateError: true})
+	require.NoError(t, err, "Failed to open test database")
+	require.NoError(t, db.AutoMigrate(&models.User{}))
+
+	database.DB = db
+	t.Cleanup(func() {
+		sqlDB, err := db.DB()
+		require.NoError(t, err)
+		sqlDB.Close()
+	})
+	return db
+}
+
+func newTestRouter() http.Handler {
+	r := chi.NewRouter()
+	r.Use(httputil.Compress(httputil.DefaultCompressMinSize))
+	r.NotFound(httputil.NotFound)
+	r.MethodNotAllowed(httputil.MethodNotAllowed(r))
+	r.With(httputil.NewIdempotency(httputil.DefaultIdempotencyTTL).Wrap).Post("/users", CreateUser)
+	r.Get("/users", GetUsers)
+	r.Get("/users/{id}", GetUser)
+	r.Post("/users/batch", GetUsersBatch)
+	r.Put("/users/{id}", UpdateUser)
+	r.Patch("/users/{id}", UpdateUser)
+	r.Delete("/users/{id}", DeleteUser)
+	r.Post("/users/{id}/restore", RestoreUser)
+	r.Patch("/users/{id}/role", SetUserRole)
+	r.Get("/openapi.json", OpenAPI)
+	r.Get("/docs", Docs)
+	return r
+}
+
+func do(router http.Handler, method, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	return rec
+}
+
+func listUserIDs(t *testing.T, router http.Handler) []uint {
+	rec := do(router, http.MethodGet, "/users")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var list UserList
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&list))
+	ids := make([]uint, len(list.Data))
+	for i, u := range list.Data {
+		ids[i] = u.ID
+	}
+	return ids
+}
+
+func TestDeleteAndRestoreUser(t *testing.T) {
+	db := setupTestDB(t)
+	user := models.User{Name: "Dorji", Email: "dorji@example.com"}
+	require.NoError(t, db.Create(&user).Error)
+	router := newTestRouter()
+	path := fmt.Sprintf("/users/%d", user.ID)
+
+	rec := do(router, http.MethodDelete, path)
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, listUserIDs(t, router))
+	assert.Equal(t, http.StatusNotFound, do(router, http.MethodGet, path).Code)
+
+	var deleted models.User
+	require.NoError(t, db.Unscoped().First(&deleted, user.ID).Error)
+	assert.True(t, deleted.DeletedAt.Valid, "delete must be soft")
+
+	rec = do(router, http.MethodPost, path+"/restore")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var restored models.UserResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&restored))
+	assert.Equal(t, "Dorji", restored.Name)
+
+	assert.Equal(t, []uint{user.ID}, listUserIDs(t, router))
+	assert.Equal(t, http.StatusOK, do(router, http.MethodGet, path).Code)
+}
+
+func TestGetUsersIncludeDeleted(t *testing.T) {
+	db := setupTestDB(t)
+	kept := models.User{Name: "Karma", Email: "karma@example.com"}
+	gone := models.User{Name: "Sonam", Email: "sonam@example.com"}
+	require.NoError(t, db.Create(&kept).Error)
+	require.NoError(t, db.Create(&gone).Error)
+	router := newTestRouter()
+	require.Equal(t, http.StatusNoContent, do(router, http.MethodDelete, fmt.Sprintf("/users/%d", gone.ID)).Code)
+
+	assert.Equal(t, []uint{kept.ID}, listUserIDs(t, router), "soft-deleted users are hidden")
+	assert.Equal(t, http.StatusForbidden, do(router, http.MethodGet, "/users?include_deleted=true").Code)
+
+	orig := AdminToken
+	AdminToken = "admin-secret"
+	t.Cleanup(func() { AdminToken = orig })
+	req := httptest.NewRequest(http.MethodGet, "/users?include_deleted=true", nil)
+	req.Header.Set("X-Admin-Token", "admin-secret")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var list UserList
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&list))
+	require.Len(t, list.Data, 2)
+	assert.EqualValues(t, 2, list.Total)
+	assert.Nil(t, list.Data[0].DeletedAt)
+	assert.NotNil(t, list.Data[1].DeletedAt, "admins see when the user was deleted")
+
+	require.Equal(t, http.StatusOK, do(router, http.MethodPost, fmt.Sprintf("/users/%d/restore", gone.ID)).Code)
+	assert.Equal(t, []uint{kept.ID, gone.ID}, listUserIDs(t, router))
+}
+
+func TestDeleteUserNotFound(t *testing.T) {
+	setupTestDB(t)
+	assert.Equal(t, http.StatusNotFound, do(newTestRouter(), http.MethodDelete, "/users/99").Code)
+}
+
+func TestRestoreUserNotDeleted(t *testing.T) {
+	db := setupTestDB(t)
+	user := models.User{Name: "Pema", Email: "pema@example.com"}
+	require.NoError(t, db.Create(&user).Error)
+	router := newTestRouter()
+
+	rec := do(router, http.MethodPost, fmt.Sprintf("/users/%d/restore", user.ID))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	rec = do(router, http.MethodPost, "/users/99/restore")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestStreamUsersProducesValidJSON(t *testing.T) {
+	db := setupTestDB(t)
+	users := make([]models.User, 500)
+	for i := range users {
+		users[i] = models.User{Name: fmt.Sprintf("User %d", i), Email: fmt.Sprintf("user%d@example.com", i)}
+	}
+	require.NoError(t, db.CreateInBatches(users, 100).Error)
+	require.NoError(t, db.Delete(&users[0]).Error)
+
+	rec := do(newTestRouter(), http.MethodGet, "/users?stream=true")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	require.True(t, json.Valid(rec.Body.Bytes()), "streamed body must be a valid JSON document")
+
+	var streamed []models.UserResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &streamed))
+	require.Len(t, streamed, 499, "soft-deleted users are excluded")
+	assert.Equal(t, "User 1", streamed[0].Name)
+	assert.Equal(t, "User 499", streamed[498].Name)
+}
+
+func TestStreamUsersEmptyTable(t *testing.T) {
+	setupTestDB(t)
+	rec := do(newTestRouter(), http.MethodGet, "/users?stream=true")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, "[]", rec.Body.String())
+}
+
+func TestGetUsersLinkHeaders(t *testing.T) {
+	db := setupTestDB(t)
+	for i := 1; i <= 5; i++ {
+		require.NoError(t, db.Create(&models.User{Name: fmt.Sprintf("User %d", i), Email: fmt.Sprintf("u%d@example.com", i)}).Error)
+	}
+	router := newTestRouter()
+
+	middle := do(router, http.MethodGet, "/users?offset=2&limit=2")
+	require.Equal(t, http.StatusOK, middle.Code)
+	assert.Equal(t, "5", middle.Header().Get("X-Total-Count"))
+	assert.Equal(t, `</users?limit=2&offset=4>; rel="next", </users?limit=2&offset=0>; rel="prev"`, middle.Header().Get("Link"))
+	var list UserList
+	require.NoError(t, json.NewDecoder(middle.Body).Decode(&list))
+	require.Len(t, list.Data, 2)
+	assert.Equal(t, "User 3", list.Data[0].Name)
+
+	first := do(router, http.MethodGet, "/users?limit=2")
+	assert.Equal(t, `</users?limit=2&offset=2>; rel="next"`, first.Header().Get("Link"))
+
+	last := do(router, http.MethodGet, "/users?offset=4&limit=2")
+	assert.Equal(t, `</users?limit=2&offset=2>; rel="prev"`, last.Header().Get("Link"))
+
+	assert.Empty(t, do(router, http.MethodGet, "/users").Header().Get("Link"))
+	assert.Equal(t, http.StatusBadRequest, do(router, http.MethodGet, "/users?limit=500").Code)
+}
+
+func seedUsers(t *testing.T, db *gorm.DB, n int) {
+	for i := 1; i <= n; i++ {
+		require.NoError(t, db.Create(&models.User{Name: fmt.Sprintf("User %d", i), Email: fmt.Sprintf("user%d@example.com", i)}).Error)
+	}
+}
+
+func getUserList(t *testing.T, router http.Handler, path string) UserList {
+	rec := do(router, http.MethodGet, path)
+	require.Equal(t, http.StatusOK, rec.Code, path)
+	var list UserList
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&list))
+	return list
+}
+
+func TestGetUsersPaging(t *testing.T) {
+	seedUsers(t, setupTestDB(t), 25)
+	router := newTestRouter()
+
+	first := getUserList(t, router, "/users")
+	assert.EqualValues(t, 25, first.Total)
+	assert.Equal(t, httputil.DefaultPageLimit, first.Limit)
+	assert.Zero(t, first.Offset)
+	require.Len(t, first.Data, httputil.DefaultPageLimit)
+	assert.Equal(t, "User 1", first.Data[0].Name)
+
+	last := getUserList(t, router, "/users?offset=20&limit=10")
+	require.Len(t, last.Data, 5)
+	assert.Equal(t, "User 21", last.Data[0].Name)
+	assert.Equal(t, "User 25", last.Data[4].Name)
+
+	beyond := getUserList(t, router, "/users?offset=25")
+	assert.Empty(t, beyond.Data)
+	assert.EqualValues(t, 25, beyond.Total)
+
+	for _, bad := range []string{"limit=-1", "offset=-5", "limit=ten", "offset=x", "limit=0"} {
+		assert.Equal(t, http.StatusBadRequest, do(router, http.MethodGet, "/users?"+bad).Code, bad)
+	}
+}
+
+func TestGetUsersEmailFilter(t *testing.T) {
+	seedUsers(t, setupTestDB(t), 25)
+	router := newTestRouter()
+
+	// user2, user20..user25
+	list := getUserList(t, router, "/users?email=user2&limit=3")
+	assert.EqualValues(t, 7, list.Total)
+	require.Len(t, list.Data, 3)
+	assert.Equal(t, "user2@example.com", list.Data[0].Email)
+
+	none := getUserList(t, router, "/users?email=nobody")
+	assert.Zero(t, none.Total)
+	assert.Empty(t, none.Data)
+}
+
+func doJSON(router http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestUpdateUserSingleField(t *testing.T) {
+	db := setupTestDB(t)
+	user := models.User{Name: "Dorji", Email: "dorji@example.com"}
+	require.NoError(t, db.Create(&user).Error)
+	path := fmt.Sprintf("/users/%d", user.ID)
+
+	rec := doJSON(newTestRouter(), http.MethodPatch, path, `{"name": "Dorji Wangchuk"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var updated models.UserResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&updated))
+	assert.Equal(t, "Dorji Wangchuk", updated.Name)
+	assert.Equal(t, "dorji@example.com", updated.Email, "fields absent from the body are kept")
+
+	var stored models.User
+	require.NoError(t, db.First(&stored, user.ID).Error)
+	assert.Equal(t, "Dorji Wangchuk", stored.Name)
+}
+
+func TestUpdateUserErrors(t *testing.T) {
+	db := setupTestDB(t)
+	user := models.User{Name: "Dorji", Email: "dorji@example.com"}
+	require.NoError(t, db.Create(&user).Error)
+	router := newTestRouter()
+	path := fmt.Sprintf("/users/%d", user.ID)
+
+	assert.Equal(t, http.StatusNotFound, doJSON(router, http.MethodPut, "/users/99", `{"name": "x"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, doJSON(router, http.MethodPut, path, `{"name": `).Code)
+	assert.Equal(t, http.StatusBadRequest, doJSON(router, http.MethodPut, path, `{"nickname": "x"}`).Code)
+
+	require.NoError(t, db.Delete(&user).Error)
+	assert.Equal(t, http.StatusNotFound, doJSON(router, http.MethodPatch, path, `{"name": "x"}`).Code,
+		"soft-deleted users cannot be updated")
+}
+
+func TestUpdateUserRejectsMalformedBodies(t *testing.T) {
+	db := setupTestDB(t)
+	user := models.User{Name: "Dorji", Email: "dorji@example.com"}
+	require.NoError(t, db.Create(&user).Error)
+	router := newTestRouter()
+	path := fmt.Sprintf("/users/%d", user.ID)
+	old := httputil.MaxBodyBytes
+	httputil.MaxBodyBytes = 256
+	t.Cleanup(func() { httputil.MaxBodyBytes = old })
+
+	rec := doJSON(router, http.MethodPatch, path, `{"name": "Dorji W"} {"name": "Sonam"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "single JSON value")
+
+	rec = doJSON(router, http.MethodPatch, path, `{"name": "`+strings.Repeat("d", 512)+`"}`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+	var stored models.User
+	require.NoError(t, db.First(&stored, user.ID).Error)
+	assert.Equal(t, "Dorji", stored.Name, "rejected bodies must not be stored")
+}
+
+func TestUpdateUserValidation(t *testing.T) {
+	db := setupTestDB(t)
+	user := models.User{Name: "Dorji", Email: "dorji@example.com"}
+	require.NoError(t, db.Create(&user).Error)
+	router := newTestRouter()
+	path := fmt.Sprintf("/users/%d", user.ID)
+
+	tests := map[string]string{
+		"blank name":   `{"name": ""}`,
+		"spaces only":  `{"name": "   "}`,
+		"bad email":    `{"email": "not-an-email"}`,
+		"display name": `{"email": "Dorji <dorji@example.com>"}`,
+		"one bad":      `{"name": "Dorji Wangchuk", "email": "dorji.example.com"}`,
+	}
+	for name, body := range tests {
+		rec := doJSON(router, http.MethodPatch, path, body)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, name)
+		assert.Contains(t, rec.Body.String(), `"error"`, name)
+	}
+
+	var stored models.User
+	require.NoError(t, db.First(&stored, user.ID).Error)
+	assert.Equal(t, "Dorji", stored.Name, "invalid updates must not be stored")
+	assert.Equal(t, "dorji@example.com", stored.Email)
+
+	assert.Equal(t, http.StatusOK, doJSON(router, http.MethodPatch, path, `{"email": "dorji@cafe.example"}`).Code,
+		"fields left out are validated as stored")
+}
+
+func TestCreateUserDuplicateEmail(t *testing.T) {
+	setupTestDB(t)
+	router := newTestRouter()
+	body := `{"name": "Dorji", "email": "dorji@example.com"}`
+
+	require.Equal(t, http.StatusCreated, doJSON(router, http.MethodPost, "/users", body).Code)
+
+	rec := doJSON(router, http.MethodPost, "/users", `{"name": "Other Dorji", "email": "dorji@example.com"}`)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.JSONEq(t, `{"error": "email already registered"}`, rec.Body.String())
+}
+
+func TestCreateUserValidation(t *testing.T) {
+	setupTestDB(t)
+	router := newTestRouter()
+
+	tests := map[string]string{
+		"missing name":  `{"email": "pema@example.com"}`,
+		"blank name":    `{"name": "  ", "email": "pema@example.com"}`,
+		"bad email":     `{"name": "Pema", "email": "pema.example.com"}`,
+		"display name":  `{"name": "Pema", "email": "Pema <pema@example.com>"}`,
+		"missing email": `{"name": "Pema"}`,
+	}
+	for name, body := range tests {
+		rec := doJSON(router, http.MethodPost, "/users", body)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, name)
+		assert.Contains(t, rec.Body.String(), `"error"`, name)
+	}
+
+	var count int64
+	require.NoError(t, database.DB.Model(&models.User{}).Count(&count).Error)
+	assert.Zero(t, count, "invalid users must not be stored")
+}
+
+func TestCreateUserRejectsMalformedBodies(t *testing.T) {
+	setupTestDB(t)
+	router := newTestRouter()
+	old := httputil.MaxBodyBytes
+	httputil.MaxBodyBytes = 256
+	t.Cleanup(func() { httputil.MaxBodyBytes = old })
+
+	rec := doJSON(router, http.MethodPost, "/users", `{"name": "Pema", "email": "pema@example.com", "emial": "typo"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.JSONEq(t, `{"error": "Invalid user data: unknown field \"emial\""}`, rec.Body.String())
+
+	rec = doJSON(router, http.MethodPost, "/users", `{"name": "Pema", "email": "pema@example.com"} {"name": "Sonam"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "single JSON value")
+
+	rec = doJSON(router, http.MethodPost, "/users", `{"name": "`+strings.Repeat("p", 512)+`", "email": "pema@example.com"}`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+
+	var count int64
+	require.NoError(t, database.DB.Model(&models.User{}).Count(&count).Error)
+	assert.Zero(t, count, "rejected bodies must not be stored")
+}
+
+func TestGetUsersCompressesLargeLists(t *testing.T) {
+	db := setupTestDB(t)
+	for i := 0; i < 40; i++ {
+		require.NoError(t, db.Create(&models.User{Name: fmt.Sprintf("User %d", i), Email: fmt.Sprintf("user%d@cafe.test", i)}).Error)
+	}
+	router := newTestRouter()
+
+	req := httptest.NewRequest(http.MethodGet, "/users", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+	assert.Contains(t, rec.Header().Values("Vary"), "Accept-Encoding")
+	zr, err := gzip.NewReader(rec.Body)
+	require.NoError(t, err)
+	var list UserList
+	require.NoError(t, json.NewDecoder(zr).Decode(&list))
+	assert.Len(t, list.Data, httputil.DefaultPageLimit)
+
+	plain := do(router, http.MethodGet, "/users")
+	require.Equal(t, http.StatusOK, plain.Code)
+	assert.Empty(t, plain.Header().Get("Content-Encoding"))
+	require.NoError(t, json.NewDecoder(plain.Body).Decode(&list))
+	assert.Len(t, list.Data, httputil.DefaultPageLimit)
+}
+
+func TestGetUserConditional(t *testing.T) {
+	db := setupTestDB(t)
+	user := models.User{Name: "Pema", Email: "pema@cafe.test"}
+	require.NoError(t, db.Create(&user).Error)
+	router := newTestRouter()
+	path := fmt.Sprintf("/users/%d", user.ID)
+
+	rec := do(router, http.MethodGet, path)
+	require.Equal(t, http.StatusOK, rec.Code)
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Zero(t, rec.Body.Len())
+
+	require.NoError(t, db.Model(&user).Update("name", "Pema Dorji").Error)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code, "an update invalidates the old ETag")
+	assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+}
+
+func TestWrongMethodGets405WithAllow(t *testing.T) {
+	setupTestDB(t)
+	router := newTestRouter()
+
+	rec := do(router, http.MethodPost, "/users/1")
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, "GET, PUT, PATCH, DELETE", rec.Header().Get("Allow"))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	rec = do(router, http.MethodDelete, "/users")
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, "GET, POST", rec.Header().Get("Allow"))
+
+	rec = do(router, http.MethodGet, "/users/1/restore")
+	assert.Equal(t, "POST", rec.Header().Get("Allow"))
+
+	rec = do(router, http.MethodGet, "/orders")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.JSONEq(t, `{"error": "no route for /orders"}`, rec.Body.String())
+}
+
+func patchRole(router http.Handler, path, token, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPatch, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("X-Admin-Token", token)
+	}
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestSetUserRole(t *testing.T) {
+	db := setupTestDB(t)
+	user := models.User{Name: "Dorji", Email: "dorji@example.com"}
+	require.NoError(t, db.Create(&user).Error)
+	router := newTestRouter()
+	orig := AdminToken
+	AdminToken = "admin-secret"
+	t.Cleanup(func() { AdminToken = orig })
+	path := fmt.Sprintf("/users/%d/role", user.ID)
+
+	for _, owner := range []bool{true, false} {
+		rec := patchRole(router, path, "admin-secret", fmt.Sprintf(`{"is_cafe_owner": %t}`, owner))
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var updated models.UserResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&updated))
+		assert.Equal(t, owner, updated.IsCafeOwner)
+
+		var stored models.User
+		require.NoError(t, db.First(&stored, user.ID).Error)
+		assert.Equal(t, owner, stored.IsCafeOwner)
+		assert.Equal(t, "Dorji", stored.Name, "only the role column changes")
+	}
+}
+
+func TestSetUserRoleErrors(t *testing.T) {
+	db := setupTestDB(t)
+	user := models.User{Name: "Dorji", Email: "dorji@example.com"}
+	require.NoError(t, db.Create(&user).Error)
+	router := newTestRouter()
+	orig := AdminToken
+	AdminToken = "admin-secret"
+	t.Cleanup(func() { AdminToken = orig })
+	path := fmt.Sprintf("/users/%d/role", user.ID)
+
+	assert.Equal(t, http.StatusForbidden, patchRole(router, path, "", `{"is_cafe_owner": true}`).Code)
+	assert.Equal(t, http.StatusForbidden, patchRole(router, path, "guess", `{"is_cafe_owner": true}`).Code)
+	assert.Equal(t, http.StatusNotFound, patchRole(router, "/users/99/role", "admin-secret", `{"is_cafe_owner": true}`).Code)
+	for _, body := range []string{`{"is_cafe_owner": `, `{}`, `{"is_cafe_owner": "yes"}`, `{"role": "owner"}`} {
+		assert.Equal(t, http.StatusBadRequest, patchRole(router, path, "admin-secret", body).Code, body)
+	}
+
+	var stored models.User
+	require.NoError(t, db.First(&stored, user.ID).Error)
+	assert.False(t, stored.IsCafeOwner, "rejected requests leave the role alone")
+}
+
+func TestOnlyAdminsGrantCafeOwner(t *testing.T) {
+	db := setupTestDB(t)
+	user := models.User{Name: "Dorji", Email: "dorji@example.com"}
+	require.NoError(t, db.Create(&user).Error)
+	router := newTestRouter()
+	orig := AdminToken
+	AdminToken = "admin-secret"
+	t.Cleanup(func() { AdminToken = orig })
+	path := fmt.Sprintf("/users/%d", user.ID)
+
+	for _, method := range []string{http.MethodPatch, http.MethodPut} {
+		rec := doJSON(router, method, path, `{"is_cafe_owner": true}`)
+		assert.Equal(t, http.StatusForbidden, rec.Code, method)
+	}
+	rec := doJSON(router, http.MethodPost, "/users", `{"name": "Pema", "email": "pema@example.com", "is_cafe_owner": true}`)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	var stored models.User
+	require.NoError(t, db.First(&stored, user.ID).Error)
+	assert.False(t, stored.IsCafeOwner, "an update without the admin token cannot promote")
+	var owners int64
+	require.NoError(t, db.Model(&models.User{}).Where("is_cafe_owner = ?", true).Count(&owners).Error)
+	assert.Zero(t, owners, "a create without the admin token cannot make an owner")
+
+	rec = doJSON(router, http.MethodPost, "/users", `{"name": "Pema", "email": "pema@example.com", "is_cafe_owner": false}`)
+	assert.Equal(t, http.StatusCreated, rec.Code, "non-owner accounts need no token")
+
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"name": "Karma", "email": "karma@example.com", "is_cafe_owner": true}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Admin-Token", "admin-secret")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	var created models.UserResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&created))
+	assert.True(t, created.IsCafeOwner, "admins may create owners")
+}
+
+// slowQueries makes every query on db wait for its context to end, or for a
+// second, before running, standing in for a query stuck on a lock.
+func slowQueries(t *testing.T, db *gorm.DB) {
+	require.NoError(t, db.Callback().Query().Before("gorm:query").Register("slow_query", func(tx *gorm.DB) {
+		select {
+		case <-tx.Statement.Context.Done():
+		case <-time.After(time.Second):
+		}
+	}))
+}
+
+func TestQueriesStopAtTheQueryTimeout(t *testing.T) {
+	db := setupTestDB(t)
+	user := models.User{Name: "Dorji", Email: "dorji@example.com"}
+	require.NoError(t, db.Create(&user).Error)
+	slowQueries(t, db)
+	old := database.QueryTimeout
+	database.QueryTimeout = 20 * time.Millisecond
+	t.Cleanup(func() { database.QueryTimeout = old })
+
+	start := time.Now()
+	rec := do(newTestRouter(), http.MethodGet, fmt.Sprintf("/users/%d", user.ID))
+	assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+	assert.Contains(t, rec.Body.String(), "deadline exceeded")
+	assert.Less(t, time.Since(start), 500*time.Millisecond, "the handler does not wait for the query")
+}
+
+func TestQueriesStopWhenTheClientGoesAway(t *testing.T) {
+	db := setupTestDB(t)
+	slowQueries(t, db)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	rec := httptest.NewRecorder()
+	start := time.Now()
+	newTestRouter().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users", nil).WithContext(ctx))
+	assert.Equal(t, httputil.StatusClientClosedRequest, rec.Code)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func postUserWithKey(router http.Handler, key, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", key)
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCreateUserIdempotencyKey(t *testing.T) {
+	setupTestDB(t)
+	router := newTestRouter()
+	body := `{"name": "Dorji", "email": "dorji@example.com"}`
+
+	first := postUserWithKey(router, "retry-1", body)
+	require.Equal(t, http.StatusCreated, first.Code, first.Body.String())
+
+	// Without the key the retry would hit the unique email and get a 409
+	replay := postUserWithKey(router, "retry-1", body)
+	assert.Equal(t, http.StatusCreated, replay.Code)
+	assert.Equal(t, "true", replay.Header().Get("Idempotent-Replayed"))
+	assert.JSONEq(t, first.Body.String(), replay.Body.String())
+
+	conflict := postUserWithKey(router, "retry-1", `{"name": "Pema", "email": "pema@example.com"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, conflict.Code)
+
+	var count int64
+	require.NoError(t, database.DB.Model(&models.User{}).Count(&count).Error)
+	assert.EqualValues(t, 1, count, "only the first request inserts")
+}
+
+func getWithAccept(router http.Handler, path, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Accept", accept)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGetUsersAsJSONAndCSV(t *testing.T) {
+	db := setupTestDB(t)
+	seedUsers(t, db, 3)
+	require.NoError(t, db.Model(&models.User{}).Where("id = ?", 2).Update("name", "=cmd|' /C calc'!A0").Error)
+	router := newTestRouter()
+
+	list := getUserList(t, router, "/users?limit=2")
+	require.Len(t, list.Data, 2)
+
+	rec := getWithAccept(router, "/users?limit=2", "text/csv")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "attachment; filename=users.csv", rec.Header().Get("Content-Disposition"))
+	assert.Contains(t, rec.Header().Values("Vary"), "Accept")
+	assert.Equal(t, "3", rec.Header().Get("X-Total-Count"), "paging applies to CSV too")
+
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, []string{"id", "name", "email", "is_cafe_owner", "created_at", "updated_at", "deleted_at"}, rows[0])
+	first := list.Data[0]
+	assert.Equal(t, []string{"1", first.Name, first.Email, "false", first.CreatedAt, first.UpdatedAt, ""}, rows[1])
+	assert.Equal(t, "'"+list.Data[1].Name, rows[2][1], "formulas are neutralised")
+
+	assert.Equal(t, http.StatusOK, getWithAccept(router, "/users", "text/csv;q=0.2, application/json").Code)
+	assert.Contains(t, getWithAccept(router, "/users", "*/*").Header().Get("Content-Type"), "application/json")
+
+	notAcceptable := getWithAccept(router, "/users", "application/xml")
+	assert.Equal(t, http.StatusNotAcceptable, notAcceptable.Code)
+	assert.JSONEq(t, `{"error": "not acceptable", "supported": ["application/json", "text/csv"]}`, notAcceptable.Body.String())
+	assert.Equal(t, http.StatusNotAcceptable, getWithAccept(router, "/users?stream=true", "text/csv").Code, "streams are JSON only")
+}
+
+func getUsersBatch(t *testing.T, router http.Handler, body string) BatchUsersResponse {
+	rec := doJSON(router, http.MethodPost, "/users/batch", body)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp BatchUsersResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+	return resp
+}
+
+func batchNames(users []models.UserResponse) []string {
+	names := make([]string, len(users))
+	for i, u := range users {
+		names[i] = u.Name
+	}
+	return names
+}
+
+func TestGetUsersBatchFullHit(t *testing.T) {
+	seedUsers(t, setupTestDB(t), 5)
+
+	resp := getUsersBatch(t, newTestRouter(), `{"ids": [4, 1, 3]}`)
+	assert.Equal(t, []string{"User 4", "User 1", "User 3"}, batchNames(resp.Data), "users come back in request order")
+	assert.Empty(t, resp.Missing)
+	assert.Equal(t, "user4@example.com", resp.Data[0].Email)
+}
+
+func TestGetUsersBatchPartialHit(t *testing.T) {
+	db := setupTestDB(t)
+	seedUsers(t, db, 3)
+	require.NoError(t, db.Delete(&models.User{}, 2).Error)
+
+	resp := getUsersBatch(t, newTestRouter(), `{"ids": [1, 2, 3, 99]}`)
+	assert.Equal(t, []string{"User 1", "User 3"}, batchNames(resp.Data))
+	assert.Equal(t, []uint{2, 99}, resp.Missing, "soft-deleted and unknown IDs are missing")
+
+	rec := doJSON(newTestRouter(), http.MethodPost, "/users/batch", `{"ids": [42]}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"data": [], "missing": [42]}`, rec.Body.String())
+}
+
+func TestGetUsersBatchDedupesInOneQuery(t *testing.T) {
+	db := setupTestDB(t)
+	seedUsers(t, db, 3)
+	var queries []string
+	require.NoError(t, db.Callback().Query().After("gorm:query").Register("record_query", func(tx *gorm.DB) {
+		queries = append(queries, tx.Statement.SQL.String())
+	}))
+
+	resp := getUsersBatch(t, newTestRouter(), `{"ids": [2, 7, 2, 1, 7, 2]}`)
+	assert.Equal(t, []string{"User 2", "User 1"}, batchNames(resp.Data))
+	assert.Equal(t, []uint{7}, resp.Missing)
+	require.Len(t, queries, 1)
+	assert.Contains(t, queries[0], "IN (?,?,?)", "duplicates are dropped before querying")
+}
+
+func TestGetUsersBatchRejectsBadRequests(t *testing.T) {
+	setupTestDB(t)
+	router := newTestRouter()
+
+	ids := make([]string, maxBatchUserIDs+1)
+	for i := range ids {
+		ids[i] = strconv.Itoa(i + 1)
+	}
+	over := doJSON(router, http.MethodPost, "/users/batch", `{"ids": [`+strings.Join(ids, ",")+`]}`)
+	assert.Equal(t, http.StatusBadRequest, over.Code)
+	assert.Contains(t, over.Body.String(), fmt.Sprintf("between 1 and %d", maxBatchUserIDs))
+
+	// Duplicates do not count towards the cap
+	repeated := strings.Repeat("1,", maxBatchUserIDs) + "1"
+	assert.Equal(t, http.StatusOK, doJSON(router, http.MethodPost, "/users/batch", `{"ids": [`+repeated+`]}`).Code)
+
+	for _, body := range []string{`{"ids": []}`, `{}`, `{"ids": [-1]}`, `{"ids": "1,2"}`, `not json`} {
+		assert.Equal(t, http.StatusBadRequest, doJSON(router, http.MethodPost, "/users/batch", body).Code, body)
+	}
+}
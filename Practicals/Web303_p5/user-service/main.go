@@ -1,40 +1,126 @@
 package main
 
 import (
+	"dbutil"
+	"fmt"
+	"httputil"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"time"
+	"user-service/config"
 	"user-service/database"
+	grpcserver "user-service/grpc"
 	"user-service/handlers"
+	"user-service/models"
 
+	userv1 "github.com/douglasswm/student-cafe-protos/gen/go/user/v1"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"google.golang.org/grpc"
 )
 
 func main() {
-	// Connect to dedicated user database
-	dsn := os.Getenv("DATABASE_URL")
-	if dsn == "" {
-		dsn = "host=localhost user=postgres password=postgres dbname=user_db port=5432 sslmode=disable"
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	database.QueryLogLevel = dbutil.QueryLogLevelFor(cfg.LogLevel)
+
+	if raw := os.Getenv("SLOW_QUERY_THRESHOLD"); raw != "" {
+		threshold, err := time.ParseDuration(raw)
+		if err != nil {
+			log.Fatalf("Invalid SLOW_QUERY_THRESHOLD: %v", err)
+		}
+		database.SlowQueryThreshold = threshold
+	}
+
+	database.Pool = database.PoolConfig{
+		MaxOpenConns:    cfg.DBMaxOpenConns,
+		MaxIdleConns:    cfg.DBMaxIdleConns,
+		ConnMaxLifetime: cfg.DBConnMaxLifetime,
 	}
+	database.ConnectRetry = cfg.DBConnectRetry
+	database.QueryTimeout = cfg.QueryTimeout
 
-	if err := database.Connect(dsn); err != nil {
+	// Connect to dedicated user database
+	if err := database.Connect(cfg.DatabaseDSN); err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
+	// `user-service migrate` applies pending schema migrations and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := database.Migrate(database.DB); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+	if err := database.EnsureSchema(database.DB, cfg.MigrateOnBoot); err != nil {
+		log.Fatalf("Database schema not ready: %v", err)
+	}
+	if cfg.SeedData {
+		inserted, err := database.Seed(database.DB, cfg.SeedFile)
+		if err != nil {
+			log.Fatalf("Seeding from %s failed: %v", cfg.SeedFile, err)
+		}
+		log.Printf("Seeded %d users from %s", inserted, cfg.SeedFile)
+	}
+
+	// Dev/test only: explain slow queries to spot missing indexes
+	if dbutil.IndexAdvisorAllowed(os.Getenv("APP_ENV")) {
+		dbutil.EnableIndexAdvisor(database.DB, database.SlowQueryThreshold)
+		log.Println("Index advisor enabled")
+	}
+
+	if format := os.Getenv("JSON_TIME_FORMAT"); format != "" {
+		models.TimeFormat = format
+	}
+
+	httputil.MaxBodyBytes = int64(cfg.MaxBodyBytes)
+
 	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
 	r.Use(middleware.Logger)
+	r.Use(httputil.Recover)
+	r.Use(httputil.Compress(httputil.DefaultCompressMinSize))
+	r.NotFound(httputil.NotFound)
+	r.MethodNotAllowed(httputil.MethodNotAllowed(r))
+
+	r.Get("/healthz", handlers.Liveness)
+	r.Get("/readyz", handlers.ReadinessCheck)
+	r.Get("/openapi.json", handlers.OpenAPI)
+	r.Get("/docs", handlers.Docs)
+
+	// Retried creates replay their first response
+	idempotency := httputil.NewIdempotency(cfg.IdempotencyTTL)
 
 	// User endpoints
-	r.Post("/users", handlers.CreateUser)
+	r.With(idempotency.Wrap).Post("/users", handlers.CreateUser)
+	r.Post("/users/batch", handlers.GetUsersBatch)
 	r.Get("/users/{id}", handlers.GetUser)
 	r.Get("/users", handlers.GetUsers)
+	r.Put("/users/{id}", handlers.UpdateUser)
+	r.Patch("/users/{id}", handlers.UpdateUser)
+	r.Delete("/users/{id}", handlers.DeleteUser)
+	r.Post("/users/{id}/restore", handlers.RestoreUser)
+	r.Patch("/users/{id}/role", handlers.SetUserRole)
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8081"
+	if cfg.GRPCPort != 0 {
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPCPort))
+		if err != nil {
+			log.Fatalf("Failed to listen on gRPC port %d: %v", cfg.GRPCPort, err)
+		}
+		server := grpc.NewServer()
+		userv1.RegisterUserServiceServer(server, grpcserver.NewUserServer())
+		go func() {
+			log.Printf("User gRPC service starting on :%d", cfg.GRPCPort)
+			if err := server.Serve(lis); err != nil {
+				log.Fatalf("gRPC server failed: %v", err)
+			}
+		}()
 	}
 
-	log.Printf("User service starting on :%s", port)
-	http.ListenAndServe(":"+port, r)
+	log.Printf("User service starting on :%d", cfg.ServicePort)
+	http.ListenAndServe(fmt.Sprintf(":%d", cfg.ServicePort), r)
 }
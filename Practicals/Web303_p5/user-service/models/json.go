@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TimeFormat is the layout used for timestamps in JSON responses.
+var TimeFormat = time.RFC3339
+
+// formatTime renders t in UTC using TimeFormat.
+func formatTime(t time.Time) string {
+	return t.UTC().Format(TimeFormat)
+}
+
+// formatDeletedAt renders a soft-delete timestamp, or nil when it is unset
+// or the caller is not allowed to see it.
+func formatDeletedAt(d gorm.DeletedAt, include bool) *string {
+	if !include || !d.Valid {
+		return nil
+	}
+	s := formatTime(d.Time)
+	return &s
+}
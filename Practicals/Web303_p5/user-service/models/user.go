@@ -1,10 +1,63 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"errors"
+	"net/mail"
+	"strings"
+
+	"gorm.io/gorm"
+)
 
 type User struct {
 	gorm.Model
-	Name       string `json:"name"`
-	Email      string `json:"email" gorm:"unique"`
+	Name        string `json:"name"`
+	Email       string `json:"email" gorm:"unique"`
 	IsCafeOwner bool   `json:"is_cafe_owner"`
 }
+
+// Validate checks the fields a new user needs before touching the DB.
+func (u User) Validate() error {
+	if strings.TrimSpace(u.Name) == "" {
+		return errors.New("name is required")
+	}
+	if addr, err := mail.ParseAddress(u.Email); err != nil || addr.Address != u.Email {
+		return errors.New("email is not a valid address")
+	}
+	return nil
+}
+
+// UserResponse is the JSON shape of a User returned by the API, with
+// timestamps formatted consistently for clients. ID and the timestamps keep
+// the keys gorm.Model serialised to, which clients already read.
+type UserResponse struct {
+	ID          uint    `json:"ID"`
+	Name        string  `json:"name"`
+	Email       string  `json:"email"`
+	IsCafeOwner bool    `json:"is_cafe_owner"`
+	CreatedAt   string  `json:"CreatedAt"`
+	UpdatedAt   string  `json:"UpdatedAt"`
+	DeletedAt   *string `json:"DeletedAt,omitempty"`
+}
+
+// ToResponse converts the user to its API representation. DeletedAt is only
+// included when includeDeleted is set (admin callers).
+func (u User) ToResponse(includeDeleted bool) UserResponse {
+	return UserResponse{
+		ID:          u.ID,
+		Name:        u.Name,
+		Email:       u.Email,
+		IsCafeOwner: u.IsCafeOwner,
+		CreatedAt:   formatTime(u.CreatedAt),
+		UpdatedAt:   formatTime(u.UpdatedAt),
+		DeletedAt:   formatDeletedAt(u.DeletedAt, includeDeleted),
+	}
+}
+
+// UsersToResponse converts a slice of users to their API representation.
+func UsersToResponse(users []User, includeDeleted bool) []UserResponse {
+	out := make([]UserResponse, len(users))
+	for i, u := range users {
+		out[i] = u.ToResponse(includeDeleted)
+	}
+	return out
+}
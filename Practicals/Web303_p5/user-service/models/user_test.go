@@ -0,0 +1,39 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func sampleUser() User {
+	local := time.FixedZone("BTT", 6*60*60)
+	u := User{Name: "Pema", Email: "pema@example.com"}
+	u.ID = 7
+	u.CreatedAt = time.Date(2025, 9, 1, 14, 30, 0, 0, local)
+	u.UpdatedAt = time.Date(2025, 9, 2, 8, 0, 0, 0, local)
+	u.DeletedAt = gorm.DeletedAt{Time: time.Date(2025, 9, 3, 6, 0, 0, 0, local), Valid: true}
+	return u
+}
+
+func TestUserToResponseFormatsTimestampsAsRFC3339UTC(t *testing.T) {
+	resp := sampleUser().ToResponse(false)
+
+	assert.Equal(t, "2025-09-01T08:30:00Z", resp.CreatedAt)
+	assert.Equal(t, "2025-09-02T02:00:00Z", resp.UpdatedAt)
+}
+
+func TestUserToResponseHidesDeletedAtUnlessAdmin(t *testing.T) {
+	body, err := json.Marshal(sampleUser().ToResponse(false))
+	require.NoError(t, err)
+	assert.NotContains(t, string(body), "DeletedAt")
+	assert.Contains(t, string(body), `"CreatedAt":"2025-09-01T08:30:00Z"`, "gorm.Model keys are kept for existing clients")
+
+	admin := sampleUser().ToResponse(true)
+	require.NotNil(t, admin.DeletedAt)
+	assert.Equal(t, "2025-09-03T00:00:00Z", *admin.DeletedAt)
+}
@@ -1,14 +1,19 @@
 package grpc
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	menuv1 "github.com/douglasswm/student-cafe-protos/gen/go/menu/v1"
 	orderv1 "github.com/douglasswm/student-cafe-protos/gen/go/order/v1"
 	userv1 "github.com/douglasswm/student-cafe-protos/gen/go/user/v1"
+	consulapi "github.com/hashicorp/consul/api"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials/insecure"
 )
 
@@ -17,44 +22,167 @@ type ServiceClients struct {
 	UserClient  userv1.UserServiceClient
 	MenuClient  menuv1.MenuServiceClient
 	OrderClient orderv1.OrderServiceClient
+
+	// UnhealthyAfter is how long a backend may be connecting or failing
+	// before Healthy reports it; zero means defaultUnhealthyAfter.
+	UnhealthyAfter time.Duration
+
+	conns []*managedConn
+}
+
+// defaultUnhealthyAfter matches the gateway's default reconnect threshold.
+const defaultUnhealthyAfter = 15 * time.Second
+
+// Backend service names, as registered in discovery.
+const (
+	UserService  = "user-service"
+	MenuService  = "menu-service"
+	OrderService = "order-service"
+)
+
+// EnvResolver resolves backends from the *_SERVICE_GRPC_ADDR variables,
+// falling back to the docker-compose hostnames.
+func EnvResolver(service string) (string, error) {
+	switch service {
+	case UserService:
+		return getEnv("USER_SERVICE_GRPC_ADDR", "user-service:9091"), nil
+	case MenuService:
+		return getEnv("MENU_SERVICE_GRPC_ADDR", "menu-service:9092"), nil
+	case OrderService:
+		return getEnv("ORDER_SERVICE_GRPC_ADDR", "order-service:9093"), nil
+	}
+	return "", fmt.Errorf("unknown service %q", service)
 }
 
 // NewServiceClients creates and initializes gRPC clients for all backend services
 func NewServiceClients() (*ServiceClients, error) {
-	// Get service addresses from environment or use defaults
-	userAddr := getEnv("USER_SERVICE_GRPC_ADDR", "user-service:9091")
-	menuAddr := getEnv("MENU_SERVICE_GRPC_ADDR", "menu-service:9092")
-	orderAddr := getEnv("ORDER_SERVICE_GRPC_ADDR", "order-service:9093")
-
-	log.Printf("Connecting to User Service at %s", userAddr)
-	// Create gRPC connection to user service
-	userConn, err := grpc.NewClient(userAddr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()))
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to user service: %w", err)
+	policy := DefaultRetryPolicy()
+	if raw := os.Getenv("GRPC_RETRYABLE_CODES"); raw != "" {
+		retryable, err := ParseRetryableCodes(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid GRPC_RETRYABLE_CODES: %w", err)
+		}
+		policy.RetryableCodes = retryable
 	}
+	retry := grpc.WithUnaryInterceptor(policy.UnaryClientInterceptor())
+	// Spans are named after the gRPC method and continue the caller's trace
+	tracing := grpc.WithStatsHandler(otelgrpc.NewClientHandler())
 
-	log.Printf("Connecting to Menu Service at %s", menuAddr)
-	// Create gRPC connection to menu service
-	menuConn, err := grpc.NewClient(menuAddr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()))
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to menu service: %w", err)
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials()), retry, tracing}
+	resolve := EnvResolver
+	// GRPC_DISCOVERY=consul balances across every healthy instance in Consul
+	switch mode := getEnv("GRPC_DISCOVERY", "static"); mode {
+	case "static":
+	case "consul":
+		config := consulapi.DefaultConfig()
+		config.Address = getEnv("CONSUL_ADDR", "consul:8500")
+		client, err := consulapi.NewClient(config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Consul client: %w", err)
+		}
+		var discoveryOpts []grpc.DialOption
+		resolve, discoveryOpts = ConsulDiscovery(client.Health(), defaultConsulRefresh)
+		opts = append(opts, discoveryOpts...)
+	default:
+		return nil, fmt.Errorf("invalid GRPC_DISCOVERY %q, expected static or consul", mode)
 	}
 
-	log.Printf("Connecting to Order Service at %s", orderAddr)
-	// Create gRPC connection to order service
-	orderConn, err := grpc.NewClient(orderAddr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()))
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to order service: %w", err)
+	return newServiceClients(resolve, opts...)
+}
+
+// defaultConsulRefresh is how often Consul is re-queried for instances.
+const defaultConsulRefresh = 10 * time.Second
+
+// newServiceClients dials every backend through resolve.
+func newServiceClients(resolve Resolver, opts ...grpc.DialOption) (*ServiceClients, error) {
+	clients := &ServiceClients{}
+	for _, service := range []string{UserService, MenuService, OrderService} {
+		conn, err := newManagedConn(service, resolve, opts...)
+		if err != nil {
+			clients.Close()
+			return nil, err
+		}
+		log.Printf("Connecting to %s at %s", service, conn.addr)
+		clients.conns = append(clients.conns, conn)
 	}
 
-	return &ServiceClients{
-		UserClient:  userv1.NewUserServiceClient(userConn),
-		MenuClient:  menuv1.NewMenuServiceClient(menuConn),
-		OrderClient: orderv1.NewOrderServiceClient(orderConn),
-	}, nil
+	clients.UserClient = userv1.NewUserServiceClient(clients.conns[0])
+	clients.MenuClient = menuv1.NewMenuServiceClient(clients.conns[1])
+	clients.OrderClient = orderv1.NewOrderServiceClient(clients.conns[2])
+	return clients, nil
+}
+
+// Healthy reports whether every backend connection is usable: ready, idle,
+// or connecting or failing for less than UnhealthyAfter, so a reconnect
+// does not take the gateway out of rotation. Clients built without managed
+// connections (as in tests) are always healthy.
+func (c *ServiceClients) Healthy() bool {
+	threshold := c.UnhealthyAfter
+	if threshold <= 0 {
+		threshold = defaultUnhealthyAfter
+	}
+	now := time.Now()
+	healthy := true
+	// Every connection is checked so each one's unsettled time is tracked
+	for _, conn := range c.conns {
+		if !conn.healthy(now, threshold) {
+			healthy = false
+		}
+	}
+	return healthy
+}
+
+// States reports the connectivity state of each backend by service name.
+func (c *ServiceClients) States() map[string]string {
+	states := make(map[string]string, len(c.conns))
+	for _, conn := range c.conns {
+		states[conn.service] = conn.State().String()
+	}
+	return states
+}
+
+// WaitForReady blocks until every backend connection is ready or ctx ends.
+func (c *ServiceClients) WaitForReady(ctx context.Context) error {
+	for _, m := range c.conns {
+		for {
+			conn := m.current()
+			state := conn.GetState()
+			if state == connectivity.Ready {
+				break
+			}
+			if state == connectivity.Idle {
+				conn.Connect()
+			}
+			if !conn.WaitForStateChange(ctx, state) {
+				return fmt.Errorf("%s not ready: %w", m.service, ctx.Err())
+			}
+		}
+	}
+	return nil
+}
+
+// Monitor probes the backend connections every interval until ctx ends,
+// re-resolving and re-dialling any that stayed failed beyond threshold.
+func (c *ServiceClients) Monitor(ctx context.Context, interval, threshold time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, conn := range c.conns {
+				conn.check(now, threshold)
+			}
+		}
+	}
+}
+
+// Close tears down every backend connection.
+func (c *ServiceClients) Close() {
+	for _, conn := range c.conns {
+		conn.Close()
+	}
 }
 
 func getEnv(key, defaultVal string) string {
@@ -0,0 +1,151 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/connectivity"
+)
+
+// connectParams bounds how quickly a failed connection is retried by gRPC
+// itself before the monitor gives up on the address and re-resolves it.
+var connectParams = grpc.ConnectParams{
+	Backoff: backoff.Config{
+		BaseDelay:  500 * time.Millisecond,
+		Multiplier: 1.6,
+		Jitter:     0.2,
+		MaxDelay:   10 * time.Second,
+	},
+	MinConnectTimeout: 5 * time.Second,
+}
+
+// Resolver returns the current address of a backend service.
+type Resolver func(service string) (string, error)
+
+// managedConn is a ClientConnInterface whose underlying connection can be
+// replaced while stubs built on it are in use.
+type managedConn struct {
+	service string
+	resolve Resolver
+	opts    []grpc.DialOption
+
+	mu          sync.RWMutex
+	conn        *grpc.ClientConn
+	addr        string
+	failedSince time.Time
+	// unsettledSince is when the connection was first seen neither ready
+	// nor idle. Unlike failedSince it survives re-dials, so a backend that
+	// stays down is reported however often it is re-resolved.
+	unsettledSince time.Time
+}
+
+func newManagedConn(service string, resolve Resolver, opts ...grpc.DialOption) (*managedConn, error) {
+	m := &managedConn{service: service, resolve: resolve, opts: opts}
+	if err := m.redial(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *managedConn) current() *grpc.ClientConn {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.conn
+}
+
+func (m *managedConn) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+	return m.current().Invoke(ctx, method, args, reply, opts...)
+}
+
+func (m *managedConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return m.current().NewStream(ctx, desc, method, opts...)
+}
+
+// State reports the connectivity state of the current connection.
+func (m *managedConn) State() connectivity.State {
+	return m.current().GetState()
+}
+
+// redial resolves the service again and swaps in a fresh connection,
+// closing the old one.
+func (m *managedConn) redial() error {
+	addr, err := m.resolve(m.service)
+	if err != nil {
+		return fmt.Errorf("resolving %s: %w", m.service, err)
+	}
+	conn, err := grpc.NewClient(addr, append([]grpc.DialOption{grpc.WithConnectParams(connectParams)}, m.opts...)...)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", m.service, err)
+	}
+	conn.Connect()
+
+	m.mu.Lock()
+	old := m.conn
+	m.conn, m.addr, m.failedSince = conn, addr, time.Time{}
+	m.mu.Unlock()
+	if old != nil {
+		old.Close()
+	}
+	return nil
+}
+
+// healthy reports whether the connection is usable. Ready and Idle (which
+// connects on the next call) are healthy; Connecting and TransientFailure
+// are too until they have lasted past threshold.
+func (m *managedConn) healthy(now time.Time, threshold time.Duration) bool {
+	state := m.State()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch state {
+	case connectivity.Ready, connectivity.Idle:
+		m.unsettledSince = time.Time{}
+		return true
+	case connectivity.Shutdown:
+		return false
+	}
+	if m.unsettledSince.IsZero() {
+		m.unsettledSince = now
+	}
+	return now.Sub(m.unsettledSince) < threshold
+}
+
+// check records how long the connection has been failing and re-dials once
+// that exceeds threshold. Idle connections are nudged to connect.
+func (m *managedConn) check(now time.Time, threshold time.Duration) {
+	m.healthy(now, threshold)
+	conn := m.current()
+	switch conn.GetState() {
+	case connectivity.Ready, connectivity.Connecting:
+		m.mu.Lock()
+		m.failedSince = time.Time{}
+		m.mu.Unlock()
+		return
+	case connectivity.Idle:
+		conn.Connect()
+		return
+	}
+
+	m.mu.Lock()
+	if m.failedSince.IsZero() {
+		m.failedSince = now
+	}
+	failing := now.Sub(m.failedSince)
+	m.mu.Unlock()
+	if failing < threshold {
+		return
+	}
+
+	log.Printf("%s connection failing for %s, re-resolving", m.service, failing.Round(time.Millisecond))
+	if err := m.redial(); err != nil {
+		log.Printf("Re-dial of %s failed: %v", m.service, err)
+	}
+}
+
+func (m *managedConn) Close() error {
+	return m.current().Close()
+}
@@ -0,0 +1,145 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	menuv1 "github.com/douglasswm/student-cafe-protos/gen/go/menu/v1"
+	orderv1 "github.com/douglasswm/student-cafe-protos/gen/go/order/v1"
+	userv1 "github.com/douglasswm/student-cafe-protos/gen/go/user/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// userBackend answers GetUser with its own name so tests can tell which
+// instance served a call.
+type userBackend struct {
+	userv1.UnimplementedUserServiceServer
+	name string
+}
+
+func (b *userBackend) GetUser(ctx context.Context, in *userv1.GetUserRequest) (*userv1.GetUserResponse, error) {
+	return &userv1.GetUserResponse{User: &userv1.User{Id: in.Id, Name: b.name}}, nil
+}
+
+// bufnet hosts in-memory backends by address and resolves every service to
+// the most recently started one.
+type bufnet struct {
+	mu        sync.Mutex
+	listeners map[string]*bufconn.Listener
+	current   string
+}
+
+func (n *bufnet) start(t *testing.T, addr string) *grpc.Server {
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	userv1.RegisterUserServiceServer(server, &userBackend{name: addr})
+	menuv1.RegisterMenuServiceServer(server, menuv1.UnimplementedMenuServiceServer{})
+	orderv1.RegisterOrderServiceServer(server, orderv1.UnimplementedOrderServiceServer{})
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	n.mu.Lock()
+	n.listeners[addr], n.current = lis, addr
+	n.mu.Unlock()
+	return server
+}
+
+func (n *bufnet) resolve(string) (string, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return "passthrough:///" + n.current, nil
+}
+
+func (n *bufnet) dial(ctx context.Context, addr string) (net.Conn, error) {
+	n.mu.Lock()
+	lis, ok := n.listeners[addr]
+	n.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("no backend at %s", addr)
+	}
+	return lis.DialContext(ctx)
+}
+
+func newTestClients(t *testing.T, n *bufnet) *ServiceClients {
+	clients, err := newServiceClients(n.resolve,
+		grpc.WithContextDialer(n.dial), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(clients.Close)
+	return clients
+}
+
+func TestServiceClientsWaitForReady(t *testing.T) {
+	n := &bufnet{listeners: map[string]*bufconn.Listener{}}
+	n.start(t, "backend-1")
+	clients := newTestClients(t, n)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, clients.WaitForReady(ctx))
+	assert.True(t, clients.Healthy())
+	assert.Equal(t, "READY", clients.States()[UserService])
+}
+
+func TestServiceClientsRecoverAfterBackendRestart(t *testing.T) {
+	n := &bufnet{listeners: map[string]*bufconn.Listener{}}
+	first := n.start(t, "backend-1")
+	clients := newTestClients(t, n)
+	clients.UnhealthyAfter = 50 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	require.NoError(t, clients.WaitForReady(ctx))
+	resp, err := clients.UserClient.GetUser(ctx, &userv1.GetUserRequest{Id: 1})
+	require.NoError(t, err)
+	assert.Equal(t, "backend-1", resp.User.Name)
+
+	// The backend dies and comes back at a new address. The monitor nudges
+	// the idle connection, which fails past UnhealthyAfter
+	first.Stop()
+	go clients.Monitor(ctx, 20*time.Millisecond, 100*time.Millisecond)
+	assert.Eventually(t, func() bool { return !clients.Healthy() }, 5*time.Second, 10*time.Millisecond)
+	n.start(t, "backend-2")
+	require.Eventually(t, clients.Healthy, 5*time.Second, 20*time.Millisecond)
+
+	resp, err = clients.UserClient.GetUser(ctx, &userv1.GetUserRequest{Id: 1})
+	require.NoError(t, err)
+	assert.Equal(t, "backend-2", resp.User.Name, "stubs follow the re-dialled connection")
+}
+
+func TestServiceClientsWithoutConnsAreHealthy(t *testing.T) {
+	assert.True(t, (&ServiceClients{}).Healthy())
+}
+
+func TestHealthyToleratesBriefFailures(t *testing.T) {
+	n := &bufnet{listeners: map[string]*bufconn.Listener{}}
+	clients := newTestClients(t, n)
+	clients.UnhealthyAfter = time.Hour
+
+	// Nothing listens, so the connections fail
+	require.Eventually(t, func() bool {
+		return clients.States()[UserService] == "TRANSIENT_FAILURE"
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.True(t, clients.Healthy(), "a failure within the threshold is tolerated")
+
+	conn := clients.conns[0]
+	assert.False(t, conn.healthy(time.Now().Add(2*time.Hour), time.Hour), "a failure past the threshold is reported")
+}
+
+func TestHealthyCountsIdleConnections(t *testing.T) {
+	conn, err := grpc.NewClient("passthrough:///idle", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	m := &managedConn{service: UserService, conn: conn}
+	assert.Equal(t, "IDLE", m.State().String())
+	assert.True(t, m.healthy(time.Now(), time.Millisecond))
+	assert.True(t, (&ServiceClients{conns: []*managedConn{m}}).Healthy())
+}
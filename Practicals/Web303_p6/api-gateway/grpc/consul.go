@@ -0,0 +1,157 @@
+package grpc
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/resolver"
+)
+
+// consulScheme is the dial target scheme served by consulBuilder, as in
+// "consul:///user-service".
+const consulScheme = "consul"
+
+// roundRobin spreads calls across every address the resolver reports.
+const roundRobin = `{"loadBalancingConfig": [{"round_robin": {}}]}`
+
+// serviceHealth is the subset of the Consul health API used for discovery.
+type serviceHealth interface {
+	Service(service, tag string, passingOnly bool, q *consulapi.QueryOptions) ([]*consulapi.ServiceEntry, *consulapi.QueryMeta, error)
+}
+
+// healthyAddrs returns the host:port of each passing instance of service,
+// collapsing duplicate registrations of the same instance ID the way the p2
+// consulutil package does: the most recently modified entry wins, in order
+// of first appearance. That package is not imported because this module's
+// Docker build context is Web303_p6, which cannot reach Web303_p2.
+func healthyAddrs(health serviceHealth, service string) ([]string, error) {
+	entries, _, err := health.Service(service, "", true, nil)
+	if err != nil {
+		return nil, fmt.Errorf("consul query failed for '%s': %w", service, err)
+	}
+
+	byID := make(map[string]int, len(entries))
+	latest := make([]*consulapi.ServiceEntry, 0, len(entries))
+	for _, entry := range entries {
+		idx, seen := byID[entry.Service.ID]
+		if !seen {
+			byID[entry.Service.ID] = len(latest)
+			latest = append(latest, entry)
+			continue
+		}
+		log.Printf("Collapsing duplicate Consul entry %q for '%s'", entry.Service.ID, service)
+		if entry.Service.ModifyIndex >= latest[idx].Service.ModifyIndex {
+			latest[idx] = entry
+		}
+	}
+
+	addrs := make([]string, 0, len(latest))
+	for _, entry := range latest {
+		host := entry.Service.Address
+		if host == "" && entry.Node != nil {
+			host = entry.Node.Address
+		}
+		addrs = append(addrs, net.JoinHostPort(host, strconv.Itoa(entry.Service.Port)))
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no healthy instances available for '%s'", service)
+	}
+	return addrs, nil
+}
+
+// consulBuilder resolves consul:/// targets to the healthy instances of the
+// named service and refreshes them every interval.
+type consulBuilder struct {
+	health   serviceHealth
+	interval time.Duration
+}
+
+// ConsulDiscovery returns the Resolver and dial options that route backend
+// connections through Consul with round-robin balancing.
+func ConsulDiscovery(health serviceHealth, interval time.Duration) (Resolver, []grpc.DialOption) {
+	builder := &consulBuilder{health: health, interval: interval}
+	resolve := func(service string) (string, error) {
+		return consulScheme + ":///" + service, nil
+	}
+	return resolve, []grpc.DialOption{
+		grpc.WithResolvers(builder),
+		grpc.WithDefaultServiceConfig(roundRobin),
+	}
+}
+
+func (b *consulBuilder) Scheme() string { return consulScheme }
+
+func (b *consulBuilder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	r := &consulResolver{
+		service: target.Endpoint(),
+		health:  b.health,
+		cc:      cc,
+		refresh: make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	r.resolve()
+	r.wg.Add(1)
+	go r.watch(b.interval)
+	return r, nil
+}
+
+// consulResolver pushes the current instance list of one service to gRPC.
+type consulResolver struct {
+	service string
+	health  serviceHealth
+	cc      resolver.ClientConn
+	refresh chan struct{}
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func (r *consulResolver) resolve() {
+	addrs, err := healthyAddrs(r.health, r.service)
+	if err != nil {
+		log.Printf("Resolving %s: %v", r.service, err)
+		r.cc.ReportError(err)
+		return
+	}
+	state := resolver.State{Addresses: make([]resolver.Address, len(addrs))}
+	for i, addr := range addrs {
+		state.Addresses[i] = resolver.Address{Addr: addr}
+	}
+	if err := r.cc.UpdateState(state); err != nil {
+		log.Printf("Updating %s addresses: %v", r.service, err)
+	}
+}
+
+func (r *consulResolver) watch(interval time.Duration) {
+	defer r.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+		case <-r.refresh:
+		}
+		r.resolve()
+	}
+}
+
+// ResolveNow is called by gRPC when a connection fails; it queues an
+// immediate refresh.
+func (r *consulResolver) ResolveNow(resolver.ResolveNowOptions) {
+	select {
+	case r.refresh <- struct{}{}:
+	default:
+	}
+}
+
+func (r *consulResolver) Close() {
+	close(r.done)
+	r.wg.Wait()
+}
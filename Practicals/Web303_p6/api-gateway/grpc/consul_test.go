@@ -0,0 +1,145 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	userv1 "github.com/douglasswm/student-cafe-protos/gen/go/user/v1"
+	consulapi "github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/serviceconfig"
+)
+
+// fakeHealth returns canned Consul entries per service.
+type fakeHealth map[string][]*consulapi.ServiceEntry
+
+func (f fakeHealth) Service(service, tag string, passingOnly bool, q *consulapi.QueryOptions) ([]*consulapi.ServiceEntry, *consulapi.QueryMeta, error) {
+	return f[service], nil, nil
+}
+
+func entry(id, host string, port int) *consulapi.ServiceEntry {
+	return &consulapi.ServiceEntry{Service: &consulapi.AgentService{ID: id, Address: host, Port: port}}
+}
+
+// recordingConn captures the state a resolver pushes.
+type recordingConn struct {
+	resolver.ClientConn
+	states chan resolver.State
+}
+
+func (c *recordingConn) UpdateState(s resolver.State) error {
+	c.states <- s
+	return nil
+}
+
+func (c *recordingConn) ReportError(error) {}
+
+func (c *recordingConn) ParseServiceConfig(string) *serviceconfig.ParseResult { return nil }
+
+func TestConsulResolverReportsEveryHealthyInstance(t *testing.T) {
+	health := fakeHealth{"user-service": {
+		entry("user-1", "10.0.0.1", 9091),
+		entry("user-2", "10.0.0.2", 9091),
+		entry("user-2", "10.0.0.2", 9091), // re-registration of the same instance
+	}}
+	builder := &consulBuilder{health: health, interval: time.Hour}
+	cc := &recordingConn{states: make(chan resolver.State, 1)}
+
+	r, err := builder.Build(resolver.Target{URL: *mustParseTarget(t, "consul:///user-service")}, cc, resolver.BuildOptions{})
+	require.NoError(t, err)
+	defer r.Close()
+
+	state := <-cc.states
+	var addrs []string
+	for _, a := range state.Addresses {
+		addrs = append(addrs, a.Addr)
+	}
+	assert.Equal(t, []string{"10.0.0.1:9091", "10.0.0.2:9091"}, addrs)
+
+	health["user-service"] = health["user-service"][:1]
+	r.ResolveNow(resolver.ResolveNowOptions{})
+	select {
+	case state = <-cc.states:
+		assert.Len(t, state.Addresses, 1, "refresh drops instances that left Consul")
+	case <-time.After(2 * time.Second):
+		t.Fatal("ResolveNow did not refresh")
+	}
+}
+
+func TestHealthyAddrsKeepsTheLatestDuplicate(t *testing.T) {
+	stale := entry("user-1", "10.0.0.5", 9091)
+	stale.Service.ModifyIndex = 10
+	fresh := entry("user-1", "10.0.0.9", 9091)
+	fresh.Service.ModifyIndex = 20
+	other := entry("user-2", "10.0.0.2", 9091)
+
+	addrs, err := healthyAddrs(fakeHealth{"user-service": {stale, other, fresh}}, "user-service")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.9:9091", "10.0.0.2:9091"}, addrs, "the re-registered address replaces the stale one in place")
+
+	addrs, err = healthyAddrs(fakeHealth{"user-service": {fresh, stale}}, "user-service")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.9:9091"}, addrs, "an older entry reported later does not win")
+}
+
+func TestHealthyAddrsErrors(t *testing.T) {
+	_, err := healthyAddrs(fakeHealth{}, "menu-service")
+	assert.ErrorContains(t, err, "no healthy instances")
+}
+
+// namedBackend is a user service that reports which instance answered.
+type namedBackend struct {
+	userv1.UnimplementedUserServiceServer
+	name string
+}
+
+func (b *namedBackend) GetUser(ctx context.Context, in *userv1.GetUserRequest) (*userv1.GetUserResponse, error) {
+	return &userv1.GetUserResponse{User: &userv1.User{Name: b.name}}, nil
+}
+
+func TestConsulDiscoveryRoundRobinsAcrossInstances(t *testing.T) {
+	health := fakeHealth{}
+	for _, name := range []string{"user-a", "user-b"} {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		server := grpc.NewServer()
+		userv1.RegisterUserServiceServer(server, &namedBackend{name: name})
+		go server.Serve(lis)
+		t.Cleanup(server.Stop)
+		port := lis.Addr().(*net.TCPAddr).Port
+		health["user-service"] = append(health["user-service"], entry(name, "127.0.0.1", port))
+	}
+
+	resolve, opts := ConsulDiscovery(health, time.Hour)
+	target, err := resolve(UserService)
+	require.NoError(t, err)
+	assert.Equal(t, "consul:///user-service", target)
+
+	conn, err := grpc.NewClient(target, append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))...)
+	require.NoError(t, err)
+	defer conn.Close()
+	client := userv1.NewUserServiceClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	served := map[string]int{}
+	for i := 0; i < 10; i++ {
+		resp, err := client.GetUser(ctx, &userv1.GetUserRequest{Id: 1}, grpc.WaitForReady(true))
+		require.NoError(t, err)
+		served[resp.User.Name]++
+	}
+	assert.Len(t, served, 2, "both instances receive calls")
+}
+
+func mustParseTarget(t *testing.T, raw string) *url.URL {
+	u, err := url.Parse(raw)
+	require.NoError(t, err)
+	return u
+}
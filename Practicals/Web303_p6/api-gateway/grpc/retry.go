@@ -0,0 +1,93 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RetryPolicy controls which gRPC calls are retried and how often.
+type RetryPolicy struct {
+	MaxAttempts    int
+	Backoff        time.Duration
+	RetryableCodes map[codes.Code]bool
+}
+
+// DefaultRetryPolicy retries Unavailable and DeadlineExceeded up to three times.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     100 * time.Millisecond,
+		RetryableCodes: map[codes.Code]bool{
+			codes.Unavailable:      true,
+			codes.DeadlineExceeded: true,
+		},
+	}
+}
+
+// ParseRetryableCodes parses a comma-separated list of gRPC code names such
+// as "Unavailable,DeadlineExceeded".
+func ParseRetryableCodes(raw string) (map[codes.Code]bool, error) {
+	byName := make(map[string]codes.Code)
+	for c := codes.OK; c <= codes.Unauthenticated; c++ {
+		byName[strings.ToLower(c.String())] = c
+	}
+
+	out := make(map[codes.Code]bool)
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		c, ok := byName[strings.ToLower(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown gRPC code %q", name)
+		}
+		out[c] = true
+	}
+	return out, nil
+}
+
+// isIdempotent reports whether a full method name such as
+// "/user.v1.UserService/GetUser" is a read-only call that is safe to retry.
+func isIdempotent(fullMethod string) bool {
+	method := fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	return strings.HasPrefix(method, "Get") || strings.HasPrefix(method, "List")
+}
+
+// UnaryClientInterceptor retries idempotent calls that fail with a retryable
+// code. Everything else fails fast on the first error.
+func (p RetryPolicy) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if err == nil || !isIdempotent(method) {
+			return err
+		}
+
+		backoff := p.Backoff
+		for attempt := 2; attempt <= p.MaxAttempts; attempt++ {
+			if !p.RetryableCodes[status.Code(err)] {
+				return err
+			}
+			log.Printf("Retrying %s after %s (attempt %d/%d)", method, status.Code(err), attempt, p.MaxAttempts)
+
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+
+			if err = invoker(ctx, method, req, reply, cc, opts...); err == nil {
+				return nil
+			}
+		}
+		return err
+	}
+}
@@ -0,0 +1,79 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// flakyInvoker fails with code for the first failures calls, then succeeds.
+func flakyInvoker(code codes.Code, failures int, calls *int) grpc.UnaryInvoker {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		*calls++
+		if *calls <= failures {
+			return status.Error(code, "boom")
+		}
+		return nil
+	}
+}
+
+func testPolicy() RetryPolicy {
+	p := DefaultRetryPolicy()
+	p.Backoff = time.Millisecond
+	return p
+}
+
+func TestRetryInterceptorRetriesUnavailable(t *testing.T) {
+	calls := 0
+	interceptor := testPolicy().UnaryClientInterceptor()
+
+	err := interceptor(context.Background(), "/user.v1.UserService/GetUser", nil, nil, nil, flakyInvoker(codes.Unavailable, 1, &calls))
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestRetryInterceptorDoesNotRetryInvalidArgument(t *testing.T) {
+	calls := 0
+	interceptor := testPolicy().UnaryClientInterceptor()
+
+	err := interceptor(context.Background(), "/user.v1.UserService/GetUser", nil, nil, nil, flakyInvoker(codes.InvalidArgument, 1, &calls))
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, 1, calls)
+}
+
+func TestRetryInterceptorSkipsNonIdempotentMethods(t *testing.T) {
+	calls := 0
+	interceptor := testPolicy().UnaryClientInterceptor()
+
+	err := interceptor(context.Background(), "/user.v1.UserService/CreateUser", nil, nil, nil, flakyInvoker(codes.Unavailable, 1, &calls))
+
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, 1, calls)
+}
+
+func TestRetryInterceptorGivesUpAfterMaxAttempts(t *testing.T) {
+	calls := 0
+	interceptor := testPolicy().UnaryClientInterceptor()
+
+	err := interceptor(context.Background(), "/menu.v1.MenuService/GetMenu", nil, nil, nil, flakyInvoker(codes.DeadlineExceeded, 10, &calls))
+
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	assert.Equal(t, 3, calls)
+}
+
+func TestParseRetryableCodes(t *testing.T) {
+	parsed, err := ParseRetryableCodes("Unavailable, resourceexhausted")
+	require.NoError(t, err)
+	assert.Equal(t, map[codes.Code]bool{codes.Unavailable: true, codes.ResourceExhausted: true}, parsed)
+
+	_, err = ParseRetryableCodes("Sometimes")
+	assert.Error(t, err)
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"api-gateway/httputil"
+
+	menuv1 "github.com/douglasswm/student-cafe-protos/gen/go/menu/v1"
+	orderv1 "github.com/douglasswm/student-cafe-protos/gen/go/order/v1"
+	userv1 "github.com/douglasswm/student-cafe-protos/gen/go/user/v1"
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc/status"
+)
+
+// Dashboard sections that can degrade independently of the user profile
+const (
+	SectionMenu   = "menu"
+	SectionOrders = "orders"
+)
+
+// CriticalDashboardSections lists sections whose failure fails the whole
+// dashboard instead of producing a partial response. The user profile is
+// always critical.
+var CriticalDashboardSections = map[string]bool{}
+
+// ParseDashboardSections parses a comma-separated list such as "menu,orders".
+func ParseDashboardSections(raw string) map[string]bool {
+	sections := make(map[string]bool)
+	for _, name := range strings.Split(raw, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			sections[name] = true
+		}
+	}
+	return sections
+}
+
+// SectionError describes why a dashboard section is missing.
+type SectionError struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// OwnerDashboard aggregates a café owner's profile, menu and orders.
+type OwnerDashboard struct {
+	User      *userv1.User             `json:"user"`
+	MenuItems []*menuv1.MenuItem       `json:"menu_items"`
+	Orders    []*orderv1.Order         `json:"orders"`
+	Partial   bool                     `json:"partial"`
+	Errors    map[string]*SectionError `json:"errors,omitempty"`
+}
+
+// GetOwnerDashboard handles GET /api/owners/{id}/dashboard
+// Fetches the user (critical) and then the menu and the owner's orders in
+// parallel, returning whatever is available when a non-critical backend fails
+func (h *Handlers) GetOwnerDashboard(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		httputil.RespondError(w, http.StatusBadRequest, "invalid owner ID")
+		return
+	}
+
+	userResp, err := h.clients.UserClient.GetUser(r.Context(), &userv1.GetUserRequest{Id: uint32(id)})
+	if err != nil {
+		handleGRPCError(w, err)
+		return
+	}
+
+	dashboard := OwnerDashboard{
+		User:      userResp.User,
+		MenuItems: []*menuv1.MenuItem{},
+		Orders:    []*orderv1.Order{},
+	}
+
+	var (
+		wg       sync.WaitGroup
+		menuErr  error
+		orderErr error
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		resp, err := h.clients.MenuClient.GetMenu(r.Context(), &menuv1.GetMenuRequest{})
+		if err != nil {
+			menuErr = err
+			return
+		}
+		dashboard.MenuItems = resp.MenuItems
+	}()
+	go func() {
+		defer wg.Done()
+		resp, err := h.clients.OrderClient.GetOrders(r.Context(), &orderv1.GetOrdersRequest{})
+		if err != nil {
+			orderErr = err
+			return
+		}
+		for _, order := range resp.Orders {
+			if order.UserId == uint32(id) {
+				dashboard.Orders = append(dashboard.Orders, order)
+			}
+		}
+	}()
+	wg.Wait()
+
+	for section, err := range map[string]error{SectionMenu: menuErr, SectionOrders: orderErr} {
+		if err == nil {
+			continue
+		}
+		if CriticalDashboardSections[section] {
+			handleGRPCError(w, err)
+			return
+		}
+		if dashboard.Errors == nil {
+			dashboard.Errors = make(map[string]*SectionError)
+		}
+		st, _ := status.FromError(err)
+		dashboard.Errors[section] = &SectionError{Error: st.Message(), Code: st.Code().String()}
+		dashboard.Partial = true
+	}
+
+	// Return HTTP JSON response
+	httputil.RespondJSON(w, http.StatusOK, dashboard)
+}
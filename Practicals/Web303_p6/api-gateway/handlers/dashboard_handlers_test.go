@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	menuv1 "github.com/douglasswm/student-cafe-protos/gen/go/menu/v1"
+	orderv1 "github.com/douglasswm/student-cafe-protos/gen/go/order/v1"
+	userv1 "github.com/douglasswm/student-cafe-protos/gen/go/user/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func dashboardFixtures() (*fakeUserClient, *fakeMenuClient, *fakeOrderClient) {
+	users := &fakeUserClient{users: map[uint32]*userv1.User{1: {Id: 1, Name: "Owner", IsCafeOwner: true}}}
+	menu := &fakeMenuClient{items: []*menuv1.MenuItem{{Id: 10, Name: "Momo"}}}
+	orders := &fakeOrderClient{orders: []*orderv1.Order{{Id: 5, UserId: 1}, {Id: 6, UserId: 2}}}
+	return users, menu, orders
+}
+
+func getDashboard(t *testing.T, router http.Handler) (*httptest.ResponseRecorder, OwnerDashboard) {
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/owners/1/dashboard", nil))
+	var body OwnerDashboard
+	if rec.Code == http.StatusOK {
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	}
+	return rec, body
+}
+
+func TestOwnerDashboardComplete(t *testing.T) {
+	rec, body := getDashboard(t, newTestRouter(dashboardFixtures()))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.False(t, body.Partial)
+	assert.Empty(t, body.Errors)
+	assert.Equal(t, "Owner", body.User.Name)
+	assert.Len(t, body.MenuItems, 1)
+	require.Len(t, body.Orders, 1)
+	assert.Equal(t, uint32(5), body.Orders[0].Id)
+}
+
+func TestOwnerDashboardMenuServiceDown(t *testing.T) {
+	users, menu, orders := dashboardFixtures()
+	menu.err = status.Error(codes.Unavailable, "menu-service unreachable")
+
+	rec, body := getDashboard(t, newTestRouter(users, menu, orders))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, body.Partial)
+	assert.Equal(t, "Owner", body.User.Name)
+	assert.Empty(t, body.MenuItems)
+	assert.Len(t, body.Orders, 1)
+	require.Contains(t, body.Errors, SectionMenu)
+	assert.Equal(t, "Unavailable", body.Errors[SectionMenu].Code)
+	assert.NotContains(t, body.Errors, SectionOrders)
+}
+
+func TestOwnerDashboardCriticalSectionFails(t *testing.T) {
+	orig := CriticalDashboardSections
+	CriticalDashboardSections = ParseDashboardSections("menu")
+	defer func() { CriticalDashboardSections = orig }()
+
+	users, menu, orders := dashboardFixtures()
+	menu.err = status.Error(codes.Unavailable, "menu-service unreachable")
+
+	rec, _ := getDashboard(t, newTestRouter(users, menu, orders))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+func TestOwnerDashboardUserIsCritical(t *testing.T) {
+	users, menu, orders := dashboardFixtures()
+	users.err = status.Error(codes.NotFound, "user not found")
+	rec, _ := getDashboard(t, newTestRouter(users, menu, orders))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	users.err = status.Error(codes.Internal, "db down")
+	rec, _ = getDashboard(t, newTestRouter(users, menu, orders))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestHandleGRPCError(t *testing.T) {
+	tests := []struct {
+		code codes.Code
+		want int
+	}{
+		{codes.NotFound, http.StatusNotFound},
+		{codes.InvalidArgument, http.StatusBadRequest},
+		{codes.AlreadyExists, http.StatusConflict},
+		{codes.Aborted, http.StatusConflict},
+		{codes.PermissionDenied, http.StatusForbidden},
+		{codes.Unauthenticated, http.StatusUnauthorized},
+		{codes.FailedPrecondition, http.StatusPreconditionFailed},
+		{codes.ResourceExhausted, http.StatusTooManyRequests},
+		{codes.Unimplemented, http.StatusNotImplemented},
+		{codes.Unavailable, http.StatusServiceUnavailable},
+		{codes.DeadlineExceeded, http.StatusGatewayTimeout},
+		{codes.Canceled, 499},
+		{codes.DataLoss, http.StatusInternalServerError},
+		{codes.Unknown, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code.String(), func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handleGRPCError(rec, status.Error(tt.code, "backend said no"))
+
+			assert.Equal(t, tt.want, rec.Code)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+			var body ErrorResponse
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+			assert.Equal(t, ErrorResponse{Error: "backend said no", Code: tt.code.String()}, body)
+		})
+	}
+}
+
+func TestHandleGRPCErrorNonStatus(t *testing.T) {
+	rec := httptest.NewRecorder()
+	handleGRPCError(rec, errors.New("boom"))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.JSONEq(t, `{"error": "internal server error", "code": "Internal"}`, rec.Body.String())
+}
@@ -4,7 +4,9 @@ import (
 	"net/http"
 
 	"api-gateway/grpc"
+	"api-gateway/httputil"
 
+	"github.com/go-chi/chi/v5"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -19,37 +21,70 @@ func NewHandlers(clients *grpc.ServiceClients) *Handlers {
 	return &Handlers{clients: clients}
 }
 
+// Register mounts every HTTP→gRPC route on r, with JSON 404 and 405
+// responses for paths and methods outside them.
+func (h *Handlers) Register(r chi.Router) {
+	r.NotFound(httputil.NotFound)
+	r.MethodNotAllowed(httputil.MethodNotAllowed(r))
+	r.Get("/readyz", h.Readiness)
+
+	// User routes
+	r.Post("/api/users", h.CreateUser)
+	r.Get("/api/users/{id}", h.GetUser)
+	r.Get("/api/users", h.GetUsers)
+
+	// Menu routes
+	r.Post("/api/menu", h.CreateMenuItem)
+	r.Get("/api/menu/{id}", h.GetMenuItem)
+	r.Get("/api/menu", h.GetMenu)
+
+	// Order routes
+	r.Post("/api/orders", h.CreateOrder)
+	r.Get("/api/orders/{id}", h.GetOrder)
+	r.Get("/api/orders", h.GetOrders)
+
+	// Aggregated owner view across user, menu and order services
+	r.Get("/api/owners/{id}/dashboard", h.GetOwnerDashboard)
+}
+
+// statusClientClosedRequest is nginx's non-standard 499, used when the caller
+// went away before the backend answered.
+const statusClientClosedRequest = 499
+
+// grpcHTTPStatus maps gRPC status codes to the HTTP status returned to clients.
+var grpcHTTPStatus = map[codes.Code]int{
+	codes.NotFound:           http.StatusNotFound,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.Aborted:            http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+	codes.FailedPrecondition: http.StatusPreconditionFailed,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.Canceled:           statusClientClosedRequest,
+}
+
+// ErrorResponse is the JSON body of every gRPC-derived error.
+type ErrorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
 // handleGRPCError translates gRPC status codes to HTTP error responses
 func handleGRPCError(w http.ResponseWriter, err error) {
 	st, ok := status.FromError(err)
 	if !ok {
 		// Not a gRPC error, return generic internal server error
-		http.Error(w, "internal server error", http.StatusInternalServerError)
+		httputil.RespondJSON(w, http.StatusInternalServerError, ErrorResponse{Error: "internal server error", Code: codes.Internal.String()})
 		return
 	}
 
-	// Map gRPC status codes to HTTP status codes
-	var httpStatus int
-	switch st.Code() {
-	case codes.NotFound:
-		httpStatus = http.StatusNotFound
-	case codes.InvalidArgument:
-		httpStatus = http.StatusBadRequest
-	case codes.AlreadyExists:
-		httpStatus = http.StatusConflict
-	case codes.PermissionDenied:
-		httpStatus = http.StatusForbidden
-	case codes.Unauthenticated:
-		httpStatus = http.StatusUnauthorized
-	case codes.FailedPrecondition:
-		httpStatus = http.StatusPreconditionFailed
-	case codes.Unimplemented:
-		httpStatus = http.StatusNotImplemented
-	case codes.Unavailable:
-		httpStatus = http.StatusServiceUnavailable
-	default:
+	httpStatus, ok := grpcHTTPStatus[st.Code()]
+	if !ok {
 		httpStatus = http.StatusInternalServerError
 	}
-
-	http.Error(w, st.Message(), httpStatus)
+	httputil.RespondJSON(w, httpStatus, ErrorResponse{Error: st.Message(), Code: st.Code().String()})
 }
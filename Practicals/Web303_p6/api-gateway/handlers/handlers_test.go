@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"api-gateway/grpc"
+
+	menuv1 "github.com/douglasswm/student-cafe-protos/gen/go/menu/v1"
+	orderv1 "github.com/douglasswm/student-cafe-protos/gen/go/order/v1"
+	userv1 "github.com/douglasswm/student-cafe-protos/gen/go/user/v1"
+	"github.com/go-chi/chi/v5"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeUserClient serves users from memory and records the last create
+// request; unimplemented methods panic.
+type fakeUserClient struct {
+	userv1.UserServiceClient
+	users   map[uint32]*userv1.User
+	err     error
+	created *userv1.CreateUserRequest
+}
+
+func (f *fakeUserClient) GetUser(ctx context.Context, in *userv1.GetUserRequest, opts ...grpclib.CallOption) (*userv1.GetUserResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	user, ok := f.users[in.Id]
+	if !ok {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+	return &userv1.GetUserResponse{User: user}, nil
+}
+
+func (f *fakeUserClient) GetUsers(ctx context.Context, in *userv1.GetUsersRequest, opts ...grpclib.CallOption) (*userv1.GetUsersResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	resp := &userv1.GetUsersResponse{}
+	for id := uint32(1); id <= uint32(len(f.users)); id++ {
+		resp.Users = append(resp.Users, f.users[id])
+	}
+	return resp, nil
+}
+
+func (f *fakeUserClient) CreateUser(ctx context.Context, in *userv1.CreateUserRequest, opts ...grpclib.CallOption) (*userv1.CreateUserResponse, error) {
+	f.created = in
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &userv1.CreateUserResponse{User: &userv1.User{Id: 7, Name: in.Name, Email: in.Email, IsCafeOwner: in.IsCafeOwner}}, nil
+}
+
+// fakeMenuClient serves a fixed menu or a fixed error and records the last
+// create request.
+type fakeMenuClient struct {
+	menuv1.MenuServiceClient
+	items   []*menuv1.MenuItem
+	err     error
+	created *menuv1.CreateMenuItemRequest
+}
+
+func (f *fakeMenuClient) GetMenuItem(ctx context.Context, in *menuv1.GetMenuItemRequest, opts ...grpclib.CallOption) (*menuv1.GetMenuItemResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	for _, item := range f.items {
+		if item.Id == in.Id {
+			return &menuv1.GetMenuItemResponse{MenuItem: item}, nil
+		}
+	}
+	return nil, status.Error(codes.NotFound, "menu item not found")
+}
+
+func (f *fakeMenuClient) CreateMenuItem(ctx context.Context, in *menuv1.CreateMenuItemRequest, opts ...grpclib.CallOption) (*menuv1.CreateMenuItemResponse, error) {
+	f.created = in
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &menuv1.CreateMenuItemResponse{MenuItem: &menuv1.MenuItem{Id: 3, Name: in.Name, Description: in.Description, Price: in.Price}}, nil
+}
+
+func (f *fakeMenuClient) GetMenu(ctx context.Context, in *menuv1.GetMenuRequest, opts ...grpclib.CallOption) (*menuv1.GetMenuResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &menuv1.GetMenuResponse{MenuItems: f.items}, nil
+}
+
+// fakeOrderClient serves a fixed order list or a fixed error.
+type fakeOrderClient struct {
+	orderv1.OrderServiceClient
+	orders []*orderv1.Order
+	err    error
+}
+
+func (f *fakeOrderClient) GetOrders(ctx context.Context, in *orderv1.GetOrdersRequest, opts ...grpclib.CallOption) (*orderv1.GetOrdersResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &orderv1.GetOrdersResponse{Orders: f.orders}, nil
+}
+
+// newTestRouter wires the gateway routes around fake backend clients.
+func newTestRouter(users *fakeUserClient, menu *fakeMenuClient, orders *fakeOrderClient) http.Handler {
+	h := NewHandlers(&grpc.ServiceClients{UserClient: users, MenuClient: menu, OrderClient: orders})
+	r := chi.NewRouter()
+	h.Register(r)
+	return r
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"net/http"
+
+	"api-gateway/httputil"
+)
+
+// ReadinessResponse reports whether the gateway can reach its backends.
+type ReadinessResponse struct {
+	Status   string            `json:"status"`
+	Backends map[string]string `json:"backends"`
+}
+
+// Readiness handles GET /readyz, answering 503 once any backend connection
+// has been down past its threshold so the gateway is taken out of rotation.
+func (h *Handlers) Readiness(w http.ResponseWriter, r *http.Request) {
+	resp := ReadinessResponse{Status: "ready", Backends: h.clients.States()}
+	code := http.StatusOK
+	if !h.clients.Healthy() {
+		resp.Status, code = "unavailable", http.StatusServiceUnavailable
+	}
+	httputil.RespondJSON(w, code, resp)
+}
@@ -0,0 +1,16 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadinessWithoutManagedConnections(t *testing.T) {
+	router := newTestRouter(&fakeUserClient{}, &fakeMenuClient{}, &fakeOrderClient{})
+
+	rec := serve(router, http.MethodGet, "/readyz", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"ready","backends":{}}`, rec.Body.String())
+}
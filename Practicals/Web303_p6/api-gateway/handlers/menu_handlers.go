@@ -1,11 +1,11 @@
 package handlers
 
 import (
-	"context"
-	"encoding/json"
 	"net/http"
 	"strconv"
 
+	"api-gateway/httputil"
+
 	menuv1 "github.com/douglasswm/student-cafe-protos/gen/go/menu/v1"
 	"github.com/go-chi/chi/v5"
 )
@@ -13,34 +13,22 @@ import (
 // CreateMenuItem handles POST /api/menu
 // Translates HTTP request to gRPC CreateMenuItem call
 func (h *Handlers) CreateMenuItem(w http.ResponseWriter, r *http.Request) {
-	// Parse HTTP JSON request body
-	var req struct {
-		Name        string  `json:"name"`
-		Description string  `json:"description"`
-		Price       float64 `json:"price"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+	// Decode the JSON or protobuf body straight into the gRPC request
+	var req menuv1.CreateMenuItemRequest
+	if !decodeMessage(w, r, &req) {
 		return
 	}
 
 	// Call gRPC service
-	resp, err := h.clients.MenuClient.CreateMenuItem(context.Background(), &menuv1.CreateMenuItemRequest{
-		Name:        req.Name,
-		Description: req.Description,
-		Price:       req.Price,
-	})
+	resp, err := h.clients.MenuClient.CreateMenuItem(r.Context(), &req)
 
 	if err != nil {
 		handleGRPCError(w, err)
 		return
 	}
 
-	// Return HTTP JSON response
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(resp.MenuItem)
+	// Return the response as JSON or protobuf, as Accept asks
+	respondMessage(w, r, http.StatusCreated, resp.MenuItem)
 }
 
 // GetMenuItem handles GET /api/menu/{id}
@@ -50,12 +38,12 @@ func (h *Handlers) GetMenuItem(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		http.Error(w, "invalid menu item ID", http.StatusBadRequest)
+		httputil.RespondError(w, http.StatusBadRequest, "invalid menu item ID")
 		return
 	}
 
 	// Call gRPC service
-	resp, err := h.clients.MenuClient.GetMenuItem(context.Background(), &menuv1.GetMenuItemRequest{
+	resp, err := h.clients.MenuClient.GetMenuItem(r.Context(), &menuv1.GetMenuItemRequest{
 		Id: uint32(id),
 	})
 
@@ -64,23 +52,21 @@ func (h *Handlers) GetMenuItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Return HTTP JSON response
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp.MenuItem)
+	// Return the response as JSON or protobuf, as Accept asks
+	respondMessage(w, r, http.StatusOK, resp.MenuItem)
 }
 
 // GetMenu handles GET /api/menu
 // Translates HTTP request to gRPC GetMenu call
 func (h *Handlers) GetMenu(w http.ResponseWriter, r *http.Request) {
 	// Call gRPC service
-	resp, err := h.clients.MenuClient.GetMenu(context.Background(), &menuv1.GetMenuRequest{})
+	resp, err := h.clients.MenuClient.GetMenu(r.Context(), &menuv1.GetMenuRequest{})
 
 	if err != nil {
 		handleGRPCError(w, err)
 		return
 	}
 
-	// Return HTTP JSON response
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp.MenuItems)
+	// Return the response as JSON or protobuf, as Accept asks
+	respondList(w, r, http.StatusOK, resp, resp.MenuItems)
 }
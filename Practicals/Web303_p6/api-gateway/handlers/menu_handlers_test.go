@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	menuv1 "github.com/douglasswm/student-cafe-protos/gen/go/menu/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCreateMenuItemTranslatesRequest(t *testing.T) {
+	menu := &fakeMenuClient{}
+	router := newTestRouter(&fakeUserClient{}, menu, &fakeOrderClient{})
+
+	rec := serve(router, http.MethodPost, "/api/menu", `{"name": "Momo", "description": "Steamed", "price": 4.5}`)
+
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.NotNil(t, menu.created)
+	assert.Equal(t, "Momo", menu.created.Name)
+	assert.Equal(t, "Steamed", menu.created.Description)
+	assert.Equal(t, 4.5, menu.created.Price)
+
+	menu.err = status.Error(codes.InvalidArgument, "price must be positive")
+	rec = serve(router, http.MethodPost, "/api/menu", `{"name": "Momo", "price": -1}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "InvalidArgument", decodeError(t, rec).Code)
+}
+
+func TestGetMenuAndItem(t *testing.T) {
+	menu := &fakeMenuClient{items: []*menuv1.MenuItem{{Id: 1, Name: "Tea"}, {Id: 2, Name: "Momo"}}}
+	router := newTestRouter(&fakeUserClient{}, menu, &fakeOrderClient{})
+
+	rec := serve(router, http.MethodGet, "/api/menu", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var items []*menuv1.MenuItem
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&items))
+	assert.Len(t, items, 2)
+
+	rec = serve(router, http.MethodGet, "/api/menu/2", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"name":"Momo"`)
+
+	assert.Equal(t, http.StatusNotFound, serve(router, http.MethodGet, "/api/menu/9", "").Code)
+	assert.Equal(t, http.StatusBadRequest, serve(router, http.MethodGet, "/api/menu/x", "").Code)
+
+	menu.err = status.Error(codes.DeadlineExceeded, "slow")
+	assert.Equal(t, http.StatusGatewayTimeout, serve(router, http.MethodGet, "/api/menu", "").Code)
+}
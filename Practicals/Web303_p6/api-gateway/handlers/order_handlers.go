@@ -1,11 +1,11 @@
 package handlers
 
 import (
-	"context"
-	"encoding/json"
 	"net/http"
 	"strconv"
 
+	"api-gateway/httputil"
+
 	orderv1 "github.com/douglasswm/student-cafe-protos/gen/go/order/v1"
 	"github.com/go-chi/chi/v5"
 )
@@ -13,44 +13,30 @@ import (
 // CreateOrder handles POST /api/orders
 // Translates HTTP request to gRPC CreateOrder call
 func (h *Handlers) CreateOrder(w http.ResponseWriter, r *http.Request) {
-	// Parse HTTP JSON request body
-	var req struct {
-		UserID uint32 `json:"user_id"`
-		Items  []struct {
-			MenuItemID uint32 `json:"menu_item_id"`
-			Quantity   uint32 `json:"quantity"`
-		} `json:"items"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+	// Decode the JSON or protobuf body straight into the gRPC request
+	var req orderv1.CreateOrderRequest
+	if !decodeMessage(w, r, &req) {
 		return
 	}
-
-	// Convert HTTP items to gRPC OrderItemRequest protobuf messages
-	var items []*orderv1.OrderItemRequest
+	// The proto quantity is signed, but the gateway has always refused
+	// negative quantities
 	for _, item := range req.Items {
-		items = append(items, &orderv1.OrderItemRequest{
-			MenuItemId: item.MenuItemID,
-			Quantity:   int32(item.Quantity),
-		})
+		if item.Quantity < 0 {
+			httputil.RespondError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
 	}
 
 	// Call gRPC service
-	resp, err := h.clients.OrderClient.CreateOrder(context.Background(), &orderv1.CreateOrderRequest{
-		UserId: req.UserID,
-		Items:  items,
-	})
+	resp, err := h.clients.OrderClient.CreateOrder(r.Context(), &req)
 
 	if err != nil {
 		handleGRPCError(w, err)
 		return
 	}
 
-	// Return HTTP JSON response
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(resp.Order)
+	// Return the response as JSON or protobuf, as Accept asks
+	respondMessage(w, r, http.StatusCreated, resp.Order)
 }
 
 // GetOrder handles GET /api/orders/{id}
@@ -60,12 +46,12 @@ func (h *Handlers) GetOrder(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		http.Error(w, "invalid order ID", http.StatusBadRequest)
+		httputil.RespondError(w, http.StatusBadRequest, "invalid order ID")
 		return
 	}
 
 	// Call gRPC service
-	resp, err := h.clients.OrderClient.GetOrder(context.Background(), &orderv1.GetOrderRequest{
+	resp, err := h.clients.OrderClient.GetOrder(r.Context(), &orderv1.GetOrderRequest{
 		Id: uint32(id),
 	})
 
@@ -74,23 +60,21 @@ func (h *Handlers) GetOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Return HTTP JSON response
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp.Order)
+	// Return the response as JSON or protobuf, as Accept asks
+	respondMessage(w, r, http.StatusOK, resp.Order)
 }
 
 // GetOrders handles GET /api/orders
 // Translates HTTP request to gRPC GetOrders call
 func (h *Handlers) GetOrders(w http.ResponseWriter, r *http.Request) {
 	// Call gRPC service
-	resp, err := h.clients.OrderClient.GetOrders(context.Background(), &orderv1.GetOrdersRequest{})
+	resp, err := h.clients.OrderClient.GetOrders(r.Context(), &orderv1.GetOrdersRequest{})
 
 	if err != nil {
 		handleGRPCError(w, err)
 		return
 	}
 
-	// Return HTTP JSON response
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp.Orders)
+	// Return the response as JSON or protobuf, as Accept asks
+	respondList(w, r, http.StatusOK, resp, resp.Orders)
 }
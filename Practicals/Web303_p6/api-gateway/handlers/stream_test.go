@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"api-gateway/grpc"
+
+	userv1 "github.com/douglasswm/student-cafe-protos/gen/go/user/v1"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// streamingUserServer streams count generated users, or fails with err
+// before sending any.
+type streamingUserServer struct {
+	userv1.UnimplementedUserServiceServer
+	count int
+	err   error
+}
+
+func (s *streamingUserServer) ListUsersStream(req *userv1.ListUsersStreamRequest, stream userv1.UserService_ListUsersStreamServer) error {
+	if s.err != nil {
+		return s.err
+	}
+	for i := 1; i <= s.count; i++ {
+		if err := stream.Send(&userv1.User{Id: uint32(i), Name: fmt.Sprintf("User %d", i)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newStreamingRouter routes the gateway to backend over a real gRPC
+// connection, since unary fakes can't stand in for a stream.
+func newStreamingRouter(t *testing.T, backend userv1.UserServiceServer) http.Handler {
+	lis := bufconn.Listen(1 << 20)
+	server := grpclib.NewServer()
+	userv1.RegisterUserServiceServer(server, backend)
+	go server.Serve(lis)
+
+	conn, err := grpclib.NewClient("passthrough:///bufnet",
+		grpclib.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpclib.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+		server.Stop()
+	})
+
+	h := NewHandlers(&grpc.ServiceClients{UserClient: userv1.NewUserServiceClient(conn)})
+	r := chi.NewRouter()
+	h.Register(r)
+	return r
+}
+
+func TestStreamUsersRelaysEveryMessageAsNDJSON(t *testing.T) {
+	const total = 500
+	router := newStreamingRouter(t, &streamingUserServer{count: total})
+
+	rec := serve(router, http.MethodGet, "/api/users?stream=true", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/x-ndjson", rec.Header().Get("Content-Type"))
+	assert.True(t, rec.Flushed, "lines are flushed as they arrive")
+
+	lines := bufio.NewScanner(rec.Body)
+	var n int
+	for lines.Scan() {
+		n++
+		var user userv1.User
+		require.NoError(t, protojson.Unmarshal(lines.Bytes(), &user), lines.Text())
+		assert.EqualValues(t, n, user.Id)
+		assert.Equal(t, fmt.Sprintf("User %d", n), user.Name)
+	}
+	assert.Equal(t, total, n, "every streamed user becomes one line")
+}
+
+func TestStreamUsersErrors(t *testing.T) {
+	router := newStreamingRouter(t, &streamingUserServer{err: status.Error(codes.Unavailable, "database down")})
+	rec := serve(router, http.MethodGet, "/api/users?stream=true", "")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code, "an error before the first user keeps its status")
+	assert.Equal(t, ErrorResponse{Error: "database down", Code: "Unavailable"}, decodeError(t, rec))
+
+	router = newStreamingRouter(t, &streamingUserServer{})
+	rec = serve(router, http.MethodGet, "/api/users?stream=true", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, strings.TrimSpace(rec.Body.String()))
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+
+	"api-gateway/httputil"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Media types the gateway transcodes between. Error bodies are always JSON.
+const (
+	mediaJSON     = "application/json"
+	mediaProtobuf = "application/x-protobuf"
+)
+
+// messageTypes are the encodings a response can be sent in, preferred first.
+var messageTypes = []string{mediaJSON, mediaProtobuf}
+
+var (
+	// Unknown fields are ignored, as they were before requests were decoded
+	// with protojson
+	jsonUnmarshal = protojson.UnmarshalOptions{DiscardUnknown: true}
+	// Field names stay snake_case, matching the proto definitions
+	jsonMarshal = protojson.MarshalOptions{UseProtoNames: true}
+)
+
+// decodeMessage fills msg from the request body, read as protobuf or JSON
+// according to Content-Type. A missing Content-Type is taken as JSON. It
+// writes the error response and returns false when the body can't be used,
+// or when no response could satisfy Accept, so a create is never run only
+// to answer 406.
+func decodeMessage(w http.ResponseWriter, r *http.Request, msg proto.Message) bool {
+	if _, ok := httputil.Negotiate(r, messageTypes...); !ok {
+		respondNotAcceptable(w)
+		return false
+	}
+
+	mediaType := mediaJSON
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		parsed, _, err := mime.ParseMediaType(ct)
+		if err != nil || (parsed != mediaJSON && parsed != mediaProtobuf) {
+			httputil.RespondError(w, http.StatusUnsupportedMediaType, "Content-Type must be "+mediaJSON+" or "+mediaProtobuf)
+			return false
+		}
+		mediaType = parsed
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err == nil {
+		if mediaType == mediaProtobuf {
+			err = proto.Unmarshal(body, msg)
+		} else {
+			err = jsonUnmarshal.Unmarshal(body, msg)
+		}
+	}
+	if err != nil {
+		httputil.RespondError(w, http.StatusBadRequest, "invalid request body")
+		return false
+	}
+	return true
+}
+
+// respondMessage writes msg with the given status, as protobuf or JSON
+// according to the Accept header.
+func respondMessage(w http.ResponseWriter, r *http.Request, status int, msg proto.Message) {
+	respond(w, r, status, msg, func() ([]byte, error) { return jsonMarshal.Marshal(msg) })
+}
+
+// respondList writes items with the given status. JSON clients get a bare
+// array of items as before; protobuf has no bare repeated type, so protobuf
+// clients get envelope, the gRPC response holding the items.
+func respondList[T proto.Message](w http.ResponseWriter, r *http.Request, status int, envelope proto.Message, items []T) {
+	respond(w, r, status, envelope, func() ([]byte, error) {
+		var buf bytes.Buffer
+		buf.WriteByte('[')
+		for i, item := range items {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			raw, err := jsonMarshal.Marshal(item)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(raw)
+		}
+		buf.WriteByte(']')
+		return buf.Bytes(), nil
+	})
+}
+
+func respondNotAcceptable(w http.ResponseWriter) {
+	httputil.RespondError(w, http.StatusNotAcceptable, "Accept must allow "+mediaJSON+" or "+mediaProtobuf)
+}
+
+// respond negotiates the response encoding, sending msg for protobuf clients
+// and the output of marshalJSON for JSON ones.
+func respond(w http.ResponseWriter, r *http.Request, status int, msg proto.Message, marshalJSON func() ([]byte, error)) {
+	w.Header().Add("Vary", "Accept")
+	mediaType, ok := httputil.Negotiate(r, messageTypes...)
+	if !ok {
+		respondNotAcceptable(w)
+		return
+	}
+
+	var body []byte
+	var err error
+	if mediaType == mediaProtobuf {
+		body, err = proto.Marshal(msg)
+	} else if body, err = marshalJSON(); err == nil {
+		body, err = compactJSON(body)
+	}
+	if err != nil {
+		log.Printf("Failed to encode response: %v", err)
+		httputil.RespondError(w, http.StatusInternalServerError, "failed to encode response")
+		return
+	}
+
+	w.Header().Set("Content-Type", mediaType)
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// compactJSON strips the whitespace protojson deliberately varies between
+// builds, keeping responses byte-stable, and ends the value with a newline.
+func compactJSON(raw []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	menuv1 "github.com/douglasswm/student-cafe-protos/gen/go/menu/v1"
+	userv1 "github.com/douglasswm/student-cafe-protos/gen/go/user/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+func post(router http.Handler, path, contentType, accept string, body []byte) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCreateUserTranscodesJSONAndProtobuf(t *testing.T) {
+	want := &userv1.CreateUserRequest{Name: "Tashi", Email: "tashi@example.com", IsCafeOwner: true}
+	protoBody, err := proto.Marshal(want)
+	require.NoError(t, err)
+	jsonBody := []byte(`{"name": "Tashi", "email": "tashi@example.com", "is_cafe_owner": true}`)
+
+	for _, tc := range []struct {
+		name, contentType, accept string
+		body                      []byte
+	}{
+		{"json in, json out", mediaJSON, mediaJSON, jsonBody},
+		{"json in, protobuf out", mediaJSON + "; charset=utf-8", mediaProtobuf, jsonBody},
+		{"protobuf in, json out", mediaProtobuf, "", protoBody},
+		{"protobuf in, protobuf out", mediaProtobuf, mediaProtobuf, protoBody},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			users := &fakeUserClient{}
+			rec := post(newTestRouter(users, &fakeMenuClient{}, &fakeOrderClient{}), "/api/users", tc.contentType, tc.accept, tc.body)
+
+			require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+			require.NotNil(t, users.created)
+			assert.True(t, proto.Equal(want, users.created), "backend got %v", users.created)
+
+			var user userv1.User
+			if tc.accept == mediaProtobuf {
+				assert.Equal(t, mediaProtobuf, rec.Header().Get("Content-Type"))
+				require.NoError(t, proto.Unmarshal(rec.Body.Bytes(), &user))
+			} else {
+				assert.Equal(t, mediaJSON, rec.Header().Get("Content-Type"))
+				assert.Contains(t, rec.Body.String(), `"is_cafe_owner":true`, "JSON keeps the proto field names")
+				require.NoError(t, protojson.Unmarshal(rec.Body.Bytes(), &user))
+			}
+			assert.True(t, proto.Equal(&userv1.User{Id: 7, Name: "Tashi", Email: "tashi@example.com", IsCafeOwner: true}, &user), "got %v", &user)
+			assert.Contains(t, rec.Header().Values("Vary"), "Accept")
+		})
+	}
+}
+
+func TestCreateMenuItemAcceptsProtobuf(t *testing.T) {
+	menu := &fakeMenuClient{}
+	body, err := proto.Marshal(&menuv1.CreateMenuItemRequest{Name: "Momo", Description: "Steamed", Price: 4.5})
+	require.NoError(t, err)
+
+	rec := post(newTestRouter(&fakeUserClient{}, menu, &fakeOrderClient{}), "/api/menu", mediaProtobuf, mediaProtobuf, body)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, 4.5, menu.created.Price)
+	var item menuv1.MenuItem
+	require.NoError(t, proto.Unmarshal(rec.Body.Bytes(), &item))
+	assert.Equal(t, "Momo", item.Name)
+}
+
+func TestListsTranscodeToTheResponseMessage(t *testing.T) {
+	menu := &fakeMenuClient{items: []*menuv1.MenuItem{{Id: 1, Name: "Tea"}, {Id: 2, Name: "Momo"}}}
+	router := newTestRouter(&fakeUserClient{}, menu, &fakeOrderClient{})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/menu", nil)
+	req.Header.Set("Accept", mediaProtobuf)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp menuv1.GetMenuResponse
+	require.NoError(t, proto.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Len(t, resp.MenuItems, 2)
+
+	// JSON clients still get a bare array
+	rec = serve(router, http.MethodGet, "/api/menu", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `[{"id": 1, "name": "Tea"}, {"id": 2, "name": "Momo"}]`, rec.Body.String())
+}
+
+func TestTranscodingRejectsUnsupportedMediaTypes(t *testing.T) {
+	users := &fakeUserClient{}
+	router := newTestRouter(users, &fakeMenuClient{}, &fakeOrderClient{})
+
+	rec := post(router, "/api/users", "text/plain", "", []byte(`{"name": "Tashi"}`))
+	assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+	assert.Contains(t, decodeError(t, rec).Error, mediaProtobuf)
+
+	rec = post(router, "/api/users", mediaProtobuf, "", []byte("not protobuf"))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Nil(t, users.created, "rejected bodies never reach the backend")
+
+	rec = post(router, "/api/users", mediaJSON, "text/html", []byte(`{"name": "Tashi"}`))
+	assert.Equal(t, http.StatusNotAcceptable, rec.Code)
+	assert.True(t, strings.HasPrefix(rec.Header().Get("Content-Type"), mediaJSON), "errors are always JSON")
+	assert.Nil(t, users.created, "nothing is created when the response can't be sent")
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+	req.Header.Set("Accept", "text/csv")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotAcceptable, rec.Code)
+}
@@ -1,11 +1,14 @@
 package handlers
 
 import (
-	"context"
-	"encoding/json"
+	"errors"
+	"io"
+	"log"
 	"net/http"
 	"strconv"
 
+	"api-gateway/httputil"
+
 	userv1 "github.com/douglasswm/student-cafe-protos/gen/go/user/v1"
 	"github.com/go-chi/chi/v5"
 )
@@ -13,34 +16,22 @@ import (
 // CreateUser handles POST /api/users
 // Translates HTTP request to gRPC CreateUser call
 func (h *Handlers) CreateUser(w http.ResponseWriter, r *http.Request) {
-	// Parse HTTP JSON request body
-	var req struct {
-		Name        string `json:"name"`
-		Email       string `json:"email"`
-		IsCafeOwner bool   `json:"is_cafe_owner"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+	// Decode the JSON or protobuf body straight into the gRPC request
+	var req userv1.CreateUserRequest
+	if !decodeMessage(w, r, &req) {
 		return
 	}
 
 	// Call gRPC service
-	resp, err := h.clients.UserClient.CreateUser(context.Background(), &userv1.CreateUserRequest{
-		Name:        req.Name,
-		Email:       req.Email,
-		IsCafeOwner: req.IsCafeOwner,
-	})
+	resp, err := h.clients.UserClient.CreateUser(r.Context(), &req)
 
 	if err != nil {
 		handleGRPCError(w, err)
 		return
 	}
 
-	// Return HTTP JSON response
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(resp.User)
+	// Return the response as JSON or protobuf, as Accept asks
+	respondMessage(w, r, http.StatusCreated, resp.User)
 }
 
 // GetUser handles GET /api/users/{id}
@@ -50,12 +41,12 @@ func (h *Handlers) GetUser(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
 	if err != nil {
-		http.Error(w, "invalid user ID", http.StatusBadRequest)
+		httputil.RespondError(w, http.StatusBadRequest, "invalid user ID")
 		return
 	}
 
 	// Call gRPC service
-	resp, err := h.clients.UserClient.GetUser(context.Background(), &userv1.GetUserRequest{
+	resp, err := h.clients.UserClient.GetUser(r.Context(), &userv1.GetUserRequest{
 		Id: uint32(id),
 	})
 
@@ -64,23 +55,69 @@ func (h *Handlers) GetUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Return HTTP JSON response
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp.User)
+	// Return the response as JSON or protobuf, as Accept asks
+	respondMessage(w, r, http.StatusOK, resp.User)
 }
 
 // GetUsers handles GET /api/users
 // Translates HTTP request to gRPC GetUsers call
 func (h *Handlers) GetUsers(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("stream") == "true" {
+		h.StreamUsers(w, r)
+		return
+	}
+
 	// Call gRPC service
-	resp, err := h.clients.UserClient.GetUsers(context.Background(), &userv1.GetUsersRequest{})
+	resp, err := h.clients.UserClient.GetUsers(r.Context(), &userv1.GetUsersRequest{})
+
+	if err != nil {
+		handleGRPCError(w, err)
+		return
+	}
 
+	// Return the response as JSON or protobuf, as Accept asks
+	respondList(w, r, http.StatusOK, resp, resp.Users)
+}
+
+// StreamUsers handles GET /api/users?stream=true
+// Relays the ListUsersStream gRPC call as NDJSON, one user per line, flushing
+// each line as it arrives so clients can start before the last page is read
+func (h *Handlers) StreamUsers(w http.ResponseWriter, r *http.Request) {
+	stream, err := h.clients.UserClient.ListUsersStream(r.Context(), &userv1.ListUsersStreamRequest{})
 	if err != nil {
 		handleGRPCError(w, err)
 		return
 	}
 
-	// Return HTTP JSON response
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(resp.Users)
+	// A backend that fails before its first user still gets a proper status
+	user, err := stream.Recv()
+	if err != nil && !errors.Is(err, io.EOF) {
+		handleGRPCError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	for ; err == nil; user, err = stream.Recv() {
+		line, encErr := jsonMarshal.Marshal(user)
+		if encErr == nil {
+			line, encErr = compactJSON(line)
+		}
+		if encErr != nil {
+			log.Printf("Aborting user stream: %v", encErr)
+			return
+		}
+		if _, err := w.Write(line); err != nil {
+			// The client went away; returning cancels the backend stream
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	if !errors.Is(err, io.EOF) {
+		// Headers are already sent; truncating the stream is all we can do
+		log.Printf("Aborting user stream: %v", err)
+	}
 }
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"api-gateway/grpc"
+	"api-gateway/httputil"
+
+	userv1 "github.com/douglasswm/student-cafe-protos/gen/go/user/v1"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func serve(router http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return rec
+}
+
+func decodeError(t *testing.T, rec *httptest.ResponseRecorder) ErrorResponse {
+	var body ErrorResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	return body
+}
+
+func TestCreateUserTranslatesRequest(t *testing.T) {
+	users := &fakeUserClient{}
+	router := newTestRouter(users, &fakeMenuClient{}, &fakeOrderClient{})
+
+	rec := serve(router, http.MethodPost, "/api/users", `{"name": "Tashi", "email": "tashi@example.com", "is_cafe_owner": true}`)
+
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.NotNil(t, users.created)
+	assert.Equal(t, "Tashi", users.created.Name)
+	assert.Equal(t, "tashi@example.com", users.created.Email)
+	assert.True(t, users.created.IsCafeOwner)
+	var user userv1.User
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&user))
+	assert.EqualValues(t, 7, user.Id)
+}
+
+func TestCreateUserErrors(t *testing.T) {
+	users := &fakeUserClient{}
+	router := newTestRouter(users, &fakeMenuClient{}, &fakeOrderClient{})
+
+	rec := serve(router, http.MethodPost, "/api/users", `{"name": `)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Nil(t, users.created, "malformed bodies never reach the backend")
+
+	users.err = status.Error(codes.AlreadyExists, "email already registered")
+	rec = serve(router, http.MethodPost, "/api/users", `{"name": "Tashi", "email": "tashi@example.com"}`)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, ErrorResponse{Error: "email already registered", Code: "AlreadyExists"}, decodeError(t, rec))
+}
+
+func TestGetUser(t *testing.T) {
+	users := &fakeUserClient{users: map[uint32]*userv1.User{1: {Id: 1, Name: "Pema"}}}
+	router := newTestRouter(users, &fakeMenuClient{}, &fakeOrderClient{})
+
+	rec := serve(router, http.MethodGet, "/api/users/1", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"name":"Pema"`)
+
+	rec = serve(router, http.MethodGet, "/api/users/2", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "NotFound", decodeError(t, rec).Code)
+
+	assert.Equal(t, http.StatusBadRequest, serve(router, http.MethodGet, "/api/users/abc", "").Code)
+}
+
+func TestGetUsers(t *testing.T) {
+	users := &fakeUserClient{users: map[uint32]*userv1.User{1: {Id: 1, Name: "Pema"}, 2: {Id: 2, Name: "Dawa"}}}
+	router := newTestRouter(users, &fakeMenuClient{}, &fakeOrderClient{})
+
+	rec := serve(router, http.MethodGet, "/api/users", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var list []*userv1.User
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&list))
+	require.Len(t, list, 2)
+	assert.Equal(t, "Dawa", list[1].Name)
+
+	users.err = status.Error(codes.Unavailable, "user service down")
+	rec = serve(router, http.MethodGet, "/api/users", "")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+func TestGetUsersCompressesLargeLists(t *testing.T) {
+	users := &fakeUserClient{users: map[uint32]*userv1.User{}}
+	for id := uint32(1); id <= 50; id++ {
+		users.users[id] = &userv1.User{Id: id, Name: fmt.Sprintf("User %d", id), Email: fmt.Sprintf("user%d@cafe.test", id)}
+	}
+	h := NewHandlers(&grpc.ServiceClients{UserClient: users, MenuClient: &fakeMenuClient{}, OrderClient: &fakeOrderClient{}})
+	router := chi.NewRouter()
+	router.Use(httputil.Compress(httputil.DefaultCompressMinSize))
+	h.Register(router)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+	zr, err := gzip.NewReader(rec.Body)
+	require.NoError(t, err)
+	var list []*userv1.User
+	require.NoError(t, json.NewDecoder(zr).Decode(&list))
+	assert.Len(t, list, 50)
+
+	rec = serve(router, http.MethodGet, "/api/users", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&list))
+	assert.Len(t, list, 50)
+}
+
+func TestWrongMethodGets405WithAllow(t *testing.T) {
+	router := newTestRouter(&fakeUserClient{}, &fakeMenuClient{}, &fakeOrderClient{})
+
+	for path, allow := range map[string]string{
+		"/api/users/1": "GET",
+		"/api/users":   "GET, POST",
+		"/api/menu":    "GET, POST",
+	} {
+		rec := serve(router, http.MethodDelete, path, "")
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code, path)
+		assert.Equal(t, allow, rec.Header().Get("Allow"), path)
+		assert.Equal(t, "DELETE is not allowed on "+path, decodeError(t, rec).Error)
+	}
+
+	rec := serve(router, http.MethodGet, "/api/nope", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+}
@@ -0,0 +1,173 @@
+package httputil
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DefaultCompressMinSize is the smallest response body worth gzipping;
+// below it the gzip framing costs more than it saves.
+const DefaultCompressMinSize = 1024
+
+// Compress gzips response bodies for clients whose Accept-Encoding allows it.
+// Bodies shorter than minSize, and responses that are already compressed,
+// are sent unchanged.
+func Compress(minSize int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// The body varies with Accept-Encoding whichever way we decide
+			w.Header().Add("Vary", "Accept-Encoding")
+			if !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{ResponseWriter: w, minSize: minSize}
+			defer cw.Close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip, either
+// by name or through "*", with a non-zero quality.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// alreadyCompressed reports whether a response should be left alone because
+// it already has an encoding or its media type is compressed by nature.
+func alreadyCompressed(h http.Header) bool {
+	if h.Get("Content-Encoding") != "" {
+		return true
+	}
+	ct := h.Get("Content-Type")
+	switch {
+	case strings.HasPrefix(ct, "image/") && !strings.HasPrefix(ct, "image/svg"),
+		strings.HasPrefix(ct, "video/"),
+		strings.HasPrefix(ct, "audio/"),
+		strings.HasPrefix(ct, "application/zip"),
+		strings.HasPrefix(ct, "application/gzip"):
+		return true
+	}
+	return false
+}
+
+// compressWriter buffers the start of a body until it knows whether the
+// response reaches minSize, then commits to gzip or to passing it through.
+type compressWriter struct {
+	http.ResponseWriter
+	minSize int
+
+	status  int
+	buf     []byte
+	gz      *gzip.Writer
+	decided bool
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.decided || cw.status != 0 {
+		return
+	}
+	cw.status = status
+	// Bodyless responses have nothing to compress
+	if status == http.StatusNoContent || status == http.StatusNotModified {
+		cw.commit(false)
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	if !cw.decided {
+		if alreadyCompressed(cw.Header()) {
+			cw.commit(false)
+		} else {
+			cw.buf = append(cw.buf, p...)
+			if len(cw.buf) < cw.minSize {
+				return len(p), nil
+			}
+			if err := cw.commit(true); err != nil {
+				return 0, err
+			}
+			return len(p), nil
+		}
+	}
+	if cw.gz != nil {
+		return cw.gz.Write(p)
+	}
+	return cw.ResponseWriter.Write(p)
+}
+
+// commit sends the headers and any buffered bytes, compressed or not.
+func (cw *compressWriter) commit(compress bool) error {
+	cw.decided = true
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	if compress {
+		cw.Header().Set("Content-Encoding", "gzip")
+		cw.Header().Del("Content-Length")
+		cw.gz = gzip.NewWriter(cw.ResponseWriter)
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if cw.gz != nil {
+		_, err = cw.gz.Write(buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// Flush commits to gzip if nothing has been sent yet, since a handler that
+// flushes is streaming and its body is likely to be large.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		cw.commit(!alreadyCompressed(cw.Header()))
+	}
+	if cw.gz != nil {
+		cw.gz.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close sends a body that never reached minSize uncompressed, or finishes
+// the gzip stream.
+func (cw *compressWriter) Close() error {
+	if !cw.decided {
+		if cw.status == 0 {
+			// The handler wrote nothing; leave the default response alone
+			return nil
+		}
+		return cw.commit(false)
+	}
+	if cw.gz != nil {
+		return cw.gz.Close()
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package httputil
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveCompressed(h http.HandlerFunc, acceptEncoding string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	rec := httptest.NewRecorder()
+	Compress(64)(h).ServeHTTP(rec, req)
+	return rec
+}
+
+func gunzip(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	zr, err := gzip.NewReader(rec.Body)
+	require.NoError(t, err)
+	body, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestCompressGzipsLargeBodies(t *testing.T) {
+	large := strings.Repeat(`{"name":"item"},`, 20)
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, large[:50])
+		io.WriteString(w, large[50:])
+	}
+
+	rec := serveCompressed(handler, "br, gzip;q=0.8")
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+	assert.Equal(t, large, gunzip(t, rec))
+
+	rec = serveCompressed(handler, "")
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+	assert.Equal(t, large, rec.Body.String())
+
+	rec = serveCompressed(handler, "gzip;q=0, identity")
+	assert.Empty(t, rec.Header().Get("Content-Encoding"), "q=0 refuses gzip")
+}
+
+func TestCompressSkipsSmallAndCompressedBodies(t *testing.T) {
+	rec := serveCompressed(func(w http.ResponseWriter, r *http.Request) {
+		RespondJSON(w, http.StatusOK, map[string]int{"id": 7})
+	}, "gzip")
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.JSONEq(t, `{"id": 7}`, rec.Body.String())
+
+	png := strings.Repeat("\x89PNG", 100)
+	rec = serveCompressed(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		io.WriteString(w, png)
+	}, "gzip")
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, png, rec.Body.String())
+
+	rec = serveCompressed(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}, "gzip")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.Zero(t, rec.Body.Len())
+}
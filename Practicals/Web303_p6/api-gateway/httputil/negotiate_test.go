@@ -0,0 +1,31 @@
+package httputil
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiate(t *testing.T) {
+	supported := []string{"application/json", "application/x-protobuf"}
+	for accept, want := range map[string]string{
+		"":                       "application/json",
+		"*/*":                    "application/json",
+		"application/*":          "application/json",
+		"application/x-protobuf": "application/x-protobuf",
+		"application/json;q=0.5, application/x-protobuf": "application/x-protobuf",
+		"application/x-protobuf;q=0.1, */*;q=0.5":        "application/json",
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept", accept)
+		got, ok := Negotiate(req, supported...)
+		assert.True(t, ok, accept)
+		assert.Equal(t, want, got, accept)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept", "text/html, application/json;q=0")
+	_, ok := Negotiate(req, supported...)
+	assert.False(t, ok)
+}
@@ -0,0 +1,51 @@
+package httputil
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func captureLog(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	orig := log.Writer()
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(orig) })
+	return &buf
+}
+
+func TestRecoverReturnsJSON500(t *testing.T) {
+	logs := captureLog(t)
+	handler := middleware.RequestID(Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("database password is hunter2")
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/boom", nil)
+	req.Header.Set("X-Request-Id", "req-123")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"error": "internal server error"}`, rec.Body.String())
+	assert.NotContains(t, rec.Body.String(), "hunter2", "panic values stay out of the response")
+
+	assert.Contains(t, logs.String(), "Panic serving GET /boom (request req-123): database password is hunter2")
+	assert.Contains(t, logs.String(), "recover_test.go", "the stack trace is logged")
+}
+
+func TestRecoverRepanicsOnAbortHandler(t *testing.T) {
+	captureLog(t)
+	handler := Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
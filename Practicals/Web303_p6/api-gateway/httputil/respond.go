@@ -0,0 +1,27 @@
+// Package httputil holds the JSON response helpers shared by the handlers.
+package httputil
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// ErrorBody is the JSON shape of every error response.
+type ErrorBody struct {
+	Error string `json:"error"`
+}
+
+// RespondJSON writes v as JSON with the given status.
+func RespondJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to encode response: %v", err)
+	}
+}
+
+// RespondError writes {"error": msg} with the given status.
+func RespondError(w http.ResponseWriter, status int, msg string) {
+	RespondJSON(w, status, ErrorBody{Error: msg})
+}
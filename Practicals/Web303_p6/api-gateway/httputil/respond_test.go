@@ -0,0 +1,27 @@
+package httputil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRespondJSON(t *testing.T) {
+	rec := httptest.NewRecorder()
+	RespondJSON(rec, http.StatusCreated, map[string]int{"id": 7})
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"id": 7}`, rec.Body.String())
+}
+
+func TestRespondError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	RespondError(rec, http.StatusNotFound, "user not found")
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"error": "user not found"}`, rec.Body.String())
+}
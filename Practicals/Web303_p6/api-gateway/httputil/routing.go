@@ -0,0 +1,39 @@
+package httputil
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// routeMethods are the methods probed when building an Allow header.
+var routeMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete, http.MethodOptions,
+}
+
+// NotFound answers unknown paths with a JSON 404.
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	RespondError(w, http.StatusNotFound, "no route for "+r.URL.Path)
+}
+
+// MethodNotAllowed answers a known path requested with the wrong method:
+// a JSON 405 whose Allow header lists the methods routes serves there.
+func MethodNotAllowed(routes chi.Routes) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", strings.Join(AllowedMethods(routes, r.URL.Path), ", "))
+		RespondError(w, http.StatusMethodNotAllowed, r.Method+" is not allowed on "+r.URL.Path)
+	}
+}
+
+// AllowedMethods lists the methods routes has a handler for at path.
+func AllowedMethods(routes chi.Routes, path string) []string {
+	var allowed []string
+	for _, method := range routeMethods {
+		if routes.Match(chi.NewRouteContext(), method, path) {
+			allowed = append(allowed, method)
+		}
+	}
+	return allowed
+}
@@ -1,49 +1,75 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"os"
+	"time"
 
 	"api-gateway/grpc"
 	"api-gateway/handlers"
+	"api-gateway/httputil"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 func main() {
+	shutdownTracing, err := setupTracing(context.Background(), "api-gateway")
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	// Initialize gRPC connections to backend microservices
 	clients, err := grpc.NewServiceClients()
 	if err != nil {
 		log.Fatalf("Failed to initialize backend service connections: %v", err)
 	}
 	log.Println("Backend service connections established")
+	// Re-resolve and re-dial backends whose connection stays failed; readiness
+	// fails only once a backend has been down that long
+	reconnectThreshold := envDuration("GRPC_RECONNECT_THRESHOLD", 15*time.Second)
+	clients.UnhealthyAfter = reconnectThreshold
+	go clients.Monitor(context.Background(),
+		envDuration("GRPC_HEALTH_INTERVAL", 5*time.Second), reconnectThreshold)
+
+	// Sections that must be available for the owner dashboard to succeed
+	handlers.CriticalDashboardSections = handlers.ParseDashboardSections(os.Getenv("DASHBOARD_CRITICAL_SECTIONS"))
 
 	// Create handlers with gRPC clients
 	h := handlers.NewHandlers(clients)
 
 	// Setup HTTP router
 	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
 	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
-
-	// User routes - HTTP to gRPC translation
-	r.Post("/api/users", h.CreateUser)
-	r.Get("/api/users/{id}", h.GetUser)
-	r.Get("/api/users", h.GetUsers)
+	r.Use(httputil.Recover)
+	r.Use(httputil.Compress(httputil.DefaultCompressMinSize))
 
-	// Menu routes - HTTP to gRPC translation
-	r.Post("/api/menu", h.CreateMenuItem)
-	r.Get("/api/menu/{id}", h.GetMenuItem)
-	r.Get("/api/menu", h.GetMenu)
-
-	// Order routes - HTTP to gRPC translation
-	r.Post("/api/orders", h.CreateOrder)
-	r.Get("/api/orders/{id}", h.GetOrder)
-	r.Get("/api/orders", h.GetOrders)
+	// HTTP to gRPC translation for users, menu, orders and the owner dashboard
+	h.Register(r)
 
 	log.Println("API Gateway starting on :8080 (HTTP→gRPC translation layer)")
-	if err := http.ListenAndServe(":8080", r); err != nil {
+	// Continue traces started upstream, e.g. by the edge gateway
+	traced := otelhttp.NewHandler(r, "api-gateway", otelhttp.WithSpanNameFormatter(func(_ string, req *http.Request) string {
+		return req.Method + " " + req.URL.Path
+	}))
+	if err := http.ListenAndServe(":8080", traced); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
+
+// envDuration reads a duration such as "10s" from the environment.
+func envDuration(key string, fallback time.Duration) time.Duration {
+	if raw := os.Getenv(key); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			log.Fatalf("Invalid %s: %q", key, raw)
+		}
+		return d
+	}
+	return fallback
+}
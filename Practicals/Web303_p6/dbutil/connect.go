@@ -0,0 +1,44 @@
+package dbutil
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Backoff between connection attempts doubles from the first value up to
+// the second.
+const (
+	initialConnectBackoff = 500 * time.Millisecond
+	maxConnectBackoff     = 10 * time.Second
+)
+
+// Connect calls open until it succeeds or retryFor has passed, logging each
+// failed attempt, then sizes the pool of the resulting DB. A retryFor of 0
+// tries once.
+func Connect(open func() (*gorm.DB, error), retryFor time.Duration, pool PoolConfig) (*gorm.DB, error) {
+	return connect(open, retryFor, initialConnectBackoff, pool)
+}
+
+func connect(open func() (*gorm.DB, error), retryFor, backoff time.Duration, pool PoolConfig) (*gorm.DB, error) {
+	deadline := time.Now().Add(retryFor)
+	for attempt := 1; ; attempt++ {
+		db, err := open()
+		if err == nil {
+			if err := ConfigurePool(db, pool); err != nil {
+				return nil, err
+			}
+			return db, nil
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, fmt.Errorf("giving up after %d attempt(s): %w", attempt, err)
+		}
+		wait := min(backoff, remaining)
+		log.Printf("Database connection attempt %d failed, retrying in %s: %v", attempt, wait, err)
+		time.Sleep(wait)
+		backoff = min(2*backoff, maxConnectBackoff)
+	}
+}
@@ -0,0 +1,31 @@
+package dbutil
+
+import (
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PoolConfig sizes the database/sql connection pool under a service's DB.
+type PoolConfig struct {
+	MaxOpenConns int
+	MaxIdleConns int
+	// ConnMaxLifetime recycles connections so they follow database
+	// failovers and load balancer changes; 0 keeps them forever.
+	ConnMaxLifetime time.Duration
+}
+
+// ConfigurePool applies cfg to the connection pool behind db.
+func ConfigurePool(db *gorm.DB, cfg PoolConfig) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
+	log.Printf("Database pool: max_open=%d max_idle=%d max_lifetime=%s", cfg.MaxOpenConns, cfg.MaxIdleConns, cfg.ConnMaxLifetime)
+	return nil
+}
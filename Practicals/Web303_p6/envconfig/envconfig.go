@@ -0,0 +1,116 @@
+// Package envconfig fills a service's settings struct from the environment,
+// falling back to a .env file for local development and then to the
+// defaults in the struct tags.
+package envconfig
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DotEnvFile is read by Lookup when present. Real environment variables take
+// precedence over its entries.
+const DotEnvFile = ".env"
+
+// Lookup returns a lookup that reads the environment first and DotEnvFile
+// second.
+func Lookup() (func(string) (string, bool), error) {
+	dotenv, err := readDotEnv(DotEnvFile)
+	if err != nil {
+		return nil, err
+	}
+	return func(key string) (string, bool) {
+		if v, ok := os.LookupEnv(key); ok {
+			return v, true
+		}
+		v, ok := dotenv[key]
+		return v, ok
+	}, nil
+}
+
+// Fill sets each field of the struct cfg points to from the variable its env
+// tag names, using the default tag when lookup has no value. Fields may be
+// strings, ints, bools or time.Durations.
+func Fill(cfg any, lookup func(string) (string, bool)) error {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("envconfig: %T is not a pointer to a struct", cfg)
+	}
+	v = v.Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		key := field.Tag.Get("env")
+		raw, ok := lookup(key)
+		if !ok || strings.TrimSpace(raw) == "" {
+			raw = field.Tag.Get("default")
+		}
+		raw = strings.TrimSpace(raw)
+
+		switch field.Type.Kind() {
+		case reflect.String:
+			v.Field(i).SetString(raw)
+		case reflect.Int:
+			n, err := strconv.Atoi(raw)
+			if err != nil {
+				return fmt.Errorf("%s: %q is not an integer", key, raw)
+			}
+			v.Field(i).SetInt(int64(n))
+		case reflect.Int64:
+			if field.Type != reflect.TypeOf(time.Duration(0)) {
+				return fmt.Errorf("%s: unsupported field type %s", key, field.Type)
+			}
+			d, err := time.ParseDuration(raw)
+			if err != nil {
+				return fmt.Errorf("%s: %q is not a duration", key, raw)
+			}
+			v.Field(i).SetInt(int64(d))
+		case reflect.Bool:
+			b, err := strconv.ParseBool(raw)
+			if err != nil {
+				return fmt.Errorf("%s: %q is not a boolean", key, raw)
+			}
+			v.Field(i).SetBool(b)
+		default:
+			return fmt.Errorf("%s: unsupported field type %s", key, field.Type)
+		}
+	}
+	return nil
+}
+
+// readDotEnv parses KEY=VALUE lines, ignoring blanks and # comments and
+// stripping matching quotes around values. A missing file is not an error.
+func readDotEnv(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(text, "export "), "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", path, line)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		values[strings.TrimSpace(key)] = value
+	}
+	return values, scanner.Err()
+}
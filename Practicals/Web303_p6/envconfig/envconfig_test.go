@@ -0,0 +1,92 @@
+package envconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lookupFrom(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+}
+
+type settings struct {
+	Port    int           `env:"PORT" default:"8080"`
+	Name    string        `env:"NAME" default:"cafe"`
+	Debug   bool          `env:"DEBUG" default:"false"`
+	Timeout time.Duration `env:"TIMEOUT" default:"5s"`
+}
+
+func TestFillDefaultsAndOverrides(t *testing.T) {
+	var cfg settings
+	require.NoError(t, Fill(&cfg, lookupFrom(map[string]string{"NAME": "  "})))
+	assert.Equal(t, settings{Port: 8080, Name: "cafe", Timeout: 5 * time.Second}, cfg, "blank values fall back to the default")
+
+	require.NoError(t, Fill(&cfg, lookupFrom(map[string]string{
+		"PORT":    " 9090 ",
+		"NAME":    "kiosk",
+		"DEBUG":   "true",
+		"TIMEOUT": "2m",
+	})))
+	assert.Equal(t, settings{Port: 9090, Name: "kiosk", Debug: true, Timeout: 2 * time.Minute}, cfg)
+}
+
+func TestFillRejectsInvalidValues(t *testing.T) {
+	for env, want := range map[string]string{
+		"PORT":    "PORT: \"http\" is not an integer",
+		"DEBUG":   "DEBUG: \"http\" is not a boolean",
+		"TIMEOUT": "TIMEOUT: \"http\" is not a duration",
+	} {
+		var cfg settings
+		assert.EqualError(t, Fill(&cfg, lookupFrom(map[string]string{env: "http"})), want)
+	}
+
+	var unsupported struct {
+		Ratio float64 `env:"RATIO" default:"0.5"`
+	}
+	assert.ErrorContains(t, Fill(&unsupported, lookupFrom(nil)), "unsupported field type")
+	assert.Error(t, Fill(settings{}, lookupFrom(nil)), "cfg must be a pointer")
+}
+
+func TestLookupFallsBackToDotEnv(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, DotEnvFile), []byte(
+		"# local development\nPORT=9191\nexport NAME='kiosk'\n\nDSN=\"host=db dbname=cafe\"\n"), 0o600))
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { os.Chdir(wd) })
+	t.Setenv("PORT", "9292")
+
+	lookup, err := Lookup()
+	require.NoError(t, err)
+	port, _ := lookup("PORT")
+	assert.Equal(t, "9292", port, "the environment wins over .env")
+	name, _ := lookup("NAME")
+	assert.Equal(t, "kiosk", name)
+	dsn, _ := lookup("DSN")
+	assert.Equal(t, "host=db dbname=cafe", dsn)
+	_, ok := lookup("MISSING")
+	assert.False(t, ok)
+
+	require.NoError(t, os.WriteFile(DotEnvFile, []byte("not a pair\n"), 0o600))
+	_, err = Lookup()
+	assert.ErrorContains(t, err, ".env:1")
+}
+
+func TestLookupWithoutDotEnv(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	_, err = Lookup()
+	assert.NoError(t, err, "a missing .env is not an error")
+}
@@ -1,8 +1,9 @@
 package database
 
 import (
+	"dbutil"
 	"log"
-	"menu-service/models"
+	"time"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -10,18 +11,20 @@ import (
 
 var DB *gorm.DB
 
-func Connect(dsn string) error {
-	var err error
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
-	if err != nil {
-		return err
-	}
+// ConnectRetry is how long Connect keeps retrying a database that is not
+// accepting connections yet, as when it is still starting under Docker
+// Compose; 0 tries once. main sets it from DB_CONNECT_RETRY.
+var ConnectRetry = time.Minute
 
-	// Only migrate menu-related tables
-	err = DB.AutoMigrate(&models.MenuItem{})
+// Connect opens dsn as DB, retrying for up to ConnectRetry.
+func Connect(dsn string) error {
+	db, err := dbutil.Connect(func() (*gorm.DB, error) {
+		return gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	}, ConnectRetry, Pool)
 	if err != nil {
 		return err
 	}
+	DB = db
 
 	log.Println("Menu database connected")
 	return nil
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"menu-service/models"
+	"os"
+
+	"gorm.io/gorm"
+)
+
+// SeedData is the shape of a seed file. Each service reads only its own key,
+// so one file can seed the whole stack.
+type SeedData struct {
+	MenuItems []models.MenuItem `json:"menu_items"`
+}
+
+// Seed inserts the menu items in the JSON file at path, all or none, and
+// returns how many were inserted. It does nothing when the menu_items table
+// already has rows, so restarting a seeded service never duplicates them.
+func Seed(db *gorm.DB, path string) (int, error) {
+	var count int64
+	if err := db.Model(&models.MenuItem{}).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("counting menu items: %w", err)
+	}
+	if count > 0 {
+		return 0, nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	var seed SeedData
+	if err := json.Unmarshal(raw, &seed); err != nil {
+		return 0, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	if len(seed.MenuItems) == 0 {
+		return 0, nil
+	}
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		return tx.Create(&seed.MenuItems).Error
+	}); err != nil {
+		return 0, fmt.Errorf("inserting menu items: %w", err)
+	}
+	return len(seed.MenuItems), nil
+}
@@ -0,0 +1,67 @@
+package database
+
+import (
+	"menu-service/models"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func openTestDB(t *testing.T) *gorm.DB {
+	db := openEmptyDB(t)
+	require.NoError(t, Migrate(db))
+	return db
+}
+
+func writeSeedFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "seed.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestSeedLoadsMenuItemsIntoEmptyTable(t *testing.T) {
+	db := openTestDB(t)
+	path := writeSeedFile(t, `{
+		"users": [{"name": "read by the user service only"}],
+		"menu_items": [
+			{"name": "Momo", "description": "Steamed dumplings", "price": 4.5},
+			{"name": "Suja", "price": 1.5}
+		]
+	}`)
+
+	inserted, err := Seed(db, path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, inserted)
+
+	var items []models.MenuItem
+	require.NoError(t, db.Order("id").Find(&items).Error)
+	require.Len(t, items, 2)
+	assert.Equal(t, "Momo", items[0].Name)
+	assert.Equal(t, 1.5, items[1].Price)
+
+	// A second boot finds the table populated and leaves it alone
+	inserted, err = Seed(db, path)
+	require.NoError(t, err)
+	assert.Zero(t, inserted)
+	var count int64
+	require.NoError(t, db.Model(&models.MenuItem{}).Count(&count).Error)
+	assert.EqualValues(t, 2, count)
+}
+
+func TestSeedRejectsBadFiles(t *testing.T) {
+	db := openTestDB(t)
+
+	_, err := Seed(db, filepath.Join(t.TempDir(), "missing.json"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	_, err = Seed(db, writeSeedFile(t, `{"menu_items": [`))
+	assert.Error(t, err)
+
+	var count int64
+	require.NoError(t, db.Model(&models.MenuItem{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
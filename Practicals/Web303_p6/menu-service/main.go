@@ -3,43 +3,64 @@ package main
 import (
 	"fmt"
 	"log"
-	"net"
-	"os"
+	"menu-service/config"
 	"menu-service/database"
 	grpcserver "menu-service/grpc"
+	"net"
+	"os"
 
 	menuv1 "github.com/douglasswm/student-cafe-protos/gen/go/menu/v1"
 	"google.golang.org/grpc"
 )
 
 func main() {
-	// Establish connection to menu service database
-	dsn := os.Getenv("DATABASE_URL")
-	if dsn == "" {
-		dsn = "host=localhost user=postgres password=postgres dbname=menu_db port=5432 sslmode=disable"
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
 	}
 
-	if err := database.Connect(dsn); err != nil {
+	database.Pool = database.PoolConfig{
+		MaxOpenConns:    cfg.DBMaxOpenConns,
+		MaxIdleConns:    cfg.DBMaxIdleConns,
+		ConnMaxLifetime: cfg.DBConnMaxLifetime,
+	}
+	database.ConnectRetry = cfg.DBConnectRetry
+
+	if err := database.Connect(cfg.DatabaseDSN); err != nil {
 		log.Fatalf("Menu service database connection failed: %v", err)
 	}
 
-	// Get gRPC port from environment
-	grpcPort := os.Getenv("GRPC_PORT")
-	if grpcPort == "" {
-		grpcPort = "9092"
+	// `menu-service migrate` applies pending schema migrations and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := database.Migrate(database.DB); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+	// MIGRATE_ON_BOOT=false requires the migrate subcommand to be run first
+	if err := database.EnsureSchema(database.DB, cfg.MigrateOnBoot); err != nil {
+		log.Fatalf("Database schema not ready: %v", err)
+	}
+
+	if cfg.SeedData {
+		inserted, err := database.Seed(database.DB, cfg.SeedFile)
+		if err != nil {
+			log.Fatalf("Seeding from %s failed: %v", cfg.SeedFile, err)
+		}
+		log.Printf("Seeded %d menu items from %s", inserted, cfg.SeedFile)
 	}
 
 	// Start listening on TCP port
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%s", grpcPort))
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPCPort))
 	if err != nil {
-		log.Fatalf("Failed to listen on gRPC port %s: %v", grpcPort, err)
+		log.Fatalf("Failed to listen on gRPC port %d: %v", cfg.GRPCPort, err)
 	}
 
 	// Create and register gRPC server
 	s := grpc.NewServer()
 	menuv1.RegisterMenuServiceServer(s, grpcserver.NewMenuServer())
 
-	log.Printf("Menu service (gRPC only) starting on :%s", grpcPort)
+	log.Printf("Menu service (gRPC only) starting on :%d", cfg.GRPCPort)
 	if err := s.Serve(lis); err != nil {
 		log.Fatalf("gRPC server failed: %v", err)
 	}
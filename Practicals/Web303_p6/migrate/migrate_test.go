@@ -0,0 +1,94 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type widget struct {
+	ID   uint
+	Name string
+}
+
+func openTestDB(t *testing.T) *gorm.DB {
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", strings.ReplaceAll(t.Name(), "/", "_"))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		sqlDB, err := db.DB()
+		require.NoError(t, err)
+		sqlDB.Close()
+	})
+	return db
+}
+
+func widgetRunner(order *[]int) Runner {
+	step := func(v int, up func(tx *gorm.DB) error) Migration {
+		return Migration{Version: v, Name: fmt.Sprintf("step_%d", v), Up: func(tx *gorm.DB) error {
+			*order = append(*order, v)
+			return up(tx)
+		}}
+	}
+	return Runner{Command: "widget-service migrate", Migrations: []Migration{
+		step(2, func(tx *gorm.DB) error { return tx.Migrator().AddColumn(&widget{}, "Name") }),
+		step(1, func(tx *gorm.DB) error { return tx.Exec("CREATE TABLE widgets (id integer PRIMARY KEY)").Error }),
+	}}
+}
+
+func TestMigrateAppliesInVersionOrderOnce(t *testing.T) {
+	db := openTestDB(t)
+	var order []int
+	r := widgetRunner(&order)
+
+	require.NoError(t, r.Migrate(db))
+	require.NoError(t, r.Migrate(db))
+
+	assert.Equal(t, []int{1, 2}, order, "re-runs are no-ops")
+	assert.True(t, db.Migrator().HasColumn(&widget{}, "name"))
+	var applied []SchemaMigration
+	require.NoError(t, db.Order("version").Find(&applied).Error)
+	require.Len(t, applied, 2)
+	assert.Equal(t, "step_1", applied[0].Name)
+}
+
+func TestMigrateRollsBackAFailedMigration(t *testing.T) {
+	db := openTestDB(t)
+	r := Runner{Migrations: []Migration{{Version: 1, Name: "broken", Up: func(tx *gorm.DB) error {
+		if err := tx.Exec("CREATE TABLE widgets (id integer PRIMARY KEY)").Error; err != nil {
+			return err
+		}
+		return errors.New("boom")
+	}}}}
+
+	assert.ErrorContains(t, r.Migrate(db), "migration 1 (broken): boom")
+	assert.False(t, db.Migrator().HasTable("widgets"))
+	pending, err := r.Pending(db)
+	require.NoError(t, err)
+	assert.Len(t, pending, 1)
+}
+
+func TestPendingRejectsDuplicateVersions(t *testing.T) {
+	db := openTestDB(t)
+	noop := func(tx *gorm.DB) error { return nil }
+	r := Runner{Migrations: []Migration{{Version: 1, Name: "a", Up: noop}, {Version: 1, Name: "b", Up: noop}}}
+
+	_, err := r.Pending(db)
+	assert.ErrorContains(t, err, "duplicate migration version 1")
+}
+
+func TestEnsureSchemaNamesTheCommand(t *testing.T) {
+	db := openTestDB(t)
+	var order []int
+	r := widgetRunner(&order)
+
+	assert.ErrorContains(t, r.EnsureSchema(db, false), "2 pending migrations, run `widget-service migrate`")
+	require.NoError(t, r.EnsureSchema(db, true))
+	assert.NoError(t, r.EnsureSchema(db, false))
+}
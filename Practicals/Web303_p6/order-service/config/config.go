@@ -0,0 +1,69 @@
+// Package config loads service settings from the environment, falling back
+// to a .env file for local development and then to the defaults in the
+// struct tags.
+package config
+
+import (
+	"envconfig"
+	"fmt"
+	"time"
+)
+
+// Config holds the settings main needs to start the service. Each field
+// names its environment variable and default in struct tags.
+type Config struct {
+	GRPCPort    int    `env:"GRPC_PORT" default:"9093"`
+	DatabaseDSN string `env:"DATABASE_URL" default:"host=localhost user=postgres password=postgres dbname=order_db port=5432 sslmode=disable"`
+	// MigrateOnBoot applies pending schema migrations at startup; when
+	// false they must be run with the migrate subcommand.
+	MigrateOnBoot bool `env:"MIGRATE_ON_BOOT" default:"true"`
+	// The DB* fields size the database connection pool.
+	DBMaxOpenConns    int           `env:"DB_MAX_OPEN_CONNS" default:"25"`
+	DBMaxIdleConns    int           `env:"DB_MAX_IDLE_CONNS" default:"5"`
+	DBConnMaxLifetime time.Duration `env:"DB_CONN_MAX_LIFETIME" default:"30m"`
+	// DBConnectRetry is how long startup keeps retrying a database that is
+	// not accepting connections yet; 0 tries once.
+	DBConnectRetry time.Duration `env:"DB_CONNECT_RETRY" default:"60s"`
+	// UserServiceAddr and MenuServiceAddr are the gRPC backends orders are
+	// checked against.
+	UserServiceAddr string `env:"USER_SERVICE_GRPC_ADDR" default:"user-service:9091"`
+	MenuServiceAddr string `env:"MENU_SERVICE_GRPC_ADDR" default:"menu-service:9092"`
+}
+
+// Load reads the configuration from the environment and
+// envconfig.DotEnvFile.
+func Load() (Config, error) {
+	lookup, err := envconfig.Lookup()
+	if err != nil {
+		return Config{}, err
+	}
+	return load(lookup)
+}
+
+// load fills a Config using lookup, then validates it.
+func load(lookup func(string) (string, bool)) (Config, error) {
+	var cfg Config
+	if err := envconfig.Fill(&cfg, lookup); err != nil {
+		return Config{}, err
+	}
+	return cfg, cfg.validate()
+}
+
+func (c Config) validate() error {
+	if c.GRPCPort < 1 || c.GRPCPort > 65535 {
+		return fmt.Errorf("GRPC_PORT: %d is out of range", c.GRPCPort)
+	}
+	if c.DBMaxOpenConns < 1 {
+		return fmt.Errorf("DB_MAX_OPEN_CONNS: %d must be positive", c.DBMaxOpenConns)
+	}
+	if c.DBMaxIdleConns < 0 || c.DBMaxIdleConns > c.DBMaxOpenConns {
+		return fmt.Errorf("DB_MAX_IDLE_CONNS: %d must be between 0 and DB_MAX_OPEN_CONNS", c.DBMaxIdleConns)
+	}
+	if c.DBConnMaxLifetime < 0 {
+		return fmt.Errorf("DB_CONN_MAX_LIFETIME: %s must not be negative", c.DBConnMaxLifetime)
+	}
+	if c.DBConnectRetry < 0 {
+		return fmt.Errorf("DB_CONNECT_RETRY: %s must not be negative", c.DBConnectRetry)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package database
+
+import (
+	"fmt"
+	"order-service/models"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func openTestDB(t *testing.T) *gorm.DB {
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", strings.ReplaceAll(t.Name(), "/", "_"))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		sqlDB, err := db.DB()
+		require.NoError(t, err)
+		sqlDB.Close()
+	})
+	return db
+}
+
+func TestMigrateCreatesOrderSchema(t *testing.T) {
+	db := openTestDB(t)
+
+	require.NoError(t, Migrate(db))
+
+	assert.True(t, db.Migrator().HasTable(&models.Order{}))
+	assert.True(t, db.Migrator().HasTable(&models.OrderItem{}))
+	assert.True(t, db.Migrator().HasColumn(&models.OrderItem{}, "order_id"))
+	assert.True(t, db.Migrator().HasTable("schema_migrations"))
+
+	var applied []SchemaMigration
+	require.NoError(t, db.Order("version").Find(&applied).Error)
+	require.Len(t, applied, len(migrations))
+	assert.Equal(t, 1, applied[0].Version)
+}
+
+func TestMigrateIsIdempotent(t *testing.T) {
+	db := openTestDB(t)
+	require.NoError(t, Migrate(db))
+	require.NoError(t, Migrate(db))
+
+	var count int64
+	require.NoError(t, db.Model(&SchemaMigration{}).Count(&count).Error)
+	assert.Equal(t, int64(len(migrations)), count)
+
+	pending, err := Pending(db)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestEnsureSchemaRequiresMigrateWhenNotOnBoot(t *testing.T) {
+	db := openTestDB(t)
+
+	assert.ErrorContains(t, EnsureSchema(db, false), "order-service migrate")
+	assert.False(t, db.Migrator().HasTable(&models.Order{}))
+
+	require.NoError(t, EnsureSchema(db, true))
+	assert.NoError(t, EnsureSchema(db, false))
+}
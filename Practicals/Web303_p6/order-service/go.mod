@@ -3,15 +3,23 @@ module order-service
 go 1.24.0
 
 require (
+	dbutil v0.0.0
+	envconfig v0.0.0
 	github.com/douglasswm/student-cafe-protos v0.0.0
 	github.com/stretchr/testify v1.11.1
 	google.golang.org/grpc v1.76.0
 	gorm.io/driver/postgres v1.4.0
 	gorm.io/driver/sqlite v1.6.0
 	gorm.io/gorm v1.30.0
+	migrate v0.0.0
 )
 
-replace github.com/douglasswm/student-cafe-protos => ../student-cafe-protos
+replace (
+	dbutil => ../dbutil
+	envconfig => ../envconfig
+	github.com/douglasswm/student-cafe-protos => ../student-cafe-protos
+	migrate => ../migrate
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
@@ -49,6 +49,14 @@ func (m *MockUserServiceClient) GetUsers(ctx context.Context, req *userv1.GetUse
 	return args.Get(0).(*userv1.GetUsersResponse), args.Error(1)
 }
 
+func (m *MockUserServiceClient) ListUsersStream(ctx context.Context, req *userv1.ListUsersStreamRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[userv1.User], error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(grpc.ServerStreamingClient[userv1.User]), args.Error(1)
+}
+
 // MockMenuServiceClient is a mock for MenuServiceClient
 type MockMenuServiceClient struct {
 	mock.Mock
@@ -4,50 +4,52 @@ import (
 	"fmt"
 	"log"
 	"net"
-	"os"
+	"order-service/config"
 	"order-service/database"
 	grpcserver "order-service/grpc"
+	"os"
 
 	orderv1 "github.com/douglasswm/student-cafe-protos/gen/go/order/v1"
 	"google.golang.org/grpc"
 )
 
 func main() {
-	// Establish connection to order service database
-	dsn := os.Getenv("DATABASE_URL")
-	if dsn == "" {
-		dsn = "host=localhost user=postgres password=postgres dbname=order_db port=5432 sslmode=disable"
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
 	}
 
-	if err := database.Connect(dsn); err != nil {
-		log.Fatalf("Order service database connection failed: %v", err)
+	database.Pool = database.PoolConfig{
+		MaxOpenConns:    cfg.DBMaxOpenConns,
+		MaxIdleConns:    cfg.DBMaxIdleConns,
+		ConnMaxLifetime: cfg.DBConnMaxLifetime,
 	}
+	database.ConnectRetry = cfg.DBConnectRetry
 
-	// Get gRPC port from environment
-	grpcPort := os.Getenv("GRPC_PORT")
-	if grpcPort == "" {
-		grpcPort = "9093"
+	if err := database.Connect(cfg.DatabaseDSN); err != nil {
+		log.Fatalf("Order service database connection failed: %v", err)
 	}
 
-	// Start listening on TCP port
-	lis, err := net.Listen("tcp", fmt.Sprintf(":%s", grpcPort))
-	if err != nil {
-		log.Fatalf("Failed to listen on gRPC port %s: %v", grpcPort, err)
+	// `order-service migrate` applies pending schema migrations and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := database.Migrate(database.DB); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
 	}
-
-	// Get service addresses for gRPC clients (order service calls user and menu)
-	userServiceAddr := os.Getenv("USER_SERVICE_GRPC_ADDR")
-	if userServiceAddr == "" {
-		userServiceAddr = "user-service:9091"
+	// MIGRATE_ON_BOOT=false requires the migrate subcommand to be run first
+	if err := database.EnsureSchema(database.DB, cfg.MigrateOnBoot); err != nil {
+		log.Fatalf("Database schema not ready: %v", err)
 	}
 
-	menuServiceAddr := os.Getenv("MENU_SERVICE_GRPC_ADDR")
-	if menuServiceAddr == "" {
-		menuServiceAddr = "menu-service:9092"
+	// Start listening on TCP port
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPCPort))
+	if err != nil {
+		log.Fatalf("Failed to listen on gRPC port %d: %v", cfg.GRPCPort, err)
 	}
 
 	// Create order gRPC server with clients to other services
-	orderServer, err := grpcserver.NewOrderServer(userServiceAddr, menuServiceAddr)
+	orderServer, err := grpcserver.NewOrderServer(cfg.UserServiceAddr, cfg.MenuServiceAddr)
 	if err != nil {
 		log.Fatalf("Failed to create gRPC order server: %v", err)
 	}
@@ -56,7 +58,7 @@ func main() {
 	s := grpc.NewServer()
 	orderv1.RegisterOrderServiceServer(s, orderServer)
 
-	log.Printf("Order service (gRPC only) starting on :%s", grpcPort)
+	log.Printf("Order service (gRPC only) starting on :%d", cfg.GRPCPort)
 	if err := s.Serve(lis); err != nil {
 		log.Fatalf("gRPC server failed: %v", err)
 	}
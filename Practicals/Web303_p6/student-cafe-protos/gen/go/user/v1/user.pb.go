@@ -384,6 +384,52 @@ func (x *GetUsersResponse) GetUsers() []*User {
 	return nil
 }
 
+// List users stream request
+type ListUsersStreamRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Users read from the database per page; 0 uses the server's default
+	PageSize      uint32 `protobuf:"varint,1,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListUsersStreamRequest) Reset() {
+	*x = ListUsersStreamRequest{}
+	mi := &file_user_v1_user_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListUsersStreamRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListUsersStreamRequest) ProtoMessage() {}
+
+func (x *ListUsersStreamRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_user_v1_user_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListUsersStreamRequest.ProtoReflect.Descriptor instead.
+func (*ListUsersStreamRequest) Descriptor() ([]byte, []int) {
+	return file_user_v1_user_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ListUsersStreamRequest) GetPageSize() uint32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
 var File_user_v1_user_proto protoreflect.FileDescriptor
 
 const file_user_v1_user_proto_rawDesc = "" +
@@ -410,12 +456,15 @@ const file_user_v1_user_proto_rawDesc = "" +
 	"\x04user\x18\x01 \x01(\v2\r.user.v1.UserR\x04user\"\x11\n" +
 	"\x0fGetUsersRequest\"7\n" +
 	"\x10GetUsersResponse\x12#\n" +
-	"\x05users\x18\x01 \x03(\v2\r.user.v1.UserR\x05users2\xd3\x01\n" +
+	"\x05users\x18\x01 \x03(\v2\r.user.v1.UserR\x05users\"5\n" +
+	"\x16ListUsersStreamRequest\x12\x1b\n" +
+	"\tpage_size\x18\x01 \x01(\rR\bpageSize2\x98\x02\n" +
 	"\vUserService\x12E\n" +
 	"\n" +
 	"CreateUser\x12\x1a.user.v1.CreateUserRequest\x1a\x1b.user.v1.CreateUserResponse\x12<\n" +
 	"\aGetUser\x12\x17.user.v1.GetUserRequest\x1a\x18.user.v1.GetUserResponse\x12?\n" +
-	"\bGetUsers\x12\x18.user.v1.GetUsersRequest\x1a\x19.user.v1.GetUsersResponseBAZ?github.com/douglasswm/student-cafe-protos/gen/go/user/v1;userv1b\x06proto3"
+	"\bGetUsers\x12\x18.user.v1.GetUsersRequest\x1a\x19.user.v1.GetUsersResponse\x12C\n" +
+	"\x0fListUsersStream\x12\x1f.user.v1.ListUsersStreamRequest\x1a\r.user.v1.User0\x01BAZ?github.com/douglasswm/student-cafe-protos/gen/go/user/v1;userv1b\x06proto3"
 
 var (
 	file_user_v1_user_proto_rawDescOnce sync.Once
@@ -429,15 +478,16 @@ func file_user_v1_user_proto_rawDescGZIP() []byte {
 	return file_user_v1_user_proto_rawDescData
 }
 
-var file_user_v1_user_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_user_v1_user_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
 var file_user_v1_user_proto_goTypes = []any{
-	(*User)(nil),               // 0: user.v1.User
-	(*CreateUserRequest)(nil),  // 1: user.v1.CreateUserRequest
-	(*CreateUserResponse)(nil), // 2: user.v1.CreateUserResponse
-	(*GetUserRequest)(nil),     // 3: user.v1.GetUserRequest
-	(*GetUserResponse)(nil),    // 4: user.v1.GetUserResponse
-	(*GetUsersRequest)(nil),    // 5: user.v1.GetUsersRequest
-	(*GetUsersResponse)(nil),   // 6: user.v1.GetUsersResponse
+	(*User)(nil),                   // 0: user.v1.User
+	(*CreateUserRequest)(nil),      // 1: user.v1.CreateUserRequest
+	(*CreateUserResponse)(nil),     // 2: user.v1.CreateUserResponse
+	(*GetUserRequest)(nil),         // 3: user.v1.GetUserRequest
+	(*GetUserResponse)(nil),        // 4: user.v1.GetUserResponse
+	(*GetUsersRequest)(nil),        // 5: user.v1.GetUsersRequest
+	(*GetUsersResponse)(nil),       // 6: user.v1.GetUsersResponse
+	(*ListUsersStreamRequest)(nil), // 7: user.v1.ListUsersStreamRequest
 }
 var file_user_v1_user_proto_depIdxs = []int32{
 	0, // 0: user.v1.CreateUserResponse.user:type_name -> user.v1.User
@@ -446,11 +496,13 @@ var file_user_v1_user_proto_depIdxs = []int32{
 	1, // 3: user.v1.UserService.CreateUser:input_type -> user.v1.CreateUserRequest
 	3, // 4: user.v1.UserService.GetUser:input_type -> user.v1.GetUserRequest
 	5, // 5: user.v1.UserService.GetUsers:input_type -> user.v1.GetUsersRequest
-	2, // 6: user.v1.UserService.CreateUser:output_type -> user.v1.CreateUserResponse
-	4, // 7: user.v1.UserService.GetUser:output_type -> user.v1.GetUserResponse
-	6, // 8: user.v1.UserService.GetUsers:output_type -> user.v1.GetUsersResponse
-	6, // [6:9] is the sub-list for method output_type
-	3, // [3:6] is the sub-list for method input_type
+	7, // 6: user.v1.UserService.ListUsersStream:input_type -> user.v1.ListUsersStreamRequest
+	2, // 7: user.v1.UserService.CreateUser:output_type -> user.v1.CreateUserResponse
+	4, // 8: user.v1.UserService.GetUser:output_type -> user.v1.GetUserResponse
+	6, // 9: user.v1.UserService.GetUsers:output_type -> user.v1.GetUsersResponse
+	0, // 10: user.v1.UserService.ListUsersStream:output_type -> user.v1.User
+	7, // [7:11] is the sub-list for method output_type
+	3, // [3:7] is the sub-list for method input_type
 	3, // [3:3] is the sub-list for extension type_name
 	3, // [3:3] is the sub-list for extension extendee
 	0, // [0:3] is the sub-list for field type_name
@@ -467,7 +519,7 @@ func file_user_v1_user_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_user_v1_user_proto_rawDesc), len(file_user_v1_user_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   7,
+			NumMessages:   8,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -19,9 +19,10 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	UserService_CreateUser_FullMethodName = "/user.v1.UserService/CreateUser"
-	UserService_GetUser_FullMethodName    = "/user.v1.UserService/GetUser"
-	UserService_GetUsers_FullMethodName   = "/user.v1.UserService/GetUsers"
+	UserService_CreateUser_FullMethodName      = "/user.v1.UserService/CreateUser"
+	UserService_GetUser_FullMethodName         = "/user.v1.UserService/GetUser"
+	UserService_GetUsers_FullMethodName        = "/user.v1.UserService/GetUsers"
+	UserService_ListUsersStream_FullMethodName = "/user.v1.UserService/ListUsersStream"
 )
 
 // UserServiceClient is the client API for UserService service.
@@ -36,6 +37,9 @@ type UserServiceClient interface {
 	GetUser(ctx context.Context, in *GetUserRequest, opts ...grpc.CallOption) (*GetUserResponse, error)
 	// Get all users
 	GetUsers(ctx context.Context, in *GetUsersRequest, opts ...grpc.CallOption) (*GetUsersResponse, error)
+	// Stream all users in ID order, one message per user, reading them from
+	// the database a page at a time
+	ListUsersStream(ctx context.Context, in *ListUsersStreamRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[User], error)
 }
 
 type userServiceClient struct {
@@ -76,6 +80,25 @@ func (c *userServiceClient) GetUsers(ctx context.Context, in *GetUsersRequest, o
 	return out, nil
 }
 
+func (c *userServiceClient) ListUsersStream(ctx context.Context, in *ListUsersStreamRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[User], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &UserService_ServiceDesc.Streams[0], UserService_ListUsersStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ListUsersStreamRequest, User]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type UserService_ListUsersStreamClient = grpc.ServerStreamingClient[User]
+
 // UserServiceServer is the server API for UserService service.
 // All implementations must embed UnimplementedUserServiceServer
 // for forward compatibility.
@@ -88,6 +111,9 @@ type UserServiceServer interface {
 	GetUser(context.Context, *GetUserRequest) (*GetUserResponse, error)
 	// Get all users
 	GetUsers(context.Context, *GetUsersRequest) (*GetUsersResponse, error)
+	// Stream all users in ID order, one message per user, reading them from
+	// the database a page at a time
+	ListUsersStream(*ListUsersStreamRequest, grpc.ServerStreamingServer[User]) error
 	mustEmbedUnimplementedUserServiceServer()
 }
 
@@ -107,6 +133,9 @@ func (UnimplementedUserServiceServer) GetUser(context.Context, *GetUserRequest)
 func (UnimplementedUserServiceServer) GetUsers(context.Context, *GetUsersRequest) (*GetUsersResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetUsers not implemented")
 }
+func (UnimplementedUserServiceServer) ListUsersStream(*ListUsersStreamRequest, grpc.ServerStreamingServer[User]) error {
+	return status.Errorf(codes.Unimplemented, "method ListUsersStream not implemented")
+}
 func (UnimplementedUserServiceServer) mustEmbedUnimplementedUserServiceServer() {}
 func (UnimplementedUserServiceServer) testEmbeddedByValue()                     {}
 
@@ -182,6 +211,17 @@ func _UserService_GetUsers_Handler(srv interface{}, ctx context.Context, dec fun
 	return interceptor(ctx, in, info, handler)
 }
 
+func _UserService_ListUsersStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListUsersStreamRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(UserServiceServer).ListUsersStream(m, &grpc.GenericServerStream[ListUsersStreamRequest, User]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type UserService_ListUsersStreamServer = grpc.ServerStreamingServer[User]
+
 // UserService_ServiceDesc is the grpc.ServiceDesc for UserService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -202,6 +242,12 @@ var UserService_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _UserService_GetUsers_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListUsersStream",
+			Handler:       _UserService_ListUsersStream_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "user/v1/user.proto",
 }
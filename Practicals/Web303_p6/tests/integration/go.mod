@@ -3,12 +3,15 @@ module integration-tests
 go 1.24.0
 
 require (
+	dbutil v0.0.0
+	envconfig v0.0.0
 	github.com/douglasswm/student-cafe-protos v0.0.0
 	github.com/stretchr/testify v1.11.1
 	google.golang.org/grpc v1.76.0
 	gorm.io/driver/sqlite v1.6.0
 	gorm.io/gorm v1.30.0
 	menu-service v0.0.0-00010101000000-000000000000
+	migrate v0.0.0
 	order-service v0.0.0-00010101000000-000000000000
 	user-service v0.0.0-00010101000000-000000000000
 )
@@ -32,10 +35,16 @@ require (
 	gorm.io/driver/postgres v1.5.4 // indirect
 )
 
+replace dbutil => ../../dbutil
+
+replace envconfig => ../../envconfig
+
 replace github.com/douglasswm/student-cafe-protos => ../../student-cafe-protos
 
 replace menu-service => ../../menu-service
 
+replace migrate => ../../migrate
+
 replace order-service => ../../order-service
 
 replace user-service => ../../user-service
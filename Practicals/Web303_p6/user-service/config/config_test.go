@@ -0,0 +1,103 @@
+package config
+
+import (
+	"envconfig"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lookupFrom(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := load(lookupFrom(nil))
+	require.NoError(t, err)
+
+	assert.Equal(t, 9091, cfg.GRPCPort)
+	assert.Contains(t, cfg.DatabaseDSN, "dbname=user_db")
+	assert.True(t, cfg.MigrateOnBoot)
+	assert.Equal(t, 25, cfg.DBMaxOpenConns)
+	assert.Equal(t, 5, cfg.DBMaxIdleConns)
+	assert.Equal(t, 30*time.Minute, cfg.DBConnMaxLifetime)
+	assert.Equal(t, time.Minute, cfg.DBConnectRetry)
+	assert.False(t, cfg.SeedData)
+	assert.Equal(t, "seed.json", cfg.SeedFile)
+	assert.Equal(t, 100, cfg.StreamPageSize)
+}
+
+func TestLoadEnvOverrides(t *testing.T) {
+	cfg, err := load(lookupFrom(map[string]string{
+		"GRPC_PORT":             "9292",
+		"DATABASE_URL":          "postgres://svc@db/user_db",
+		"MIGRATE_ON_BOOT":       "false",
+		"DB_MAX_OPEN_CONNS":     "10",
+		"DB_MAX_IDLE_CONNS":     "2",
+		"DB_CONN_MAX_LIFETIME":  "5m",
+		"DB_CONNECT_RETRY":      "2m",
+		"SEED_DATA":             "true",
+		"SEED_FILE":             "/seed/demo.json",
+		"USER_STREAM_PAGE_SIZE": "250",
+	}))
+	require.NoError(t, err)
+
+	assert.Equal(t, Config{
+		GRPCPort:          9292,
+		DatabaseDSN:       "postgres://svc@db/user_db",
+		DBMaxOpenConns:    10,
+		DBMaxIdleConns:    2,
+		DBConnMaxLifetime: 5 * time.Minute,
+		DBConnectRetry:    2 * time.Minute,
+		SeedData:          true,
+		SeedFile:          "/seed/demo.json",
+		StreamPageSize:    250,
+	}, cfg)
+}
+
+func TestLoadRejectsInvalidValues(t *testing.T) {
+	for _, env := range []map[string]string{
+		{"GRPC_PORT": "grpc"},
+		{"GRPC_PORT": "70000"},
+		{"GRPC_PORT": "0"},
+		{"MIGRATE_ON_BOOT": "sometimes"},
+		{"DB_MAX_OPEN_CONNS": "0"},
+		{"DB_MAX_IDLE_CONNS": "-1"},
+		{"DB_MAX_OPEN_CONNS": "4", "DB_MAX_IDLE_CONNS": "8"},
+		{"DB_CONN_MAX_LIFETIME": "forever"},
+		{"DB_CONNECT_RETRY": "-1s"},
+		{"USER_STREAM_PAGE_SIZE": "0"},
+		{"SEED_DATA": "maybe"},
+	} {
+		_, err := load(lookupFrom(env))
+		assert.Error(t, err, env)
+	}
+}
+
+func TestDotEnvFallback(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, envconfig.DotEnvFile), []byte(
+		"# local development\nGRPC_PORT=9191\nexport MIGRATE_ON_BOOT='false'\n\nDATABASE_URL=\"host=db dbname=user_db\"\n"), 0o600))
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { os.Chdir(wd) })
+	t.Setenv("GRPC_PORT", "9292")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, 9292, cfg.GRPCPort, "the environment wins over .env")
+	assert.False(t, cfg.MigrateOnBoot)
+	assert.Equal(t, "host=db dbname=user_db", cfg.DatabaseDSN)
+
+	require.NoError(t, os.WriteFile(envconfig.DotEnvFile, []byte("not a pair\n"), 0o600))
+	_, err = Load()
+	assert.ErrorContains(t, err, ".env:1")
+}
@@ -12,14 +12,25 @@ import (
 	"user-service/models"
 )
 
+// DefaultStreamPageSize is how many users ListUsersStream reads per query
+// when the request doesn't ask for a size.
+const DefaultStreamPageSize = 100
+
+// maxStreamPageSize caps the page_size a client may ask for.
+const maxStreamPageSize = 1000
+
 // UserServer implements the gRPC UserService interface for user operations
 type UserServer struct {
 	userv1.UnimplementedUserServiceServer
+
+	// StreamPageSize is the ListUsersStream page size used when the request
+	// leaves page_size unset
+	StreamPageSize int
 }
 
 // NewUserServer initializes a new gRPC user service server
 func NewUserServer() *UserServer {
-	return &UserServer{}
+	return &UserServer{StreamPageSize: DefaultStreamPageSize}
 }
 
 // CreateUser handles user creation with email validation
@@ -71,6 +82,41 @@ func (s *UserServer) GetUsers(ctx context.Context, req *userv1.GetUsersRequest)
 	}, nil
 }
 
+// ListUsersStream sends every user in ID order, one message each. Users are
+// read a page at a time, keyed on the last ID sent rather than an offset, so
+// memory stays flat and late pages cost no more than the first.
+func (s *UserServer) ListUsersStream(req *userv1.ListUsersStreamRequest, stream userv1.UserService_ListUsersStreamServer) error {
+	pageSize := s.StreamPageSize
+	if req.PageSize > 0 {
+		pageSize = min(int(req.PageSize), maxStreamPageSize)
+	}
+	if pageSize <= 0 {
+		pageSize = DefaultStreamPageSize
+	}
+
+	// The stream context ends when the client goes away
+	db := database.DB.WithContext(stream.Context())
+	var lastID uint
+	for {
+		var users []models.User
+		if err := db.Where("id > ?", lastID).Order("id").Limit(pageSize).Find(&users).Error; err != nil {
+			if ctxErr := stream.Context().Err(); ctxErr != nil {
+				return status.FromContextError(ctxErr).Err()
+			}
+			return status.Errorf(codes.Internal, "failed to list users: %v", err)
+		}
+		for i := range users {
+			if err := stream.Send(modelToProto(&users[i])); err != nil {
+				return err
+			}
+		}
+		if len(users) < pageSize {
+			return nil
+		}
+		lastID = users[len(users)-1].ID
+	}
+}
+
 // modelToProto converts a GORM User model to proto User message
 func modelToProto(user *models.User) *userv1.User {
 	return &userv1.User{
@@ -2,6 +2,10 @@ package grpc
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
 	"testing"
 	"time"
 	"user-service/database"
@@ -10,8 +14,11 @@ import (
 	userv1 "github.com/douglasswm/student-cafe-protos/gen/go/user/v1"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
@@ -237,3 +244,84 @@ func TestModelToProto(t *testing.T) {
 	assert.Equal(t, now.Format(time.RFC3339), protoUser.CreatedAt)
 	assert.Equal(t, now.Format(time.RFC3339), protoUser.UpdatedAt)
 }
+
+// serveBufconn serves server over an in-memory bufconn listener and returns
+// a client connected to it.
+func serveBufconn(t *testing.T, server *UserServer) userv1.UserServiceClient {
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	userv1.RegisterUserServiceServer(s, server)
+	go s.Serve(lis)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+		s.Stop()
+	})
+	return userv1.NewUserServiceClient(conn)
+}
+
+// countQueries counts the SELECTs run against db from now on.
+func countQueries(t *testing.T, db *gorm.DB) *int {
+	var n int
+	require.NoError(t, db.Callback().Query().After("gorm:query").Register("test:count", func(*gorm.DB) { n++ }))
+	return &n
+}
+
+func TestListUsersStreamPagesThroughEveryUser(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+	database.DB = db
+
+	const total = 250
+	users := make([]models.User, total)
+	for i := range users {
+		users[i] = models.User{Name: fmt.Sprintf("User %d", i+1), Email: fmt.Sprintf("user%d@example.com", i+1)}
+	}
+	require.NoError(t, db.CreateInBatches(users, 100).Error)
+	queries := countQueries(t, db)
+
+	server := NewUserServer()
+	server.StreamPageSize = 40
+	client := serveBufconn(t, server)
+
+	receive := func(req *userv1.ListUsersStreamRequest) []*userv1.User {
+		stream, err := client.ListUsersStream(context.Background(), req)
+		require.NoError(t, err)
+		var got []*userv1.User
+		for {
+			user, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				return got
+			}
+			require.NoError(t, err)
+			got = append(got, user)
+		}
+	}
+
+	got := receive(&userv1.ListUsersStreamRequest{})
+	require.Len(t, got, total, "every user arrives, one message each")
+	for i, user := range got {
+		assert.EqualValues(t, i+1, user.Id, "users arrive in ID order")
+	}
+	assert.Equal(t, "user250@example.com", got[total-1].Email)
+	assert.Equal(t, 7, *queries, "250 users in pages of 40 take 7 queries")
+
+	*queries = 0
+	assert.Len(t, receive(&userv1.ListUsersStreamRequest{PageSize: 125}), total)
+	assert.Equal(t, 3, *queries, "page_size overrides the server default; the last page comes back empty")
+}
+
+func TestListUsersStreamEmpty(t *testing.T) {
+	db := setupTestDB(t)
+	defer teardownTestDB(t, db)
+	database.DB = db
+
+	stream, err := serveBufconn(t, NewUserServer()).ListUsersStream(context.Background(), &userv1.ListUsersStreamRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.ErrorIs(t, err, io.EOF)
+}